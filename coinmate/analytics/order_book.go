@@ -0,0 +1,220 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"tourGo/coinmate/public"
+)
+
+const basisPoints = 10000
+
+// Order side
+type Side string
+
+const (
+	Buy  Side = "BUY"
+	Sell Side = "SELL"
+)
+
+// Fill estimate for a market order walking the order book
+type FillEstimate struct {
+	Side            Side    `json:"side"`
+	RequestedAmount float64 `json:"requestedAmount"`
+	FilledAmount    float64 `json:"filledAmount"`
+	Notional        float64 `json:"notional"`
+	AveragePrice    float64 `json:"averagePrice"`
+	BestPrice       float64 `json:"bestPrice"`
+	WorstPrice      float64 `json:"worstPrice"`
+	MidPrice        float64 `json:"midPrice"`
+	Slippage        float64 `json:"slippage"`
+	SlippageBps     float64 `json:"slippageBps"`
+	PriceImpactBps  float64 `json:"priceImpactBps"`
+	LevelsConsumed  int     `json:"levelsConsumed"`
+	Complete        bool    `json:"complete"`
+}
+
+// Liquidity available close to the mid price
+type Liquidity struct {
+	MidPrice    float64 `json:"midPrice"`
+	Percent     float64 `json:"percent"`
+	BidAmount   float64 `json:"bidAmount"`
+	AskAmount   float64 `json:"askAmount"`
+	BidNotional float64 `json:"bidNotional"`
+	AskNotional float64 `json:"askNotional"`
+}
+
+// Return mid price between best bid and best ask
+func MidPrice(book public.OrderBookData) (float64, error) {
+	bid, ask, err := bestPrices(book)
+	if err != nil {
+		return 0, err
+	}
+	return (bid + ask) / 2, nil
+}
+
+// Return absolute spread and spread in basis points of mid
+func Spread(book public.OrderBookData) (float64, float64, error) {
+	bid, ask, err := bestPrices(book)
+	if err != nil {
+		return 0, 0, err
+	}
+	spread := ask - bid
+	return spread, spread / ((bid + ask) / 2) * basisPoints, nil
+}
+
+// Estimate the fill of a market order of the given base currency amount.
+// Buys walk the asks, sells walk the bids. Slippage is measured against the
+// best price on the consumed side and price impact against the mid price;
+// both are positive when the fill is worse than the reference. Only the
+// consumed side is required, mid price and price impact stay zero on a
+// one-sided book.
+func EstimateFill(book public.OrderBookData, side Side, amount float64) (FillEstimate, error) {
+	fe := FillEstimate{Side: side, RequestedAmount: amount}
+
+	if amount <= 0 {
+		return fe, fmt.Errorf("amount must be positive")
+	}
+	levels, err := sideLevels(book, side)
+	if err != nil {
+		return fe, err
+	}
+	remaining := amount
+	for _, level := range levels {
+		if remaining <= 0 {
+			break
+		}
+		take := math.Min(remaining, level.Amount)
+		fe.FilledAmount += take
+		fe.Notional += take * level.Price
+		fe.WorstPrice = level.Price
+		fe.LevelsConsumed++
+		remaining -= take
+	}
+
+	fe.BestPrice = levels[0].Price
+	fe.Complete = remaining <= 0
+	// Levels without amount fill nothing, there is no average price then
+	if fe.FilledAmount <= 0 {
+		return fe, nil
+	}
+	fe.AveragePrice = fe.Notional / fe.FilledAmount
+	fe.Slippage = adverse(side, fe.AveragePrice-fe.BestPrice)
+	fe.SlippageBps = fe.Slippage / fe.BestPrice * basisPoints
+	if mid, err := MidPrice(book); err == nil {
+		fe.MidPrice = mid
+		fe.PriceImpactBps = adverse(side, fe.AveragePrice-mid) / mid * basisPoints
+	}
+
+	return fe, nil
+}
+
+// Return order book imbalance in range <-1, 1> over the top depth levels of
+// each side (all levels when depth <= 0). Positive values mean more bid volume.
+func Imbalance(book public.OrderBookData, depth int) (float64, error) {
+	bids, err := sideLevels(book, Sell)
+	if err != nil {
+		return 0, err
+	}
+	asks, err := sideLevels(book, Buy)
+	if err != nil {
+		return 0, err
+	}
+
+	bidVolume := volume(bids, depth)
+	askVolume := volume(asks, depth)
+	if bidVolume+askVolume == 0 {
+		return 0, fmt.Errorf("order book has no volume")
+	}
+
+	return (bidVolume - askVolume) / (bidVolume + askVolume), nil
+}
+
+// Return liquidity resting within percent (e.g. 0.5 for 0.5 %) of the mid price
+func LiquidityWithin(book public.OrderBookData, percent float64) (Liquidity, error) {
+	l := Liquidity{Percent: percent}
+
+	if percent < 0 {
+		return l, fmt.Errorf("percent must not be negative")
+	}
+	mid, err := MidPrice(book)
+	if err != nil {
+		return l, err
+	}
+	l.MidPrice = mid
+
+	lower := mid * (1 - percent/100)
+	upper := mid * (1 + percent/100)
+	for _, bid := range book.Bids {
+		if bid.Price >= lower {
+			l.BidAmount += bid.Amount
+			l.BidNotional += bid.Amount * bid.Price
+		}
+	}
+	for _, ask := range book.Asks {
+		if ask.Price <= upper {
+			l.AskAmount += ask.Amount
+			l.AskNotional += ask.Amount * ask.Price
+		}
+	}
+
+	return l, nil
+}
+
+// Helper functions
+
+// Return best bid and best ask regardless of the level ordering
+func bestPrices(book public.OrderBookData) (float64, float64, error) {
+	bids, err := sideLevels(book, Sell)
+	if err != nil {
+		return 0, 0, err
+	}
+	asks, err := sideLevels(book, Buy)
+	if err != nil {
+		return 0, 0, err
+	}
+	return bids[0].Price, asks[0].Price, nil
+}
+
+// Return levels consumed by an order of the given side, best price first
+func sideLevels(book public.OrderBookData, side Side) ([]public.OrderBookAsksBids, error) {
+	var levels []public.OrderBookAsksBids
+
+	switch side {
+	case Buy:
+		if len(book.Asks) == 0 {
+			return nil, fmt.Errorf("order book has no asks")
+		}
+		levels = append(levels, book.Asks...)
+		sort.SliceStable(levels, func(i, j int) bool { return levels[i].Price < levels[j].Price })
+	case Sell:
+		if len(book.Bids) == 0 {
+			return nil, fmt.Errorf("order book has no bids")
+		}
+		levels = append(levels, book.Bids...)
+		sort.SliceStable(levels, func(i, j int) bool { return levels[i].Price > levels[j].Price })
+	default:
+		return nil, fmt.Errorf("unknown side %q", side)
+	}
+
+	return levels, nil
+}
+
+func volume(levels []public.OrderBookAsksBids, depth int) float64 {
+	if depth > 0 && depth < len(levels) {
+		levels = levels[:depth]
+	}
+	total := 0.0
+	for _, level := range levels {
+		total += level.Amount
+	}
+	return total
+}
+
+// Flip the sign so that a worse price is always positive
+func adverse(side Side, diff float64) float64 {
+	if side == Sell {
+		return -diff
+	}
+	return diff
+}
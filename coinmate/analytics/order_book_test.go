@@ -0,0 +1,200 @@
+package analytics
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"tourGo/coinmate/public"
+)
+
+const epsilon = 1e-9
+
+func testBook() public.OrderBookData {
+	return public.OrderBookData{
+		Asks: []public.OrderBookAsksBids{
+			{Price: 101.0, Amount: 2.0},
+			{Price: 100.0, Amount: 1.0},
+			{Price: 102.0, Amount: 3.0},
+		},
+		Bids: []public.OrderBookAsksBids{
+			{Price: 99.0, Amount: 1.0},
+			{Price: 98.0, Amount: 2.0},
+		},
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < epsilon
+}
+
+func TestMidPriceAndSpread(t *testing.T) {
+	mid, err := MidPrice(testBook())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !almostEqual(mid, 99.5) {
+		t.Errorf("Expected mid price 99.5, got %f", mid)
+	}
+
+	spread, spreadBps, err := Spread(testBook())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !almostEqual(spread, 1.0) {
+		t.Errorf("Expected spread 1.0, got %f", spread)
+	}
+	if !almostEqual(spreadBps, 1.0/99.5*10000) {
+		t.Errorf("Expected spread bps %f, got %f", 1.0/99.5*10000, spreadBps)
+	}
+}
+
+func TestEstimateFillBuy(t *testing.T) {
+	fe, err := EstimateFill(testBook(), Buy, 2.0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !fe.Complete {
+		t.Error("Expected fill to be complete")
+	}
+	if !almostEqual(fe.AveragePrice, 100.5) {
+		t.Errorf("Expected average price 100.5, got %f", fe.AveragePrice)
+	}
+	if !almostEqual(fe.Notional, 201.0) {
+		t.Errorf("Expected notional 201.0, got %f", fe.Notional)
+	}
+	if fe.BestPrice != 100.0 || fe.WorstPrice != 101.0 {
+		t.Errorf("Expected best/worst 100/101, got %f/%f", fe.BestPrice, fe.WorstPrice)
+	}
+	if fe.LevelsConsumed != 2 {
+		t.Errorf("Expected 2 levels consumed, got %d", fe.LevelsConsumed)
+	}
+	if !almostEqual(fe.Slippage, 0.5) {
+		t.Errorf("Expected slippage 0.5, got %f", fe.Slippage)
+	}
+	if !almostEqual(fe.SlippageBps, 50.0) {
+		t.Errorf("Expected slippage 50 bps, got %f", fe.SlippageBps)
+	}
+	if !almostEqual(fe.PriceImpactBps, 1.0/99.5*10000) {
+		t.Errorf("Expected price impact %f bps, got %f", 1.0/99.5*10000, fe.PriceImpactBps)
+	}
+}
+
+func TestEstimateFillSell(t *testing.T) {
+	fe, err := EstimateFill(testBook(), Sell, 2.0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !almostEqual(fe.AveragePrice, 98.5) {
+		t.Errorf("Expected average price 98.5, got %f", fe.AveragePrice)
+	}
+	if !almostEqual(fe.Slippage, 0.5) {
+		t.Errorf("Expected positive slippage 0.5, got %f", fe.Slippage)
+	}
+	if !almostEqual(fe.PriceImpactBps, 1.0/99.5*10000) {
+		t.Errorf("Expected price impact %f bps, got %f", 1.0/99.5*10000, fe.PriceImpactBps)
+	}
+}
+
+func TestEstimateFillInsufficientDepth(t *testing.T) {
+	fe, err := EstimateFill(testBook(), Buy, 10.0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if fe.Complete {
+		t.Error("Expected fill to be incomplete")
+	}
+	if !almostEqual(fe.FilledAmount, 6.0) {
+		t.Errorf("Expected filled amount 6.0, got %f", fe.FilledAmount)
+	}
+	if fe.LevelsConsumed != 3 {
+		t.Errorf("Expected 3 levels consumed, got %d", fe.LevelsConsumed)
+	}
+}
+
+func TestEstimateFillOneSidedBook(t *testing.T) {
+	book := public.OrderBookData{Asks: []public.OrderBookAsksBids{{Price: 100.0, Amount: 1.0}, {Price: 102.0, Amount: 1.0}}}
+	fe, err := EstimateFill(book, Buy, 2.0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !almostEqual(fe.AveragePrice, 101.0) || !almostEqual(fe.Slippage, 1.0) {
+		t.Errorf("Expected average price 101 and slippage 1, got %f and %f", fe.AveragePrice, fe.Slippage)
+	}
+	if fe.MidPrice != 0 || fe.PriceImpactBps != 0 {
+		t.Errorf("Expected no mid price without bids, got %f", fe.MidPrice)
+	}
+}
+
+func TestEstimateFillEmptyLevels(t *testing.T) {
+	book := testBook()
+	book.Asks = []public.OrderBookAsksBids{{Price: 100.0, Amount: 0}}
+	fe, err := EstimateFill(book, Buy, 1.0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if fe.FilledAmount != 0 || fe.AveragePrice != 0 || fe.Complete {
+		t.Errorf("Expected nothing filled, got %+v", fe)
+	}
+	if _, err := json.Marshal(fe); err != nil {
+		t.Errorf("Expected estimate to encode as JSON, got %v", err)
+	}
+}
+
+func TestEstimateFillInvalidInput(t *testing.T) {
+	if _, err := EstimateFill(testBook(), Buy, 0); err == nil {
+		t.Error("Expected error for zero amount")
+	}
+	if _, err := EstimateFill(testBook(), Side("HOLD"), 1); err == nil {
+		t.Error("Expected error for unknown side")
+	}
+	if _, err := EstimateFill(public.OrderBookData{}, Buy, 1); err == nil {
+		t.Error("Expected error for empty order book")
+	}
+}
+
+func TestImbalance(t *testing.T) {
+	imbalance, err := Imbalance(testBook(), 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !almostEqual(imbalance, -1.0/3.0) {
+		t.Errorf("Expected imbalance -0.333, got %f", imbalance)
+	}
+
+	imbalance, err = Imbalance(testBook(), 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !almostEqual(imbalance, 0) {
+		t.Errorf("Expected top of book imbalance 0, got %f", imbalance)
+	}
+}
+
+func TestLiquidityWithin(t *testing.T) {
+	l, err := LiquidityWithin(testBook(), 1.0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !almostEqual(l.BidAmount, 1.0) || !almostEqual(l.AskAmount, 1.0) {
+		t.Errorf("Expected 1.0 on each side, got bids %f asks %f", l.BidAmount, l.AskAmount)
+	}
+	if !almostEqual(l.BidNotional, 99.0) || !almostEqual(l.AskNotional, 100.0) {
+		t.Errorf("Expected notionals 99/100, got %f/%f", l.BidNotional, l.AskNotional)
+	}
+
+	l, err = LiquidityWithin(testBook(), 5.0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !almostEqual(l.BidAmount, 3.0) || !almostEqual(l.AskAmount, 6.0) {
+		t.Errorf("Expected whole book within 5%%, got bids %f asks %f", l.BidAmount, l.AskAmount)
+	}
+
+	if _, err := LiquidityWithin(testBook(), -1); err == nil {
+		t.Error("Expected error for negative percent")
+	}
+}
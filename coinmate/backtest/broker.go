@@ -0,0 +1,486 @@
+package backtest
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"tourGo/coinmate/matching"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+// Orders travelling to the exchange
+const statusPending = "PENDING"
+
+// Simulated execution
+type Fill = matching.Fill
+
+type order struct {
+	*matching.Order
+	// Quote amount a market buy still spends
+	budget            float64
+	immediateOrCancel bool
+	// Sequence of arrival at the exchange, the time priority
+	arrival         uint64
+	triggered       bool
+	cancelRequested bool
+}
+
+// Request travelling to the exchange
+type action struct {
+	due    int64
+	seq    uint64
+	order  *order
+	cancel bool
+}
+
+// Latest snapshot of a pair, levels best first. Taker fills consume the
+// levels until the next snapshot replaces them.
+type book struct {
+	asks []public.OrderBookAsksBids
+	bids []public.OrderBookAsksBids
+}
+
+// Broker is the simulated exchange a strategy trades against during a
+// backtest. It implements secure.OrderInterface and secure.BalancesInterface.
+//
+// Requests are acknowledged immediately but reach the book only after the
+// latency of the configured model. On arrival, orders crossing the spread
+// take liquidity from the latest order book snapshot as taker, with slippage
+// applied; without a snapshot the last trade price stands in for the touch
+// with unlimited depth. Resting orders fill as maker when a later trade prints
+// at or through their price, never for more than the traded amount. Stop
+// orders activate when a trade reaches the stop price.
+type Broker struct {
+	fees     FeeModel
+	slippage SlippageModel
+	latency  LatencyModel
+
+	now         int64
+	account     *matching.Account
+	orders      map[uint64]*order
+	pending     []*action
+	books       map[string]*book
+	last        map[string]float64
+	fills       []Fill
+	nextOrderId uint64
+	nextSeq     uint64
+}
+
+func newBroker(config Config) *Broker {
+	b := &Broker{
+		fees:     config.Fees,
+		slippage: config.Slippage,
+		latency:  config.Latency,
+		account:  matching.NewAccount("", config.Balances),
+		orders:   make(map[uint64]*order),
+		books:    make(map[string]*book),
+		last:     make(map[string]float64),
+	}
+	if b.fees == nil {
+		b.fees = FlatFees{}
+	}
+	if b.slippage == nil {
+		b.slippage = FixedSlippage(0)
+	}
+	if b.latency == nil {
+		b.latency = FixedLatency(0)
+	}
+	return b
+}
+
+// Current simulated time
+func (b *Broker) Now() time.Time {
+	return time.UnixMilli(b.now)
+}
+
+// Price of the last trade of a pair replayed so far
+func (b *Broker) LastPrice(currencyPair string) (float64, bool) {
+	price, ok := b.last[strings.ToUpper(currencyPair)]
+	return price, ok
+}
+
+// Return all simulated fills, oldest first
+func (b *Broker) Fills() []Fill {
+	return append([]Fill(nil), b.fills...)
+}
+
+// Balances endpoint
+func (b *Broker) GetBalances() (secure.BalancesResponse, error) {
+	return secure.BalancesResponse{Data: b.account.Data()}, nil
+}
+
+// Order history, orders still travelling to the exchange are not included
+func (b *Broker) GetHistory(currencyPair string, limit int64) (secure.OrderHistoryResponse, error) {
+	response := secure.OrderHistoryResponse{Data: []secure.OrderHistoryData{}}
+	for _, o := range b.sortedOrders(currencyPair) {
+		if o.Status == statusPending {
+			continue
+		}
+		if limit > 0 && int64(len(response.Data)) == limit {
+			break
+		}
+		response.Data = append(response.Data, o.HistoryData())
+	}
+	return response, nil
+}
+
+// Open orders
+func (b *Broker) GetOpenOrders(currencyPair string) (secure.OpenOrdersResponse, error) {
+	response := secure.OpenOrdersResponse{Data: []secure.OpenOrdersData{}}
+	for _, o := range b.sortedOrders(currencyPair) {
+		if !o.Open() {
+			continue
+		}
+		response.Data = append(response.Data, o.OpenData())
+	}
+	return response, nil
+}
+
+// Buy limit
+func (b *Broker) BuyLimit(amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (secure.SellLimit, error) {
+	return b.limit(matching.Buy, amount, price, stopPrice, currencyPair, hidden, immediateOrCancel, clientOrderId)
+}
+
+// Sell limit
+func (b *Broker) SellLimit(amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (secure.SellLimit, error) {
+	return b.limit(matching.Sell, amount, price, stopPrice, currencyPair, hidden, immediateOrCancel, clientOrderId)
+}
+
+// Buy instantly for a total in the quote currency
+func (b *Broker) BuyInstant(total float64, cp string, clientOrderId uint64) (secure.BuyAndSellResponse, error) {
+	return b.instant(matching.Buy, total, cp, clientOrderId)
+}
+
+// Sell instantly an amount in the base currency
+func (b *Broker) SellInstant(total float64, cp string, clientOrderId uint64) (secure.BuyAndSellResponse, error) {
+	return b.instant(matching.Sell, total, cp, clientOrderId)
+}
+
+// Cancel order. The cancel reaches the exchange after the latency, so the
+// order can still fill in between; the response only tells whether the
+// request was accepted.
+func (b *Broker) CancelOrder(orderId uint64) (secure.CancelOrderResponse, error) {
+	_, ok := b.requestCancel(orderId)
+	return secure.CancelOrderResponse{Data: ok}, nil
+}
+
+// Cancel order with info, the remaining amount is the one at request time
+func (b *Broker) CancelOrderWithInfo(orderId uint64) (secure.CancelOrderWithInfoResponse, error) {
+	remaining, ok := b.requestCancel(orderId)
+	return secure.CancelOrderWithInfoResponse{Data: secure.CancelOrderWithInfoData{Success: ok, RemainingAmount: remaining}}, nil
+}
+
+// Helper functions
+
+func (b *Broker) limit(side string, amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (secure.SellLimit, error) {
+	response := secure.SellLimit{}
+
+	o, err := b.newOrder(side, currencyPair, matching.Limit, clientOrderId)
+	if err != nil {
+		return response, err
+	}
+	if amount <= 0 || price <= 0 {
+		response.Error = true
+		response.ErrorMessage = "Amount and price must be positive"
+		return response, nil
+	}
+
+	reservePerUnit := 1.0
+	if side == matching.Buy {
+		reservePerUnit = price * (1 + b.fees.Rate(o.Pair, false))
+	}
+	if err := o.Reserve(amount*reservePerUnit, reservePerUnit); err != nil {
+		response.Error = true
+		response.ErrorMessage = err.Error()
+		return response, nil
+	}
+
+	o.Price = price
+	o.StopPrice = stopPrice
+	o.Original = amount
+	o.Remaining = amount
+	o.Hidden = hidden
+	o.immediateOrCancel = immediateOrCancel
+	o.triggered = stopPrice <= 0
+	b.add(o)
+	b.schedule(o, false)
+
+	response.OrderId = o.Id
+	return response, nil
+}
+
+func (b *Broker) instant(side string, quantity float64, currencyPair string, clientOrderId uint64) (secure.BuyAndSellResponse, error) {
+	response := secure.BuyAndSellResponse{}
+
+	o, err := b.newOrder(side, currencyPair, matching.Market, clientOrderId)
+	if err != nil {
+		return response, err
+	}
+	if quantity <= 0 {
+		response.Error = true
+		response.ErrorMessage = "Amount must be positive"
+		return response, nil
+	}
+
+	// Market buys release the cost of each fill, sells the sold amount
+	required, perUnit := quantity, 1.0
+	if side == matching.Buy {
+		required, perUnit = quantity*(1+b.fees.Rate(o.Pair, false)), 0
+	}
+	if err := o.Reserve(required, perUnit); err != nil {
+		response.Error = true
+		response.ErrorMessage = err.Error()
+		return response, nil
+	}
+
+	if side == matching.Buy {
+		o.budget = quantity
+	} else {
+		o.Original = quantity
+		o.Remaining = quantity
+	}
+	o.triggered = true
+	b.add(o)
+	b.schedule(o, false)
+
+	response.OrderId = o.Id
+	return response, nil
+}
+
+func (b *Broker) requestCancel(orderId uint64) (float64, bool) {
+	o, ok := b.orders[orderId]
+	if !ok || o.cancelRequested || o.Type == matching.Market || !(o.Open() || o.Status == statusPending) {
+		return 0, false
+	}
+	o.cancelRequested = true
+	b.schedule(o, true)
+	return o.Remaining, true
+}
+
+func (b *Broker) schedule(o *order, cancel bool) {
+	b.nextSeq++
+	b.pending = append(b.pending, &action{
+		due:    b.now + b.latency.Delay(o.Pair).Milliseconds(),
+		seq:    b.nextSeq,
+		order:  o,
+		cancel: cancel,
+	})
+}
+
+// Process requests reaching the exchange up to the given time
+func (b *Broker) processDue(until int64) {
+	for len(b.pending) > 0 {
+		sort.SliceStable(b.pending, func(i, j int) bool {
+			if b.pending[i].due != b.pending[j].due {
+				return b.pending[i].due < b.pending[j].due
+			}
+			return b.pending[i].seq < b.pending[j].seq
+		})
+		next := b.pending[0]
+		if next.due > until {
+			return
+		}
+		b.pending = b.pending[1:]
+		if next.due > b.now {
+			b.now = next.due
+		}
+		b.arrive(next)
+	}
+}
+
+func (b *Broker) arrive(a *action) {
+	o := a.order
+	if a.cancel {
+		if o.Open() {
+			o.Cancel()
+		}
+		return
+	}
+
+	b.nextSeq++
+	o.arrival = b.nextSeq
+	o.Status = matching.StatusOpen
+	if !o.triggered {
+		return
+	}
+	b.take(o)
+
+	if o.Type == matching.Market {
+		o.Complete()
+		return
+	}
+	if o.Open() && o.immediateOrCancel {
+		o.Cancel()
+	}
+}
+
+// Fill order as taker against the latest snapshot, or the last trade price
+// when there is none
+func (b *Broker) take(o *order) {
+	market := o.Type == matching.Market
+	bk := b.books[o.Pair]
+	if bk == nil {
+		last, ok := b.last[o.Pair]
+		if !ok || (!market && !o.Crosses(last)) {
+			return
+		}
+		levels := []public.OrderBookAsksBids{{Price: last, Amount: math.Inf(1)}}
+		b.takeLevels(o, levels, market)
+		return
+	}
+
+	levels := bk.asks
+	if o.Side == matching.Sell {
+		levels = bk.bids
+	}
+	b.takeLevels(o, levels, market)
+}
+
+func (b *Broker) takeLevels(o *order, levels []public.OrderBookAsksBids, market bool) {
+	for i := range levels {
+		level := &levels[i]
+		if level.Amount <= matching.Epsilon {
+			continue
+		}
+		if !market && !o.Crosses(level.Price) {
+			break
+		}
+
+		var amount, price float64
+		if market && o.Side == matching.Buy {
+			if o.budget <= matching.Epsilon {
+				break
+			}
+			amount = math.Min(level.Amount, o.budget/level.Price)
+			price = b.slippage.Price(o.Side, level.Price, amount)
+			if amount*price > o.budget {
+				amount = o.budget / price
+			}
+			o.budget -= amount * price
+			o.Original += amount
+			o.Remaining += amount
+		} else {
+			if o.Remaining <= matching.Epsilon {
+				break
+			}
+			amount = math.Min(level.Amount, o.Remaining)
+			price = b.slippage.Price(o.Side, level.Price, amount)
+		}
+
+		// A limit order never executes worse than its limit price
+		if !market {
+			if o.Side == matching.Buy {
+				price = math.Min(price, o.Price)
+			} else {
+				price = math.Max(price, o.Price)
+			}
+		}
+		level.Amount -= amount
+		b.fill(o, amount, price, false)
+	}
+}
+
+// Replace the book of a pair with a new snapshot
+func (b *Broker) applyBook(currencyPair string, data public.OrderBookData) {
+	b.books[currencyPair] = &book{asks: matching.Levels(data, matching.Buy), bids: matching.Levels(data, matching.Sell)}
+}
+
+// Trigger stop orders and fill resting orders against a public trade
+func (b *Broker) applyTrade(currencyPair string, tx public.TransactionsData) {
+	b.last[currencyPair] = tx.Price
+
+	var resting []*order
+	for _, o := range b.orders {
+		if o.Pair == currencyPair && o.Open() && o.Type == matching.Limit {
+			resting = append(resting, o)
+		}
+	}
+	sort.Slice(resting, func(i, j int) bool { return resting[i].arrival < resting[j].arrival })
+
+	// Triggered stops take liquidity now and rest from the next trade on
+	var triggered []*order
+	available := tx.Amount
+	for _, o := range resting {
+		if !o.triggered {
+			if o.StopReached(tx.Price) {
+				triggered = append(triggered, o)
+			}
+			continue
+		}
+		if available <= matching.Epsilon || !o.FilledBy(tx) {
+			continue
+		}
+		amount := math.Min(available, o.Remaining)
+		available -= amount
+		b.fill(o, amount, o.Price, true)
+	}
+
+	for _, o := range triggered {
+		o.triggered = true
+		b.take(o)
+		if o.Open() && o.immediateOrCancel {
+			o.Cancel()
+		}
+	}
+}
+
+// Settle fill of an order
+func (b *Broker) fill(o *order, amount, price float64, maker bool) {
+	fee := amount * price * b.fees.Rate(o.Pair, maker)
+	b.fills = append(b.fills, o.Settle(amount, price, fee, maker, b.now))
+}
+
+// Value all balances in the quote currency using last trade prices. Returns
+// false when a held currency has no price yet.
+func (b *Broker) equity(quote string) (float64, bool) {
+	total := 0.0
+	for currency, amount := range b.account.Totals() {
+		if math.Abs(amount) <= matching.Epsilon {
+			continue
+		}
+		if currency == quote {
+			total += amount
+			continue
+		}
+		if price, ok := b.last[currency+"_"+quote]; ok {
+			total += amount * price
+			continue
+		}
+		if price, ok := b.last[quote+"_"+currency]; ok && price > 0 {
+			total += amount / price
+			continue
+		}
+		return 0, false
+	}
+	return total, true
+}
+
+// Return order with the next ID, added once accepted
+func (b *Broker) newOrder(side, currencyPair, orderType string, clientOrderId uint64) (*order, error) {
+	o, err := b.account.NewOrder(b.nextOrderId+1, side, currencyPair, orderType, clientOrderId, b.now)
+	if err != nil {
+		return nil, err
+	}
+	o.Status = statusPending
+	return &order{Order: o}, nil
+}
+
+func (b *Broker) add(o *order) {
+	b.nextOrderId = o.Id
+	b.orders[o.Id] = o
+}
+
+// Return orders of a pair (all pairs when empty), newest first
+func (b *Broker) sortedOrders(currencyPair string) []*order {
+	pair := strings.ToUpper(currencyPair)
+	var orders []*order
+	for _, o := range b.orders {
+		if pair == "" || o.Pair == pair {
+			orders = append(orders, o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].Id > orders[j].Id })
+	return orders
+}
@@ -0,0 +1,166 @@
+package backtest
+
+import (
+	"math"
+	"testing"
+	"time"
+	"tourGo/coinmate/matching"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+func newTestBroker(config Config) *Broker {
+	if config.Balances == nil {
+		config.Balances = map[string]float64{"EUR": 10000, "BTC": 1}
+	}
+	b := newBroker(config)
+	b.applyBook("BTC_EUR", public.OrderBookData{
+		Asks: []public.OrderBookAsksBids{{Price: 50100, Amount: 0.2}, {Price: 50000, Amount: 0.1}},
+		Bids: []public.OrderBookAsksBids{{Price: 49900, Amount: 0.1}, {Price: 49800, Amount: 0.2}},
+	})
+	return b
+}
+
+func TestBrokerImplementsSecureInterfaces(t *testing.T) {
+	var _ secure.OrderInterface = &Broker{}
+	var _ secure.BalancesInterface = &Broker{}
+}
+
+func TestLatencyDelaysArrival(t *testing.T) {
+	b := newTestBroker(Config{Latency: FixedLatency(200 * time.Millisecond)})
+	b.now = 1000
+
+	placed, _ := b.BuyLimit(0.05, 49000, 0, "BTC_EUR", false, false, 0)
+	if open, _ := b.GetOpenOrders("BTC_EUR"); len(open.Data) != 0 {
+		t.Errorf("Expected order not on the book before latency, got %+v", open.Data)
+	}
+	if bal, _ := b.GetBalances(); bal.Data["EUR"].Reserved != 0.05*49000 {
+		t.Errorf("Expected funds reserved at request time, got %f", bal.Data["EUR"].Reserved)
+	}
+
+	b.processDue(1199)
+	if open, _ := b.GetOpenOrders("BTC_EUR"); len(open.Data) != 0 {
+		t.Error("Expected order still in flight")
+	}
+	b.processDue(1200)
+	open, _ := b.GetOpenOrders("BTC_EUR")
+	if len(open.Data) != 1 || open.Data[0].Id != placed.OrderId {
+		t.Errorf("Expected order on the book after latency, got %+v", open.Data)
+	}
+}
+
+func TestCrossingOrderTakesSnapshotWithSlippage(t *testing.T) {
+	b := newTestBroker(Config{Fees: FlatFees{Taker: 0.001}, Slippage: FixedSlippage(10)})
+
+	b.BuyLimit(0.15, 50100, 0, "BTC_EUR", false, false, 0)
+	b.processDue(0)
+
+	fills := b.Fills()
+	if len(fills) != 2 || math.Abs(fills[0].Price-50050) > 1e-6 || fills[1].Price != 50100 {
+		t.Fatalf("Expected slipped fill and fill capped at the limit, got %+v", fills)
+	}
+	if fills[0].Maker || math.Abs(fills[0].Fee-0.1*50050*0.001) > 1e-9 {
+		t.Errorf("Expected taker fee, got %+v", fills[0])
+	}
+
+	// Taken liquidity stays consumed until the next snapshot
+	b.BuyInstant(1000, "BTC_EUR", 0)
+	b.processDue(0)
+	if last := b.Fills()[2]; math.Abs(last.Price-50100*1.001) > 1e-6 {
+		t.Errorf("Expected consumed level to be skipped, got %+v", last)
+	}
+}
+
+func TestLimitOrderWithoutSnapshotUsesLastPrice(t *testing.T) {
+	b := newBroker(Config{Balances: map[string]float64{"EUR": 10000}})
+	b.applyTrade("BTC_EUR", public.TransactionsData{Price: 50000, Amount: 0.01, TradeType: matching.Buy})
+
+	b.BuyLimit(0.1, 49000, 0, "BTC_EUR", false, false, 0)
+	b.BuyLimit(0.1, 51000, 0, "BTC_EUR", false, false, 0)
+	b.processDue(0)
+
+	fills := b.Fills()
+	if len(fills) != 1 || fills[0].OrderId != 2 || fills[0].Price != 50000 || fills[0].Amount != 0.1 {
+		t.Errorf("Expected only the crossing order to fill at the last price, got %+v", fills)
+	}
+}
+
+func TestRestingOrderFillsFromTrades(t *testing.T) {
+	b := newTestBroker(Config{Fees: FlatFees{Maker: 0.0005}})
+
+	first, _ := b.SellLimit(0.3, 50500, 0, "BTC_EUR", false, false, 0)
+	second, _ := b.SellLimit(0.3, 50500, 0, "BTC_EUR", false, false, 0)
+	b.processDue(0)
+
+	b.applyTrade("BTC_EUR", public.TransactionsData{Price: 50600, Amount: 1, TradeType: matching.Sell})
+	b.applyTrade("BTC_EUR", public.TransactionsData{Price: 50400, Amount: 1, TradeType: matching.Buy})
+	b.applyTrade("BTC_EUR", public.TransactionsData{Price: 50500, Amount: 0.4, TradeType: matching.Buy})
+
+	fills := b.Fills()
+	if len(fills) != 2 || fills[0].OrderId != first.OrderId || fills[1].OrderId != second.OrderId {
+		t.Fatalf("Expected fills in time priority, got %+v", fills)
+	}
+	if math.Abs(fills[1].Amount-0.1) > 1e-9 || !fills[1].Maker || fills[1].Price != 50500 {
+		t.Errorf("Expected maker fill limited by the trade amount, got %+v", fills[1])
+	}
+}
+
+func TestStopOrderTriggersOnTrade(t *testing.T) {
+	b := newTestBroker(Config{})
+
+	placed, _ := b.SellLimit(0.2, 49000, 49950, "BTC_EUR", false, false, 0)
+	b.processDue(0)
+	b.applyTrade("BTC_EUR", public.TransactionsData{Price: 50000, Amount: 1})
+	if len(b.Fills()) != 0 {
+		t.Fatal("Expected stop order to wait")
+	}
+
+	b.applyTrade("BTC_EUR", public.TransactionsData{Price: 49900, Amount: 1})
+	history, _ := b.GetHistory("BTC_EUR", 1)
+	if history.Data[0].Id != placed.OrderId || history.Data[0].Status != matching.StatusFilled {
+		t.Errorf("Expected stop order to trigger and take the bids, got %+v", history.Data)
+	}
+}
+
+func TestCancelArrivesAfterLatency(t *testing.T) {
+	b := newTestBroker(Config{Latency: FixedLatency(100 * time.Millisecond)})
+
+	placed, _ := b.SellLimit(0.5, 51000, 0, "BTC_EUR", false, false, 0)
+	b.processDue(100)
+
+	cancelled, _ := b.CancelOrder(placed.OrderId)
+	if !cancelled.Data {
+		t.Fatal("Expected cancel request to be accepted")
+	}
+	if again, _ := b.CancelOrder(placed.OrderId); again.Data {
+		t.Error("Expected duplicate cancel request to be refused")
+	}
+
+	// The order fills while the cancel is in flight
+	b.applyTrade("BTC_EUR", public.TransactionsData{Price: 51000, Amount: 0.2, TradeType: matching.Buy})
+	b.processDue(200)
+
+	history, _ := b.GetHistory("BTC_EUR", 1)
+	if history.Data[0].Status != matching.StatusCancelled || history.Data[0].RemainingAmount != 0.3 {
+		t.Errorf("Expected cancelled order with 0.3 left, got %+v", history.Data[0])
+	}
+	if bal, _ := b.GetBalances(); bal.Data["BTC"].Reserved != 0 || bal.Data["BTC"].Balance != 0.8 {
+		t.Errorf("Expected 0.8 BTC without reservation, got %+v", bal.Data["BTC"])
+	}
+}
+
+func TestInsufficientBalanceIsRejected(t *testing.T) {
+	b := newTestBroker(Config{})
+
+	response, err := b.BuyLimit(1, 50000, 0, "BTC_EUR", false, false, 0)
+	if err != nil || !response.Error {
+		t.Errorf("Expected balance error, got %+v (%v)", response, err)
+	}
+	instant, err := b.SellInstant(2, "BTC_EUR", 0)
+	if err != nil || !instant.Error {
+		t.Errorf("Expected balance error, got %+v (%v)", instant, err)
+	}
+	if _, err := b.BuyInstant(10, "BTCEUR", 0); err == nil {
+		t.Error("Expected error for malformed currency pair")
+	}
+}
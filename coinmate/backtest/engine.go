@@ -0,0 +1,188 @@
+package backtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"tourGo/coinmate/matching"
+	"tourGo/coinmate/public"
+)
+
+// Order book of a pair at a point in time, timestamp in milliseconds.
+// Snapshots are read as JSON lines, one encoded Snapshot per line.
+type Snapshot struct {
+	Timestamp    int64                `json:"timestamp"`
+	CurrencyPair string               `json:"currencyPair"`
+	Book         public.OrderBookData `json:"book"`
+}
+
+// Recorded market data replayed by the backtest. Trades must carry their
+// CurrencyPair, as the ones loaded from recorder.Store do.
+type Data struct {
+	Trades []public.TransactionsData
+	Books  []Snapshot
+}
+
+// Market data event passed to the strategy, either Trade or Book is set
+type Event struct {
+	Timestamp    int64
+	CurrencyPair string
+	Trade        *public.TransactionsData
+	Book         *public.OrderBookData
+}
+
+// Strategy reacts to replayed market data by trading through the broker
+type Strategy interface {
+	OnEvent(broker *Broker, event Event)
+}
+
+// Adapter to use a function as Strategy
+type StrategyFunc func(broker *Broker, event Event)
+
+func (f StrategyFunc) OnEvent(broker *Broker, event Event) {
+	f(broker, event)
+}
+
+// Backtest configuration
+type Config struct {
+	// Currency the equity curve is valued in, e.g. EUR
+	QuoteCurrency string
+
+	// Initial balances, e.g. {"EUR": 10000}
+	Balances map[string]float64
+
+	// Execution models, zero fees, no slippage and no latency when nil
+	Fees     FeeModel
+	Slippage SlippageModel
+	Latency  LatencyModel
+
+	// Spacing of equity curve samples, every event when zero. It is also
+	// the period the Sharpe ratio is computed over.
+	EquityInterval time.Duration
+}
+
+// Replay data through the strategy and return the report. Events are
+// processed in timestamp order, snapshots before trades with the same
+// timestamp. The run is deterministic for the same input and models.
+func Run(config Config, data Data, strategy Strategy) (Report, error) {
+	quote := strings.ToUpper(config.QuoteCurrency)
+	if quote == "" {
+		return Report{}, fmt.Errorf("quote currency must be set")
+	}
+	if strategy == nil {
+		return Report{}, fmt.Errorf("strategy must be set")
+	}
+	if config.EquityInterval < 0 {
+		return Report{}, fmt.Errorf("equity interval must not be negative")
+	}
+
+	events, err := merge(data)
+	if err != nil {
+		return Report{}, err
+	}
+	if len(events) == 0 {
+		return Report{}, fmt.Errorf("no market data to replay")
+	}
+
+	broker := newBroker(config)
+	interval := config.EquityInterval.Milliseconds()
+	var equity []EquityPoint
+	initial, valued := 0.0, false
+	sample := func() {
+		value, ok := broker.equity(quote)
+		if !ok {
+			return
+		}
+		if n := len(equity); n > 0 && equity[n-1].Timestamp == broker.now {
+			equity[n-1].Equity = value
+			return
+		}
+		equity = append(equity, EquityPoint{Timestamp: broker.now, Equity: value})
+	}
+
+	for _, event := range events {
+		broker.processDue(event.Timestamp)
+		if event.Timestamp > broker.now {
+			broker.now = event.Timestamp
+		}
+		if event.Trade != nil {
+			broker.applyTrade(event.CurrencyPair, *event.Trade)
+		} else {
+			broker.applyBook(event.CurrencyPair, *event.Book)
+		}
+		// Value the initial balances before the strategy first trades
+		if !valued {
+			initial, valued = broker.equity(quote)
+		}
+
+		strategy.OnEvent(broker, event)
+		broker.processDue(broker.now)
+
+		if n := len(equity); n == 0 || interval <= 0 || broker.now/interval > equity[n-1].Timestamp/interval {
+			sample()
+		}
+	}
+	// Requests still in flight at the end never reach the exchange
+	sample()
+
+	if !valued || len(equity) == 0 {
+		return Report{}, fmt.Errorf("balances could not be valued in %s, no prices for held currencies", quote)
+	}
+	return newReport(quote, initial, equity, broker.Fills()), nil
+}
+
+// Read order book snapshots stored as JSON lines
+func ReadSnapshots(r io.Reader) ([]Snapshot, error) {
+	var snapshots []Snapshot
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var snapshot Snapshot
+		if err := json.Unmarshal([]byte(text), &snapshot); err != nil {
+			return nil, fmt.Errorf("failed to decode snapshot on line %d: %w", line, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// Helper functions
+
+func merge(data Data) ([]Event, error) {
+	events := make([]Event, 0, len(data.Trades)+len(data.Books))
+	for i := range data.Books {
+		snapshot := &data.Books[i]
+		base, quote, err := matching.SplitPair(snapshot.CurrencyPair)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot at %d: %w", snapshot.Timestamp, err)
+		}
+		events = append(events, Event{Timestamp: snapshot.Timestamp, CurrencyPair: base + "_" + quote, Book: &snapshot.Book})
+	}
+	for i := range data.Trades {
+		trade := &data.Trades[i]
+		base, quote, err := matching.SplitPair(trade.CurrencyPair)
+		if err != nil {
+			return nil, fmt.Errorf("trade %s: %w", trade.TransactionId, err)
+		}
+		events = append(events, Event{Timestamp: trade.Timestamp, CurrencyPair: base + "_" + quote, Trade: trade})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Timestamp != events[j].Timestamp {
+			return events[i].Timestamp < events[j].Timestamp
+		}
+		return events[i].Book != nil && events[j].Book == nil
+	})
+	return events, nil
+}
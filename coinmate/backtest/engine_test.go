@@ -0,0 +1,204 @@
+package backtest
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"tourGo/coinmate/matching"
+	"tourGo/coinmate/public"
+)
+
+func trades(prices ...float64) []public.TransactionsData {
+	var result []public.TransactionsData
+	for i, price := range prices {
+		result = append(result, public.TransactionsData{
+			Timestamp:     int64(i) * time.Minute.Milliseconds(),
+			TransactionId: string(rune('a' + i)),
+			Price:         price,
+			Amount:        1,
+			CurrencyPair:  "BTC_EUR",
+			TradeType:     matching.Buy,
+		})
+	}
+	return result
+}
+
+// Buy on the first trade, sell on the third
+func roundTripStrategy() Strategy {
+	count := 0
+	return StrategyFunc(func(broker *Broker, event Event) {
+		count++
+		switch count {
+		case 1:
+			broker.BuyInstant(900, "BTC_EUR", 0)
+		case 3:
+			broker.SellInstant(0.018, "BTC_EUR", 0)
+		}
+	})
+}
+
+func TestRunRoundTrip(t *testing.T) {
+	config := Config{
+		QuoteCurrency: "EUR",
+		Balances:      map[string]float64{"EUR": 1000},
+		Fees:          FlatFees{Taker: 0.001},
+	}
+	report, err := Run(config, Data{Trades: trades(50000, 51000, 52000, 49000)}, roundTripStrategy())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(report.Fills) != 2 || report.Fills[0].Price != 50000 || report.Fills[1].Price != 52000 {
+		t.Fatalf("Expected fills at 50000 and 52000, got %+v", report.Fills)
+	}
+	if len(report.Trades) != 1 {
+		t.Fatalf("Expected one round trip, got %+v", report.Trades)
+	}
+	trade := report.Trades[0]
+	expected := 0.018*2000 - 0.018*50000*0.001 - 0.018*52000*0.001
+	if trade.Side != matching.Buy || math.Abs(trade.PnL-expected) > 1e-9 {
+		t.Errorf("Expected long trade with PnL %f, got %+v", expected, trade)
+	}
+
+	if report.InitialEquity != 1000 || math.Abs(report.FinalEquity-(1000+expected)) > 1e-9 {
+		t.Errorf("Unexpected equity %f -> %f", report.InitialEquity, report.FinalEquity)
+	}
+	if math.Abs(report.Turnover-(900+936)) > 1e-9 || math.Abs(report.TurnoverRatio-1.836) > 1e-9 {
+		t.Errorf("Unexpected turnover %f (%f)", report.Turnover, report.TurnoverRatio)
+	}
+	if len(report.Equity) != 4 || report.Start != 0 || report.End != 3*time.Minute.Milliseconds() {
+		t.Errorf("Expected equity sample per event, got %+v", report.Equity)
+	}
+}
+
+func TestRunDrawdownAndSharpe(t *testing.T) {
+	config := Config{
+		QuoteCurrency:  "EUR",
+		Balances:       map[string]float64{"BTC": 1},
+		EquityInterval: time.Minute,
+	}
+	report, err := Run(config, Data{Trades: trades(100, 120, 90, 110)}, StrategyFunc(func(*Broker, Event) {}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if math.Abs(report.MaxDrawdown-0.25) > 1e-9 {
+		t.Errorf("Expected 25 %% drawdown from 120 to 90, got %f", report.MaxDrawdown)
+	}
+	if report.Equity[2].Drawdown != report.MaxDrawdown || report.Equity[1].Drawdown != 0 {
+		t.Errorf("Unexpected drawdown curve %+v", report.Equity)
+	}
+
+	returns := []float64{0.2, -0.25, 110.0/90 - 1}
+	mean := (returns[0] + returns[1] + returns[2]) / 3
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	expected := mean / math.Sqrt(variance/2) * math.Sqrt(365*24*60)
+	if math.Abs(report.Sharpe-expected) > 1e-9 {
+		t.Errorf("Expected Sharpe %f, got %f", expected, report.Sharpe)
+	}
+}
+
+func TestRunLatencyUsesLaterSnapshot(t *testing.T) {
+	books := []Snapshot{
+		{Timestamp: 0, CurrencyPair: "btc_eur", Book: public.OrderBookData{Asks: []public.OrderBookAsksBids{{Price: 50000, Amount: 1}}}},
+		{Timestamp: 500, CurrencyPair: "BTC_EUR", Book: public.OrderBookData{Asks: []public.OrderBookAsksBids{{Price: 50500, Amount: 1}}}},
+	}
+	data := Data{Books: books, Trades: []public.TransactionsData{{Timestamp: 1000, Price: 50500, Amount: 1, CurrencyPair: "BTC_EUR"}}}
+
+	sent := false
+	strategy := StrategyFunc(func(broker *Broker, event Event) {
+		if !sent {
+			sent = true
+			broker.BuyLimit(0.01, 51000, 0, "BTC_EUR", false, false, 0)
+		}
+	})
+
+	config := Config{QuoteCurrency: "EUR", Balances: map[string]float64{"EUR": 1000}, Latency: FixedLatency(600 * time.Millisecond)}
+	report, err := Run(config, data, strategy)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(report.Fills) != 1 || report.Fills[0].Price != 50500 || report.Fills[0].Timestamp != 600 {
+		t.Errorf("Expected fill at 50500 on arrival at 600 ms, got %+v", report.Fills)
+	}
+}
+
+func TestRunIsDeterministic(t *testing.T) {
+	config := func() Config {
+		return Config{
+			QuoteCurrency: "EUR",
+			Balances:      map[string]float64{"EUR": 1000},
+			Latency:       NewRandomLatency(0, 2*time.Minute, 42),
+			Slippage:      FixedSlippage(5),
+		}
+	}
+	data := Data{Trades: trades(50000, 51000, 52000, 49000, 50000, 51000)}
+
+	first, err := Run(config(), data, roundTripStrategy())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	second, _ := Run(config(), data, roundTripStrategy())
+	if !reflect.DeepEqual(first, second) {
+		t.Error("Expected identical reports for identical runs")
+	}
+}
+
+func TestRunValidation(t *testing.T) {
+	noop := StrategyFunc(func(*Broker, Event) {})
+	data := Data{Trades: trades(100)}
+
+	if _, err := Run(Config{}, data, noop); err == nil {
+		t.Error("Expected error without quote currency")
+	}
+	if _, err := Run(Config{QuoteCurrency: "EUR"}, Data{}, noop); err == nil {
+		t.Error("Expected error without data")
+	}
+	if _, err := Run(Config{QuoteCurrency: "EUR"}, Data{Trades: []public.TransactionsData{{Price: 1}}}, noop); err == nil {
+		t.Error("Expected error for trade without currency pair")
+	}
+	if _, err := Run(Config{QuoteCurrency: "EUR", Balances: map[string]float64{"ETH": 1}}, data, noop); err == nil {
+		t.Error("Expected error when balances cannot be valued")
+	}
+}
+
+func TestShortRoundTrip(t *testing.T) {
+	fills := []Fill{
+		{CurrencyPair: "BTC_EUR", Side: matching.Sell, Price: 100, Amount: 2, Fee: 2, Timestamp: 1},
+		{CurrencyPair: "BTC_EUR", Side: matching.Buy, Price: 90, Amount: 1, Fee: 1, Timestamp: 2},
+		{CurrencyPair: "BTC_EUR", Side: matching.Buy, Price: 110, Amount: 2, Timestamp: 3},
+	}
+	trades := roundTrips(fills)
+	if len(trades) != 2 {
+		t.Fatalf("Expected two round trips, got %+v", trades)
+	}
+	if trades[0].Side != matching.Sell || trades[0].PnL != 10-1-1 {
+		t.Errorf("Expected short profit net of fees, got %+v", trades[0])
+	}
+	if trades[1].PnL != -10-1 || trades[1].Amount != 1 {
+		t.Errorf("Expected remaining short closed at a loss, got %+v", trades[1])
+	}
+}
+
+func TestReadSnapshots(t *testing.T) {
+	input := `{"timestamp":1,"currencyPair":"BTC_EUR","book":{"asks":[{"price":50000,"amount":1}],"bids":[]}}
+
+{"timestamp":2,"currencyPair":"BTC_EUR","book":{"asks":[],"bids":[{"price":49000,"amount":2}]}}
+`
+	snapshots, err := ReadSnapshots(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Book.Asks[0].Price != 50000 || snapshots[1].Book.Bids[0].Amount != 2 {
+		t.Errorf("Unexpected snapshots %+v", snapshots)
+	}
+
+	if _, err := ReadSnapshots(strings.NewReader("{broken")); err == nil {
+		t.Error("Expected error for corrupt line")
+	}
+}
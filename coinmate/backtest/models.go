@@ -0,0 +1,74 @@
+package backtest
+
+import (
+	"math/rand"
+	"time"
+	"tourGo/coinmate/matching"
+)
+
+// Fee rate charged on the notional of a fill, e.g. 0.0025 for 0.25 %
+type FeeModel interface {
+	Rate(currencyPair string, maker bool) float64
+}
+
+// Execution price of a taker fill, given the price it would get without
+// market impact. Maker fills are not adjusted.
+type SlippageModel interface {
+	Price(side string, price, amount float64) float64
+}
+
+// Delay between a strategy sending a request and the exchange processing it
+type LatencyModel interface {
+	Delay(currencyPair string) time.Duration
+}
+
+// Same maker and taker rate for every pair
+type FlatFees struct {
+	Maker float64
+	Taker float64
+}
+
+func (f FlatFees) Rate(currencyPair string, maker bool) float64 {
+	if maker {
+		return f.Maker
+	}
+	return f.Taker
+}
+
+// Slippage in basis points against the taker, 10 means buying 0.1 % higher
+type FixedSlippage float64
+
+func (s FixedSlippage) Price(side string, price, amount float64) float64 {
+	if side == matching.Buy {
+		return price * (1 + float64(s)/10000)
+	}
+	return price * (1 - float64(s)/10000)
+}
+
+// Constant delay for every request
+type FixedLatency time.Duration
+
+func (l FixedLatency) Delay(currencyPair string) time.Duration {
+	return time.Duration(l)
+}
+
+// Uniformly distributed delay between Min and Max. The sequence is fixed by
+// the seed, so runs are reproducible.
+type RandomLatency struct {
+	Min time.Duration
+	Max time.Duration
+
+	rand *rand.Rand
+}
+
+// Return random latency model with the given seed
+func NewRandomLatency(min, max time.Duration, seed int64) *RandomLatency {
+	return &RandomLatency{Min: min, Max: max, rand: rand.New(rand.NewSource(seed))}
+}
+
+func (l *RandomLatency) Delay(currencyPair string) time.Duration {
+	if l.Max <= l.Min {
+		return l.Min
+	}
+	return l.Min + time.Duration(l.rand.Int63n(int64(l.Max-l.Min)+1))
+}
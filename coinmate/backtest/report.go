@@ -0,0 +1,184 @@
+package backtest
+
+import (
+	"math"
+	"sort"
+	"time"
+	"tourGo/coinmate/matching"
+)
+
+const year = 365 * 24 * time.Hour
+
+// Equity sample, drawdown is the fraction below the running peak
+type EquityPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Equity    float64 `json:"equity"`
+	Drawdown  float64 `json:"drawdown"`
+}
+
+// Closed round trip, matched from fills first in first out. PnL is in the
+// quote currency of the pair and net of entry and exit fees.
+type Trade struct {
+	CurrencyPair string  `json:"currencyPair"`
+	Side         string  `json:"side"`
+	Amount       float64 `json:"amount"`
+	EntryPrice   float64 `json:"entryPrice"`
+	ExitPrice    float64 `json:"exitPrice"`
+	EntryTime    int64   `json:"entryTime"`
+	ExitTime     int64   `json:"exitTime"`
+	Fees         float64 `json:"fees"`
+	PnL          float64 `json:"pnl"`
+}
+
+// Backtest results, equity values are in QuoteCurrency. InitialEquity values
+// the balances before the strategy first trades.
+type Report struct {
+	QuoteCurrency string `json:"quoteCurrency"`
+	Start         int64  `json:"start"`
+	End           int64  `json:"end"`
+
+	InitialEquity float64 `json:"initialEquity"`
+	FinalEquity   float64 `json:"finalEquity"`
+	Return        float64 `json:"return"`
+	MaxDrawdown   float64 `json:"maxDrawdown"`
+
+	// Annualized from the returns between equity samples, risk-free rate 0
+	Sharpe float64 `json:"sharpe"`
+
+	// Traded notional, and the same relative to the initial equity
+	Turnover      float64 `json:"turnover"`
+	TurnoverRatio float64 `json:"turnoverRatio"`
+	Fees          float64 `json:"fees"`
+
+	Equity []EquityPoint `json:"equity"`
+	Fills  []Fill        `json:"fills"`
+	Trades []Trade       `json:"trades"`
+}
+
+// Open position left by fills not closed yet
+type lot struct {
+	side   string
+	amount float64
+	price  float64
+	fee    float64
+	time   int64
+}
+
+func newReport(quote string, initial float64, equity []EquityPoint, fills []Fill) Report {
+	report := Report{
+		QuoteCurrency: quote,
+		Start:         equity[0].Timestamp,
+		End:           equity[len(equity)-1].Timestamp,
+		InitialEquity: initial,
+		FinalEquity:   equity[len(equity)-1].Equity,
+		Equity:        equity,
+		Fills:         fills,
+		Trades:        roundTrips(fills),
+	}
+	if report.InitialEquity != 0 {
+		report.Return = report.FinalEquity/report.InitialEquity - 1
+	}
+
+	peak := initial
+	for i := range equity {
+		peak = math.Max(peak, equity[i].Equity)
+		if peak > 0 {
+			equity[i].Drawdown = 1 - equity[i].Equity/peak
+		}
+		report.MaxDrawdown = math.Max(report.MaxDrawdown, equity[i].Drawdown)
+	}
+	report.Sharpe = sharpe(equity)
+
+	for _, f := range fills {
+		report.Turnover += f.Amount * f.Price
+		report.Fees += f.Fee
+	}
+	if report.InitialEquity > 0 {
+		report.TurnoverRatio = report.Turnover / report.InitialEquity
+	}
+	return report
+}
+
+func sharpe(equity []EquityPoint) float64 {
+	if len(equity) < 3 {
+		return 0
+	}
+	returns := make([]float64, 0, len(equity)-1)
+	for i := 1; i < len(equity); i++ {
+		if equity[i-1].Equity <= 0 {
+			return 0
+		}
+		returns = append(returns, equity[i].Equity/equity[i-1].Equity-1)
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	std := math.Sqrt(variance / float64(len(returns)-1))
+
+	period := float64(equity[len(equity)-1].Timestamp-equity[0].Timestamp) / float64(len(returns))
+	if std == 0 || period <= 0 {
+		return 0
+	}
+	periodsPerYear := float64(year.Milliseconds()) / period
+	return mean / std * math.Sqrt(periodsPerYear)
+}
+
+// Match fills of each pair into round trips, a fill first closes opposite
+// lots and opens a new lot with what is left
+func roundTrips(fills []Fill) []Trade {
+	open := map[string][]*lot{}
+	trades := []Trade{}
+	for _, f := range fills {
+		lots := open[f.CurrencyPair]
+		remaining := f.Amount
+		for len(lots) > 0 && remaining > matching.Epsilon && lots[0].side != f.Side {
+			l := lots[0]
+			amount := math.Min(remaining, l.amount)
+			entryFee := l.fee * amount / l.amount
+			exitFee := f.Fee * amount / f.Amount
+
+			direction := 1.0
+			if l.side == matching.Sell {
+				direction = -1
+			}
+			trades = append(trades, Trade{
+				CurrencyPair: f.CurrencyPair,
+				Side:         l.side,
+				Amount:       amount,
+				EntryPrice:   l.price,
+				ExitPrice:    f.Price,
+				EntryTime:    l.time,
+				ExitTime:     f.Timestamp,
+				Fees:         entryFee + exitFee,
+				PnL:          direction*(f.Price-l.price)*amount - entryFee - exitFee,
+			})
+
+			l.fee -= entryFee
+			l.amount -= amount
+			remaining -= amount
+			if l.amount <= matching.Epsilon {
+				lots = lots[1:]
+			}
+		}
+		if remaining > matching.Epsilon {
+			lots = append(lots, &lot{
+				side:   f.Side,
+				amount: remaining,
+				price:  f.Price,
+				fee:    f.Fee * remaining / f.Amount,
+				time:   f.Timestamp,
+			})
+		}
+		open[f.CurrencyPair] = lots
+	}
+
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].ExitTime < trades[j].ExitTime })
+	return trades
+}
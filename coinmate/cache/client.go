@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate"
+)
+
+// Reference data endpoints cached by default
+const (
+	TradingPairsEndpoint  = "/tradingPairs"
+	CurrenciesEndpoint    = "/currencies"
+	CurrencyPairsEndpoint = "/currency-pairs"
+	ServerTimeEndpoint    = "/system/get-server-time"
+)
+
+// Caching policy of a single endpoint. A response is fresh for TTL and is
+// served stale for another StaleTTL while it is refreshed in the background.
+type Policy struct {
+	TTL      time.Duration
+	StaleTTL time.Duration
+}
+
+// Return default policies for reference data endpoints. Server time is
+// cached only briefly, callers needing an exact clock should not cache it.
+func DefaultPolicies() map[string]Policy {
+	return map[string]Policy{
+		TradingPairsEndpoint:  {TTL: time.Hour, StaleTTL: 24 * time.Hour},
+		CurrenciesEndpoint:    {TTL: time.Hour, StaleTTL: 24 * time.Hour},
+		CurrencyPairsEndpoint: {TTL: time.Hour, StaleTTL: 24 * time.Hour},
+		ServerTimeEndpoint:    {TTL: time.Second},
+	}
+}
+
+type entry struct {
+	response coinmate.Response
+	fetched  time.Time
+}
+
+// In-flight request shared by concurrent callers
+type call struct {
+	done     chan struct{}
+	response coinmate.Response
+	err      error
+}
+
+// Client decorates a ClientInterface and caches successful public GET
+// responses of the configured endpoints. Secure requests and endpoints
+// without a policy are passed through untouched, so the decorator can be
+// handed to any public or secure service:
+//
+//	cached := cache.NewClient(client, cache.DefaultPolicies())
+//	pairs := &public.TradingPairs{Client: cached}
+type Client struct {
+	coinmate.ClientInterface
+
+	// Called when a background refresh fails, the stale entry is kept
+	OnRefreshError func(endpoint string, err error)
+
+	policies   map[string]Policy
+	mu         sync.Mutex
+	entries    map[string]entry
+	calls      map[string]*call
+	generation uint64
+	now        func() time.Time
+}
+
+// Return caching client with per-endpoint policies keyed by endpoint path
+// relative to the base URL, e.g. "/tradingPairs"
+func NewClient(client coinmate.ClientInterface, policies map[string]Policy) *Client {
+	p := make(map[string]Policy, len(policies))
+	for endpoint, policy := range policies {
+		p[endpoint] = policy
+	}
+	return &Client{
+		ClientInterface: client,
+		policies:        p,
+		entries:         make(map[string]entry),
+		calls:           make(map[string]*call),
+		now:             time.Now,
+	}
+}
+
+// Make public request, served from cache when the endpoint has a policy
+func (c *Client) MakePublicRequest(r coinmate.Request) (coinmate.Response, error) {
+	endpoint := c.endpoint(r)
+	policy, ok := c.policies[endpoint]
+	if !ok || r.HTTPMethod != http.MethodGet || policy.TTL <= 0 {
+		return c.ClientInterface.MakePublicRequest(r)
+	}
+
+	key := r.URL
+	c.mu.Lock()
+	e, cached := c.entries[key]
+	age := c.now().Sub(e.fetched)
+	c.mu.Unlock()
+
+	if cached && age < policy.TTL {
+		return copyResponse(e.response), nil
+	}
+	if cached && age < policy.TTL+policy.StaleTTL {
+		c.refresh(key, endpoint, r)
+		return copyResponse(e.response), nil
+	}
+
+	return c.fetch(key, r)
+}
+
+// Drop cached responses of an endpoint, e.g. "/tradingPairs"
+func (c *Client) Invalidate(endpoint string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for key := range c.entries {
+		if c.endpoint(coinmate.Request{URL: key}) == endpoint {
+			delete(c.entries, key)
+		}
+	}
+	for key := range c.calls {
+		if c.endpoint(coinmate.Request{URL: key}) == endpoint {
+			delete(c.calls, key)
+		}
+	}
+}
+
+// Drop all cached responses
+func (c *Client) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries = make(map[string]entry)
+	c.calls = make(map[string]*call)
+}
+
+// Helper functions
+
+// Refresh entry in the background unless a request is already in flight
+func (c *Client) refresh(key, endpoint string, r coinmate.Request) {
+	c.mu.Lock()
+	_, busy := c.calls[key]
+	c.mu.Unlock()
+	if busy {
+		return
+	}
+
+	go func() {
+		if _, err := c.fetch(key, r); err != nil && c.OnRefreshError != nil {
+			c.OnRefreshError(endpoint, err)
+		}
+	}()
+}
+
+// Perform request once for all concurrent callers of the same key
+func (c *Client) fetch(key string, r coinmate.Request) (coinmate.Response, error) {
+	c.mu.Lock()
+	if inFlight, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		<-inFlight.done
+		return copyResponse(inFlight.response), inFlight.err
+	}
+	current := &call{done: make(chan struct{})}
+	c.calls[key] = current
+	generation := c.generation
+	c.mu.Unlock()
+
+	current.response, current.err = c.ClientInterface.MakePublicRequest(r)
+
+	c.mu.Lock()
+	// Responses started before an invalidation are not stored
+	if current.err == nil && cacheable(current.response) && generation == c.generation {
+		c.entries[key] = entry{response: current.response, fetched: c.now()}
+	}
+	if c.calls[key] == current {
+		delete(c.calls, key)
+	}
+	c.mu.Unlock()
+	close(current.done)
+
+	return copyResponse(current.response), current.err
+}
+
+// Return endpoint path relative to the base URL
+func (c *Client) endpoint(r coinmate.Request) string {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return ""
+	}
+	base, err := url.Parse(c.ClientInterface.GetBaseUrl())
+	if err != nil {
+		return u.Path
+	}
+	return strings.TrimPrefix(u.Path, strings.TrimSuffix(base.Path, "/"))
+}
+
+// Only successful responses without an API error are cached
+func cacheable(response coinmate.Response) bool {
+	if response.StatusCode != http.StatusOK {
+		return false
+	}
+	apiError := struct {
+		Error bool `json:"error"`
+	}{}
+	if err := json.Unmarshal(response.Body, &apiError); err != nil {
+		return false
+	}
+	return !apiError.Error
+}
+
+func copyResponse(response coinmate.Response) coinmate.Response {
+	if response.Body != nil {
+		response.Body = append([]byte(nil), response.Body...)
+	}
+	return response
+}
@@ -0,0 +1,271 @@
+package cache
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"tourGo/coinmate"
+	"tourGo/coinmate/public"
+)
+
+const tradingPairsBody = `{
+	"error": false,
+	"errorMessage": null,
+	"data": [{"name": "BTC_EUR", "firstCurrency": "BTC", "secondCurrency": "EUR", "priceDecimals": 2, "lotDecimals": 8, "minAmount": 0.0002}]
+}`
+
+// Mock client for testing, counts public requests
+type MockClient struct {
+	coinmate.ClientInterface
+	response *coinmate.Response
+	err      error
+	release  chan struct{}
+	requests int32
+}
+
+func (m *MockClient) GetBaseUrl() string {
+	return "https://coinmate.io/api"
+}
+
+func (m *MockClient) MakePublicRequest(r coinmate.Request) (coinmate.Response, error) {
+	atomic.AddInt32(&m.requests, 1)
+	if m.release != nil {
+		<-m.release
+	}
+	if m.err != nil {
+		return coinmate.Response{}, m.err
+	}
+	return *m.response, nil
+}
+
+func (m *MockClient) MakeSecureRequest(r coinmate.Request) (coinmate.Response, error) {
+	atomic.AddInt32(&m.requests, 1)
+	return *m.response, nil
+}
+
+func (m *MockClient) count() int32 {
+	return atomic.LoadInt32(&m.requests)
+}
+
+func okResponse(body string) *coinmate.Response {
+	return &coinmate.Response{StatusCode: http.StatusOK, Status: "200 OK", Body: []byte(body)}
+}
+
+// Fake clock safe for use from background refreshes
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func newTestClient(mock *MockClient, policies map[string]Policy) (*Client, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1704067200, 0)}
+	c := NewClient(mock, policies)
+	c.now = clock.Now
+	return c, clock
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCachedWithinTTL(t *testing.T) {
+	mock := &MockClient{response: okResponse(tradingPairsBody)}
+	c, clock := newTestClient(mock, DefaultPolicies())
+	pairs := &public.TradingPairs{Client: c}
+
+	for i := 0; i < 3; i++ {
+		response, err := pairs.GetTradingPairs()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(response.Data) != 1 || response.Data[0].Name != "BTC_EUR" {
+			t.Errorf("Unexpected response %+v", response)
+		}
+	}
+	if mock.count() != 1 {
+		t.Errorf("Expected 1 upstream request, got %d", mock.count())
+	}
+
+	clock.Advance(time.Hour + 25*time.Hour)
+	if _, err := pairs.GetTradingPairs(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if mock.count() != 2 {
+		t.Errorf("Expected expired entry to be fetched again, got %d requests", mock.count())
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	mock := &MockClient{response: okResponse(tradingPairsBody)}
+	c, clock := newTestClient(mock, map[string]Policy{
+		TradingPairsEndpoint: {TTL: time.Minute, StaleTTL: time.Hour},
+	})
+	pairs := &public.TradingPairs{Client: c}
+
+	pairs.GetTradingPairs()
+	clock.Advance(2 * time.Minute)
+
+	response, err := pairs.GetTradingPairs()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(response.Data) != 1 {
+		t.Errorf("Expected stale data to be served, got %+v", response)
+	}
+
+	waitFor(t, func() bool { return mock.count() == 2 })
+	waitFor(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.calls) == 0
+	})
+
+	pairs.GetTradingPairs()
+	if mock.count() != 2 {
+		t.Errorf("Expected refreshed entry to be fresh, got %d requests", mock.count())
+	}
+}
+
+func TestRefreshErrorKeepsStaleEntry(t *testing.T) {
+	mock := &MockClient{response: okResponse(tradingPairsBody)}
+	c, clock := newTestClient(mock, map[string]Policy{
+		TradingPairsEndpoint: {TTL: time.Minute, StaleTTL: time.Hour},
+	})
+	refreshErrors := make(chan error, 1)
+	c.OnRefreshError = func(endpoint string, err error) { refreshErrors <- err }
+	pairs := &public.TradingPairs{Client: c}
+
+	pairs.GetTradingPairs()
+	clock.Advance(2 * time.Minute)
+	mock.err = errors.New("connection refused")
+
+	if _, err := pairs.GetTradingPairs(); err != nil {
+		t.Fatalf("Expected stale entry without error, got %v", err)
+	}
+	select {
+	case <-refreshErrors:
+	case <-time.After(time.Second):
+		t.Fatal("Expected refresh error to be reported")
+	}
+}
+
+func TestConcurrentRequestsAreDeduplicated(t *testing.T) {
+	mock := &MockClient{response: okResponse(`{"error": false, "data": ["BTC", "EUR"]}`), release: make(chan struct{})}
+	c, _ := newTestClient(mock, DefaultPolicies())
+	currencies := &public.Currencies{Client: c}
+
+	var wg sync.WaitGroup
+	results := make(chan int, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := currencies.GetCurrencies()
+			if err == nil {
+				results <- len(response.Data)
+			}
+		}()
+	}
+
+	waitFor(t, func() bool { return mock.count() == 1 })
+	close(mock.release)
+	wg.Wait()
+	close(results)
+
+	n := 0
+	for length := range results {
+		if length != 2 {
+			t.Errorf("Expected 2 currencies, got %d", length)
+		}
+		n++
+	}
+	if n != 10 {
+		t.Errorf("Expected 10 successful callers, got %d", n)
+	}
+	if mock.count() != 1 {
+		t.Errorf("Expected 1 upstream request, got %d", mock.count())
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	mock := &MockClient{response: okResponse(tradingPairsBody)}
+	c, _ := newTestClient(mock, DefaultPolicies())
+	pairs := &public.TradingPairs{Client: c}
+
+	pairs.GetTradingPairs()
+	c.Invalidate(CurrenciesEndpoint)
+	pairs.GetTradingPairs()
+	if mock.count() != 1 {
+		t.Errorf("Expected unrelated invalidation to keep entry, got %d requests", mock.count())
+	}
+
+	c.Invalidate(TradingPairsEndpoint)
+	pairs.GetTradingPairs()
+	if mock.count() != 2 {
+		t.Errorf("Expected invalidated entry to be fetched again, got %d requests", mock.count())
+	}
+
+	c.InvalidateAll()
+	pairs.GetTradingPairs()
+	if mock.count() != 3 {
+		t.Errorf("Expected entry to be fetched after InvalidateAll, got %d requests", mock.count())
+	}
+}
+
+func TestErrorsAreNotCached(t *testing.T) {
+	mock := &MockClient{response: okResponse(`{"error": true, "errorMessage": "Maintenance", "data": null}`)}
+	c, _ := newTestClient(mock, DefaultPolicies())
+	pairs := &public.TradingPairs{Client: c}
+
+	pairs.GetTradingPairs()
+	pairs.GetTradingPairs()
+	if mock.count() != 2 {
+		t.Errorf("Expected API errors not to be cached, got %d requests", mock.count())
+	}
+
+	mock.response = &coinmate.Response{StatusCode: http.StatusServiceUnavailable, Body: []byte("down")}
+	pairs.GetTradingPairs()
+	pairs.GetTradingPairs()
+	if mock.count() != 4 {
+		t.Errorf("Expected HTTP errors not to be cached, got %d requests", mock.count())
+	}
+}
+
+func TestUncachedEndpointsPassThrough(t *testing.T) {
+	mock := &MockClient{response: okResponse(`{"error": false, "data": {"last": 1.0}}`)}
+	c, _ := newTestClient(mock, DefaultPolicies())
+	ticker := &public.Ticker{Client: c}
+
+	ticker.GetTicker("BTC_EUR")
+	ticker.GetTicker("BTC_EUR")
+	if mock.count() != 2 {
+		t.Errorf("Expected ticker not to be cached, got %d requests", mock.count())
+	}
+
+	c.MakeSecureRequest(coinmate.Request{HTTPMethod: http.MethodPost, URL: c.GetBaseUrl() + TradingPairsEndpoint})
+	if mock.count() != 3 {
+		t.Errorf("Expected secure request to pass through, got %d requests", mock.count())
+	}
+}
@@ -0,0 +1,265 @@
+package candles
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"tourGo/coinmate/public"
+)
+
+// Common candle intervals
+const (
+	OneMinute      = time.Minute
+	FiveMinutes    = 5 * time.Minute
+	FifteenMinutes = 15 * time.Minute
+	OneHour        = time.Hour
+	FourHours      = 4 * time.Hour
+	OneDay         = 24 * time.Hour
+)
+
+// How intervals without any trade are represented
+type GapPolicy int
+
+const (
+	// Intervals without trades are left out of the series
+	GapSkip GapPolicy = iota
+	// Intervals without trades get a flat candle at the previous close with zero volume
+	GapFill
+)
+
+// OHLCV candle
+type Candle struct {
+	Start       time.Time `json:"start"`
+	Open        float64   `json:"open"`
+	High        float64   `json:"high"`
+	Low         float64   `json:"low"`
+	Close       float64   `json:"close"`
+	Volume      float64   `json:"volume"`
+	QuoteVolume float64   `json:"quoteVolume"`
+	Trades      int       `json:"trades"`
+}
+
+// End of the candle interval (exclusive)
+func (c Candle) End(interval time.Duration) time.Time {
+	return c.Start.Add(interval)
+}
+
+// Candle under construction, keeps track of which trades set open and close
+type bucket struct {
+	candle    Candle
+	openTime  int64
+	openId    string
+	closeTime int64
+	closeId   string
+}
+
+// Builder aggregates trades of a single currency pair into candles. Candles
+// are aligned to the Unix epoch in UTC, so daily candles start at midnight
+// UTC. Trades may arrive in any order and more than once; duplicates are
+// detected by transaction ID and the result does not depend on arrival order.
+type Builder struct {
+	interval time.Duration
+	gaps     GapPolicy
+	buckets  map[int64]*bucket
+	seen     map[string]int64
+}
+
+// Return candle builder for the given interval
+func NewBuilder(interval time.Duration, gaps GapPolicy) (*Builder, error) {
+	if interval < time.Millisecond {
+		return nil, fmt.Errorf("interval must be at least 1ms")
+	}
+	if gaps != GapSkip && gaps != GapFill {
+		return nil, fmt.Errorf("unknown gap policy %d", gaps)
+	}
+	return &Builder{
+		interval: interval,
+		gaps:     gaps,
+		buckets:  make(map[int64]*bucket),
+		seen:     make(map[string]int64),
+	}, nil
+}
+
+// Aggregate a batch of trades into candles
+func Aggregate(transactions []public.TransactionsData, interval time.Duration, gaps GapPolicy) ([]Candle, error) {
+	b, err := NewBuilder(interval, gaps)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := b.AddAll(transactions); err != nil {
+		return nil, err
+	}
+	return b.Candles(), nil
+}
+
+// Return builder interval
+func (b *Builder) Interval() time.Duration {
+	return b.interval
+}
+
+// Add single trade. Returns false when the trade was already added.
+func (b *Builder) Add(tx public.TransactionsData) (bool, error) {
+	if tx.TransactionId == "" {
+		return false, fmt.Errorf("transactionId must not be empty")
+	}
+	if tx.Price <= 0 || tx.Amount < 0 {
+		return false, fmt.Errorf("invalid trade %s: price=%f amount=%f", tx.TransactionId, tx.Price, tx.Amount)
+	}
+	if _, ok := b.seen[tx.TransactionId]; ok {
+		return false, nil
+	}
+
+	start := b.bucketStart(tx.Timestamp)
+	b.seen[tx.TransactionId] = start
+
+	bk, ok := b.buckets[start]
+	if !ok {
+		b.buckets[start] = &bucket{
+			candle: Candle{
+				Start:       time.UnixMilli(start).UTC(),
+				Open:        tx.Price,
+				High:        tx.Price,
+				Low:         tx.Price,
+				Close:       tx.Price,
+				Volume:      tx.Amount,
+				QuoteVolume: tx.Amount * tx.Price,
+				Trades:      1,
+			},
+			openTime:  tx.Timestamp,
+			openId:    tx.TransactionId,
+			closeTime: tx.Timestamp,
+			closeId:   tx.TransactionId,
+		}
+		return true, nil
+	}
+
+	c := &bk.candle
+	if tx.Price > c.High {
+		c.High = tx.Price
+	}
+	if tx.Price < c.Low {
+		c.Low = tx.Price
+	}
+	c.Volume += tx.Amount
+	c.QuoteVolume += tx.Amount * tx.Price
+	c.Trades++
+
+	// Equal timestamps are ordered by transaction ID to stay deterministic
+	if before(tx.Timestamp, tx.TransactionId, bk.openTime, bk.openId) {
+		c.Open = tx.Price
+		bk.openTime = tx.Timestamp
+		bk.openId = tx.TransactionId
+	}
+	if before(bk.closeTime, bk.closeId, tx.Timestamp, tx.TransactionId) {
+		c.Close = tx.Price
+		bk.closeTime = tx.Timestamp
+		bk.closeId = tx.TransactionId
+	}
+
+	return true, nil
+}
+
+// Add multiple trades, returns number of trades that were not seen before
+func (b *Builder) AddAll(transactions []public.TransactionsData) (int, error) {
+	added := 0
+	for _, tx := range transactions {
+		ok, err := b.Add(tx)
+		if err != nil {
+			return added, err
+		}
+		if ok {
+			added++
+		}
+	}
+	return added, nil
+}
+
+// Return all candles sorted by start time, including the one still in progress
+func (b *Builder) Candles() []Candle {
+	starts := make([]int64, 0, len(b.buckets))
+	for start := range b.buckets {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	step := b.interval.Milliseconds()
+	candles := make([]Candle, 0, len(starts))
+	for i, start := range starts {
+		if b.gaps == GapFill && i > 0 {
+			prev := candles[len(candles)-1]
+			for gap := starts[i-1] + step; gap < start; gap += step {
+				candles = append(candles, Candle{
+					Start: time.UnixMilli(gap).UTC(),
+					Open:  prev.Close,
+					High:  prev.Close,
+					Low:   prev.Close,
+					Close: prev.Close,
+				})
+			}
+		}
+		candles = append(candles, b.buckets[start].candle)
+	}
+
+	return candles
+}
+
+// Return candles whose interval ended at or before now
+func (b *Builder) Closed(now time.Time) []Candle {
+	all := b.Candles()
+	closed := all[:0]
+	for _, c := range all {
+		if !c.End(b.interval).After(now) {
+			closed = append(closed, c)
+		}
+	}
+	return closed
+}
+
+// Return candle containing the latest trade
+func (b *Builder) Last() (Candle, bool) {
+	var last *bucket
+	for start, bk := range b.buckets {
+		if last == nil || start > last.candle.Start.UnixMilli() {
+			last = bk
+		}
+	}
+	if last == nil {
+		return Candle{}, false
+	}
+	return last.candle, true
+}
+
+// Drop candles starting before t together with their deduplication state.
+// Trades older than t that arrive afterwards start a new candle.
+func (b *Builder) Prune(t time.Time) {
+	cutoff := b.bucketStart(t.UnixMilli())
+	for start := range b.buckets {
+		if start < cutoff {
+			delete(b.buckets, start)
+		}
+	}
+	for id, start := range b.seen {
+		if start < cutoff {
+			delete(b.seen, id)
+		}
+	}
+}
+
+// Helper functions
+
+// Return start of the interval containing timestamp (milliseconds)
+func (b *Builder) bucketStart(timestamp int64) int64 {
+	step := b.interval.Milliseconds()
+	start := timestamp - timestamp%step
+	if timestamp < 0 && timestamp%step != 0 {
+		start -= step
+	}
+	return start
+}
+
+func before(timeA int64, idA string, timeB int64, idB string) bool {
+	if timeA != timeB {
+		return timeA < timeB
+	}
+	return idA < idB
+}
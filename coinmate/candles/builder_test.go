@@ -0,0 +1,198 @@
+package candles
+
+import (
+	"testing"
+	"time"
+	"tourGo/coinmate/public"
+)
+
+// 2024-01-01T00:00:00Z in milliseconds
+const baseTime int64 = 1704067200000
+
+func trade(id string, offset time.Duration, price, amount float64) public.TransactionsData {
+	return public.TransactionsData{
+		Timestamp:     baseTime + offset.Milliseconds(),
+		TransactionId: id,
+		Price:         price,
+		Amount:        amount,
+		CurrencyPair:  "BTC_EUR",
+		TradeType:     "BUY",
+	}
+}
+
+func TestAggregateOneMinute(t *testing.T) {
+	trades := []public.TransactionsData{
+		trade("1", 5*time.Second, 100, 1),
+		trade("2", 20*time.Second, 105, 0.5),
+		trade("3", 40*time.Second, 95, 2),
+		trade("4", 50*time.Second, 101, 1),
+		trade("5", 70*time.Second, 102, 1),
+	}
+
+	candles, err := Aggregate(trades, OneMinute, GapSkip)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(candles) != 2 {
+		t.Fatalf("Expected 2 candles, got %d", len(candles))
+	}
+
+	c := candles[0]
+	if !c.Start.Equal(time.UnixMilli(baseTime)) {
+		t.Errorf("Expected first candle to start at %v, got %v", time.UnixMilli(baseTime).UTC(), c.Start)
+	}
+	if c.Open != 100 || c.High != 105 || c.Low != 95 || c.Close != 101 {
+		t.Errorf("Unexpected OHLC %f/%f/%f/%f", c.Open, c.High, c.Low, c.Close)
+	}
+	if c.Volume != 4.5 {
+		t.Errorf("Expected volume 4.5, got %f", c.Volume)
+	}
+	if c.QuoteVolume != 100+52.5+190+101 {
+		t.Errorf("Expected quote volume 443.5, got %f", c.QuoteVolume)
+	}
+	if c.Trades != 4 {
+		t.Errorf("Expected 4 trades, got %d", c.Trades)
+	}
+	if candles[1].Open != 102 || candles[1].Trades != 1 {
+		t.Errorf("Unexpected second candle %+v", candles[1])
+	}
+}
+
+func TestBuilderOutOfOrderAndDuplicates(t *testing.T) {
+	b, err := NewBuilder(FiveMinutes, GapSkip)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	trades := []public.TransactionsData{
+		trade("3", 3*time.Minute, 103, 1),
+		trade("1", 1*time.Minute, 101, 1),
+		trade("2", 2*time.Minute, 102, 1),
+		trade("1", 1*time.Minute, 101, 1),
+	}
+	added, err := b.AddAll(trades)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if added != 3 {
+		t.Errorf("Expected 3 new trades, got %d", added)
+	}
+
+	ok, err := b.Add(trade("2", 2*time.Minute, 102, 1))
+	if err != nil || ok {
+		t.Errorf("Expected duplicate to be ignored, got ok=%v err=%v", ok, err)
+	}
+
+	last, ok := b.Last()
+	if !ok {
+		t.Fatal("Expected a candle")
+	}
+	if last.Open != 101 || last.Close != 103 || last.Volume != 3 {
+		t.Errorf("Unexpected candle %+v", last)
+	}
+}
+
+func TestBuilderEqualTimestampsAreDeterministic(t *testing.T) {
+	forward, _ := Aggregate([]public.TransactionsData{
+		trade("a", 0, 100, 1),
+		trade("b", 0, 200, 1),
+	}, OneMinute, GapSkip)
+	backward, _ := Aggregate([]public.TransactionsData{
+		trade("b", 0, 200, 1),
+		trade("a", 0, 100, 1),
+	}, OneMinute, GapSkip)
+
+	if forward[0] != backward[0] {
+		t.Errorf("Expected same candle regardless of order, got %+v and %+v", forward[0], backward[0])
+	}
+	if forward[0].Open != 100 || forward[0].Close != 200 {
+		t.Errorf("Expected open 100 close 200, got %f/%f", forward[0].Open, forward[0].Close)
+	}
+}
+
+func TestGapPolicies(t *testing.T) {
+	trades := []public.TransactionsData{
+		trade("1", 0, 100, 1),
+		trade("2", 3*time.Hour+time.Minute, 110, 1),
+	}
+
+	skipped, err := Aggregate(trades, OneHour, GapSkip)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(skipped) != 2 {
+		t.Errorf("Expected 2 candles with GapSkip, got %d", len(skipped))
+	}
+
+	filled, err := Aggregate(trades, OneHour, GapFill)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(filled) != 4 {
+		t.Fatalf("Expected 4 candles with GapFill, got %d", len(filled))
+	}
+	for _, c := range filled[1:3] {
+		if c.Open != 100 || c.Close != 100 || c.Volume != 0 || c.Trades != 0 {
+			t.Errorf("Expected flat gap candle at 100, got %+v", c)
+		}
+	}
+	if !filled[3].Start.Equal(time.UnixMilli(baseTime).Add(3 * time.Hour)) {
+		t.Errorf("Unexpected last candle start %v", filled[3].Start)
+	}
+}
+
+func TestDailyCandlesAlignToMidnightUTC(t *testing.T) {
+	candles, err := Aggregate([]public.TransactionsData{
+		trade("1", 13*time.Hour, 100, 1),
+		trade("2", 23*time.Hour, 100, 1),
+		trade("3", 25*time.Hour, 100, 1),
+	}, OneDay, GapSkip)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(candles) != 2 {
+		t.Fatalf("Expected 2 daily candles, got %d", len(candles))
+	}
+	if candles[0].Start.Hour() != 0 || candles[0].Trades != 2 {
+		t.Errorf("Unexpected daily candle %+v", candles[0])
+	}
+}
+
+func TestClosedAndPrune(t *testing.T) {
+	b, _ := NewBuilder(OneMinute, GapSkip)
+	b.AddAll([]public.TransactionsData{
+		trade("1", 0, 100, 1),
+		trade("2", 90*time.Second, 101, 1),
+	})
+
+	closed := b.Closed(time.UnixMilli(baseTime).Add(100 * time.Second))
+	if len(closed) != 1 {
+		t.Errorf("Expected 1 closed candle, got %d", len(closed))
+	}
+
+	b.Prune(time.UnixMilli(baseTime).Add(time.Minute))
+	if len(b.Candles()) != 1 {
+		t.Errorf("Expected 1 candle after prune, got %d", len(b.Candles()))
+	}
+	ok, _ := b.Add(trade("2", 90*time.Second, 101, 1))
+	if ok {
+		t.Error("Expected retained trade to still be deduplicated")
+	}
+}
+
+func TestBuilderInvalidInput(t *testing.T) {
+	if _, err := NewBuilder(0, GapSkip); err == nil {
+		t.Error("Expected error for zero interval")
+	}
+	if _, err := NewBuilder(OneMinute, GapPolicy(9)); err == nil {
+		t.Error("Expected error for unknown gap policy")
+	}
+
+	b, _ := NewBuilder(OneMinute, GapSkip)
+	if _, err := b.Add(trade("", 0, 100, 1)); err == nil {
+		t.Error("Expected error for missing transaction ID")
+	}
+	if _, err := b.Add(trade("1", 0, 0, 1)); err == nil {
+		t.Error("Expected error for zero price")
+	}
+}
@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"tourGo/coinmate"
+)
+
+// Exit codes
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// Standard streams and environment of a run
+type Env struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Getenv func(string) string
+}
+
+// Subcommand like "ticker"
+type command struct {
+	args    string
+	summary string
+	// Needs credentials
+	secure bool
+	run    func(a *app, args []string) error
+}
+
+// Error shown with the usage of the command
+type usageError struct {
+	message string
+}
+
+func (e usageError) Error() string {
+	return e.message
+}
+
+// State of one run
+type app struct {
+	env    Env
+	in     *bufio.Reader
+	output string
+	yes    bool
+	config Config
+	client *coinmate.CoinmateClient
+}
+
+// Run the command line with the process environment and return the exit code
+func Main(args []string) int {
+	return Run(args, Env{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr, Getenv: os.Getenv})
+}
+
+// Run the command line, e.g. ["-o", "json", "ticker", "BTC_EUR"], and return
+// the exit code
+func Run(args []string, env Env) int {
+	a := &app{env: env, in: bufio.NewReader(env.Stdin), output: formatTable}
+
+	var configPath, baseUrl string
+	var timeout time.Duration
+	fs := a.flagSet("coinmate")
+	fs.StringVar(&configPath, "config", "", "config file, $COINMATE_CONFIG or the user config directory by default")
+	fs.StringVar(&baseUrl, "url", "", "API root, e.g. of a local emulator")
+	fs.DurationVar(&timeout, "timeout", 0, "HTTP timeout")
+	fs.Usage = func() { a.usage() }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() == 0 || fs.Arg(0) == "help" {
+		a.usage()
+		if fs.NArg() == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	name := fs.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(env.Stderr, "unknown command %q\n\n", name)
+		a.usage()
+		return exitUsage
+	}
+
+	config, warning, err := LoadConfig(configPath, env.Getenv)
+	if err != nil {
+		fmt.Fprintln(env.Stderr, "error:", err)
+		return exitError
+	}
+	if warning != "" {
+		fmt.Fprintln(env.Stderr, "warning:", warning)
+	}
+	if baseUrl != "" {
+		config.BaseUrl = baseUrl
+	}
+	if cmd.secure && !config.HasCredentials() {
+		fmt.Fprintf(env.Stderr, "error: %s needs credentials, set %s, %s and %s or use a config file\n", name, envClientId, envApiKey, envPrivateKey)
+		return exitError
+	}
+	a.config = config
+	a.client = newClient(config, timeout)
+
+	if err := cmd.run(a, fs.Args()[1:]); err != nil {
+		var usage usageError
+		if errors.As(err, &usage) {
+			fmt.Fprintf(env.Stderr, "error: %s\nusage: coinmate %s %s\n", usage.message, name, cmd.args)
+			return exitUsage
+		}
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		fmt.Fprintln(env.Stderr, "error:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// Helper functions
+
+// Flag set with the flags accepted before and after the command
+func (a *app) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.env.Stderr)
+	fs.StringVar(&a.output, "o", a.output, "output format: table, json or csv")
+	fs.BoolVar(&a.yes, "y", a.yes, "place and cancel orders without confirmation")
+	return fs
+}
+
+// Parse command flags and check the number of positional arguments
+func (a *app) parse(fs *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, usageError{err.Error()}
+	}
+	// Flags may follow positional arguments
+	var positional []string
+	for rest := fs.Args(); len(rest) > 0; rest = fs.Args() {
+		positional = append(positional, rest[0])
+		if err := fs.Parse(rest[1:]); err != nil {
+			return nil, usageError{err.Error()}
+		}
+	}
+	if len(positional) < min || len(positional) > max {
+		return nil, usageError{"wrong number of arguments"}
+	}
+	switch a.output {
+	case formatTable, formatJSON, formatCSV:
+	default:
+		// Checked before orders are placed
+		return nil, usageError{fmt.Sprintf("unknown output format %q, expected table, json or csv", a.output)}
+	}
+	return positional, nil
+}
+
+// Ask for confirmation unless -y is set
+func (a *app) confirm(format string, args ...interface{}) error {
+	if a.yes {
+		return nil
+	}
+	fmt.Fprintf(a.env.Stderr, format+" [y/N] ", args...)
+	answer, err := a.in.ReadString('\n')
+	if err != nil && answer == "" {
+		return errors.New("aborted, no confirmation")
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return errors.New("aborted")
+}
+
+func (a *app) print(data interface{}, t table) error {
+	return write(a.env.Stdout, a.output, data, t)
+}
+
+func (a *app) usage() {
+	w := a.env.Stderr
+	fmt.Fprintln(w, "usage: coinmate [-config file] [-url url] [-timeout d] [-o table|json|csv] [-y] <command> [args]")
+	fmt.Fprintln(w, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, commands[name].summary)
+		if args := commands[name].args; args != "" {
+			fmt.Fprintf(tw, "  \t  %s\n", args)
+		}
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\ncredentials are read from %s, %s and %s or the config file\n", envClientId, envApiKey, envPrivateKey)
+}
+
+func newClient(c Config, timeout time.Duration) *coinmate.CoinmateClient {
+	client := coinmate.GetCoinmateClient(c.ClientId, c.ApiKey, c.PrivateKey)
+	client.SetBaseUrl(c.BaseUrl)
+	if timeout == 0 && c.Timeout != "" {
+		timeout, _ = time.ParseDuration(c.Timeout)
+	}
+	client.SetTimeout(timeout)
+	return client
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/secure"
+)
+
+var credentials = map[string]string{
+	envClientId:   "1",
+	envApiKey:     "public-key",
+	envPrivateKey: "private-key",
+}
+
+func newTestServer(t *testing.T) *coinmatetest.Server {
+	t.Helper()
+	server := coinmatetest.NewServer()
+	t.Cleanup(server.Close)
+
+	server.AddAccount("1", "public-key", "private-key")
+	server.Deposit("1", "EUR", 10000)
+	server.AddLiquidity("BTC_EUR", "SELL", 50000, 1)
+	server.AddLiquidity("BTC_EUR", "BUY", 49000, 1)
+	return server
+}
+
+// Run command line against the server, returns exit code, stdout and stderr
+func run(t *testing.T, server *coinmatetest.Server, env map[string]string, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	vars := map[string]string{}
+	for k, v := range env {
+		vars[k] = v
+	}
+	if _, ok := vars[envConfig]; !ok {
+		// Never read the config of the user running the tests
+		path := filepath.Join(t.TempDir(), "config.json")
+		os.WriteFile(path, []byte("{}"), 0o600)
+		vars[envConfig] = path
+	}
+	if server != nil {
+		args = append([]string{"-url", server.BaseUrl()}, args...)
+	}
+	code := Run(args, Env{
+		Stdin:  strings.NewReader(stdin),
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Getenv: func(key string) string { return vars[key] },
+	})
+	return code, stdout.String(), stderr.String()
+}
+
+func TestTickerJSON(t *testing.T) {
+	server := newTestServer(t)
+
+	code, stdout, stderr := run(t, server, nil, "", "-o", "json", "ticker", "btc_eur")
+	if code != exitOK {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+	var ticker struct {
+		Bid float64 `json:"bid"`
+		Ask float64 `json:"ask"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &ticker); err != nil {
+		t.Fatalf("Expected JSON output, got %q", stdout)
+	}
+	if ticker.Bid != 49000 || ticker.Ask != 50000 {
+		t.Errorf("Expected bid 49000 and ask 50000, got %+v", ticker)
+	}
+}
+
+func TestBalancesTableAndCSV(t *testing.T) {
+	server := newTestServer(t)
+
+	code, stdout, stderr := run(t, server, credentials, "", "balances")
+	if code != exitOK {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "CURRENCY") || !strings.HasPrefix(lines[1], "EUR ") {
+		t.Errorf("Expected header and EUR row, got %q", stdout)
+	}
+
+	// Flags may follow the command
+	code, stdout, _ = run(t, server, credentials, "", "balances", "-o", "csv")
+	if code != exitOK {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	if stdout != "currency,balance,reserved,available\nEUR,10000,0,10000\n" {
+		t.Errorf("Expected CSV balances, got %q", stdout)
+	}
+}
+
+func TestSecureCommandWithoutCredentials(t *testing.T) {
+	server := newTestServer(t)
+
+	code, _, stderr := run(t, server, nil, "", "balances")
+	if code != exitError {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr, envClientId) {
+		t.Errorf("Expected credentials hint, got %q", stderr)
+	}
+}
+
+func TestBuyLimitConfirmation(t *testing.T) {
+	server := newTestServer(t)
+	client := server.NewClient("1")
+	order := &secure.Order{Client: client}
+
+	code, _, stderr := run(t, server, credentials, "n\n", "buy-limit", "BTC_EUR", "0.01", "48000")
+	if code != exitError || !strings.Contains(stderr, "aborted") {
+		t.Errorf("Expected aborted order, got %d: %s", code, stderr)
+	}
+	if !strings.Contains(stderr, "BUY 0.01 BTC_EUR at 48000?") {
+		t.Errorf("Expected order in prompt, got %q", stderr)
+	}
+	if open, _ := order.GetOpenOrders("BTC_EUR"); len(open.Data) != 0 {
+		t.Fatalf("Expected no order placed, got %+v", open.Data)
+	}
+
+	code, stdout, stderr := run(t, server, credentials, "y\n", "-o", "json", "buy-limit", "BTC_EUR", "0.01", "48000")
+	if code != exitOK {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+	var result placed
+	json.Unmarshal([]byte(stdout), &result)
+	open, _ := order.GetOpenOrders("BTC_EUR")
+	if len(open.Data) != 1 || open.Data[0].Id != result.OrderId {
+		t.Fatalf("Expected open order %d, got %+v", result.OrderId, open.Data)
+	}
+
+	// -y skips the prompt
+	code, _, stderr = run(t, server, credentials, "", "-y", "cancel", id(result.OrderId))
+	if code != exitOK {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+	if open, _ := order.GetOpenOrders("BTC_EUR"); len(open.Data) != 0 {
+		t.Errorf("Expected order cancelled, got %+v", open.Data)
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	os.WriteFile(path, []byte(`{"clientId":"1","apiKey":"file-key","privateKey":"file-private","timeout":"5s"}`), 0o644)
+
+	env := map[string]string{envConfig: path, envApiKey: "env-key"}
+	c, warning, err := LoadConfig("", func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if c.ClientId != "1" || c.ApiKey != "env-key" || c.PrivateKey != "file-private" || c.Timeout != "5s" {
+		t.Errorf("Expected file config with env API key, got %+v", c)
+	}
+	if warning == "" {
+		t.Errorf("Expected warning for a config readable by others")
+	}
+
+	os.Chmod(path, 0o600)
+	if _, warning, _ := LoadConfig(path, func(string) string { return "" }); warning != "" {
+		t.Errorf("Expected no warning, got %q", warning)
+	}
+
+	if _, _, err := LoadConfig(filepath.Join(dir, "missing.json"), func(string) string { return "" }); err == nil {
+		t.Errorf("Expected error for a missing explicit config")
+	}
+}
+
+func TestUsage(t *testing.T) {
+	code, _, stderr := run(t, nil, nil, "", "bogus")
+	if code != exitUsage || !strings.Contains(stderr, "unknown command") {
+		t.Errorf("Expected usage error, got %d: %s", code, stderr)
+	}
+
+	code, _, stderr = run(t, nil, nil, "", "ticker")
+	if code != exitUsage || !strings.Contains(stderr, "usage: coinmate ticker PAIR") {
+		t.Errorf("Expected ticker usage, got %d: %s", code, stderr)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	server := newTestServer(t)
+
+	code, stdout, stderr := run(t, server, credentials, "q", "dashboard", "btc_eur", "-depth", "3")
+	if code != exitOK {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "BTC_EUR") || !strings.Contains(stdout, "ask 50000") {
+		t.Errorf("Expected BTC_EUR dashboard, got %q", stdout)
+	}
+}
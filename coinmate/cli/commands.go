@@ -0,0 +1,672 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"tourGo/coinmate/dashboard"
+	"tourGo/coinmate/gateway"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/ratelimit"
+	"tourGo/coinmate/rpc"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	buySide  = "BUY"
+	sellSide = "SELL"
+)
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"ticker":       {args: "PAIR", summary: "last price, bid and ask of a pair", run: runTicker},
+		"tickers":      {args: "", summary: "tickers of all pairs", run: runTickers},
+		"orderbook":    {args: "PAIR [-group] [-depth n]", summary: "asks and bids of a pair", run: runOrderBook},
+		"transactions": {args: "PAIR [-minutes n]", summary: "recent public trades of a pair", run: runTransactions},
+		"pairs":        {args: "", summary: "currency pairs", run: runPairs},
+		"currencies":   {args: "", summary: "currencies", run: runCurrencies},
+		"server-time":  {args: "", summary: "exchange time", run: runServerTime},
+
+		"balances":    {args: "[-all]", summary: "balances, non-zero only by default", secure: true, run: runBalances},
+		"open-orders": {args: "[PAIR]", summary: "open orders, of all pairs by default", secure: true, run: runOpenOrders},
+		"history":     {args: "PAIR [-limit n]", summary: "order history of a pair", secure: true, run: runHistory},
+		"order":       {args: "ID", summary: "order by ID", secure: true, run: runOrder},
+		"trades":      {args: "[-pair PAIR] [-limit n]", summary: "own trades, newest first", secure: true, run: runTrades},
+		"transfers":   {args: "[-currency CUR] [-limit n]", summary: "deposits and withdrawals, newest first", secure: true, run: runTransfers},
+
+		"buy-limit":    {args: "PAIR AMOUNT PRICE [-stop p] [-hidden] [-ioc] [-client-order-id n]", summary: "place a limit buy order", secure: true, run: limitOrder(buySide)},
+		"sell-limit":   {args: "PAIR AMOUNT PRICE [-stop p] [-hidden] [-ioc] [-client-order-id n]", summary: "place a limit sell order", secure: true, run: limitOrder(sellSide)},
+		"buy-instant":  {args: "PAIR TOTAL [-client-order-id n]", summary: "buy at market for TOTAL of the quote currency", secure: true, run: instantOrder(buySide)},
+		"sell-instant": {args: "PAIR AMOUNT [-client-order-id n]", summary: "sell AMOUNT at market", secure: true, run: instantOrder(sellSide)},
+		"cancel":       {args: "ID", summary: "cancel an order", secure: true, run: runCancel},
+
+		"dashboard": {args: "[PAIR...] [-interval d] [-depth n]", summary: "live terminal dashboard, tab switches pairs", secure: true, run: runDashboard},
+		"serve":     {args: "[-addr host:port] [-rate n]", summary: "local REST/JSON gateway, tokens from $" + envReadToken + " and $" + envTradeToken, secure: true, run: runServe},
+		"grpc":      {args: "[-addr host:port] [-interval d] [-rate n]", summary: "gRPC server of proto/coinmate/v1, tokens as for serve", secure: true, run: runGrpc},
+	}
+}
+
+// Public commands
+
+func runTicker(a *app, args []string) error {
+	fs := a.flagSet("ticker")
+	pos, err := a.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	pair := strings.ToUpper(pos[0])
+	r, err := (&public.Ticker{Client: a.client}).GetTicker(pair)
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return apiError(r.ErrorMessage)
+	}
+	d := r.Data
+	t := table{header: []string{"pair", "last", "bid", "ask", "high", "low", "change", "amount"}}
+	t.add(pair, number(d.Last), number(d.Bid), number(d.Ask), number(d.High), number(d.Low), number(d.Change), number(d.Amount))
+	return a.print(d, t)
+}
+
+func runTickers(a *app, args []string) error {
+	if _, err := a.parse(a.flagSet("tickers"), args, 0, 0); err != nil {
+		return err
+	}
+	r, err := (&public.TickerAll{Client: a.client}).GetTickerAll()
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return apiError(r.ErrorMessage)
+	}
+	t := table{header: []string{"pair", "last", "bid", "ask", "high", "low", "change"}}
+	for _, pair := range sortedKeys(r.Data) {
+		d := r.Data[pair]
+		t.add(pair, number(d.Last), number(d.Bid), number(d.Ask), number(d.High), number(d.Low), number(d.Change))
+	}
+	return a.print(r.Data, t)
+}
+
+func runOrderBook(a *app, args []string) error {
+	fs := a.flagSet("orderbook")
+	group := fs.Bool("group", false, "group orders by price")
+	depth := fs.Int("depth", 10, "price levels per side, 0 for all")
+	pos, err := a.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	r, err := (&public.OrderBook{Client: a.client}).GetOrderBook(strings.ToUpper(pos[0]), *group)
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return apiError(r.ErrorMessage)
+	}
+	book := r.Data
+	if *depth > 0 {
+		book.Asks = book.Asks[:min(*depth, len(book.Asks))]
+		book.Bids = book.Bids[:min(*depth, len(book.Bids))]
+	}
+	t := table{header: []string{"side", "price", "amount"}}
+	// Asks from the highest so the spread is in the middle
+	for i := len(book.Asks) - 1; i >= 0; i-- {
+		t.add("ask", number(book.Asks[i].Price), number(book.Asks[i].Amount))
+	}
+	for _, b := range book.Bids {
+		t.add("bid", number(b.Price), number(b.Amount))
+	}
+	return a.print(book, t)
+}
+
+func runTransactions(a *app, args []string) error {
+	fs := a.flagSet("transactions")
+	minutes := fs.Uint64("minutes", 10, "minutes into history")
+	pos, err := a.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	r, err := (&public.Transactions{Client: a.client}).GetTransactions(strings.ToUpper(pos[0]), *minutes)
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return apiError(r.ErrorMessage)
+	}
+	t := table{header: []string{"time", "id", "type", "price", "amount"}}
+	for _, d := range r.Data {
+		t.add(timestamp(d.Timestamp), d.TransactionId, d.TradeType, number(d.Price), number(d.Amount))
+	}
+	return a.print(r.Data, t)
+}
+
+func runPairs(a *app, args []string) error {
+	if _, err := a.parse(a.flagSet("pairs"), args, 0, 0); err != nil {
+		return err
+	}
+	r, err := (&public.CurrencyPairs{Client: a.client}).GetCurrencyPairs()
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return apiError(r.ErrorMessage)
+	}
+	t := table{header: []string{"pair", "base", "quote"}}
+	for _, p := range r.Data {
+		t.add(p.Name, p.FirstCurrency, p.SecondCurrency)
+	}
+	return a.print(r.Data, t)
+}
+
+func runCurrencies(a *app, args []string) error {
+	if _, err := a.parse(a.flagSet("currencies"), args, 0, 0); err != nil {
+		return err
+	}
+	r, err := (&public.Currencies{Client: a.client}).GetCurrencies()
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return apiError(r.ErrorMessage)
+	}
+	t := table{header: []string{"currency"}}
+	for _, c := range r.Data {
+		t.add(c)
+	}
+	return a.print(r.Data, t)
+}
+
+func runServerTime(a *app, args []string) error {
+	if _, err := a.parse(a.flagSet("server-time"), args, 0, 0); err != nil {
+		return err
+	}
+	r, err := (&public.ServerTime{Client: a.client}).GetServerTime()
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return apiError(r.ErrorMessage)
+	}
+	t := table{header: []string{"time", "timestamp"}}
+	t.add(timestamp(r.Data), strconv.FormatInt(r.Data, 10))
+	return a.print(r.Data, t)
+}
+
+// Account commands
+
+func runBalances(a *app, args []string) error {
+	fs := a.flagSet("balances")
+	all := fs.Bool("all", false, "include zero balances")
+	if _, err := a.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	r, err := (&secure.Balances{Client: a.client}).GetBalances()
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return apiError(r.ErrorMessage)
+	}
+	var balances []secure.BalanceCurrency
+	t := table{header: []string{"currency", "balance", "reserved", "available"}}
+	for _, currency := range sortedKeys(r.Data) {
+		b := r.Data[currency]
+		if b.Balance == 0 && b.Reserved == 0 && !*all {
+			continue
+		}
+		if b.Currency == "" {
+			b.Currency = currency
+		}
+		balances = append(balances, b)
+		t.add(b.Currency, number32(b.Balance), number32(b.Reserved), number32(b.Available))
+	}
+	return a.print(balances, t)
+}
+
+func runOpenOrders(a *app, args []string) error {
+	pos, err := a.parse(a.flagSet("open-orders"), args, 0, 1)
+	if err != nil {
+		return err
+	}
+	pair := ""
+	if len(pos) == 1 {
+		pair = strings.ToUpper(pos[0])
+	}
+	r, err := (&secure.Order{Client: a.client}).GetOpenOrders(pair)
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return apiError(r.ErrorMessage)
+	}
+	t := table{header: []string{"id", "time", "pair", "type", "price", "amount", "stop", "client_order_id"}}
+	for _, o := range r.Data {
+		t.add(id(o.Id), timestamp(o.Timestamp), o.CurrencyPair, o.Type, number(o.Price), number(o.Amount), optional(o.StopPrice), optionalId(o.ClientOrderId))
+	}
+	return a.print(r.Data, t)
+}
+
+func runHistory(a *app, args []string) error {
+	fs := a.flagSet("history")
+	limit := fs.Int64("limit", 20, "number of orders")
+	pos, err := a.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	r, err := (&secure.Order{Client: a.client}).GetHistory(strings.ToUpper(pos[0]), *limit)
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return apiError(r.ErrorMessage)
+	}
+	t := historyTable()
+	for _, o := range r.Data {
+		addHistory(&t, o)
+	}
+	return a.print(r.Data, t)
+}
+
+func runOrder(a *app, args []string) error {
+	pos, err := a.parse(a.flagSet("order"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	orderId, err := parseId(pos[0])
+	if err != nil {
+		return err
+	}
+	r, err := (&secure.Order{Client: a.client}).GetOrderById(orderId)
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return apiError(r.ErrorMessage)
+	}
+	if r.Data == nil {
+		return fmt.Errorf("order %d not found", orderId)
+	}
+	t := historyTable()
+	addHistory(&t, *r.Data)
+	return a.print(r.Data, t)
+}
+
+func runTrades(a *app, args []string) error {
+	fs := a.flagSet("trades")
+	pair := fs.String("pair", "", "currency pair, all pairs by default")
+	limit := fs.Int64("limit", 20, "number of trades")
+	if _, err := a.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	params := secure.TradeHistoryParams{Limit: *limit, Sort: "DESC", CurrencyPair: strings.ToUpper(*pair)}
+	r, err := (&secure.TradeHistory{Client: a.client}).GetTradeHistory(params)
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return apiError(r.ErrorMessage)
+	}
+	t := table{header: []string{"id", "time", "pair", "type", "price", "amount", "fee", "fee_type", "order_id"}}
+	for _, d := range r.Data {
+		t.add(id(d.TransactionId), timestamp(d.CreatedTimestamp), d.CurrencyPair, d.Type, number(d.Price), number(d.Amount), number(d.Fee), d.FeeType, id(d.OrderId))
+	}
+	return a.print(r.Data, t)
+}
+
+func runTransfers(a *app, args []string) error {
+	fs := a.flagSet("transfers")
+	currency := fs.String("currency", "", "currency, all currencies by default")
+	limit := fs.Int64("limit", 20, "number of transfers")
+	if _, err := a.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	params := secure.TransferHistoryParams{Limit: *limit, Sort: "DESC", Currency: strings.ToUpper(*currency)}
+	r, err := (&secure.TransferHistory{Client: a.client}).GetTransferHistory(params)
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return apiError(r.ErrorMessage)
+	}
+	t := table{header: []string{"id", "time", "type", "currency", "amount", "fee", "status", "destination"}}
+	for _, d := range r.Data {
+		t.add(id(d.TransactionId), timestamp(d.Timestamp), d.TransferType, d.AmountCurrency, number(d.Amount), number(d.Fee), d.TransferStatus, d.Destination)
+	}
+	return a.print(r.Data, t)
+}
+
+// Order commands, confirmed before sending unless -y is set
+
+// Placed order as printed
+type placed struct {
+	OrderId uint64 `json:"orderId"`
+}
+
+func limitOrder(side string) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		name := strings.ToLower(side) + "-limit"
+		fs := a.flagSet(name)
+		stop := fs.Float64("stop", 0, "stop price")
+		hidden := fs.Bool("hidden", false, "hide the order from the order book")
+		ioc := fs.Bool("ioc", false, "immediate or cancel")
+		clientOrderId := fs.Uint64("client-order-id", 0, "own order ID")
+		pos, err := a.parse(fs, args, 3, 3)
+		if err != nil {
+			return err
+		}
+		pair := strings.ToUpper(pos[0])
+		amount, err := parseAmount("amount", pos[1])
+		if err != nil {
+			return err
+		}
+		price, err := parseAmount("price", pos[2])
+		if err != nil {
+			return err
+		}
+
+		prompt := fmt.Sprintf("%s %s %s at %s", side, number(amount), pair, number(price))
+		if *stop > 0 {
+			prompt += fmt.Sprintf(", stop %s", number(*stop))
+		}
+		if err := a.confirm("%s?", prompt); err != nil {
+			return err
+		}
+
+		o := &secure.Order{Client: a.client}
+		var r secure.SellLimit
+		if side == buySide {
+			r, err = o.BuyLimit(amount, price, *stop, pair, *hidden, *ioc, *clientOrderId)
+		} else {
+			r, err = o.SellLimit(amount, price, *stop, pair, *hidden, *ioc, *clientOrderId)
+		}
+		if err != nil {
+			return err
+		}
+		if r.Error {
+			return apiError(r.ErrorMessage)
+		}
+		return a.printPlaced(r.OrderId)
+	}
+}
+
+func instantOrder(side string) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		name := strings.ToLower(side) + "-instant"
+		fs := a.flagSet(name)
+		clientOrderId := fs.Uint64("client-order-id", 0, "own order ID")
+		pos, err := a.parse(fs, args, 2, 2)
+		if err != nil {
+			return err
+		}
+		pair := strings.ToUpper(pos[0])
+		total, err := parseAmount("amount", pos[1])
+		if err != nil {
+			return err
+		}
+
+		o := &secure.Order{Client: a.client}
+		var r secure.BuyAndSellResponse
+		if side == buySide {
+			if err := a.confirm("BUY %s for %s at market?", pair, number(total)); err != nil {
+				return err
+			}
+			r, err = o.BuyInstant(total, pair, *clientOrderId)
+		} else {
+			if err := a.confirm("SELL %s %s at market?", number(total), pair); err != nil {
+				return err
+			}
+			r, err = o.SellInstant(total, pair, *clientOrderId)
+		}
+		if err != nil {
+			return err
+		}
+		if r.Error {
+			return apiError(r.ErrorMessage)
+		}
+		return a.printPlaced(r.OrderId)
+	}
+}
+
+func runCancel(a *app, args []string) error {
+	pos, err := a.parse(a.flagSet("cancel"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	orderId, err := parseId(pos[0])
+	if err != nil {
+		return err
+	}
+	if err := a.confirm("Cancel order %d?", orderId); err != nil {
+		return err
+	}
+	r, err := (&secure.Order{Client: a.client}).CancelOrderWithInfo(orderId)
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return apiError(r.ErrorMessage)
+	}
+	if !r.Data.Success {
+		return fmt.Errorf("order %d was not cancelled", orderId)
+	}
+	t := table{header: []string{"id", "remaining"}}
+	t.add(id(orderId), number(r.Data.RemainingAmount))
+	return a.print(r.Data, t)
+}
+
+// Interactive commands
+
+func runDashboard(a *app, args []string) error {
+	fs := a.flagSet("dashboard")
+	interval := fs.Duration("interval", 2*time.Second, "polling interval")
+	depth := fs.Int("depth", 10, "order book levels shown")
+	pairs, err := a.parse(fs, args, 0, 100)
+	if err != nil {
+		return err
+	}
+	if len(pairs) == 0 {
+		pairs = []string{"BTC_EUR"}
+	}
+	if *interval <= 0 {
+		return usageError{"interval must be positive"}
+	}
+	d, err := dashboard.NewDashboard(a.client, pairs...)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+	d.Depth = max(1, *depth)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if in, ok := a.env.Stdin.(*os.File); ok {
+		return d.RunTerminal(ctx, in, a.env.Stdout, *interval)
+	}
+	return d.Run(ctx, a.env.Stdin, a.env.Stdout, *interval)
+}
+
+func runServe(a *app, args []string) error {
+	fs := a.flagSet("serve")
+	addr := fs.String("addr", "127.0.0.1:8420", "listen address")
+	rate := fs.Float64("rate", ratelimit.CoinmateRequestsPerMinute, "requests per minute to Coinmate, unlimited when 0")
+	if _, err := a.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *rate < 0 {
+		return usageError{"rate must not be negative"}
+	}
+
+	tokens, err := a.tokens()
+	if err != nil {
+		return err
+	}
+	// All callers share one rate limit, read routes are retried by the gateway
+	g, err := gateway.NewGateway(ratelimit.NewClient(a.client, *rate/60, 10), tokens)
+	if err != nil {
+		return err
+	}
+
+	a.warnExposed(*addr)
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: g.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(a.env.Stderr, "serving on http://%s, OpenAPI document at /openapi.json\n", listener.Addr())
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func runGrpc(a *app, args []string) error {
+	fs := a.flagSet("grpc")
+	addr := fs.String("addr", "127.0.0.1:8421", "listen address")
+	interval := fs.Duration("interval", time.Second, "polling interval of streams")
+	rate := fs.Float64("rate", ratelimit.CoinmateRequestsPerMinute, "requests per minute to Coinmate, unlimited when 0")
+	if _, err := a.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *interval <= 0 || *rate < 0 {
+		return usageError{"interval must be positive and rate must not be negative"}
+	}
+
+	tokens, err := a.tokens()
+	if err != nil {
+		return err
+	}
+	// All callers share one connection and one rate limit
+	limited := ratelimit.NewClient(a.client, *rate/60, 10)
+	s := rpc.NewServer(limited)
+	s.Tokens = tokens
+	s.Interval = *interval
+	s.OnPollError = func(key string, err error) {
+		fmt.Fprintf(a.env.Stderr, "polling %s failed: %v\n", key, err)
+	}
+
+	a.warnExposed(*addr)
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Fprintf(a.env.Stderr, "serving gRPC without TLS on %s\n", listener.Addr())
+	return s.Serve(ctx, listener)
+}
+
+// Helper functions
+
+// Tokens of the servers, a random read-only token when none is configured
+func (a *app) tokens() (map[string]gateway.Permission, error) {
+	tokens := map[string]gateway.Permission{}
+	if token := a.env.Getenv(envReadToken); token != "" {
+		tokens[token] = gateway.PermissionRead
+	}
+	if token := a.env.Getenv(envTradeToken); token != "" {
+		tokens[token] = gateway.PermissionTrade
+	}
+	if len(tokens) == 0 {
+		// Read-only unless trading is enabled explicitly
+		token := make([]byte, 24)
+		if _, err := rand.Read(token); err != nil {
+			return nil, fmt.Errorf("failed to generate token: %w", err)
+		}
+		tokens[hex.EncodeToString(token)] = gateway.PermissionRead
+		fmt.Fprintf(a.env.Stderr, "no %s or %s set, read-only token: %s\n", envReadToken, envTradeToken, hex.EncodeToString(token))
+	}
+	return tokens, nil
+}
+
+func (a *app) warnExposed(addr string) {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			fmt.Fprintf(a.env.Stderr, "warning: %s is reachable from other hosts, tokens are sent unencrypted\n", addr)
+		}
+	}
+}
+
+func apiError(message string) error {
+	if message == "" {
+		message = "unknown error"
+	}
+	return errors.New("API error: " + message)
+}
+
+func (a *app) printPlaced(orderId uint64) error {
+	t := table{header: []string{"order_id"}}
+	t.add(id(orderId))
+	return a.print(placed{OrderId: orderId}, t)
+}
+
+func historyTable() table {
+	return table{header: []string{"id", "time", "pair", "type", "price", "original", "remaining", "status", "stop", "client_order_id"}}
+}
+
+func addHistory(t *table, o secure.OrderHistoryData) {
+	t.add(id(o.Id), timestamp(o.Timestamp), o.CurrencyPair, o.Type, number(o.Price), number(o.OriginalAmount), number(o.RemainingAmount), o.Status, optional(o.StopPrice), optionalId(o.ClientOrderId))
+}
+
+func parseAmount(name, s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 {
+		return 0, usageError{fmt.Sprintf("invalid %s %q, expected a positive number", name, s)}
+	}
+	return v, nil
+}
+
+func parseId(s string) (uint64, error) {
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, usageError{fmt.Sprintf("invalid order ID %q", s)}
+	}
+	return v, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Balances are float32, printed without float64 noise
+func number32(v float32) string {
+	return strconv.FormatFloat(float64(v), 'f', -1, 32)
+}
+
+func optional(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return number(v)
+}
+
+func optionalId(v uint64) string {
+	if v == 0 {
+		return ""
+	}
+	return id(v)
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Environment variables, they take precedence over the config file
+const (
+	envConfig     = "COINMATE_CONFIG"
+	envClientId   = "COINMATE_CLIENT_ID"
+	envApiKey     = "COINMATE_API_KEY"
+	envPrivateKey = "COINMATE_PRIVATE_KEY"
+	envBaseUrl    = "COINMATE_BASE_URL"
+	// Bearer tokens of the gateway started by serve
+	envReadToken  = "COINMATE_GATEWAY_READ_TOKEN"
+	envTradeToken = "COINMATE_GATEWAY_TRADE_TOKEN"
+)
+
+// Contents of the JSON config file
+type Config struct {
+	ClientId   string `json:"clientId"`
+	ApiKey     string `json:"apiKey"`
+	PrivateKey string `json:"privateKey"`
+	// API root, e.g. of a local emulator, the Coinmate API when empty
+	BaseUrl string `json:"baseUrl,omitempty"`
+	// HTTP timeout like "5s", the client default when empty
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// Load config from path, or from $COINMATE_CONFIG or the user config
+// directory when path is empty, and apply the environment. A missing default
+// file is not an error. The returned warning is set when the file is
+// readable by other users.
+func LoadConfig(path string, getenv func(string) string) (Config, string, error) {
+	c := Config{}
+	explicit := path != ""
+	if !explicit {
+		path = getenv(envConfig)
+		explicit = path != ""
+	}
+	if !explicit {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "coinmate", "config.json")
+		}
+	}
+
+	warning := ""
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist) && !explicit:
+		case err != nil:
+			return c, "", fmt.Errorf("failed to read config: %w", err)
+		default:
+			if err := json.Unmarshal(data, &c); err != nil {
+				return c, "", fmt.Errorf("failed to decode config %s: %w", path, err)
+			}
+			if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0o077 != 0 && c.PrivateKey != "" {
+				warning = fmt.Sprintf("config %s holds a private key and is readable by other users, run chmod 600", path)
+			}
+		}
+	}
+
+	for env, field := range map[string]*string{
+		envClientId:   &c.ClientId,
+		envApiKey:     &c.ApiKey,
+		envPrivateKey: &c.PrivateKey,
+		envBaseUrl:    &c.BaseUrl,
+	} {
+		if v := getenv(env); v != "" {
+			*field = v
+		}
+	}
+	if c.Timeout != "" {
+		if _, err := time.ParseDuration(c.Timeout); err != nil {
+			return c, "", fmt.Errorf("invalid timeout %q in config", c.Timeout)
+		}
+	}
+	return c, warning, nil
+}
+
+// Credentials for secure endpoints are set
+func (c Config) HasCredentials() bool {
+	return c.ClientId != "" && c.ApiKey != "" && c.PrivateKey != ""
+}
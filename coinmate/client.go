@@ -5,7 +5,6 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -21,7 +20,7 @@ const (
 	secureContentTypeValue = "application/x-www-form-urlencoded"
 
 	// Http request timeout to 2s
-	requestTimeout = 2 * time.Second
+	RequestTimeout = 2 * time.Second
 )
 
 // Request data
@@ -54,6 +53,7 @@ type CoinmateClient struct {
 	Nonce      string
 	Signature  string
 	httpClient http.Client
+	baseUrl    string
 	lastNonce  int64
 }
 
@@ -78,8 +78,9 @@ func GetCoinmateClient(clientId, publicKey, privateKey string) *CoinmateClient {
 	client.ClientID = clientId
 	client.ApiKey = publicKey
 	client.PrivateKey = privateKey
+	client.baseUrl = baseUrl
 	client.httpClient = http.Client{
-		Timeout: time.Duration(requestTimeout),
+		Timeout: time.Duration(RequestTimeout),
 	}
 	return client
 }
@@ -92,6 +93,15 @@ func (c *CoinmateClient) SetTimeout(timeout time.Duration) {
 	c.httpClient.Timeout = timeout
 }
 
+// SetBaseUrl points the client to another API root, e.g. a local emulator.
+// Empty values are ignored.
+func (c *CoinmateClient) SetBaseUrl(url string) {
+	if url == "" {
+		return
+	}
+	c.baseUrl = strings.TrimSuffix(url, "/")
+}
+
 // Return nonce (security)
 func (c *CoinmateClient) GetNonce() string {
 	now := time.Now().UnixNano()
@@ -123,7 +133,10 @@ func (c *CoinmateClient) GetSignature(clientId, apiKey, nonce, privateKey string
 
 // Return url prefix
 func (c *CoinmateClient) GetBaseUrl() string {
-	return baseUrl
+	if c.baseUrl == "" {
+		return baseUrl
+	}
+	return c.baseUrl
 }
 
 // Return request body due to security
@@ -143,10 +156,7 @@ func (c *CoinmateClient) GetRequestBody(additionalParams map[string]string) []by
 	q.Set("publicKey", c.ApiKey)
 	q.Set("nonce", nonce)
 	q.Set("signature", c.GetSignature(c.ClientID, c.ApiKey, nonce, c.PrivateKey))
-	v := q.Encode()
-	fmt.Println(v)
-
-	return []byte(v)
+	return []byte(q.Encode())
 }
 
 // Make public request
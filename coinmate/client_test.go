@@ -1,6 +1,8 @@
 package coinmate
 
 import (
+	"io"
+	"os"
 	"testing"
 	"time"
 )
@@ -123,6 +125,25 @@ func TestGetRequestBody(t *testing.T) {
 	}
 }
 
+func TestGetRequestBodyPrintsNothing(t *testing.T) {
+	client := GetCoinmateClient("test", "test", "test")
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	client.GetRequestBody(map[string]string{"param1": "value1"})
+	os.Stdout = stdout
+	w.Close()
+
+	// The body carries the signature, it must never reach the output
+	if output, _ := io.ReadAll(r); len(output) != 0 {
+		t.Errorf("Expected no output, got %q", output)
+	}
+}
+
 func TestGetRequestBodyEmpty(t *testing.T) {
 	client := GetCoinmateClient("test", "test", "test")
 
@@ -176,6 +197,26 @@ func TestSetTimeout(t *testing.T) {
 	}
 }
 
+func TestSetBaseUrl(t *testing.T) {
+	client := GetCoinmateClient("test", "test", "test")
+
+	client.SetBaseUrl("http://127.0.0.1:8080/api/")
+	if client.GetBaseUrl() != "http://127.0.0.1:8080/api" {
+		t.Errorf("Expected base URL without trailing slash, got %s", client.GetBaseUrl())
+	}
+
+	// ignore empty values
+	client.SetBaseUrl("")
+	if client.GetBaseUrl() != "http://127.0.0.1:8080/api" {
+		t.Errorf("Expected base URL unchanged when setting empty value, got %s", client.GetBaseUrl())
+	}
+
+	// zero value client falls back to the production API
+	if (&CoinmateClient{}).GetBaseUrl() != "https://coinmate.io/api" {
+		t.Error("Expected zero value client to use the default base URL")
+	}
+}
+
 // Helper function to check if a string contains a substring
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) &&
@@ -0,0 +1,459 @@
+package coinmatetest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"tourGo/coinmate/matching"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+// Public endpoints
+
+func (s *Server) handleTicker(r *http.Request, _ *account) (interface{}, error) {
+	pair, err := s.pairParam(r, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.ticker(pair), nil
+}
+
+func (s *Server) handleTickerAll(r *http.Request, _ *account) (interface{}, error) {
+	tickers := map[string]public.TickerAllItem{}
+	for _, pair := range s.pairOrder {
+		t := s.ticker(pair)
+		tickers[pair] = public.TickerAllItem{
+			Last:   t.Last,
+			High:   t.High,
+			Low:    t.Low,
+			Bid:    t.Bid,
+			Ask:    t.Ask,
+			Change: t.Change,
+		}
+	}
+	return tickers, nil
+}
+
+func (s *Server) handleOrderBook(r *http.Request, _ *account) (interface{}, error) {
+	pair, err := s.pairParam(r, true)
+	if err != nil {
+		return nil, err
+	}
+
+	b := s.books[pair]
+	return public.OrderBookData{
+		Asks: aggregate(b.Asks),
+		Bids: aggregate(b.Bids),
+	}, nil
+}
+
+func (s *Server) handleTradingPairs(r *http.Request, _ *account) (interface{}, error) {
+	pairs := make([]public.TradingPairsData, 0, len(s.pairOrder))
+	for _, name := range s.pairOrder {
+		pairs = append(pairs, s.pairs[name])
+	}
+	return pairs, nil
+}
+
+func (s *Server) handleTransactions(r *http.Request, _ *account) (interface{}, error) {
+	pair, err := s.pairParam(r, true)
+	if err != nil {
+		return nil, err
+	}
+	minutes, err := strconv.ParseUint(r.Form.Get("minutesIntoHistory"), 10, 64)
+	if err != nil {
+		minutes = 10
+	}
+
+	since := s.now().Add(-time.Duration(minutes) * time.Minute).UnixMilli()
+	transactions := []public.TransactionsData{}
+	trades := s.trades[pair]
+	for i := len(trades) - 1; i >= 0; i-- {
+		if trades[i].Timestamp < since {
+			break
+		}
+		transactions = append(transactions, trades[i])
+	}
+	return transactions, nil
+}
+
+func (s *Server) handleCurrencies(r *http.Request, _ *account) (interface{}, error) {
+	seen := map[string]bool{}
+	currencies := []string{}
+	for _, name := range s.pairOrder {
+		for _, c := range []string{s.pairs[name].FirstCurrency, s.pairs[name].SecondCurrency} {
+			if !seen[c] {
+				seen[c] = true
+				currencies = append(currencies, c)
+			}
+		}
+	}
+	sort.Strings(currencies)
+	return currencies, nil
+}
+
+func (s *Server) handleCurrencyPairs(r *http.Request, _ *account) (interface{}, error) {
+	pairs := make([]public.CurrencyPair, 0, len(s.pairOrder))
+	for _, name := range s.pairOrder {
+		p := s.pairs[name]
+		pairs = append(pairs, public.CurrencyPair{Name: p.Name, FirstCurrency: p.FirstCurrency, SecondCurrency: p.SecondCurrency})
+	}
+	return pairs, nil
+}
+
+func (s *Server) handleServerTime(r *http.Request, _ *account) (interface{}, error) {
+	return s.now().UnixMilli(), nil
+}
+
+// Secure endpoints
+
+func (s *Server) handleBalances(r *http.Request, acc *account) (interface{}, error) {
+	return acc.Data(), nil
+}
+
+func (s *Server) handleOrderHistory(r *http.Request, acc *account) (interface{}, error) {
+	pair, err := s.pairParam(r, false)
+	if err != nil {
+		return nil, err
+	}
+	limit, _ := strconv.Atoi(r.Form.Get("limit"))
+
+	history := []secure.OrderHistoryData{}
+	for _, o := range s.accountOrders(acc, pair) {
+		if limit > 0 && len(history) == limit {
+			break
+		}
+		history = append(history, o.HistoryData())
+	}
+	return history, nil
+}
+
+func (s *Server) handleOrderById(r *http.Request, acc *account) (interface{}, error) {
+	orderId, err := strconv.ParseUint(r.Form.Get("orderId"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid orderId")
+	}
+	for _, o := range s.accountOrders(acc, "") {
+		if o.Id == orderId {
+			return o.HistoryData(), nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *Server) handleOrderByClientOrderId(r *http.Request, acc *account) (interface{}, error) {
+	clientOrderId, err := strconv.ParseUint(r.Form.Get("clientOrderId"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid clientOrderId")
+	}
+	orders := []secure.OrderHistoryData{}
+	for _, o := range s.accountOrders(acc, "") {
+		if o.ClientOrderId == clientOrderId {
+			orders = append(orders, o.HistoryData())
+		}
+	}
+	return orders, nil
+}
+
+func (s *Server) handleOpenOrders(r *http.Request, acc *account) (interface{}, error) {
+	pair, err := s.pairParam(r, false)
+	if err != nil {
+		return nil, err
+	}
+
+	open := []secure.OpenOrdersData{}
+	orders := s.accountOrders(acc, pair)
+	for i := len(orders) - 1; i >= 0; i-- {
+		o := orders[i]
+		if !o.Open() {
+			continue
+		}
+		open = append(open, o.OpenData())
+	}
+	return open, nil
+}
+
+func (s *Server) handleTradeHistory(r *http.Request, acc *account) (interface{}, error) {
+	pair, err := s.pairParam(r, false)
+	if err != nil {
+		return nil, err
+	}
+	limit, _ := strconv.Atoi(r.Form.Get("limit"))
+	offset, _ := strconv.Atoi(r.Form.Get("offset"))
+	lastId, _ := strconv.ParseUint(r.Form.Get("lastId"), 10, 64)
+	orderId, _ := strconv.ParseUint(r.Form.Get("orderId"), 10, 64)
+	from, _ := strconv.ParseInt(r.Form.Get("timestampFrom"), 10, 64)
+	to, _ := strconv.ParseInt(r.Form.Get("timestampTo"), 10, 64)
+	ascending := strings.ToUpper(r.Form.Get("sort")) == "ASC"
+
+	trades := []secure.TradeHistoryData{}
+	for _, f := range s.fills {
+		transactionId, _ := strconv.ParseUint(f.transactionId, 10, 64)
+		if f.clientId != acc.Id || (pair != "" && f.CurrencyPair != pair) || (orderId > 0 && f.OrderId != orderId) ||
+			transactionId <= lastId || (from > 0 && f.Timestamp < from) || (to > 0 && f.Timestamp > to) {
+			continue
+		}
+		feeType := "TAKER"
+		if f.Maker {
+			feeType = "MAKER"
+		}
+		trades = append(trades, secure.TradeHistoryData{
+			TransactionId:    transactionId,
+			CreatedTimestamp: f.Timestamp,
+			CurrencyPair:     f.CurrencyPair,
+			Type:             f.Side,
+			OrderType:        s.orders[f.OrderId].Type,
+			OrderId:          f.OrderId,
+			Amount:           f.Amount,
+			Price:            f.Price,
+			Fee:              f.Fee,
+			FeeType:          feeType,
+		})
+	}
+	if !ascending {
+		for i, j := 0, len(trades)-1; i < j; i, j = i+1, j-1 {
+			trades[i], trades[j] = trades[j], trades[i]
+		}
+	}
+
+	if offset > len(trades) {
+		offset = len(trades)
+	}
+	trades = trades[offset:]
+	if limit > 0 && len(trades) > limit {
+		trades = trades[:limit]
+	}
+	return trades, nil
+}
+
+func (s *Server) handleTransferHistory(r *http.Request, acc *account) (interface{}, error) {
+	limit, _ := strconv.Atoi(r.Form.Get("limit"))
+	lastId, _ := strconv.ParseUint(r.Form.Get("lastId"), 10, 64)
+	from, _ := strconv.ParseInt(r.Form.Get("timestampFrom"), 10, 64)
+	to, _ := strconv.ParseInt(r.Form.Get("timestampTo"), 10, 64)
+	currency := strings.ToUpper(r.Form.Get("currency"))
+	ascending := strings.ToUpper(r.Form.Get("sort")) == "ASC"
+
+	transfers := []secure.TransferHistoryData{}
+	for _, t := range acc.transfers {
+		if (currency != "" && t.AmountCurrency != currency) || t.TransactionId <= lastId ||
+			(from > 0 && t.Timestamp < from) || (to > 0 && t.Timestamp > to) {
+			continue
+		}
+		transfers = append(transfers, t)
+	}
+	if !ascending {
+		for i, j := 0, len(transfers)-1; i < j; i, j = i+1, j-1 {
+			transfers[i], transfers[j] = transfers[j], transfers[i]
+		}
+	}
+	if limit > 0 && len(transfers) > limit {
+		transfers = transfers[:limit]
+	}
+	return transfers, nil
+}
+
+func (s *Server) handleCancelOrder(r *http.Request, acc *account) (interface{}, error) {
+	orderId, err := strconv.ParseUint(r.Form.Get("orderId"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid orderId")
+	}
+	_, ok := s.cancel(acc, orderId)
+	return ok, nil
+}
+
+func (s *Server) handleCancelOrderWithInfo(r *http.Request, acc *account) (interface{}, error) {
+	orderId, err := strconv.ParseUint(r.Form.Get("orderId"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid orderId")
+	}
+	remaining, ok := s.cancel(acc, orderId)
+	return secure.CancelOrderWithInfoData{Success: ok, RemainingAmount: remaining}, nil
+}
+
+func (s *Server) handleBuyLimit(r *http.Request, acc *account) (interface{}, error) {
+	return s.handleLimit(r, acc, matching.Buy)
+}
+
+func (s *Server) handleSellLimit(r *http.Request, acc *account) (interface{}, error) {
+	return s.handleLimit(r, acc, matching.Sell)
+}
+
+func (s *Server) handleBuyInstant(r *http.Request, acc *account) (interface{}, error) {
+	return s.handleInstant(r, acc, matching.Buy, "total")
+}
+
+func (s *Server) handleSellInstant(r *http.Request, acc *account) (interface{}, error) {
+	return s.handleInstant(r, acc, matching.Sell, "amount")
+}
+
+func (s *Server) handleReplaceByBuyLimit(r *http.Request, acc *account) (interface{}, error) {
+	return s.handleReplace(r, acc, matching.Buy)
+}
+
+func (s *Server) handleReplaceBySellLimit(r *http.Request, acc *account) (interface{}, error) {
+	return s.handleReplace(r, acc, matching.Sell)
+}
+
+// Helper functions
+
+// Cancel order and place limit order, nothing is placed when the order is
+// not open
+func (s *Server) handleReplace(r *http.Request, acc *account, side string) (interface{}, error) {
+	orderId, err := strconv.ParseUint(r.Form.Get("orderIdToBeReplaced"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid orderIdToBeReplaced")
+	}
+	amount, err := floatParam(r, "amount", true)
+	if err != nil {
+		return nil, err
+	}
+	price, err := floatParam(r, "price", true)
+	if err != nil {
+		return nil, err
+	}
+	stopPrice, err := floatParam(r, "stopPrice", false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.pairParam(r, true); err != nil {
+		return nil, err
+	}
+	clientOrderId, _ := strconv.ParseUint(r.Form.Get("clientOrderId"), 10, 64)
+
+	remaining, ok := s.cancel(acc, orderId)
+	if !ok {
+		return secure.ReplaceOrderData{}, nil
+	}
+	created, err := s.placeLimit(acc, side, r.Form.Get("currencyPair"), amount, price, stopPrice,
+		r.Form.Get("hidden") == "1", r.Form.Get("immediateOrCancel") == "1", clientOrderId)
+	if err != nil {
+		return nil, err
+	}
+	return secure.ReplaceOrderData{Success: true, RemainingAmount: remaining, CreatedOrderId: created}, nil
+}
+
+func (s *Server) handleLimit(r *http.Request, acc *account, side string) (interface{}, error) {
+	amount, err := floatParam(r, "amount", true)
+	if err != nil {
+		return nil, err
+	}
+	price, err := floatParam(r, "price", true)
+	if err != nil {
+		return nil, err
+	}
+	stopPrice, err := floatParam(r, "stopPrice", false)
+	if err != nil {
+		return nil, err
+	}
+	clientOrderId, _ := strconv.ParseUint(r.Form.Get("clientOrderId"), 10, 64)
+
+	return s.placeLimit(acc, side, r.Form.Get("currencyPair"), amount, price, stopPrice,
+		r.Form.Get("hidden") == "1", r.Form.Get("immediateOrCancel") == "1", clientOrderId)
+}
+
+func (s *Server) handleInstant(r *http.Request, acc *account, side, quantityParam string) (interface{}, error) {
+	quantity, err := floatParam(r, quantityParam, true)
+	if err != nil {
+		return nil, err
+	}
+	clientOrderId, _ := strconv.ParseUint(r.Form.Get("clientOrderId"), 10, 64)
+
+	return s.placeInstant(acc, side, r.Form.Get("currencyPair"), quantity, clientOrderId)
+}
+
+// Return currency pair parameter, empty when optional and missing
+func (s *Server) pairParam(r *http.Request, required bool) (string, error) {
+	pair := strings.ToUpper(r.Form.Get("currencyPair"))
+	if pair == "" && !required {
+		return "", nil
+	}
+	if _, ok := s.pairs[pair]; !ok {
+		return "", fmt.Errorf("Invalid currency pair: %s", r.Form.Get("currencyPair"))
+	}
+	return pair, nil
+}
+
+func floatParam(r *http.Request, name string, required bool) (float64, error) {
+	value := r.Form.Get(name)
+	if value == "" && !required {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid %s: %s", name, value)
+	}
+	return f, nil
+}
+
+// Return orders of an account, newest first
+func (s *Server) accountOrders(acc *account, pair string) []*order {
+	var orders []*order
+	for _, o := range s.orders {
+		if o.Account == acc.Account && (pair == "" || o.Pair == pair) {
+			orders = append(orders, o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].Id > orders[j].Id })
+	return orders
+}
+
+func (s *Server) ticker(pair string) public.TickerData {
+	now := s.now()
+	t := public.TickerData{Timestamp: uint64(now.Unix())}
+
+	b := s.books[pair]
+	if len(b.Bids) > 0 {
+		t.Bid = b.Bids[0].Price
+	}
+	if len(b.Asks) > 0 {
+		t.Ask = b.Asks[0].Price
+	}
+
+	trades := s.trades[pair]
+	if len(trades) == 0 {
+		return t
+	}
+	t.Last = trades[len(trades)-1].Price
+
+	since := now.Add(-24 * time.Hour).UnixMilli()
+	for _, tx := range trades {
+		if tx.Timestamp < since {
+			continue
+		}
+		if t.Open == 0 {
+			t.Open, t.High, t.Low = tx.Price, tx.Price, tx.Price
+		}
+		if tx.Price > t.High {
+			t.High = tx.Price
+		}
+		if tx.Price < t.Low {
+			t.Low = tx.Price
+		}
+		t.Amount += tx.Amount
+	}
+	if t.Open > 0 {
+		t.Change = (t.Last - t.Open) / t.Open * 100
+	}
+	return t
+}
+
+// Sum visible resting orders per price level
+func aggregate(orders []*order) []public.OrderBookAsksBids {
+	levels := []public.OrderBookAsksBids{}
+	for _, o := range orders {
+		if o.Hidden {
+			continue
+		}
+		if n := len(levels); n > 0 && levels[n-1].Price == o.Price {
+			levels[n-1].Amount += o.Remaining
+			continue
+		}
+		levels = append(levels, public.OrderBookAsksBids{Price: o.Price, Amount: o.Remaining})
+	}
+	return levels
+}
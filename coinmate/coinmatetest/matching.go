@@ -0,0 +1,219 @@
+package coinmatetest
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"tourGo/coinmate/matching"
+	"tourGo/coinmate/public"
+)
+
+type order = matching.Order
+
+// Executed trade from the point of view of one order
+type fill struct {
+	matching.Fill
+	transactionId string
+	clientId      string
+}
+
+// Place limit order, stop orders wait until the last price reaches stopPrice
+func (s *Server) placeLimit(acc *account, side, currencyPair string, amount, price, stopPrice float64, hidden, immediateOrCancel bool, clientOrderId uint64) (uint64, error) {
+	pair, ok := s.pairs[strings.ToUpper(currencyPair)]
+	if !ok {
+		return 0, fmt.Errorf("Invalid currency pair: %s", currencyPair)
+	}
+	if amount <= 0 || price <= 0 || math.IsNaN(amount) || math.IsNaN(price) {
+		return 0, fmt.Errorf("Amount and price must be positive")
+	}
+	if amount < pair.MinAmount-matching.Epsilon {
+		return 0, fmt.Errorf("Minimum order size is %v %s", pair.MinAmount, pair.FirstCurrency)
+	}
+
+	o := s.newOrder(acc, side, pair, matching.Limit, clientOrderId)
+	o.Price = price
+	o.StopPrice = stopPrice
+	o.Original = amount
+	o.Remaining = amount
+	o.Hidden = hidden
+
+	reservePerUnit := 1.0
+	if side == matching.Buy {
+		reservePerUnit = price * (1 + s.takerFee)
+	}
+	if err := o.Reserve(amount*reservePerUnit, reservePerUnit); err != nil {
+		return 0, err
+	}
+	s.orders[o.Id] = o
+
+	if stopPrice > 0 && !s.stopTriggered(o) {
+		s.stops = append(s.stops, o)
+		return o.Id, nil
+	}
+
+	s.matchLimit(o, immediateOrCancel)
+	s.triggerStops()
+	return o.Id, nil
+}
+
+// Buy with a quote currency total or sell a base currency amount at market
+func (s *Server) placeInstant(acc *account, side, currencyPair string, quantity float64, clientOrderId uint64) (uint64, error) {
+	pair, ok := s.pairs[strings.ToUpper(currencyPair)]
+	if !ok {
+		return 0, fmt.Errorf("Invalid currency pair: %s", currencyPair)
+	}
+	if quantity <= 0 || math.IsNaN(quantity) {
+		return 0, fmt.Errorf("Amount must be positive")
+	}
+
+	o := s.newOrder(acc, side, pair, matching.Market, clientOrderId)
+	b := s.books[pair.Name]
+
+	if side == matching.Buy {
+		if !o.Affords(quantity * (1 + s.takerFee)) {
+			return 0, matching.ErrInsufficientBalance
+		}
+		budget := quantity
+		for len(b.Asks) > 0 && budget > matching.Epsilon {
+			maker := b.Asks[0]
+			amount := math.Min(maker.Remaining, budget/maker.Price)
+			budget -= amount * maker.Price
+			o.Original += amount
+			o.Remaining += amount
+			s.execute(o, maker, amount, maker.Price)
+		}
+	} else {
+		if !o.Affords(quantity) {
+			return 0, matching.ErrInsufficientBalance
+		}
+		o.Original = quantity
+		o.Remaining = quantity
+		for len(b.Bids) > 0 && o.Remaining > matching.Epsilon {
+			maker := b.Bids[0]
+			s.execute(o, maker, math.Min(maker.Remaining, o.Remaining), maker.Price)
+		}
+	}
+
+	o.Complete()
+	s.orders[o.Id] = o
+	s.triggerStops()
+
+	return o.Id, nil
+}
+
+// Cancel open order, returns remaining amount
+func (s *Server) cancel(acc *account, orderId uint64) (float64, bool) {
+	o, ok := s.orders[orderId]
+	if !ok || o.Account != acc.Account || !o.Open() {
+		return 0, false
+	}
+
+	s.books[o.Pair].Remove(o)
+	for i, stop := range s.stops {
+		if stop == o {
+			s.stops = append(s.stops[:i], s.stops[i+1:]...)
+			break
+		}
+	}
+	o.Cancel()
+
+	return o.Remaining, true
+}
+
+// Match incoming limit order and rest the remainder
+func (s *Server) matchLimit(o *order, immediateOrCancel bool) {
+	b := s.books[o.Pair]
+	for o.Remaining > matching.Epsilon {
+		levels := b.Opposite(o.Side)
+		if len(levels) == 0 || !o.Crosses(levels[0].Price) {
+			break
+		}
+		maker := levels[0]
+		s.execute(o, maker, math.Min(o.Remaining, maker.Remaining), maker.Price)
+	}
+
+	if !o.Open() {
+		return
+	}
+	if immediateOrCancel {
+		o.Cancel()
+		return
+	}
+	b.Insert(o)
+}
+
+// Execute trade between incoming taker and resting maker order
+func (s *Server) execute(taker, maker *order, amount, price float64) {
+	s.nextTradeId++
+	transactionId := strconv.FormatUint(s.nextTradeId, 10)
+	timestamp := s.now().UnixMilli()
+
+	for _, o := range []*order{taker, maker} {
+		feeRate := s.takerFee
+		if o == maker {
+			feeRate = s.makerFee
+		}
+		s.fills = append(s.fills, fill{
+			Fill:          o.Settle(amount, price, amount*price*feeRate, o == maker, timestamp),
+			transactionId: transactionId,
+			clientId:      o.Account.Id,
+		})
+	}
+
+	if !maker.Open() {
+		s.books[maker.Pair].Remove(maker)
+	}
+
+	s.trades[taker.Pair] = append(s.trades[taker.Pair], public.TransactionsData{
+		Timestamp:     timestamp,
+		TransactionId: transactionId,
+		Price:         price,
+		Amount:        amount,
+		CurrencyPair:  taker.Pair,
+		TradeType:     taker.Side,
+	})
+}
+
+func (s *Server) stopTriggered(o *order) bool {
+	trades := s.trades[o.Pair]
+	if len(trades) == 0 {
+		return false
+	}
+	return o.StopReached(trades[len(trades)-1].Price)
+}
+
+// Activate stop orders whose stop price was reached
+func (s *Server) triggerStops() {
+	for {
+		triggered := -1
+		for i, o := range s.stops {
+			if s.stopTriggered(o) {
+				triggered = i
+				break
+			}
+		}
+		if triggered < 0 {
+			return
+		}
+		o := s.stops[triggered]
+		s.stops = append(s.stops[:triggered], s.stops[triggered+1:]...)
+		s.matchLimit(o, false)
+	}
+}
+
+func (s *Server) newOrder(acc *account, side string, pair public.TradingPairsData, orderType string, clientOrderId uint64) *order {
+	s.nextOrderId++
+	return &order{
+		Id:            s.nextOrderId,
+		ClientOrderId: clientOrderId,
+		Account:       acc.Account,
+		Pair:          pair.Name,
+		Base:          pair.FirstCurrency,
+		Quote:         pair.SecondCurrency,
+		Side:          side,
+		Type:          orderType,
+		Status:        matching.StatusOpen,
+		Timestamp:     s.now().UnixMilli(),
+	}
+}
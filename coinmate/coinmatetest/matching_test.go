@@ -0,0 +1,181 @@
+package coinmatetest
+
+import (
+	"math"
+	"testing"
+	"tourGo/coinmate/matching"
+)
+
+func newMatchingServer(t *testing.T) (*Server, *account) {
+	t.Helper()
+	s := NewServer()
+	t.Cleanup(s.Close)
+
+	s.AddAccount("1", "public-key", "private-key")
+	s.Deposit("1", "EUR", 100000)
+	s.Deposit("1", "BTC", 10)
+	return s, s.accounts["1"]
+}
+
+func TestLimitOrderPriceTimePriority(t *testing.T) {
+	s, acc := newMatchingServer(t)
+	first, _ := s.AddLiquidity("BTC_EUR", "SELL", 50000, 0.3)
+	second, _ := s.AddLiquidity("BTC_EUR", "SELL", 50000, 0.3)
+	s.AddLiquidity("BTC_EUR", "SELL", 49000, 0.1)
+
+	id, err := s.placeLimit(acc, matching.Buy, "btc_eur", 0.5, 50000, 0, false, false, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if s.orders[id].Status != matching.StatusFilled {
+		t.Errorf("Expected taker to be filled, got %s", s.orders[id].Status)
+	}
+	if s.orders[first].Status != matching.StatusFilled {
+		t.Errorf("Expected older order at the level to fill first, got %s", s.orders[first].Status)
+	}
+	if math.Abs(s.orders[second].Remaining-0.2) > matching.Epsilon || s.orders[second].Status != matching.StatusPartiallyFilled {
+		t.Errorf("Expected newer order partially filled with 0.2 left, got %f %s", s.orders[second].Remaining, s.orders[second].Status)
+	}
+
+	trades := s.trades["BTC_EUR"]
+	if len(trades) != 3 || trades[0].Price != 49000 {
+		t.Errorf("Expected best price to trade first, got %+v", trades)
+	}
+}
+
+func TestLimitOrderRestsAndIsMatchedLater(t *testing.T) {
+	s, acc := newMatchingServer(t)
+
+	id, _ := s.placeLimit(acc, matching.Sell, "BTC_EUR", 1, 51000, 0, false, false, 0)
+	if _, reserved := s.Balance("1", "BTC"); reserved != 1 {
+		t.Errorf("Expected 1 BTC reserved, got %f", reserved)
+	}
+
+	s.AddLiquidity("BTC_EUR", "BUY", 52000, 0.4)
+	o := s.orders[id]
+	if o.Status != matching.StatusPartiallyFilled || math.Abs(o.Remaining-0.6) > matching.Epsilon {
+		t.Errorf("Expected partial fill at maker price, got %s %f", o.Status, o.Remaining)
+	}
+	if s.trades["BTC_EUR"][0].Price != 51000 {
+		t.Errorf("Expected trade at resting price 51000, got %f", s.trades["BTC_EUR"][0].Price)
+	}
+
+	total, reserved := s.Balance("1", "BTC")
+	if math.Abs(total-9.6) > matching.Epsilon || math.Abs(reserved-0.6) > matching.Epsilon {
+		t.Errorf("Expected 9.6 BTC with 0.6 reserved, got %f/%f", total, reserved)
+	}
+	if eur, _ := s.Balance("1", "EUR"); math.Abs(eur-(100000+0.4*51000)) > 1e-6 {
+		t.Errorf("Unexpected EUR balance %f", eur)
+	}
+}
+
+func TestImmediateOrCancel(t *testing.T) {
+	s, acc := newMatchingServer(t)
+	s.AddLiquidity("BTC_EUR", "SELL", 50000, 0.2)
+
+	id, _ := s.placeLimit(acc, matching.Buy, "BTC_EUR", 0.5, 50000, 0, false, true, 0)
+	o := s.orders[id]
+	if o.Status != matching.StatusCancelled || math.Abs(o.Remaining-0.3) > matching.Epsilon {
+		t.Errorf("Expected remainder to be cancelled, got %s %f", o.Status, o.Remaining)
+	}
+	if len(s.books["BTC_EUR"].Bids) != 0 {
+		t.Error("Expected IOC order not to rest on the book")
+	}
+	if _, reserved := s.Balance("1", "EUR"); reserved != 0 {
+		t.Errorf("Expected no EUR reserved, got %f", reserved)
+	}
+}
+
+func TestFeesAreCharged(t *testing.T) {
+	s, acc := newMatchingServer(t)
+	s.SetFees(0.001, 0.002)
+	s.AddLiquidity("BTC_EUR", "SELL", 50000, 1)
+
+	s.placeLimit(acc, matching.Buy, "BTC_EUR", 1, 50000, 0, false, false, 0)
+
+	eur, reserved := s.Balance("1", "EUR")
+	if math.Abs(eur-(100000-50000*1.002)) > 1e-6 {
+		t.Errorf("Expected taker fee to be charged, got %f", eur)
+	}
+	if reserved != 0 {
+		t.Errorf("Expected reservation to be released, got %f", reserved)
+	}
+	if len(s.fills) != 2 || !s.fills[1].Maker || s.fills[1].Fee != 50 {
+		t.Errorf("Expected maker fill with 50 EUR fee, got %+v", s.fills)
+	}
+}
+
+func TestStopOrderTriggersOnLastPrice(t *testing.T) {
+	s, acc := newMatchingServer(t)
+	s.AddLiquidity("BTC_EUR", "BUY", 49000, 1)
+	s.AddLiquidity("BTC_EUR", "BUY", 48000, 1)
+
+	id, _ := s.placeLimit(acc, matching.Sell, "BTC_EUR", 0.5, 48000, 48500, false, false, 0)
+	if s.orders[id].Status != matching.StatusOpen || len(s.stops) != 1 {
+		t.Fatalf("Expected stop order to wait, got %s", s.orders[id].Status)
+	}
+
+	// Trade at 49000 does not reach the stop, selling into 48000 does
+	s.placeLimit(acc, matching.Sell, "BTC_EUR", 1, 49000, 0, false, false, 0)
+	if len(s.stops) != 1 {
+		t.Error("Expected stop order to still wait")
+	}
+	s.placeLimit(acc, matching.Sell, "BTC_EUR", 0.1, 48000, 0, false, false, 0)
+	if len(s.stops) != 0 || s.orders[id].Status != matching.StatusFilled {
+		t.Errorf("Expected stop order to trigger and fill, got %s", s.orders[id].Status)
+	}
+}
+
+func TestInstantOrders(t *testing.T) {
+	s, acc := newMatchingServer(t)
+	s.AddLiquidity("BTC_EUR", "SELL", 50000, 0.1)
+	s.AddLiquidity("BTC_EUR", "BUY", 40000, 0.1)
+
+	id, err := s.placeInstant(acc, matching.Buy, "BTC_EUR", 10000, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if o := s.orders[id]; o.Status != matching.StatusFilled || math.Abs(o.Original-0.1) > matching.Epsilon {
+		t.Errorf("Expected instant buy limited by book depth, got %s %f", o.Status, o.Original)
+	}
+
+	id, _ = s.placeInstant(acc, matching.Sell, "BTC_EUR", 0.05, 0)
+	if o := s.orders[id]; o.Status != matching.StatusFilled || math.Abs(o.Original-0.05) > matching.Epsilon {
+		t.Errorf("Expected instant sell of 0.05, got %s %f", o.Status, o.Original)
+	}
+
+	id, _ = s.placeInstant(acc, matching.Buy, "BTC_EUR", 100, 0)
+	if s.orders[id].Status != matching.StatusCancelled {
+		t.Errorf("Expected instant buy without liquidity to be cancelled, got %s", s.orders[id].Status)
+	}
+}
+
+func TestHiddenOrdersAreNotInOrderBook(t *testing.T) {
+	s, acc := newMatchingServer(t)
+	s.placeLimit(acc, matching.Sell, "BTC_EUR", 1, 50000, 0, true, false, 0)
+	s.AddLiquidity("BTC_EUR", "SELL", 50000, 1)
+	s.AddLiquidity("BTC_EUR", "SELL", 50000, 2)
+
+	levels := aggregate(s.books["BTC_EUR"].Asks)
+	if len(levels) != 1 || levels[0].Amount != 3 {
+		t.Errorf("Expected one visible level of 3 BTC, got %+v", levels)
+	}
+}
+
+func TestOrderValidation(t *testing.T) {
+	s, acc := newMatchingServer(t)
+
+	if _, err := s.placeLimit(acc, matching.Buy, "DOGE_EUR", 1, 1, 0, false, false, 0); err == nil {
+		t.Error("Expected error for unknown pair")
+	}
+	if _, err := s.placeLimit(acc, matching.Buy, "BTC_EUR", 0.0001, 50000, 0, false, false, 0); err == nil {
+		t.Error("Expected error below minimum amount")
+	}
+	if _, err := s.placeLimit(acc, matching.Buy, "BTC_EUR", 1, -1, 0, false, false, 0); err == nil {
+		t.Error("Expected error for negative price")
+	}
+	if _, err := s.placeInstant(acc, matching.Sell, "BTC_EUR", 100, 0); err == nil {
+		t.Error("Expected error for insufficient balance")
+	}
+}
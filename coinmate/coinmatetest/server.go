@@ -0,0 +1,468 @@
+package coinmatetest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"tourGo/coinmate"
+	"tourGo/coinmate/matching"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	apiPath = "/api"
+
+	// Owner of the liquidity added through AddLiquidity, its balances are unlimited
+	MarketClientId = "market"
+
+	// Account registered by NewFundedServer
+	FundedClientId = "1"
+)
+
+// Fault injected into matching requests
+type Fault struct {
+	// Respond with this HTTP status and Body instead of the real response
+	StatusCode int
+	Body       string
+	// Respond with HTTP 200 and an API error carrying this message
+	APIError string
+	// Close the connection without any response
+	CloseConnection bool
+	// Wait before handling the request
+	Delay time.Duration
+	// Handle the request before failing, the request takes effect but the
+	// caller does not learn about it
+	AfterHandling bool
+	// Number of requests affected, 0 keeps the fault until ClearFaults
+	Times int
+}
+
+type account struct {
+	*matching.Account
+	publicKey  string
+	privateKey string
+	lastNonce  int64
+	transfers  []secure.TransferHistoryData
+}
+
+// Server is a local Coinmate API emulator built on httptest.Server. It
+// implements the public and secure endpoints supported by this client,
+// verifies request signatures and nonces, keeps balances per account and
+// matches orders in memory with price-time priority. Point a real client
+// to it with NewClient or BaseUrl:
+//
+//	server := coinmatetest.NewServer()
+//	defer server.Close()
+//	server.AddAccount("1", "public", "private")
+//	server.Deposit("1", "EUR", 1000)
+//	client := server.NewClient("1")
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	pairs       map[string]public.TradingPairsData
+	pairOrder   []string
+	books       map[string]*matching.Book
+	stops       []*order
+	orders      map[uint64]*order
+	trades      map[string][]public.TransactionsData
+	fills       []fill
+	accounts    map[string]*account
+	faults      map[string][]*Fault
+	nextOrderId uint64
+	nextTradeId uint64
+	makerFee    float64
+	takerFee    float64
+	now         func() time.Time
+}
+
+// Return default trading pairs of the emulator
+func DefaultTradingPairs() []public.TradingPairsData {
+	return []public.TradingPairsData{
+		{Name: "BTC_EUR", FirstCurrency: "BTC", SecondCurrency: "EUR", PriceDecimals: 2, LotDecimals: 8, MinAmount: 0.0002},
+		{Name: "BTC_CZK", FirstCurrency: "BTC", SecondCurrency: "CZK", PriceDecimals: 0, LotDecimals: 8, MinAmount: 0.0002},
+		{Name: "ETH_EUR", FirstCurrency: "ETH", SecondCurrency: "EUR", PriceDecimals: 2, LotDecimals: 8, MinAmount: 0.001},
+		{Name: "ETH_CZK", FirstCurrency: "ETH", SecondCurrency: "CZK", PriceDecimals: 0, LotDecimals: 8, MinAmount: 0.001},
+	}
+}
+
+// Start emulator with the given trading pairs, DefaultTradingPairs when none
+func NewServer(pairs ...public.TradingPairsData) *Server {
+	if len(pairs) == 0 {
+		pairs = DefaultTradingPairs()
+	}
+
+	s := &Server{
+		pairs:       make(map[string]public.TradingPairsData),
+		books:       make(map[string]*matching.Book),
+		orders:      make(map[uint64]*order),
+		trades:      make(map[string][]public.TransactionsData),
+		accounts:    make(map[string]*account),
+		faults:      make(map[string][]*Fault),
+		nextOrderId: 1000,
+		nextTradeId: 5000,
+		now:         time.Now,
+	}
+	for _, p := range pairs {
+		name := strings.ToUpper(p.Name)
+		p.Name = name
+		s.pairs[name] = p
+		s.pairOrder = append(s.pairOrder, name)
+		s.books[name] = &matching.Book{}
+	}
+	s.accounts[MarketClientId] = &account{Account: &matching.Account{Id: MarketClientId, Unlimited: true}}
+
+	s.Server = httptest.NewServer(s.routes())
+	return s
+}
+
+// Start emulator with account FundedClientId holding 10000 EUR and 1 BTC,
+// closed when the test ends
+func NewFundedServer(t testing.TB, pairs ...public.TradingPairsData) *Server {
+	t.Helper()
+	s := NewServer(pairs...)
+	t.Cleanup(s.Close)
+
+	s.AddAccount(FundedClientId, "public-key", "private-key")
+	s.Deposit(FundedClientId, "EUR", 10000)
+	s.Deposit(FundedClientId, "BTC", 1)
+	return s
+}
+
+// Return API root to be used as client base URL
+func (s *Server) BaseUrl() string {
+	return s.URL + apiPath
+}
+
+// Return real client using the credentials of a registered account
+func (s *Server) NewClient(clientId string) *coinmate.CoinmateClient {
+	s.mu.Lock()
+	acc, ok := s.accounts[clientId]
+	s.mu.Unlock()
+
+	client := coinmate.GetCoinmateClient("", "", "")
+	if ok {
+		client = coinmate.GetCoinmateClient(acc.Id, acc.publicKey, acc.privateKey)
+	}
+	client.SetBaseUrl(s.BaseUrl())
+	return client
+}
+
+// Register account with API credentials
+func (s *Server) AddAccount(clientId, publicKey, privateKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.accounts[clientId] = &account{
+		Account:    matching.NewAccount(clientId, nil),
+		publicKey:  publicKey,
+		privateKey: privateKey,
+	}
+}
+
+// Credit account balance, negative amounts withdraw
+func (s *Server) Deposit(clientId, currency string, amount float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acc, ok := s.accounts[clientId]
+	if !ok {
+		return fmt.Errorf("unknown account %s", clientId)
+	}
+	b := acc.Balance(strings.ToUpper(currency))
+	if b.Total-b.Reserved+amount < -matching.Epsilon {
+		return fmt.Errorf("insufficient %s balance", currency)
+	}
+	b.Total += amount
+	return nil
+}
+
+// Credit a deposit or debit a withdrawal with its fee and record it in the
+// transfer history, unlike Deposit
+func (s *Server) AddTransfer(clientId, currency, transferType string, amount, fee float64) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acc, ok := s.accounts[clientId]
+	if !ok {
+		return 0, fmt.Errorf("unknown account %s", clientId)
+	}
+	currency = strings.ToUpper(currency)
+	b := acc.Balance(currency)
+	switch transferType {
+	case secure.TransferDeposit:
+		b.Total += amount - fee
+	case secure.TransferWithdrawal:
+		if b.Total-b.Reserved-amount-fee < -matching.Epsilon {
+			return 0, fmt.Errorf("insufficient %s balance", currency)
+		}
+		b.Total -= amount + fee
+	default:
+		return 0, fmt.Errorf("invalid transfer type %q", transferType)
+	}
+
+	s.nextTradeId++
+	acc.transfers = append(acc.transfers, secure.TransferHistoryData{
+		TransactionId:  s.nextTradeId,
+		Timestamp:      s.now().UnixMilli(),
+		AmountCurrency: currency,
+		Amount:         amount,
+		Fee:            fee,
+		WalletType:     currency,
+		TransferType:   transferType,
+		TransferStatus: secure.TransferCompleted,
+	})
+	return s.nextTradeId, nil
+}
+
+// Return total and reserved balance of an account
+func (s *Server) Balance(clientId, currency string) (float64, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acc, ok := s.accounts[clientId]
+	if !ok {
+		return 0, 0
+	}
+	b := acc.Balance(strings.ToUpper(currency))
+	return b.Total, b.Reserved
+}
+
+// Place resting order owned by the market account, e.g. to build an order book
+func (s *Server) AddLiquidity(currencyPair, side string, price, amount float64) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.placeLimit(s.accounts[MarketClientId], strings.ToUpper(side), currencyPair, amount, price, 0, false, false, 0)
+}
+
+// Append historical trade returned by the transactions endpoint
+func (s *Server) AddTransaction(tx public.TransactionsData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pair := strings.ToUpper(tx.CurrencyPair)
+	tx.CurrencyPair = pair
+	if tx.TransactionId == "" {
+		s.nextTradeId++
+		tx.TransactionId = strconv.FormatUint(s.nextTradeId, 10)
+	}
+	if tx.Timestamp == 0 {
+		tx.Timestamp = s.now().UnixMilli()
+	}
+	s.trades[pair] = append(s.trades[pair], tx)
+	sort.SliceStable(s.trades[pair], func(i, j int) bool { return s.trades[pair][i].Timestamp < s.trades[pair][j].Timestamp })
+}
+
+// Set maker and taker fee rates, e.g. 0.0025 for 0.25 %
+func (s *Server) SetFees(maker, taker float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.makerFee = maker
+	s.takerFee = taker
+}
+
+// Replace the clock used for timestamps
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = now
+}
+
+// Inject fault into requests of an endpoint, e.g. "/buyLimit". An empty
+// endpoint matches every request.
+func (s *Server) InjectFault(endpoint string, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fault := f
+	s.faults[endpoint] = append(s.faults[endpoint], &fault)
+}
+
+// Remove all injected faults
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = make(map[string][]*Fault)
+}
+
+// Helper functions
+
+// Endpoint handler, returns response data or an API error
+type handlerFunc func(r *http.Request, acc *account) (interface{}, error)
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	publicRoutes := map[string]handlerFunc{
+		"/ticker":                 s.handleTicker,
+		"/ticker-all":             s.handleTickerAll,
+		"/orderBook":              s.handleOrderBook,
+		"/tradingPairs":           s.handleTradingPairs,
+		"/transactions":           s.handleTransactions,
+		"/currencies":             s.handleCurrencies,
+		"/currency-pairs":         s.handleCurrencyPairs,
+		"/system/get-server-time": s.handleServerTime,
+	}
+	secureRoutes := map[string]handlerFunc{
+		"/balances":            s.handleBalances,
+		"/orderHistory":        s.handleOrderHistory,
+		"/openOrders":          s.handleOpenOrders,
+		"/orderById":           s.handleOrderById,
+		"/order":               s.handleOrderByClientOrderId,
+		"/tradeHistory":        s.handleTradeHistory,
+		"/transferHistory":     s.handleTransferHistory,
+		"/cancelOrder":         s.handleCancelOrder,
+		"/cancelOrderWithInfo": s.handleCancelOrderWithInfo,
+		"/buyLimit":            s.handleBuyLimit,
+		"/sellLimit":           s.handleSellLimit,
+		"/buyInstant":          s.handleBuyInstant,
+		"/sellInstant":         s.handleSellInstant,
+		"/replaceByBuyLimit":   s.handleReplaceByBuyLimit,
+		"/replaceBySellLimit":  s.handleReplaceBySellLimit,
+	}
+
+	for endpoint, h := range publicRoutes {
+		mux.Handle(apiPath+endpoint, s.endpoint(endpoint, false, h))
+	}
+	for endpoint, h := range secureRoutes {
+		mux.Handle(apiPath+endpoint, s.endpoint(endpoint, true, h))
+	}
+	return mux
+}
+
+func (s *Server) endpoint(endpoint string, secure bool, h handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fault := s.takeFault(endpoint)
+		if fault != nil && fault.Delay > 0 {
+			time.Sleep(fault.Delay)
+		}
+		if fault != nil && !fault.AfterHandling && s.writeFault(w, fault) {
+			return
+		}
+
+		if secure && r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			writeJSON(w, nil, fmt.Errorf("invalid request: %v", err))
+			return
+		}
+
+		s.mu.Lock()
+		var acc *account
+		var err error
+		if secure {
+			acc, err = s.authenticate(r)
+		}
+		var data interface{}
+		if err == nil {
+			data, err = h(r, acc)
+		}
+		s.mu.Unlock()
+
+		if fault != nil && fault.AfterHandling && s.writeFault(w, fault) {
+			return
+		}
+		writeJSON(w, data, err)
+	})
+}
+
+// Return and consume the first fault matching the endpoint
+func (s *Server) takeFault(endpoint string) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range []string{endpoint, ""} {
+		faults := s.faults[key]
+		if len(faults) == 0 {
+			continue
+		}
+		f := faults[0]
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults[key] = faults[1:]
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// Write fault response, returns false when the fault only delays the request
+func (s *Server) writeFault(w http.ResponseWriter, f *Fault) bool {
+	switch {
+	case f.CloseConnection:
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return true
+			}
+		}
+		http.Error(w, "connection closed", http.StatusBadGateway)
+		return true
+	case f.StatusCode != 0:
+		w.WriteHeader(f.StatusCode)
+		w.Write([]byte(f.Body))
+		return true
+	case f.APIError != "":
+		writeJSON(w, nil, fmt.Errorf("%s", f.APIError))
+		return true
+	}
+	return false
+}
+
+// Verify credentials, signature and nonce of a secure request
+func (s *Server) authenticate(r *http.Request) (*account, error) {
+	clientId := r.Form.Get("clientId")
+	acc, ok := s.accounts[clientId]
+	if !ok || acc.Unlimited || acc.publicKey != r.Form.Get("publicKey") {
+		return nil, fmt.Errorf("Access denied.")
+	}
+
+	nonce := r.Form.Get("nonce")
+	expected := signature(acc.privateKey, nonce+acc.Id+acc.publicKey)
+	if !strings.EqualFold(expected, r.Form.Get("signature")) {
+		return nil, fmt.Errorf("Invalid signature.")
+	}
+
+	n, err := strconv.ParseInt(nonce, 10, 64)
+	if err != nil || n <= acc.lastNonce {
+		return nil, fmt.Errorf("Invalid nonce.")
+	}
+	acc.lastNonce = n
+
+	return acc, nil
+}
+
+func signature(privateKey, message string) string {
+	h := hmac.New(sha256.New, []byte(privateKey))
+	h.Write([]byte(message))
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
+}
+
+func writeJSON(w http.ResponseWriter, data interface{}, err error) {
+	response := coinmate.CoinmateResponse{Data: data}
+	if err != nil {
+		response.Error = true
+		response.ErrorMessage = err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
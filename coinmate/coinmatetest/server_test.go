@@ -0,0 +1,364 @@
+package coinmatetest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"tourGo/coinmate"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	s := NewFundedServer(t)
+
+	s.AddLiquidity("BTC_EUR", "SELL", 50100, 0.5)
+	s.AddLiquidity("BTC_EUR", "SELL", 50000, 0.5)
+	s.AddLiquidity("BTC_EUR", "BUY", 49900, 0.5)
+	s.AddLiquidity("BTC_EUR", "BUY", 49800, 1.0)
+	return s
+}
+
+func TestPublicEndpoints(t *testing.T) {
+	s := newTestServer(t)
+	client := s.NewClient("")
+
+	book, err := (&public.OrderBook{Client: client}).GetOrderBook("BTC_EUR", false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(book.Data.Asks) != 2 || book.Data.Asks[0].Price != 50000 {
+		t.Errorf("Expected asks sorted from 50000, got %+v", book.Data.Asks)
+	}
+	if len(book.Data.Bids) != 2 || book.Data.Bids[0].Price != 49900 {
+		t.Errorf("Expected bids sorted from 49900, got %+v", book.Data.Bids)
+	}
+
+	ticker, err := (&public.Ticker{Client: client}).GetTicker("BTC_EUR")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ticker.Data.Bid != 49900 || ticker.Data.Ask != 50000 {
+		t.Errorf("Unexpected ticker %+v", ticker.Data)
+	}
+
+	pairs, err := (&public.TradingPairs{Client: client}).GetTradingPairs()
+	if err != nil || len(pairs.Data) != 4 {
+		t.Errorf("Expected 4 trading pairs, got %d (%v)", len(pairs.Data), err)
+	}
+
+	currencies, err := (&public.Currencies{Client: client}).GetCurrencies()
+	if err != nil || strings.Join(currencies.Data, ",") != "BTC,CZK,ETH,EUR" {
+		t.Errorf("Unexpected currencies %v (%v)", currencies.Data, err)
+	}
+
+	currencyPairs, err := (&public.CurrencyPairs{Client: client}).GetCurrencyPairs()
+	if err != nil || len(currencyPairs.Data) != 4 {
+		t.Errorf("Expected 4 currency pairs, got %d (%v)", len(currencyPairs.Data), err)
+	}
+
+	tickers, err := (&public.TickerAll{Client: client}).GetTickerAll()
+	if err != nil || tickers.Data["BTC_EUR"].Ask != 50000 {
+		t.Errorf("Unexpected ticker-all %+v (%v)", tickers.Data, err)
+	}
+
+	serverTime, err := (&public.ServerTime{Client: client}).GetServerTime()
+	if err != nil || serverTime.Data == 0 {
+		t.Errorf("Expected server time, got %d (%v)", serverTime.Data, err)
+	}
+
+	invalid, err := (&public.Ticker{Client: client}).GetTicker("DOGE_EUR")
+	if err != nil || !invalid.Error {
+		t.Errorf("Expected API error for unknown pair, got %+v (%v)", invalid, err)
+	}
+}
+
+func TestTransactionsWindow(t *testing.T) {
+	s := newTestServer(t)
+	now := time.Now()
+	s.AddTransaction(public.TransactionsData{Timestamp: now.Add(-2 * time.Hour).UnixMilli(), Price: 49000, Amount: 1, CurrencyPair: "BTC_EUR", TradeType: "BUY"})
+	s.AddTransaction(public.TransactionsData{Timestamp: now.Add(-time.Minute).UnixMilli(), Price: 49500, Amount: 1, CurrencyPair: "BTC_EUR", TradeType: "SELL"})
+
+	response, err := (&public.Transactions{Client: s.NewClient("")}).GetTransactions("BTC_EUR", 60)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(response.Data) != 1 || response.Data[0].Price != 49500 {
+		t.Errorf("Expected only the recent trade, got %+v", response.Data)
+	}
+}
+
+func TestSecureOrderLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	client := s.NewClient("1")
+	order := &secure.Order{Client: client}
+
+	placed, err := order.BuyLimit(0.1, 49000, 0, "BTC_EUR", false, false, 42)
+	if err != nil || placed.Error {
+		t.Fatalf("Expected order to be placed, got %+v (%v)", placed, err)
+	}
+
+	open, err := order.GetOpenOrders("BTC_EUR")
+	if err != nil || len(open.Data) != 1 || open.Data[0].Id != placed.OrderId {
+		t.Fatalf("Expected 1 open order, got %+v (%v)", open.Data, err)
+	}
+	if _, reserved := s.Balance("1", "EUR"); reserved != 4900 {
+		t.Errorf("Expected 4900 EUR reserved, got %f", reserved)
+	}
+
+	cancelled, err := order.CancelOrderWithInfo(placed.OrderId)
+	if err != nil || !cancelled.Data.Success || cancelled.Data.RemainingAmount != 0.1 {
+		t.Errorf("Expected successful cancel with 0.1 remaining, got %+v (%v)", cancelled, err)
+	}
+	if _, reserved := s.Balance("1", "EUR"); reserved != 0 {
+		t.Errorf("Expected reservation to be released, got %f", reserved)
+	}
+
+	again, err := order.CancelOrder(placed.OrderId)
+	if err != nil || again.Data {
+		t.Errorf("Expected second cancel to fail, got %+v (%v)", again, err)
+	}
+
+	history, err := order.GetHistory("BTC_EUR", 10)
+	if err != nil || len(history.Data) != 1 || history.Data[0].Status != "CANCELLED" {
+		t.Errorf("Expected cancelled order in history, got %+v (%v)", history.Data, err)
+	}
+}
+
+func TestSecureTradingUpdatesBalances(t *testing.T) {
+	s := newTestServer(t)
+	client := s.NewClient("1")
+	order := &secure.Order{Client: client}
+
+	bought, err := order.BuyInstant(7500, "BTC_EUR", 0)
+	if err != nil || bought.Error {
+		t.Fatalf("Expected instant buy, got %+v (%v)", bought, err)
+	}
+	sold, err := order.SellLimit(0.25, 49900, 0, "BTC_EUR", false, false, 0)
+	if err != nil || sold.Error {
+		t.Fatalf("Expected sell limit, got %+v (%v)", sold, err)
+	}
+
+	balances, err := (&secure.Balances{Client: client}).GetBalances()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// 0.15 @ 50000 bought, 0.25 @ 49900 sold
+	eur := balances.Data["EUR"]
+	if diff := eur.Balance - float32(10000-7500+0.25*49900); diff > 0.01 || diff < -0.01 {
+		t.Errorf("Unexpected EUR balance %f", eur.Balance)
+	}
+	btc := balances.Data["BTC"]
+	if diff := btc.Balance - float32(1+0.15-0.25); diff > 1e-5 || diff < -1e-5 {
+		t.Errorf("Unexpected BTC balance %f", btc.Balance)
+	}
+
+	transactions, _ := (&public.Transactions{Client: client}).GetTransactions("BTC_EUR", 10)
+	if len(transactions.Data) != 2 {
+		t.Errorf("Expected 2 public trades, got %d", len(transactions.Data))
+	}
+}
+
+func TestInsufficientBalance(t *testing.T) {
+	s := newTestServer(t)
+	order := &secure.Order{Client: s.NewClient("1")}
+
+	response, err := order.BuyLimit(1, 49000, 0, "BTC_EUR", false, false, 0)
+	if err != nil {
+		t.Fatalf("Expected no transport error, got %v", err)
+	}
+	if !response.Error || !strings.Contains(response.ErrorMessage, "balance") {
+		t.Errorf("Expected balance error, got %+v", response)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	s := newTestServer(t)
+
+	wrongKey := coinmate.GetCoinmateClient("1", "public-key", "wrong-private-key")
+	wrongKey.SetBaseUrl(s.BaseUrl())
+	response, err := (&secure.Balances{Client: wrongKey}).GetBalances()
+	if err != nil || !response.Error || response.ErrorMessage != "Invalid signature." {
+		t.Errorf("Expected invalid signature, got %+v (%v)", response, err)
+	}
+
+	unknown := coinmate.GetCoinmateClient("2", "public-key", "private-key")
+	unknown.SetBaseUrl(s.BaseUrl())
+	response, err = (&secure.Balances{Client: unknown}).GetBalances()
+	if err != nil || !response.Error {
+		t.Errorf("Expected access denied for unknown client, got %+v (%v)", response, err)
+	}
+}
+
+func TestNonceReplayIsRejected(t *testing.T) {
+	s := newTestServer(t)
+	client := s.NewClient("1")
+
+	body := client.GetRequestBody(nil)
+	request := coinmate.Request{HTTPMethod: http.MethodPost, URL: s.BaseUrl() + "/balances", Body: body}
+
+	first, err := client.MakeSecureRequest(request)
+	if err != nil || strings.Contains(string(first.Body), `"error":true`) {
+		t.Fatalf("Expected first request to succeed, got %s (%v)", first.Body, err)
+	}
+	replay, err := client.MakeSecureRequest(request)
+	if err != nil || !strings.Contains(string(replay.Body), "Invalid nonce.") {
+		t.Errorf("Expected replayed nonce to be rejected, got %s (%v)", replay.Body, err)
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	s := newTestServer(t)
+	client := s.NewClient("1")
+	ticker := &public.Ticker{Client: client}
+
+	s.InjectFault("/ticker", Fault{StatusCode: http.StatusServiceUnavailable, Body: "maintenance", Times: 1})
+	if _, err := ticker.GetTicker("BTC_EUR"); err == nil {
+		t.Error("Expected error for injected HTTP status")
+	}
+	if _, err := ticker.GetTicker("BTC_EUR"); err != nil {
+		t.Errorf("Expected fault to be consumed, got %v", err)
+	}
+
+	s.InjectFault("", Fault{APIError: "Rate limit exceeded", Times: 1})
+	response, err := ticker.GetTicker("BTC_EUR")
+	if err != nil || response.ErrorMessage != "Rate limit exceeded" {
+		t.Errorf("Expected injected API error, got %+v (%v)", response, err)
+	}
+
+	s.InjectFault("/ticker", Fault{CloseConnection: true})
+	if _, err := ticker.GetTicker("BTC_EUR"); err == nil {
+		t.Error("Expected error for closed connection")
+	}
+	s.ClearFaults()
+
+	client.SetTimeout(50 * time.Millisecond)
+	s.InjectFault("/ticker", Fault{Delay: 200 * time.Millisecond, Times: 1})
+	if _, err := ticker.GetTicker("BTC_EUR"); err == nil {
+		t.Error("Expected timeout for delayed response")
+	}
+}
+
+func TestFaultAfterHandlingStillPlacesOrder(t *testing.T) {
+	s := newTestServer(t)
+	order := &secure.Order{Client: s.NewClient("1")}
+
+	s.InjectFault("/buyLimit", Fault{StatusCode: http.StatusGatewayTimeout, AfterHandling: true, Times: 1})
+	if _, err := order.BuyLimit(0.1, 49000, 0, "BTC_EUR", false, false, 7); err == nil {
+		t.Error("Expected error for lost response")
+	}
+
+	open, err := order.GetOpenOrders("BTC_EUR")
+	if err != nil || len(open.Data) != 1 {
+		t.Errorf("Expected order to be placed despite the error, got %+v (%v)", open.Data, err)
+	}
+}
+
+func TestTradeHistory(t *testing.T) {
+	s := newTestServer(t)
+	s.SetFees(0.001, 0.002)
+	order := &secure.Order{Client: s.NewClient("1")}
+	tradeHistory := &secure.TradeHistory{Client: s.NewClient("1")}
+
+	placed, _ := order.BuyLimit(0.15, 50000, 0, "BTC_EUR", false, false, 0)
+	order.SellLimit(0.1, 49900, 0, "BTC_EUR", false, false, 0)
+
+	response, err := tradeHistory.GetTradeHistory(secure.TradeHistoryParams{CurrencyPair: "BTC_EUR"})
+	if err != nil || len(response.Data) != 2 {
+		t.Fatalf("Expected 2 trades, got %+v (%v)", response.Data, err)
+	}
+	if response.Data[0].Type != "SELL" || response.Data[0].FeeType != "TAKER" {
+		t.Errorf("Expected newest trade first, got %+v", response.Data[0])
+	}
+
+	byOrder, _ := tradeHistory.GetTradeHistory(secure.TradeHistoryParams{OrderId: placed.OrderId, Sort: "ASC"})
+	if len(byOrder.Data) != 1 || byOrder.Data[0].Amount != 0.15 || byOrder.Data[0].OrderType != "LIMIT" {
+		t.Errorf("Expected one fill of the buy order, got %+v", byOrder.Data)
+	}
+
+	paged, _ := tradeHistory.GetTradeHistory(secure.TradeHistoryParams{Sort: "ASC", LastId: byOrder.Data[0].TransactionId})
+	if len(paged.Data) != 1 || paged.Data[0].Type != "SELL" {
+		t.Errorf("Expected trades after lastId, got %+v", paged.Data)
+	}
+}
+
+func TestTransferHistory(t *testing.T) {
+	s := newTestServer(t)
+	transferHistory := &secure.TransferHistory{Client: s.NewClient("1")}
+
+	deposit, _ := s.AddTransfer("1", "btc", secure.TransferDeposit, 0.5, 0)
+	s.AddTransfer("1", "BTC", secure.TransferWithdrawal, 0.2, 0.0005)
+	if _, err := s.AddTransfer("1", "EUR", secure.TransferWithdrawal, 20000, 0); err == nil {
+		t.Error("Expected insufficient balance")
+	}
+	if total, _ := s.Balance("1", "BTC"); total != 1.2995 {
+		t.Errorf("Expected 1.2995 BTC, got %v", total)
+	}
+
+	response, err := transferHistory.GetTransferHistory(secure.TransferHistoryParams{Currency: "BTC"})
+	if err != nil || len(response.Data) != 2 {
+		t.Fatalf("Expected 2 transfers, got %+v (%v)", response.Data, err)
+	}
+	if w := response.Data[0]; w.TransferType != secure.TransferWithdrawal || w.Fee != 0.0005 || w.TransferStatus != secure.TransferCompleted {
+		t.Errorf("Expected newest transfer first, got %+v", w)
+	}
+
+	paged, _ := transferHistory.GetTransferHistory(secure.TransferHistoryParams{Sort: "ASC", LastId: deposit})
+	if len(paged.Data) != 1 || paged.Data[0].TransferType != secure.TransferWithdrawal {
+		t.Errorf("Expected transfers after lastId, got %+v", paged.Data)
+	}
+}
+
+func TestOrderLookup(t *testing.T) {
+	s := newTestServer(t)
+	order := &secure.Order{Client: s.NewClient("1")}
+
+	placed, _ := order.BuyLimit(0.1, 40000, 0, "BTC_EUR", false, false, 77)
+
+	byId, err := order.GetOrderById(placed.OrderId)
+	if err != nil || byId.Data == nil || byId.Data.ClientOrderId != 77 || byId.Data.CurrencyPair != "BTC_EUR" {
+		t.Fatalf("Expected order by ID, got %+v (%v)", byId.Data, err)
+	}
+	if missing, _ := order.GetOrderById(placed.OrderId + 100); missing.Data != nil {
+		t.Errorf("Expected no order for unknown ID, got %+v", missing.Data)
+	}
+
+	byClientId, err := order.GetOrderByClientOrderId(77)
+	if err != nil || len(byClientId.Data) != 1 || byClientId.Data[0].Id != placed.OrderId {
+		t.Errorf("Expected order by client order ID, got %+v (%v)", byClientId.Data, err)
+	}
+	s.AddAccount("2", "other-key", "other-secret")
+	other := &secure.Order{Client: s.NewClient("2")}
+	if foreign, _ := other.GetOrderByClientOrderId(77); len(foreign.Data) != 0 {
+		t.Errorf("Expected orders of other accounts to be hidden, got %+v", foreign.Data)
+	}
+}
+
+func TestReplaceOrder(t *testing.T) {
+	s := newTestServer(t)
+	order := &secure.Order{Client: s.NewClient("1")}
+
+	placed, _ := order.BuyLimit(0.1, 49000, 0, "BTC_EUR", false, false, 0)
+	replaced, err := order.ReplaceByBuyLimit(placed.OrderId, 0.05, 49500, 0, "BTC_EUR", false, false, 0)
+	if err != nil || replaced.Error || !replaced.Data.Success || replaced.Data.RemainingAmount != 0.1 || replaced.Data.CreatedOrderId == 0 {
+		t.Fatalf("Expected order replaced, got %+v (%v)", replaced, err)
+	}
+
+	open, _ := order.GetOpenOrders("BTC_EUR")
+	if len(open.Data) != 1 || open.Data[0].Id != replaced.Data.CreatedOrderId || open.Data[0].Price != 49500 {
+		t.Errorf("Expected only the new order open, got %+v", open.Data)
+	}
+	if _, reserved := s.Balance("1", "EUR"); reserved != 2475 {
+		t.Errorf("Expected reservation of the new order only, got %f", reserved)
+	}
+
+	again, err := order.ReplaceBySellLimit(placed.OrderId, 0.1, 51000, 0, "BTC_EUR", false, false, 0)
+	if err != nil || again.Data.Success || again.Data.CreatedOrderId != 0 {
+		t.Errorf("Expected no order placed for a closed order, got %+v (%v)", again, err)
+	}
+	if open, _ := order.GetOpenOrders("BTC_EUR"); len(open.Data) != 1 {
+		t.Errorf("Expected no new order, got %+v", open.Data)
+	}
+}
@@ -0,0 +1,170 @@
+package conditional
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	buySide  = "BUY"
+	sellSide = "SELL"
+)
+
+// Condition type
+type Kind string
+
+const (
+	// Triggers when the price moves against the position: a sell at or below
+	// the trigger price, a buy at or above it
+	KindStopLoss Kind = "STOP_LOSS"
+	// Triggers when the price moves in favour of the position: a sell at or
+	// above the trigger price, a buy at or below it
+	KindTakeProfit Kind = "TAKE_PROFIT"
+	// Stop following the best price seen at a fixed or relative distance
+	KindTrailingStop Kind = "TRAILING_STOP"
+)
+
+// Condition state
+type Status string
+
+const (
+	// Bracket exit waiting for the order of its entry to fill
+	StatusWaiting Status = "WAITING"
+	StatusPending Status = "PENDING"
+	// Order sent, OrderId is zero until the order is confirmed
+	StatusTriggered Status = "TRIGGERED"
+	StatusCancelled Status = "CANCELLED"
+	// Triggered but the order was not placed
+	StatusFailed Status = "FAILED"
+)
+
+// Condition placing an order once the price of a pair reaches a level. The
+// order is a limit order at LimitPrice when set, otherwise an instant order.
+type Condition struct {
+	Id           uint64 `json:"id"`
+	CurrencyPair string `json:"currencyPair"`
+	Kind         Kind   `json:"kind"`
+	// Side of the order placed when triggered
+	Side string `json:"side"`
+
+	// Price level of stop-loss and take-profit conditions
+	TriggerPrice float64 `json:"triggerPrice,omitempty"`
+	// Distance of a trailing stop from the best price, absolute or relative,
+	// e.g. 0.05 for 5 %
+	TrailAmount  float64 `json:"trailAmount,omitempty"`
+	TrailPercent float64 `json:"trailPercent,omitempty"`
+	// Best price seen by a trailing stop, highest for sells, lowest for buys
+	Extreme float64 `json:"extreme,omitempty"`
+
+	// Base amount of the order
+	Amount float64 `json:"amount"`
+	// Quote total of instant buys, Amount at the observed price when zero
+	Total      float64 `json:"total,omitempty"`
+	LimitPrice float64 `json:"limitPrice,omitempty"`
+
+	// Conditions of one group cancel each other when one triggers (OCO)
+	Group string `json:"group,omitempty"`
+	// Bracket entry this condition waits for
+	Parent uint64 `json:"parent,omitempty"`
+
+	Status Status `json:"status"`
+	// Price that triggered the condition
+	TriggeredAt float64 `json:"triggeredAt,omitempty"`
+	// Generated for the order and stored before it is sent
+	ClientOrderId uint64 `json:"clientOrderId,omitempty"`
+	OrderId       uint64 `json:"orderId,omitempty"`
+	Error         string `json:"error,omitempty"`
+	CreatedAt     int64  `json:"createdAt"`
+	UpdatedAt     int64  `json:"updatedAt"`
+}
+
+// Stop-loss selling amount when the price falls to triggerPrice
+func StopLoss(currencyPair string, amount, triggerPrice float64) Condition {
+	return Condition{CurrencyPair: currencyPair, Kind: KindStopLoss, Side: sellSide, Amount: amount, TriggerPrice: triggerPrice}
+}
+
+// Take-profit selling amount when the price rises to triggerPrice
+func TakeProfit(currencyPair string, amount, triggerPrice float64) Condition {
+	return Condition{CurrencyPair: currencyPair, Kind: KindTakeProfit, Side: sellSide, Amount: amount, TriggerPrice: triggerPrice}
+}
+
+// Trailing stop selling amount when the price falls by trailPercent from
+// its highest value, e.g. 0.05 for 5 %
+func TrailingStop(currencyPair string, amount, trailPercent float64) Condition {
+	return Condition{CurrencyPair: currencyPair, Kind: KindTrailingStop, Side: sellSide, Amount: amount, TrailPercent: trailPercent}
+}
+
+// Stop price of the condition, for trailing stops derived from the best
+// price seen so far
+func (c Condition) StopPrice() float64 {
+	if c.Kind != KindTrailingStop {
+		return c.TriggerPrice
+	}
+	distance := c.TrailAmount
+	if c.TrailPercent > 0 {
+		distance = c.Extreme * c.TrailPercent
+	}
+	if c.Side == sellSide {
+		return c.Extreme - distance
+	}
+	return c.Extreme + distance
+}
+
+// Whether the condition is still waiting to trigger
+func (c Condition) Active() bool {
+	return c.Status == StatusPending || c.Status == StatusWaiting
+}
+
+// Helper functions
+
+func (c *Condition) validate() error {
+	c.CurrencyPair = strings.ToUpper(c.CurrencyPair)
+	c.Side = strings.ToUpper(c.Side)
+
+	if c.CurrencyPair == "" {
+		return fmt.Errorf("currencyPair must not be empty")
+	}
+	if c.Side != buySide && c.Side != sellSide {
+		return fmt.Errorf("side must be %s or %s, got %q", buySide, sellSide, c.Side)
+	}
+	if c.Amount <= 0 && !(c.Side == buySide && c.Total > 0) {
+		return fmt.Errorf("amount must be positive")
+	}
+	switch c.Kind {
+	case KindStopLoss, KindTakeProfit:
+		if c.TriggerPrice <= 0 {
+			return fmt.Errorf("triggerPrice must be positive")
+		}
+	case KindTrailingStop:
+		if (c.TrailAmount <= 0) == (c.TrailPercent <= 0) {
+			return fmt.Errorf("exactly one of trailAmount and trailPercent must be positive")
+		}
+		if c.TrailPercent >= 1 {
+			return fmt.Errorf("trailPercent must be below 1")
+		}
+	default:
+		return fmt.Errorf("unknown condition kind %q", c.Kind)
+	}
+	return nil
+}
+
+// Update trailing extreme and report whether price triggers the condition
+func (c *Condition) observe(price float64) (triggered, changed bool) {
+	if c.Kind == KindTrailingStop {
+		better := c.Extreme == 0 || (c.Side == sellSide && price > c.Extreme) || (c.Side == buySide && price < c.Extreme)
+		if better {
+			c.Extreme = price
+			return false, true
+		}
+	}
+
+	stop := c.StopPrice()
+	fallsThrough := c.Side == sellSide
+	if c.Kind == KindTakeProfit {
+		fallsThrough = !fallsThrough
+	}
+	if fallsThrough {
+		return price <= stop, false
+	}
+	return price >= stop, false
+}
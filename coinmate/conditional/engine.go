@@ -0,0 +1,639 @@
+package conditional
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate"
+	"tourGo/coinmate/idempotent"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	filledStatus    = "FILLED"
+	cancelledStatus = "CANCELLED"
+
+	// Longer than the client timeout, see Engine
+	defaultRetryDelay = coinmate.RequestTimeout + time.Second
+	historyLimit      = 100
+)
+
+var (
+	ErrUnknownCondition  = errors.New("unknown condition")
+	ErrConditionInactive = errors.New("condition already triggered or cancelled")
+
+	errRejected = errors.New("order rejected")
+)
+
+// Engine keeps stop-loss, take-profit and trailing stop conditions and
+// places their orders when observed prices reach them. Prices come from
+// Poll, which reads the ticker, or from Observe, e.g. for every public trade.
+//
+// A condition is marked triggered and persisted with a generated client
+// order ID before its order is sent, so an order is never placed twice, also
+// across restarts. A send without a definite answer is looked up by the
+// client order ID after RetryDelay, Resolve looks up conditions left
+// triggered without an order, e.g. by a crash. An order that was not placed
+// leaves the condition failed. Bracket exits are armed once the order of
+// their entry has filled.
+type Engine struct {
+	Orders secure.OrderInterface
+	// Lookup of orders, recent order history is searched when nil
+	Lookup    secure.OrderLookupInterface
+	Ticker    *public.Ticker
+	Store     Store
+	Generator *idempotent.Generator
+	// Wait before looking up an order after a failed send, defaults to the
+	// client timeout and one second
+	RetryDelay time.Duration
+
+	// Called after a triggered condition placed its order or failed
+	OnTrigger func(Condition)
+
+	mu         sync.Mutex
+	conditions map[uint64]*Condition
+	// Conditions whose order is being sent
+	busy   map[uint64]bool
+	nextId uint64
+	now    func() time.Time
+	sleep  func(time.Duration)
+}
+
+// Return engine with conditions recovered from store, which may be nil.
+// Orders implementing secure.OrderLookupInterface are also used for lookups.
+func NewEngine(orders secure.OrderInterface, ticker *public.Ticker, store Store) (*Engine, error) {
+	e := &Engine{
+		Orders:     orders,
+		Ticker:     ticker,
+		Store:      store,
+		Generator:  idempotent.NewGenerator(),
+		conditions: make(map[uint64]*Condition),
+		busy:       make(map[uint64]bool),
+		now:        time.Now,
+		sleep:      time.Sleep,
+	}
+	if lookup, ok := orders.(secure.OrderLookupInterface); ok {
+		e.Lookup = lookup
+	}
+	if store == nil {
+		return e, nil
+	}
+
+	conditions, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range conditions {
+		c := conditions[i]
+		e.conditions[c.Id] = &c
+		if c.Id > e.nextId {
+			e.nextId = c.Id
+		}
+	}
+	return e, nil
+}
+
+// Add condition
+func (e *Engine) Add(c Condition) (Condition, error) {
+	added, err := e.add([]Condition{c}, nil)
+	if err != nil {
+		return Condition{}, err
+	}
+	return added[0], nil
+}
+
+// Add two conditions cancelling each other, e.g. a stop-loss and a
+// take-profit of one position
+func (e *Engine) OCO(a, b Condition) ([]Condition, error) {
+	return e.add([]Condition{a, b}, func(c []*Condition) {
+		group := fmt.Sprintf("oco-%d", c[0].Id)
+		c[0].Group, c[1].Group = group, group
+	})
+}
+
+// Add entry condition with a stop-loss and a take-profit, armed as OCO pair
+// once the order of the entry has filled
+func (e *Engine) Bracket(entry, stopLoss, takeProfit Condition) ([]Condition, error) {
+	return e.add([]Condition{entry, stopLoss, takeProfit}, func(c []*Condition) {
+		group := fmt.Sprintf("bracket-%d", c[0].Id)
+		for _, exit := range c[1:] {
+			exit.Parent = c[0].Id
+			exit.Group = group
+			exit.Status = StatusWaiting
+		}
+	})
+}
+
+// Cancel condition and the bracket exits waiting for it
+func (e *Engine) Cancel(id uint64) (Condition, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c, ok := e.conditions[id]
+	if !ok {
+		return Condition{}, fmt.Errorf("%w: %d", ErrUnknownCondition, id)
+	}
+	if !c.Active() {
+		return *c, fmt.Errorf("%w: %d", ErrConditionInactive, id)
+	}
+
+	backup := e.backup()
+	e.setStatus(c, StatusCancelled)
+	e.cancelChildren(c.Id)
+	if err := e.save(); err != nil {
+		e.restore(backup)
+		return *e.conditions[id], err
+	}
+	return *c, nil
+}
+
+// Condition by ID
+func (e *Engine) Condition(id uint64) (Condition, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c, ok := e.conditions[id]
+	if !ok {
+		return Condition{}, false
+	}
+	return *c, true
+}
+
+// All conditions ordered by ID
+func (e *Engine) All() []Condition {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.sorted(func(c *Condition) bool { return true })
+}
+
+// Conditions not yet triggered or cancelled
+func (e *Engine) Active() []Condition {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.sorted(func(c *Condition) bool { return c.Active() })
+}
+
+// Evaluate pending conditions of a pair at price and place the orders of
+// those triggered
+func (e *Engine) Observe(currencyPair string, price float64) error {
+	pair := strings.ToUpper(currencyPair)
+	if price <= 0 {
+		return nil
+	}
+
+	e.mu.Lock()
+	backup := e.backup()
+	changed := false
+	var fired []*Condition
+	for _, c := range e.pointers() {
+		if c.Status != StatusPending || c.CurrencyPair != pair {
+			continue
+		}
+		triggered, moved := c.observe(price)
+		if moved {
+			c.UpdatedAt = e.now().UnixMilli()
+			changed = true
+		}
+		if !triggered {
+			continue
+		}
+
+		c.TriggeredAt = price
+		c.ClientOrderId = e.Generator.Next()
+		e.setStatus(c, StatusTriggered)
+		changed = true
+		fired = append(fired, c)
+		for _, other := range e.conditions {
+			if c.Group != "" && other.Group == c.Group && other.Id != c.Id && other.Active() && other.Parent == c.Parent {
+				e.setStatus(other, StatusCancelled)
+			}
+		}
+	}
+	// Never send an order whose condition could trigger again after a crash
+	if changed {
+		if err := e.save(); err != nil {
+			e.restore(backup)
+			e.mu.Unlock()
+			return err
+		}
+	}
+	triggered := make([]Condition, len(fired))
+	for i, c := range fired {
+		triggered[i] = *c
+		e.busy[c.Id] = true
+	}
+	e.mu.Unlock()
+
+	var errs []error
+	for _, c := range triggered {
+		if err := e.send(c, price); err != nil {
+			errs = append(errs, fmt.Errorf("condition %d: %w", c.Id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Look up the orders of triggered conditions: orders sent without a definite
+// answer, e.g. before a crash, and bracket entries whose exits wait for the
+// fill. Called by Poll, call it after a restart when using Observe only.
+func (e *Engine) Resolve() error {
+	e.mu.Lock()
+	var unresolved []Condition
+	for _, c := range e.pointers() {
+		if c.Status == StatusTriggered && !e.busy[c.Id] && (c.OrderId == 0 || e.waiting(c.Id)) {
+			unresolved = append(unresolved, *c)
+		}
+	}
+	e.mu.Unlock()
+
+	var errs []error
+	for _, c := range unresolved {
+		if err := e.resolve(c); err != nil {
+			errs = append(errs, fmt.Errorf("condition %d: %w", c.Id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Resolve triggered conditions, then read last prices of pairs with pending
+// conditions and evaluate them
+func (e *Engine) Poll() error {
+	var errs []error
+	if err := e.Resolve(); err != nil {
+		errs = append(errs, err)
+	}
+
+	e.mu.Lock()
+	pairs := map[string]bool{}
+	for _, c := range e.conditions {
+		if c.Status == StatusPending {
+			pairs[c.CurrencyPair] = true
+		}
+	}
+	e.mu.Unlock()
+
+	sorted := make([]string, 0, len(pairs))
+	for pair := range pairs {
+		sorted = append(sorted, pair)
+	}
+	sort.Strings(sorted)
+
+	for _, pair := range sorted {
+		r, err := e.Ticker.GetTicker(pair)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if r.Error {
+			errs = append(errs, fmt.Errorf("ticker %s: %s", pair, r.ErrorMessage))
+			continue
+		}
+		if err := e.Observe(pair, r.Data.Last); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Poll periodically until the context is done
+func (e *Engine) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Poll(); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Helper functions
+
+func (e *Engine) add(conditions []Condition, link func([]*Condition)) ([]Condition, error) {
+	for i := range conditions {
+		if err := conditions[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now().UnixMilli()
+	added := make([]*Condition, len(conditions))
+	for i := range conditions {
+		c := conditions[i]
+		e.nextId++
+		c.Id = e.nextId
+		c.Status = StatusPending
+		c.Parent = 0
+		c.TriggeredAt, c.ClientOrderId, c.OrderId, c.Error = 0, 0, 0, ""
+		c.CreatedAt, c.UpdatedAt = now, now
+		added[i] = &c
+	}
+	if link != nil {
+		link(added)
+	}
+	for _, c := range added {
+		e.conditions[c.Id] = c
+	}
+
+	if err := e.save(); err != nil {
+		for _, c := range added {
+			delete(e.conditions, c.Id)
+		}
+		e.nextId -= uint64(len(added))
+		return nil, err
+	}
+
+	result := make([]Condition, len(added))
+	for i, c := range added {
+		result[i] = *c
+	}
+	return result, nil
+}
+
+// Send the order of a triggered condition and record the outcome, a send
+// without a definite answer is looked up after RetryDelay
+func (e *Engine) send(c Condition, price float64) error {
+	defer func() {
+		e.mu.Lock()
+		delete(e.busy, c.Id)
+		e.mu.Unlock()
+	}()
+
+	orderId, err := e.place(c, price)
+	if err != nil && !errors.Is(err, errRejected) && !errors.Is(err, secure.ErrNotSent) {
+		e.sleep(e.retryDelay())
+		found, ok, lookupErr := e.find(c)
+		if lookupErr != nil {
+			// Left triggered for Resolve, the order may exist
+			e.update(c.Id, func(current *Condition) { current.Error = err.Error() })
+			return fmt.Errorf("order outcome unknown: %w, lookup failed: %w", err, lookupErr)
+		}
+		if ok {
+			orderId, err = found, nil
+		}
+	}
+	if err != nil {
+		return errors.Join(err, e.fail(c.Id, err))
+	}
+	if saveErr := e.placed(c.Id, orderId); saveErr != nil {
+		return saveErr
+	}
+	if e.hasWaiting(c.Id) {
+		c.OrderId = orderId
+		return e.resolve(c)
+	}
+	return nil
+}
+
+// Look up the order of a triggered condition, then arm or cancel the bracket
+// exits waiting for it once it filled or was cancelled
+func (e *Engine) resolve(c Condition) error {
+	if c.OrderId == 0 {
+		if c.ClientOrderId == 0 {
+			return e.fail(c.Id, errors.New("interrupted before the order was confirmed, check the order history"))
+		}
+		orderId, ok, err := e.find(c)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return e.fail(c.Id, errors.New("order was not placed"))
+		}
+		if err := e.placed(c.Id, orderId); err != nil {
+			return err
+		}
+		c.OrderId = orderId
+	}
+	if !e.hasWaiting(c.Id) {
+		return nil
+	}
+
+	order, err := e.order(c.OrderId, c.CurrencyPair)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch order.Status {
+	case filledStatus:
+		e.armChildren(c.Id)
+	case cancelledStatus:
+		// Exits of a partial entry would sell what was never bought
+		e.cancelChildren(c.Id)
+	default:
+		return nil
+	}
+	return e.save()
+}
+
+// Record the order of a triggered condition
+func (e *Engine) placed(id, orderId uint64) error {
+	result, err := e.update(id, func(c *Condition) {
+		c.OrderId = orderId
+		c.Error = ""
+	})
+	if e.OnTrigger != nil {
+		e.OnTrigger(result)
+	}
+	return err
+}
+
+// Mark a triggered condition failed, its order was not placed
+func (e *Engine) fail(id uint64, cause error) error {
+	result, err := e.update(id, func(c *Condition) {
+		c.Error = cause.Error()
+		e.setStatus(c, StatusFailed)
+		e.cancelChildren(c.Id)
+	})
+	if e.OnTrigger != nil {
+		e.OnTrigger(result)
+	}
+	return err
+}
+
+func (e *Engine) update(id uint64, change func(*Condition)) (Condition, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c := e.conditions[id]
+	change(c)
+	c.UpdatedAt = e.now().UnixMilli()
+	return *c, e.save()
+}
+
+// Order placed with the client order ID of a condition
+func (e *Engine) find(c Condition) (uint64, bool, error) {
+	o, found, err := idempotent.FindOrder(e.Orders, e.Lookup, c.ClientOrderId, c.CurrencyPair, historyLimit)
+	return o.Id, found, err
+}
+
+// Order by ID
+func (e *Engine) order(orderId uint64, currencyPair string) (secure.OrderHistoryData, error) {
+	if e.Lookup != nil {
+		r, err := e.Lookup.GetOrderById(orderId)
+		if err != nil {
+			return secure.OrderHistoryData{}, err
+		}
+		if r.Error {
+			return secure.OrderHistoryData{}, fmt.Errorf("order lookup failed: %s", r.ErrorMessage)
+		}
+		if r.Data == nil {
+			return secure.OrderHistoryData{}, fmt.Errorf("order %d not found", orderId)
+		}
+		return *r.Data, nil
+	}
+
+	r, err := e.Orders.GetHistory(currencyPair, historyLimit)
+	if err != nil {
+		return secure.OrderHistoryData{}, err
+	}
+	if r.Error {
+		return secure.OrderHistoryData{}, fmt.Errorf("order history failed: %s", r.ErrorMessage)
+	}
+	for _, o := range r.Data {
+		if o.Id == orderId {
+			return o, nil
+		}
+	}
+	return secure.OrderHistoryData{}, fmt.Errorf("order %d not found", orderId)
+}
+
+// Place the order of a triggered condition
+func (e *Engine) place(c Condition, price float64) (uint64, error) {
+	if c.LimitPrice > 0 {
+		var r secure.SellLimit
+		var err error
+		if c.Side == buySide {
+			r, err = e.Orders.BuyLimit(c.Amount, c.LimitPrice, 0, c.CurrencyPair, false, false, c.ClientOrderId)
+		} else {
+			r, err = e.Orders.SellLimit(c.Amount, c.LimitPrice, 0, c.CurrencyPair, false, false, c.ClientOrderId)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if r.Error {
+			return 0, fmt.Errorf("%w: %s", errRejected, r.ErrorMessage)
+		}
+		return r.OrderId, nil
+	}
+
+	var r secure.BuyAndSellResponse
+	var err error
+	if c.Side == buySide {
+		total := c.Total
+		if total <= 0 {
+			total = c.Amount * price
+		}
+		r, err = e.Orders.BuyInstant(total, c.CurrencyPair, c.ClientOrderId)
+	} else {
+		r, err = e.Orders.SellInstant(c.Amount, c.CurrencyPair, c.ClientOrderId)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if r.Error {
+		return 0, fmt.Errorf("%w: %s", errRejected, r.ErrorMessage)
+	}
+	return r.OrderId, nil
+}
+
+func (e *Engine) setStatus(c *Condition, status Status) {
+	c.Status = status
+	c.UpdatedAt = e.now().UnixMilli()
+}
+
+func (e *Engine) armChildren(parent uint64) {
+	for _, c := range e.conditions {
+		if c.Parent == parent && c.Status == StatusWaiting {
+			e.setStatus(c, StatusPending)
+		}
+	}
+}
+
+func (e *Engine) waiting(parent uint64) bool {
+	for _, c := range e.conditions {
+		if c.Parent == parent && c.Status == StatusWaiting {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Engine) hasWaiting(parent uint64) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.waiting(parent)
+}
+
+func (e *Engine) cancelChildren(parent uint64) {
+	for _, c := range e.conditions {
+		if c.Parent == parent && c.Active() {
+			e.setStatus(c, StatusCancelled)
+		}
+	}
+}
+
+func (e *Engine) backup() map[uint64]Condition {
+	backup := make(map[uint64]Condition, len(e.conditions))
+	for id, c := range e.conditions {
+		backup[id] = *c
+	}
+	return backup
+}
+
+func (e *Engine) restore(backup map[uint64]Condition) {
+	for id, c := range backup {
+		*e.conditions[id] = c
+	}
+}
+
+func (e *Engine) save() error {
+	if e.Store == nil {
+		return nil
+	}
+	return e.Store.Save(e.sorted(func(c *Condition) bool { return true }))
+}
+
+func (e *Engine) pointers() []*Condition {
+	conditions := make([]*Condition, 0, len(e.conditions))
+	for _, c := range e.conditions {
+		conditions = append(conditions, c)
+	}
+	sort.Slice(conditions, func(i, j int) bool { return conditions[i].Id < conditions[j].Id })
+	return conditions
+}
+
+func (e *Engine) sorted(include func(*Condition) bool) []Condition {
+	var conditions []Condition
+	for _, c := range e.pointers() {
+		if include(c) {
+			conditions = append(conditions, *c)
+		}
+	}
+	return conditions
+}
+
+func (e *Engine) retryDelay() time.Duration {
+	if e.RetryDelay > 0 {
+		return e.RetryDelay
+	}
+	return defaultRetryDelay
+}
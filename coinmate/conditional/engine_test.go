@@ -0,0 +1,334 @@
+package conditional
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+func newTestEngine(t *testing.T, store Store) (*Engine, *coinmatetest.Server) {
+	t.Helper()
+	server := coinmatetest.NewFundedServer(t)
+	server.AddLiquidity("BTC_EUR", "BUY", 40000, 1)
+	server.AddLiquidity("BTC_EUR", "SELL", 60000, 1)
+
+	client := server.NewClient("1")
+	e, err := NewEngine(&secure.Order{Client: client}, &public.Ticker{Client: client}, store)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	e.sleep = func(time.Duration) {}
+	return e, server
+}
+
+func TestStopLoss(t *testing.T) {
+	e, _ := newTestEngine(t, nil)
+	var triggered []Condition
+	e.OnTrigger = func(c Condition) { triggered = append(triggered, c) }
+
+	c, err := e.Add(StopLoss("btc_eur", 0.1, 45000))
+	if err != nil || c.Status != StatusPending || c.CurrencyPair != "BTC_EUR" {
+		t.Fatalf("Expected pending condition, got %+v (%v)", c, err)
+	}
+
+	e.Observe("BTC_EUR", 46000)
+	e.Observe("ETH_EUR", 1000)
+	if len(triggered) != 0 {
+		t.Fatalf("Expected no trigger above the stop, got %+v", triggered)
+	}
+
+	if err := e.Observe("BTC_EUR", 44900); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	c, _ = e.Condition(c.Id)
+	if c.Status != StatusTriggered || c.OrderId == 0 || c.TriggeredAt != 44900 || len(triggered) != 1 {
+		t.Errorf("Expected triggered stop-loss with order, got %+v", c)
+	}
+
+	e.Observe("BTC_EUR", 44000)
+	if len(triggered) != 1 {
+		t.Error("Expected condition to trigger only once")
+	}
+}
+
+func TestTakeProfitWithLimitPrice(t *testing.T) {
+	e, server := newTestEngine(t, nil)
+
+	tp := TakeProfit("BTC_EUR", 0.2, 55000)
+	tp.LimitPrice = 54900
+	c, _ := e.Add(tp)
+
+	e.Observe("BTC_EUR", 54000)
+	e.Observe("BTC_EUR", 55000)
+	c, _ = e.Condition(c.Id)
+	if c.Status != StatusTriggered {
+		t.Fatalf("Expected triggered take-profit, got %+v", c)
+	}
+
+	orders := &secure.Order{Client: server.NewClient("1")}
+	open, _ := orders.GetOpenOrders("BTC_EUR")
+	if len(open.Data) != 1 || open.Data[0].Price != 54900 || open.Data[0].Type != "SELL" || open.Data[0].Id != c.OrderId {
+		t.Errorf("Expected resting sell limit at 54900, got %+v", open.Data)
+	}
+}
+
+func TestTrailingStop(t *testing.T) {
+	e, _ := newTestEngine(t, nil)
+	c, _ := e.Add(TrailingStop("BTC_EUR", 0.1, 0.1))
+
+	for _, price := range []float64{50000, 55000, 52000, 49600} {
+		e.Observe("BTC_EUR", price)
+	}
+	c, _ = e.Condition(c.Id)
+	if c.Status != StatusPending || c.Extreme != 55000 || c.StopPrice() != 49500 {
+		t.Fatalf("Expected stop trailing at 49500, got %+v", c)
+	}
+
+	e.Observe("BTC_EUR", 49500)
+	if c, _ = e.Condition(c.Id); c.Status != StatusTriggered {
+		t.Errorf("Expected trailing stop triggered, got %+v", c)
+	}
+
+	buy := Condition{CurrencyPair: "BTC_EUR", Kind: KindTrailingStop, Side: "BUY", Amount: 0.01, TrailAmount: 1000}
+	b, _ := e.Add(buy)
+	for _, price := range []float64{50000, 48000, 48900} {
+		e.Observe("BTC_EUR", price)
+	}
+	if b, _ = e.Condition(b.Id); b.Status != StatusPending || b.StopPrice() != 49000 {
+		t.Fatalf("Expected buy stop trailing at 49000, got %+v", b)
+	}
+	e.Observe("BTC_EUR", 49000)
+	if b, _ = e.Condition(b.Id); b.Status != StatusTriggered {
+		t.Errorf("Expected trailing buy stop triggered, got %+v", b)
+	}
+}
+
+func TestOCO(t *testing.T) {
+	e, _ := newTestEngine(t, nil)
+
+	pair, err := e.OCO(StopLoss("BTC_EUR", 0.1, 45000), TakeProfit("BTC_EUR", 0.1, 55000))
+	if err != nil || pair[0].Group == "" || pair[0].Group != pair[1].Group {
+		t.Fatalf("Expected grouped conditions, got %+v (%v)", pair, err)
+	}
+
+	e.Observe("BTC_EUR", 55500)
+	stop, _ := e.Condition(pair[0].Id)
+	profit, _ := e.Condition(pair[1].Id)
+	if profit.Status != StatusTriggered || stop.Status != StatusCancelled {
+		t.Errorf("Expected take-profit triggered and stop-loss cancelled, got %s and %s", profit.Status, stop.Status)
+	}
+
+	e.Observe("BTC_EUR", 44000)
+	if stop, _ = e.Condition(pair[0].Id); stop.Status != StatusCancelled {
+		t.Errorf("Expected cancelled stop-loss to stay cancelled, got %s", stop.Status)
+	}
+}
+
+func TestBracket(t *testing.T) {
+	e, _ := newTestEngine(t, nil)
+
+	entry := Condition{CurrencyPair: "BTC_EUR", Kind: KindStopLoss, Side: "BUY", TriggerPrice: 51000, Total: 6000}
+	bracket, err := e.Bracket(entry, StopLoss("BTC_EUR", 0.1, 45000), TakeProfit("BTC_EUR", 0.1, 56000))
+	if err != nil || bracket[1].Status != StatusWaiting || bracket[2].Status != StatusWaiting {
+		t.Fatalf("Expected exits waiting for entry, got %+v (%v)", bracket, err)
+	}
+
+	e.Observe("BTC_EUR", 44000)
+	if stop, _ := e.Condition(bracket[1].Id); stop.Status != StatusWaiting {
+		t.Fatalf("Expected stop-loss not armed before entry, got %s", stop.Status)
+	}
+
+	e.Observe("BTC_EUR", 51000)
+	if entry, _ := e.Condition(bracket[0].Id); entry.Status != StatusTriggered || entry.OrderId == 0 {
+		t.Fatalf("Expected entry triggered, got %+v", entry)
+	}
+	if len(e.Active()) != 2 {
+		t.Fatalf("Expected exits armed, got %+v", e.Active())
+	}
+
+	e.Observe("BTC_EUR", 56000)
+	stop, _ := e.Condition(bracket[1].Id)
+	profit, _ := e.Condition(bracket[2].Id)
+	if profit.Status != StatusTriggered || stop.Status != StatusCancelled {
+		t.Errorf("Expected take-profit exit, got %s and %s", profit.Status, stop.Status)
+	}
+}
+
+func TestBracketArmedWhenEntryFills(t *testing.T) {
+	e, server := newTestEngine(t, nil)
+
+	entry := Condition{CurrencyPair: "BTC_EUR", Kind: KindStopLoss, Side: "BUY", TriggerPrice: 51000, Amount: 0.1, LimitPrice: 50000}
+	bracket, _ := e.Bracket(entry, StopLoss("BTC_EUR", 0.1, 45000), TakeProfit("BTC_EUR", 0.1, 56000))
+
+	e.Observe("BTC_EUR", 51000)
+	if entry, _ := e.Condition(bracket[0].Id); entry.Status != StatusTriggered || entry.OrderId == 0 {
+		t.Fatalf("Expected resting entry order, got %+v", entry)
+	}
+	if stop, _ := e.Condition(bracket[1].Id); stop.Status != StatusWaiting {
+		t.Fatalf("Expected stop-loss not armed before the entry fills, got %s", stop.Status)
+	}
+
+	server.AddLiquidity("BTC_EUR", "SELL", 50000, 0.1)
+	if err := e.Resolve(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(e.Active()) != 2 {
+		t.Errorf("Expected exits armed after the fill, got %+v", e.Active())
+	}
+}
+
+func TestCancelBracketEntry(t *testing.T) {
+	e, _ := newTestEngine(t, nil)
+
+	bracket, _ := e.Bracket(TakeProfit("BTC_EUR", 0.1, 55000), StopLoss("BTC_EUR", 0.1, 60000), TakeProfit("BTC_EUR", 0.1, 50000))
+	if _, err := e.Cancel(bracket[0].Id); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(e.Active()) != 0 {
+		t.Errorf("Expected exits cancelled with entry, got %+v", e.Active())
+	}
+	if _, err := e.Cancel(bracket[0].Id); !errors.Is(err, ErrConditionInactive) {
+		t.Errorf("Expected inactive condition error, got %v", err)
+	}
+	if _, err := e.Cancel(99); !errors.Is(err, ErrUnknownCondition) {
+		t.Errorf("Expected unknown condition error, got %v", err)
+	}
+}
+
+func TestFailedOrder(t *testing.T) {
+	e, _ := newTestEngine(t, nil)
+
+	c, _ := e.Add(StopLoss("BTC_EUR", 5, 45000))
+	if err := e.Observe("BTC_EUR", 44000); err == nil {
+		t.Fatal("Expected error for order over balance")
+	}
+	if c, _ = e.Condition(c.Id); c.Status != StatusFailed || c.Error == "" {
+		t.Errorf("Expected failed condition, got %+v", c)
+	}
+}
+
+func TestTimedOutOrderLookedUp(t *testing.T) {
+	e, server := newTestEngine(t, nil)
+	c, _ := e.Add(StopLoss("BTC_EUR", 0.1, 45000))
+
+	// The order executes, its response is lost
+	server.InjectFault("/sellInstant", coinmatetest.Fault{StatusCode: http.StatusGatewayTimeout, AfterHandling: true, Times: 1})
+	if err := e.Observe("BTC_EUR", 44000); err != nil {
+		t.Fatalf("Expected order found by its client order ID, got %v", err)
+	}
+	c, _ = e.Condition(c.Id)
+	if c.Status != StatusTriggered || c.OrderId == 0 || c.ClientOrderId == 0 {
+		t.Errorf("Expected triggered condition with its order, got %+v", c)
+	}
+
+	// The request never reaches the exchange
+	c, _ = e.Add(StopLoss("BTC_EUR", 0.1, 43000))
+	server.InjectFault("/sellInstant", coinmatetest.Fault{StatusCode: http.StatusGatewayTimeout, Times: 1})
+	if err := e.Observe("BTC_EUR", 42000); err == nil {
+		t.Fatal("Expected error for order not placed")
+	}
+	if c, _ = e.Condition(c.Id); c.Status != StatusFailed || c.OrderId != 0 {
+		t.Errorf("Expected failed condition, got %+v", c)
+	}
+}
+
+func TestTriggeredResolvedAfterRestart(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "conditions.json"))
+	e, server := newTestEngine(t, store)
+	sent, _ := e.Add(StopLoss("BTC_EUR", 0.1, 45000))
+	lost, _ := e.Add(StopLoss("BTC_EUR", 0.1, 45000))
+
+	// Crash after saving the trigger, only the first order was sent
+	conditions := e.All()
+	for i := range conditions {
+		conditions[i].Status = StatusTriggered
+		conditions[i].ClientOrderId = uint64(100 + i)
+	}
+	store.Save(conditions)
+	orders := &secure.Order{Client: server.NewClient("1")}
+	orders.SellInstant(0.1, "BTC_EUR", 100)
+
+	restarted, err := NewEngine(orders, nil, store)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := restarted.Resolve(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if c, _ := restarted.Condition(sent.Id); c.Status != StatusTriggered || c.OrderId == 0 {
+		t.Errorf("Expected sent order found, got %+v", c)
+	}
+	if c, _ := restarted.Condition(lost.Id); c.Status != StatusFailed {
+		t.Errorf("Expected condition without order failed, got %+v", c)
+	}
+}
+
+func TestPoll(t *testing.T) {
+	e, server := newTestEngine(t, nil)
+	c, _ := e.Add(StopLoss("BTC_EUR", 0.1, 45000))
+
+	server.AddTransaction(public.TransactionsData{CurrencyPair: "BTC_EUR", Price: 46000, Amount: 0.1, TradeType: "SELL"})
+	if err := e.Poll(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	server.AddTransaction(public.TransactionsData{CurrencyPair: "BTC_EUR", Price: 44000, Amount: 0.1, TradeType: "SELL"})
+	e.Poll()
+	if c, _ = e.Condition(c.Id); c.Status != StatusTriggered {
+		t.Errorf("Expected stop-loss triggered by ticker, got %+v", c)
+	}
+}
+
+func TestConditionsSurviveRestart(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "conditions.json"))
+	first, _ := newTestEngine(t, store)
+
+	trailing, _ := first.Add(TrailingStop("BTC_EUR", 0.1, 0.05))
+	stop, _ := first.Add(StopLoss("BTC_EUR", 0.1, 50500))
+	first.Observe("BTC_EUR", 52000)
+	first.Observe("BTC_EUR", 50000)
+
+	second, _ := newTestEngine(t, store)
+	restored, _ := second.Condition(trailing.Id)
+	if restored.Status != StatusPending || restored.Extreme != 52000 {
+		t.Fatalf("Expected trailing stop restored with its extreme, got %+v", restored)
+	}
+	if s, _ := second.Condition(stop.Id); s.Status != StatusTriggered || s.OrderId == 0 {
+		t.Errorf("Expected triggered stop-loss restored, got %+v", s)
+	}
+
+	next, _ := second.Add(StopLoss("BTC_EUR", 0.1, 40000))
+	if next.Id != 3 {
+		t.Errorf("Expected IDs to continue after restart, got %d", next.Id)
+	}
+	if math.Abs(restored.StopPrice()-49400) > 1e-9 {
+		t.Errorf("Expected stop price 49400, got %f", restored.StopPrice())
+	}
+}
+
+func TestValidation(t *testing.T) {
+	e, _ := newTestEngine(t, nil)
+
+	invalid := []Condition{
+		StopLoss("", 0.1, 45000),
+		StopLoss("BTC_EUR", 0, 45000),
+		StopLoss("BTC_EUR", 0.1, 0),
+		{CurrencyPair: "BTC_EUR", Kind: KindTrailingStop, Side: "SELL", Amount: 0.1},
+		{CurrencyPair: "BTC_EUR", Kind: KindTrailingStop, Side: "SELL", Amount: 0.1, TrailAmount: 100, TrailPercent: 0.1},
+		{CurrencyPair: "BTC_EUR", Kind: KindStopLoss, Side: "HOLD", Amount: 0.1, TriggerPrice: 1},
+		{CurrencyPair: "BTC_EUR", Kind: "OTHER", Side: "SELL", Amount: 0.1},
+	}
+	for _, c := range invalid {
+		if _, err := e.Add(c); err == nil {
+			t.Errorf("Expected error for %+v", c)
+		}
+	}
+	if len(e.All()) != 0 {
+		t.Error("Expected no invalid conditions added")
+	}
+}
@@ -0,0 +1,17 @@
+package conditional
+
+import "tourGo/coinmate/filestore"
+
+// Persistence of conditions, used to keep them armed across restarts
+type Store interface {
+	Load() ([]Condition, error)
+	Save(conditions []Condition) error
+}
+
+// Store keeping all conditions in one JSON file, see filestore.JSONFileStore
+type FileStore = filestore.JSONFileStore[Condition]
+
+// Return file store at path, the directory is created on first save
+func NewFileStore(path string) *FileStore {
+	return filestore.New[Condition](path, "condition")
+}
@@ -0,0 +1,389 @@
+package dashboard
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"tourGo/coinmate"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	buySide  = "BUY"
+	sellSide = "SELL"
+
+	defaultDepth   = 10
+	defaultTrades  = 10
+	defaultMinutes = 60
+)
+
+// Keys
+const (
+	keyBackspace = 127
+	keyCtrlH     = 8
+	keyEscape    = 27
+	keyTab       = '\t'
+)
+
+// Polled market and account data of the selected pair. Data of a failed
+// source is kept from the previous poll and its error is listed.
+type State struct {
+	Pair       string                            `json:"pair"`
+	Ticker     public.TickerData                 `json:"ticker"`
+	Book       public.OrderBookData              `json:"book"`
+	Trades     []public.TransactionsData         `json:"trades"`
+	Balances   map[string]secure.BalanceCurrency `json:"balances"`
+	OpenOrders []secure.OpenOrdersData           `json:"openOrders"`
+	Updated    time.Time                         `json:"updated"`
+	Errors     []string                          `json:"errors,omitempty"`
+}
+
+// Action waiting for input or confirmation
+type prompt struct {
+	label   string
+	buffer  string
+	confirm bool
+	// Parses the input and returns the confirmation question and the action
+	parse func(input string) (string, func() (string, error), error)
+	run   func() (string, error)
+}
+
+// Dashboard shows the order book, recent trades, ticker, balances and open
+// orders of one pair and places and cancels orders from single keys:
+//
+//	b, s     buy or sell limit, asks for amount and price
+//	c        cancel an order, asks for its ID
+//	x        cancel all open orders of the pair
+//	tab, p   next pair
+//	+, -     order book depth
+//	r        refresh now
+//	q        quit
+//
+// Orders are placed only after confirming with y.
+type Dashboard struct {
+	Book         *public.OrderBook
+	Transactions *public.Transactions
+	Ticker       *public.Ticker
+	Balances     secure.BalancesInterface
+	Orders       secure.OrderInterface
+
+	// Pairs switched between, the first one is shown first
+	Pairs []string
+	// Order book levels and trades shown
+	Depth  int
+	Trades int
+	// Trade history polled
+	Minutes uint64
+
+	pair    int
+	state   State
+	prompt  *prompt
+	message string
+	now     func() time.Time
+}
+
+// Return dashboard polling the client for the pairs
+func NewDashboard(client coinmate.ClientInterface, pairs ...string) (*Dashboard, error) {
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("at least one pair is required")
+	}
+	names := make([]string, len(pairs))
+	for i, p := range pairs {
+		names[i] = strings.ToUpper(strings.TrimSpace(p))
+		if names[i] == "" {
+			return nil, fmt.Errorf("pair must not be empty")
+		}
+	}
+	return &Dashboard{
+		Book:         &public.OrderBook{Client: client},
+		Transactions: &public.Transactions{Client: client},
+		Ticker:       &public.Ticker{Client: client},
+		Balances:     &secure.Balances{Client: client},
+		Orders:       &secure.Order{Client: client},
+		Pairs:        names,
+		Depth:        defaultDepth,
+		Trades:       defaultTrades,
+		Minutes:      defaultMinutes,
+		now:          time.Now,
+	}, nil
+}
+
+// Selected pair
+func (d *Dashboard) Pair() string {
+	return d.Pairs[d.pair]
+}
+
+// Last polled state
+func (d *Dashboard) State() State {
+	return d.state
+}
+
+// Poll all sources of the selected pair, the returned error joins the
+// errors of the failed sources
+func (d *Dashboard) Refresh() error {
+	pair := d.Pair()
+	if d.state.Pair != pair {
+		d.state = State{Pair: pair}
+	}
+	var errs []error
+
+	if r, err := d.Ticker.GetTicker(pair); err != nil {
+		errs = append(errs, err)
+	} else if r.Error {
+		errs = append(errs, fmt.Errorf("ticker %s: %s", pair, r.ErrorMessage))
+	} else {
+		d.state.Ticker = r.Data
+	}
+
+	if r, err := d.Book.GetOrderBook(pair, true); err != nil {
+		errs = append(errs, err)
+	} else if r.Error {
+		errs = append(errs, fmt.Errorf("order book %s: %s", pair, r.ErrorMessage))
+	} else {
+		d.state.Book = r.Data
+	}
+
+	if r, err := d.Transactions.GetTransactions(pair, d.Minutes); err != nil {
+		errs = append(errs, err)
+	} else if r.Error {
+		errs = append(errs, fmt.Errorf("transactions %s: %s", pair, r.ErrorMessage))
+	} else {
+		d.state.Trades = r.Data
+	}
+
+	if r, err := d.Balances.GetBalances(); err != nil {
+		errs = append(errs, err)
+	} else if r.Error {
+		errs = append(errs, fmt.Errorf("balances: %s", r.ErrorMessage))
+	} else {
+		d.state.Balances = r.Data
+	}
+
+	if r, err := d.Orders.GetOpenOrders(pair); err != nil {
+		errs = append(errs, err)
+	} else if r.Error {
+		errs = append(errs, fmt.Errorf("open orders %s: %s", pair, r.ErrorMessage))
+	} else {
+		d.state.OpenOrders = r.Data
+	}
+
+	d.state.Errors = nil
+	for _, err := range errs {
+		d.state.Errors = append(d.state.Errors, err.Error())
+	}
+	d.state.Updated = d.now()
+	return errors.Join(errs...)
+}
+
+// Handle a key press, returns true when the dashboard should quit
+func (d *Dashboard) Key(key rune) bool {
+	if d.prompt != nil {
+		d.promptKey(key)
+		return false
+	}
+
+	switch key {
+	case 'q', 'Q':
+		return true
+	case 'r':
+		d.message = ""
+		d.Refresh()
+	case keyTab, 'p':
+		d.pair = (d.pair + 1) % len(d.Pairs)
+		d.message = ""
+		d.Refresh()
+	case '+':
+		d.Depth++
+	case '-':
+		d.Depth = max(1, d.Depth-1)
+	case 'b':
+		d.prompt = &prompt{label: fmt.Sprintf("Buy %s, amount and price", d.Pair()), parse: d.limitOrder(buySide)}
+	case 's':
+		d.prompt = &prompt{label: fmt.Sprintf("Sell %s, amount and price", d.Pair()), parse: d.limitOrder(sellSide)}
+	case 'c':
+		d.prompt = &prompt{label: "Cancel order ID", parse: d.cancelOrder}
+	case 'x':
+		d.cancelAllPrompt()
+	}
+	return false
+}
+
+// Run the dashboard until q is pressed, the input ends or the context is
+// done. State is polled every interval and the screen redrawn after every
+// poll and key press.
+func (d *Dashboard) Run(ctx context.Context, in io.Reader, out io.Writer, interval time.Duration) error {
+	keys := make(chan rune)
+	go func() {
+		defer close(keys)
+		r := bufio.NewReader(in)
+		for {
+			key, _, err := r.ReadRune()
+			if err != nil {
+				return
+			}
+			select {
+			case keys <- key:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	d.Refresh()
+	for {
+		if err := d.Render(out); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			d.Refresh()
+		case key, ok := <-keys:
+			if !ok || d.Key(key) {
+				return nil
+			}
+		}
+	}
+}
+
+// Helper functions
+
+func (d *Dashboard) promptKey(key rune) {
+	p := d.prompt
+	if p.confirm {
+		d.prompt = nil
+		if key != 'y' && key != 'Y' {
+			d.message = "Aborted"
+			return
+		}
+		message, err := p.run()
+		if err != nil {
+			d.message = "Error: " + err.Error()
+		} else {
+			d.message = message
+		}
+		d.Refresh()
+		return
+	}
+
+	switch key {
+	case keyEscape:
+		d.prompt = nil
+		d.message = "Aborted"
+	case keyBackspace, keyCtrlH:
+		if p.buffer != "" {
+			runes := []rune(p.buffer)
+			p.buffer = string(runes[:len(runes)-1])
+		}
+	case '\r', '\n':
+		question, run, err := p.parse(p.buffer)
+		if err != nil {
+			d.prompt = nil
+			d.message = "Error: " + err.Error()
+			return
+		}
+		d.prompt = &prompt{label: question, confirm: true, run: run}
+	default:
+		if key >= ' ' {
+			p.buffer += string(key)
+		}
+	}
+}
+
+func (d *Dashboard) limitOrder(side string) func(string) (string, func() (string, error), error) {
+	return func(input string) (string, func() (string, error), error) {
+		fields := strings.Fields(input)
+		if len(fields) != 2 {
+			return "", nil, fmt.Errorf("expected amount and price")
+		}
+		amount, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || amount <= 0 {
+			return "", nil, fmt.Errorf("invalid amount %q", fields[0])
+		}
+		price, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || price <= 0 {
+			return "", nil, fmt.Errorf("invalid price %q", fields[1])
+		}
+
+		pair := d.Pair()
+		question := fmt.Sprintf("%s %s %s at %s?", side, formatNumber(amount), pair, formatNumber(price))
+		return question, func() (string, error) {
+			var r secure.SellLimit
+			var err error
+			if side == buySide {
+				r, err = d.Orders.BuyLimit(amount, price, 0, pair, false, false, 0)
+			} else {
+				r, err = d.Orders.SellLimit(amount, price, 0, pair, false, false, 0)
+			}
+			if err != nil {
+				return "", err
+			}
+			if r.Error {
+				return "", errors.New(r.ErrorMessage)
+			}
+			return fmt.Sprintf("Placed %s order %d", side, r.OrderId), nil
+		}, nil
+	}
+}
+
+func (d *Dashboard) cancelOrder(input string) (string, func() (string, error), error) {
+	orderId, err := strconv.ParseUint(strings.TrimSpace(input), 10, 64)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid order ID %q", input)
+	}
+	return fmt.Sprintf("Cancel order %d?", orderId), func() (string, error) {
+		if err := d.cancel(orderId); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Cancelled order %d", orderId), nil
+	}, nil
+}
+
+func (d *Dashboard) cancelAllPrompt() {
+	ids := make([]uint64, 0, len(d.state.OpenOrders))
+	for _, o := range d.state.OpenOrders {
+		ids = append(ids, o.Id)
+	}
+	if len(ids) == 0 {
+		d.message = "No open orders"
+		return
+	}
+	d.prompt = &prompt{
+		label:   fmt.Sprintf("Cancel %d open orders of %s?", len(ids), d.state.Pair),
+		confirm: true,
+		run: func() (string, error) {
+			var errs []error
+			for _, id := range ids {
+				errs = append(errs, d.cancel(id))
+			}
+			if err := errors.Join(errs...); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Cancelled %d orders", len(ids)), nil
+		},
+	}
+}
+
+func (d *Dashboard) cancel(orderId uint64) error {
+	r, err := d.Orders.CancelOrderWithInfo(orderId)
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return fmt.Errorf("order %d: %s", orderId, r.ErrorMessage)
+	}
+	if !r.Data.Success {
+		return fmt.Errorf("order %d was not cancelled", orderId)
+	}
+	return nil
+}
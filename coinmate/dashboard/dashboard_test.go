@@ -0,0 +1,136 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/secure"
+)
+
+func newTestDashboard(t *testing.T) (*Dashboard, *coinmatetest.Server) {
+	t.Helper()
+	server := coinmatetest.NewServer()
+	t.Cleanup(server.Close)
+
+	server.AddAccount("1", "public-key", "private-key")
+	server.Deposit("1", "EUR", 10000)
+	server.AddLiquidity("BTC_EUR", "SELL", 50000, 1)
+	server.AddLiquidity("BTC_EUR", "BUY", 49000, 1)
+	server.AddLiquidity("ETH_EUR", "SELL", 3000, 10)
+
+	d, err := NewDashboard(server.NewClient("1"), "btc_eur", "ETH_EUR")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	d.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+	return d, server
+}
+
+func typeKeys(d *Dashboard, keys string) bool {
+	for _, key := range keys {
+		if d.Key(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRefresh(t *testing.T) {
+	d, server := newTestDashboard(t)
+	order := &secure.Order{Client: server.NewClient("1")}
+	order.BuyInstant(500, "BTC_EUR", 0)
+	order.BuyLimit(0.01, 45000, 0, "BTC_EUR", false, false, 0)
+
+	if err := d.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	s := d.State()
+	if s.Pair != "BTC_EUR" || s.Ticker.Ask != 50000 || s.Ticker.Bid != 49000 {
+		t.Errorf("Expected BTC_EUR ticker, got %+v", s.Ticker)
+	}
+	if len(s.Book.Asks) != 1 || len(s.Book.Bids) != 2 {
+		t.Errorf("Expected 1 ask and 2 bids, got %+v", s.Book)
+	}
+	if len(s.Trades) != 1 || s.Trades[0].Price != 50000 {
+		t.Errorf("Expected instant buy in trades, got %+v", s.Trades)
+	}
+	if len(s.OpenOrders) != 1 || s.OpenOrders[0].Price != 45000 {
+		t.Errorf("Expected open order at 45000, got %+v", s.OpenOrders)
+	}
+	if b := s.Balances["BTC"].Balance; b <= 0 {
+		t.Errorf("Expected BTC balance, got %v", b)
+	}
+
+	// Next pair replaces the state
+	d.Key(keyTab)
+	if s := d.State(); s.Pair != "ETH_EUR" || s.Ticker.Ask != 3000 || len(s.OpenOrders) != 0 {
+		t.Errorf("Expected ETH_EUR state, got %+v", s)
+	}
+}
+
+func TestOrderKeys(t *testing.T) {
+	d, _ := newTestDashboard(t)
+	d.Refresh()
+
+	typeKeys(d, "b0.01 48000\r")
+	if d.prompt == nil || !d.prompt.confirm || d.prompt.label != "BUY 0.01 BTC_EUR at 48000?" {
+		t.Fatalf("Expected confirmation, got %+v", d.prompt)
+	}
+	typeKeys(d, "n")
+	if len(d.State().OpenOrders) != 0 || d.message != "Aborted" {
+		t.Fatalf("Expected no order, got %+v (%s)", d.State().OpenOrders, d.message)
+	}
+
+	// Backspace edits the input
+	typeKeys(d, "b0.01 4700\x7f00\ry")
+	typeKeys(d, "b0.01 48000\ry")
+	open := d.State().OpenOrders
+	if len(open) != 2 {
+		t.Fatalf("Expected 2 open orders, got %+v (%s)", open, d.message)
+	}
+	if !strings.HasPrefix(d.message, "Placed BUY order") {
+		t.Errorf("Expected placed message, got %q", d.message)
+	}
+
+	typeKeys(d, "c"+formatNumber(float64(open[0].Id))+"\ry")
+	if len(d.State().OpenOrders) != 1 {
+		t.Fatalf("Expected 1 open order, got %+v (%s)", d.State().OpenOrders, d.message)
+	}
+	typeKeys(d, "xy")
+	if len(d.State().OpenOrders) != 0 || d.message != "Cancelled 1 orders" {
+		t.Errorf("Expected all orders cancelled, got %+v (%s)", d.State().OpenOrders, d.message)
+	}
+
+	typeKeys(d, "bfoo\r")
+	if d.prompt != nil || !strings.HasPrefix(d.message, "Error: expected amount and price") {
+		t.Errorf("Expected input error, got %q", d.message)
+	}
+	typeKeys(d, "b0.01\x1b")
+	if d.prompt != nil || d.message != "Aborted" {
+		t.Errorf("Expected escape to abort, got %q", d.message)
+	}
+}
+
+func TestRun(t *testing.T) {
+	d, _ := newTestDashboard(t)
+	var out bytes.Buffer
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Run(ctx, strings.NewReader("+q"), &out, time.Hour); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if d.Depth != defaultDepth+1 {
+		t.Errorf("Expected depth %d, got %d", defaultDepth+1, d.Depth)
+	}
+
+	screen := out.String()
+	for _, want := range []string{"BTC_EUR", "bid 49000", "ask 50000", "EUR 10000 (available 10000)", "updated 12:00:00", help, "\r\n"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("Expected screen to contain %q, got %q", want, screen)
+		}
+	}
+}
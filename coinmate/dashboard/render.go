@@ -0,0 +1,135 @@
+package dashboard
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ANSI escape sequences
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	bold        = "\x1b[1m"
+	red         = "\x1b[31m"
+	green       = "\x1b[32m"
+	reset       = "\x1b[0m"
+)
+
+const help = "b buy  s sell  c cancel  x cancel all  tab pair  +/- depth  r refresh  q quit"
+
+// Draw the screen, lines end with CR LF so that they also start at the left
+// edge in raw terminal mode. Colors are kept out of the aligned tables as
+// tabwriter counts escape sequences as text.
+func (d *Dashboard) Render(w io.Writer) error {
+	var buf bytes.Buffer
+	d.render(&buf)
+	out := strings.ReplaceAll(buf.String(), "\n", "\r\n")
+	_, err := io.WriteString(w, clearScreen+out)
+	return err
+}
+
+// Helper functions
+
+func (d *Dashboard) render(w io.Writer) {
+	s := d.state
+	t := s.Ticker
+
+	fmt.Fprintf(w, "%s%s%s  last %s  bid %s  ask %s  high %s  low %s  change %s",
+		bold, d.Pair(), reset, formatNumber(t.Last), formatNumber(t.Bid), formatNumber(t.Ask),
+		formatNumber(t.High), formatNumber(t.Low), colored(t.Change, strconv.FormatFloat(t.Change, 'f', 2, 64)+" %"))
+	if !s.Updated.IsZero() {
+		fmt.Fprintf(w, "  updated %s", s.Updated.Format(time.TimeOnly))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, d.balances())
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BID AMOUNT\tBID\tASK\tASK AMOUNT\t\tTIME\tTRADE\tPRICE\tAMOUNT")
+	rows := max(min(d.Depth, max(len(s.Book.Bids), len(s.Book.Asks))), min(d.Trades, len(s.Trades)))
+	for i := 0; i < rows; i++ {
+		var cells [9]string
+		if i < d.Depth && i < len(s.Book.Bids) {
+			cells[0], cells[1] = formatNumber(s.Book.Bids[i].Amount), formatNumber(s.Book.Bids[i].Price)
+		}
+		if i < d.Depth && i < len(s.Book.Asks) {
+			cells[2], cells[3] = formatNumber(s.Book.Asks[i].Price), formatNumber(s.Book.Asks[i].Amount)
+		}
+		if i < d.Trades && i < len(s.Trades) {
+			trade := s.Trades[i]
+			cells[5] = time.UnixMilli(trade.Timestamp).Format(time.TimeOnly)
+			cells[6] = trade.TradeType
+			cells[7] = formatNumber(trade.Price)
+			cells[8] = formatNumber(trade.Amount)
+		}
+		fmt.Fprintln(tw, strings.Join(cells[:], "\t"))
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%sOpen orders (%d)%s\n", bold, len(s.OpenOrders), reset)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, o := range s.OpenOrders {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", o.Id, o.Type, formatNumber(o.Amount), formatNumber(o.Price), time.UnixMilli(o.Timestamp).Format(time.DateTime))
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	for _, e := range s.Errors {
+		fmt.Fprintf(w, "%s%s%s\n", red, e, reset)
+	}
+	switch {
+	case d.prompt != nil && d.prompt.confirm:
+		fmt.Fprintf(w, "%s [y/N] ", d.prompt.label)
+	case d.prompt != nil:
+		fmt.Fprintf(w, "%s: %s_", d.prompt.label, d.prompt.buffer)
+	default:
+		if d.message != "" {
+			fmt.Fprintln(w, d.message)
+		}
+		fmt.Fprint(w, help)
+	}
+}
+
+// Balances of the pair currencies first, then the other non-zero ones
+func (d *Dashboard) balances() string {
+	first, second, _ := strings.Cut(d.Pair(), "_")
+	var currencies []string
+	for currency, b := range d.state.Balances {
+		if currency != first && currency != second && b.Balance != 0 {
+			currencies = append(currencies, currency)
+		}
+	}
+	sort.Strings(currencies)
+
+	var parts []string
+	for _, currency := range append([]string{first, second}, currencies...) {
+		b := d.state.Balances[currency]
+		parts = append(parts, fmt.Sprintf("%s %s (available %s)", currency, formatBalance(b.Balance), formatBalance(b.Available)))
+	}
+	return strings.Join(parts, "  ")
+}
+
+func colored(v float64, s string) string {
+	switch {
+	case v > 0:
+		return green + s + reset
+	case v < 0:
+		return red + s + reset
+	}
+	return s
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Balances are float32, printed without float64 noise
+func formatBalance(v float32) string {
+	return strconv.FormatFloat(float64(v), 'f', -1, 32)
+}
@@ -0,0 +1,26 @@
+package dashboard
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	hideCursor = "\x1b[?25l"
+	showCursor = "\x1b[?25h"
+)
+
+// Run on a terminal: keys take effect without enter where raw mode is
+// supported and the terminal is restored and cleared on exit. Input that is
+// not a terminal is read as is.
+func (d *Dashboard) RunTerminal(ctx context.Context, in *os.File, out io.Writer, interval time.Duration) error {
+	if restore, err := makeRaw(in.Fd()); err == nil {
+		defer restore()
+	}
+	io.WriteString(out, hideCursor)
+	defer io.WriteString(out, clearScreen+showCursor)
+
+	return d.Run(ctx, in, out, interval)
+}
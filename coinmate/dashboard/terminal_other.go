@@ -0,0 +1,10 @@
+//go:build !linux
+
+package dashboard
+
+import "errors"
+
+// Keys are read line by line, each followed by enter
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("raw terminal mode not supported")
+}
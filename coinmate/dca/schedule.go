@@ -0,0 +1,149 @@
+package dca
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedules searched for the next run, a spec matching nothing never runs
+const maxScheduleYears = 5
+
+var scheduleMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// Cron schedule with the fields minute, hour, day of month, month and day of
+// week. Fields accept *, lists, ranges and steps, e.g. "30 8 * * 1-5" or
+// "0 */6 1,15 * *". As in cron, a day matches either day field when both are
+// restricted. The macros @hourly, @daily, @weekly, @monthly and @yearly are
+// supported as well.
+type Schedule struct {
+	spec    string
+	minutes [60]bool
+	hours   [24]bool
+	days    [32]bool
+	months  [13]bool
+	// Sunday is 0, 7 is accepted as Sunday as well
+	weekdays [7]bool
+	anyDay   bool
+	anyWeek  bool
+}
+
+// Parse cron spec
+func ParseSchedule(spec string) (*Schedule, error) {
+	expanded := strings.TrimSpace(spec)
+	if macro, ok := scheduleMacros[expanded]; ok {
+		expanded = macro
+	}
+	fields := strings.Fields(expanded)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q must have 5 fields", spec)
+	}
+
+	s := &Schedule{spec: spec, anyDay: fields[2] == "*", anyWeek: fields[4] == "*"}
+	if err := parseField(fields[0], 0, 59, s.minutes[:]); err != nil {
+		return nil, fmt.Errorf("schedule %q minute: %w", spec, err)
+	}
+	if err := parseField(fields[1], 0, 23, s.hours[:]); err != nil {
+		return nil, fmt.Errorf("schedule %q hour: %w", spec, err)
+	}
+	if err := parseField(fields[2], 1, 31, s.days[:]); err != nil {
+		return nil, fmt.Errorf("schedule %q day of month: %w", spec, err)
+	}
+	if err := parseField(fields[3], 1, 12, s.months[:]); err != nil {
+		return nil, fmt.Errorf("schedule %q month: %w", spec, err)
+	}
+	var weekdays [8]bool
+	if err := parseField(fields[4], 0, 7, weekdays[:]); err != nil {
+		return nil, fmt.Errorf("schedule %q day of week: %w", spec, err)
+	}
+	copy(s.weekdays[:], weekdays[:7])
+	s.weekdays[0] = s.weekdays[0] || weekdays[7]
+	return s, nil
+}
+
+// First run after t in the location of t, zero when there is none
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(maxScheduleYears, 0, 0)
+
+	for t.Before(end) {
+		switch {
+		case !s.months[t.Month()]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !s.hours[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !s.minutes[t.Minute()]:
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// Helper functions
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	day, weekday := s.days[t.Day()], s.weekdays[t.Weekday()]
+	switch {
+	case s.anyDay && s.anyWeek:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeek:
+		return day
+	}
+	return day || weekday
+}
+
+// Mark values of a field like "*/15", "1-5" or "0,30" in set
+func parseField(field string, min, max int, set []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		from, to := min, max
+		if rangePart != "*" {
+			low, high, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if from, err = strconv.Atoi(low); err != nil {
+				return fmt.Errorf("invalid value %q", low)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(high); err != nil {
+					return fmt.Errorf("invalid value %q", high)
+				}
+			} else if hasStep {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return fmt.Errorf("%q outside %d-%d", part, min, max)
+		}
+		for v := from; v <= to; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package dca
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	tests := []struct {
+		spec     string
+		after    string
+		expected string
+	}{
+		{"30 8 * * 1-5", "2024-05-03 09:00", "2024-05-06 08:30"},
+		{"30 8 * * 1-5", "2024-05-06 08:29", "2024-05-06 08:30"},
+		{"0 */6 1,15 * *", "2024-05-01 07:00", "2024-05-01 12:00"},
+		{"0 */6 1,15 * *", "2024-05-01 23:00", "2024-05-15 00:00"},
+		{"@monthly", "2024-01-31 12:00", "2024-02-01 00:00"},
+		{"@weekly", "2024-05-01 12:00", "2024-05-05 00:00"},
+		{"0 0 * * 7", "2024-05-01 12:00", "2024-05-05 00:00"},
+		// Either day field matches when both are restricted
+		{"0 0 13 * 5", "2024-09-01 00:00", "2024-09-06 00:00"},
+		{"0 0 13 * 5", "2024-09-12 00:00", "2024-09-13 00:00"},
+		{"15,45 10-11 * 2 *", "2024-01-15 00:00", "2024-02-01 10:15"},
+		{"0 0 29 2 *", "2024-03-01 00:00", "2028-02-29 00:00"},
+	}
+
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Fatalf("Expected %q to parse, got %v", tt.spec, err)
+		}
+		after, _ := time.Parse("2006-01-02 15:04", tt.after)
+		if next := s.Next(after).Format("2006-01-02 15:04"); next != tt.expected {
+			t.Errorf("Expected %q after %s at %s, got %s", tt.spec, tt.after, tt.expected, next)
+		}
+	}
+}
+
+func TestScheduleNever(t *testing.T) {
+	s, err := ParseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if next := s.Next(time.Now()); !next.IsZero() {
+		t.Errorf("Expected no run on February 30, got %v", next)
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{"", "* * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@daily 5"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
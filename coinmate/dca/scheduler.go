@@ -0,0 +1,597 @@
+package dca
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	orderStatusFilled    = "FILLED"
+	orderStatusCancelled = "CANCELLED"
+	historyLimit         = 100
+)
+
+// Execution state
+type Status string
+
+const (
+	// Recorded before the order is sent, resolved by Reconcile after a crash
+	StatusPending Status = "PENDING"
+	// Limit order resting on the book
+	StatusOpen   Status = "OPEN"
+	StatusFilled Status = "FILLED"
+	// Limit order cancelled, possibly partially filled
+	StatusCancelled Status = "CANCELLED"
+	StatusSkipped   Status = "SKIPPED"
+	StatusFailed    Status = "FAILED"
+)
+
+// Recurring buy
+type Plan struct {
+	Name string
+	// Pair bought, limit prices and amounts are rounded to its decimals
+	Pair public.TradingPairsData
+	// Cron spec, see Schedule
+	Schedule string
+	// Quote currency amount spent per run
+	Amount float64
+	// Buy with a limit order this far below the ask, e.g. 0.005 for 0.5 %,
+	// with an instant order when zero
+	LimitOffset float64
+	// Skipped dates as YYYY-MM-DD, e.g. holidays, and weekdays
+	SkipDates    []string
+	SkipWeekdays []time.Weekday
+	// Skip runs while the ask is higher, zero for no limit
+	MaxPrice float64
+	// Location of the schedule, e.g. "Europe/Prague", local time when empty
+	Location string
+}
+
+// Run of a plan
+type Execution struct {
+	Plan      string `json:"plan"`
+	Scheduled int64  `json:"scheduled"`
+	Timestamp int64  `json:"timestamp"`
+	Status    Status `json:"status"`
+	// Derived from the plan and the scheduled time
+	ClientOrderId uint64 `json:"clientOrderId,omitempty"`
+	OrderId       uint64 `json:"orderId,omitempty"`
+	// Quote amount spent and base amount bought
+	Total  float64 `json:"total"`
+	Amount float64 `json:"amount"`
+	// Average fill price, the limit price while open
+	Price  float64 `json:"price,omitempty"`
+	Fee    float64 `json:"fee,omitempty"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+// Totals of a plan
+type Summary struct {
+	Plan    string `json:"plan"`
+	Runs    int    `json:"runs"`
+	Skipped int    `json:"skipped"`
+	Failed  int    `json:"failed"`
+	// Quote amount spent including fees
+	Invested float64 `json:"invested"`
+	Amount   float64 `json:"amount"`
+	Fees     float64 `json:"fees"`
+	// Invested per base unit
+	AverageCost float64 `json:"averageCost"`
+}
+
+// Scheduler buys for plans on their schedules. Every run is recorded in the
+// store before its order is sent, so a run is never repeated after a
+// restart; runs missed while the scheduler was down are made up once. A run
+// interrupted after it was recorded is looked up by its client order ID.
+type Scheduler struct {
+	Orders secure.OrderInterface
+	Ticker *public.Ticker
+	// Fills of orders, instant buys are recorded at the ask when nil
+	Trades secure.TradeHistoryInterface
+	Store  Store
+
+	// Called after every run
+	OnExecution func(Execution)
+
+	mu      sync.Mutex
+	plans   map[string]*plan
+	history []Execution
+	now     func() time.Time
+}
+
+type plan struct {
+	Plan
+	schedule  *Schedule
+	location  *time.Location
+	skipDates map[string]bool
+	added     time.Time
+}
+
+// Return scheduler with the history from store, which may be nil. Runs
+// interrupted before their order was confirmed stay pending until Reconcile
+// finds their order.
+func NewScheduler(orders secure.OrderInterface, ticker *public.Ticker, store Store) (*Scheduler, error) {
+	s := &Scheduler{
+		Orders: orders,
+		Ticker: ticker,
+		Store:  store,
+		plans:  make(map[string]*plan),
+		now:    time.Now,
+	}
+	if store == nil {
+		return s, nil
+	}
+
+	history, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	s.history = history
+	return s, nil
+}
+
+// Add plan, its first run is the first scheduled time after its last
+// recorded run or after now
+func (s *Scheduler) Add(p Plan) error {
+	p.Pair.Name = strings.ToUpper(p.Pair.Name)
+	switch {
+	case p.Name == "":
+		return fmt.Errorf("plan name must not be empty")
+	case p.Pair.Name == "":
+		return fmt.Errorf("pair name must not be empty")
+	case p.Amount <= 0:
+		return fmt.Errorf("amount must be positive")
+	case p.LimitOffset < 0 || p.LimitOffset >= 1:
+		return fmt.Errorf("limitOffset must be in [0, 1)")
+	}
+	schedule, err := ParseSchedule(p.Schedule)
+	if err != nil {
+		return err
+	}
+	location := time.Local
+	if p.Location != "" {
+		if location, err = time.LoadLocation(p.Location); err != nil {
+			return fmt.Errorf("plan %s: %w", p.Name, err)
+		}
+	}
+	skipDates := make(map[string]bool, len(p.SkipDates))
+	for _, d := range p.SkipDates {
+		if _, err := time.Parse(time.DateOnly, d); err != nil {
+			return fmt.Errorf("plan %s: invalid skip date %q", p.Name, d)
+		}
+		skipDates[d] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.plans[p.Name]; ok {
+		return fmt.Errorf("plan %s already added", p.Name)
+	}
+	s.plans[p.Name] = &plan{Plan: p, schedule: schedule, location: location, skipDates: skipDates, added: s.now()}
+	return nil
+}
+
+// Remove plan, its history is kept
+func (s *Scheduler) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.plans, name)
+}
+
+// Next run of a plan
+func (s *Scheduler) Next(name string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.plans[name]
+	if !ok {
+		return time.Time{}, fmt.Errorf("unknown plan %s", name)
+	}
+	return p.schedule.Next(s.lastRun(p).In(p.location)), nil
+}
+
+// Run plans that are due
+func (s *Scheduler) RunDue() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.plans))
+	for name := range s.plans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		p := s.plans[name]
+		scheduled, due := s.due(p)
+		if !due {
+			continue
+		}
+		if err := s.run(p, scheduled); err != nil {
+			errs = append(errs, fmt.Errorf("plan %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Update open limit orders from their fills and look up the orders of
+// interrupted runs, runs whose order does not exist are marked failed
+func (s *Scheduler) Reconcile() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	changed := false
+	for i := range s.history {
+		e := &s.history[i]
+		if e.Status != StatusOpen && e.Status != StatusPending {
+			continue
+		}
+		pair := ""
+		if p, ok := s.plans[e.Plan]; ok {
+			pair = p.Pair.Name
+		}
+		if e.Status == StatusPending {
+			if err := s.resolve(e, pair); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			changed = true
+			if e.Status == StatusFailed {
+				e.Timestamp = s.now().UnixMilli()
+				if s.OnExecution != nil {
+					s.OnExecution(*e)
+				}
+				continue
+			}
+		}
+		o, err := s.lookup(e.OrderId, pair)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		filled := o.OriginalAmount - o.RemainingAmount
+		switch o.Status {
+		case orderStatusFilled:
+			e.Status = StatusFilled
+		case orderStatusCancelled:
+			e.Status = StatusCancelled
+		}
+		if filled > e.Amount || e.Status != StatusOpen {
+			if err := s.fill(e, filled, o.Price); err != nil {
+				errs = append(errs, err)
+			}
+			e.Timestamp = s.now().UnixMilli()
+			changed = true
+			if e.Status != StatusOpen && s.OnExecution != nil {
+				s.OnExecution(*e)
+			}
+		}
+	}
+	if changed {
+		errs = append(errs, s.save())
+	}
+	return errors.Join(errs...)
+}
+
+// Run due plans and reconcile open orders periodically until the context
+// is done
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := errors.Join(s.RunDue(), s.Reconcile()); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Executions of a plan, of all plans when name is empty
+func (s *Scheduler) History(name string) []Execution {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var history []Execution
+	for _, e := range s.history {
+		if name == "" || e.Plan == name {
+			history = append(history, e)
+		}
+	}
+	return history
+}
+
+// Totals of a plan
+func (s *Scheduler) Summary(name string) Summary {
+	summary := Summary{Plan: name}
+	for _, e := range s.History(name) {
+		switch e.Status {
+		case StatusSkipped:
+			summary.Skipped++
+			continue
+		case StatusFailed:
+			summary.Failed++
+			continue
+		}
+		summary.Runs++
+		summary.Invested += e.Total + e.Fee
+		summary.Amount += e.Amount
+		summary.Fees += e.Fee
+	}
+	if summary.Amount > 0 {
+		summary.AverageCost = summary.Invested / summary.Amount
+	}
+	return summary
+}
+
+// Helper functions
+
+// Latest scheduled time not after now since the last run, missed runs are
+// collapsed into one
+func (s *Scheduler) due(p *plan) (time.Time, bool) {
+	now := s.now().In(p.location)
+	next := p.schedule.Next(s.lastRun(p).In(p.location))
+	if next.IsZero() || next.After(now) {
+		return time.Time{}, false
+	}
+	for {
+		later := p.schedule.Next(next)
+		if later.IsZero() || later.After(now) {
+			return next, true
+		}
+		next = later
+	}
+}
+
+// Scheduled time of the last recorded run, the time the plan was added when
+// it never ran
+func (s *Scheduler) lastRun(p *plan) time.Time {
+	var last int64
+	found := false
+	for _, e := range s.history {
+		if e.Plan == p.Name && (!found || e.Scheduled > last) {
+			last, found = e.Scheduled, true
+		}
+	}
+	if !found {
+		return p.added
+	}
+	return time.UnixMilli(last)
+}
+
+// Record and execute a run, the caller holds mu
+func (s *Scheduler) run(p *plan, scheduled time.Time) error {
+	e := Execution{
+		Plan:          p.Name,
+		Scheduled:     scheduled.UnixMilli(),
+		Timestamp:     s.now().UnixMilli(),
+		Status:        StatusPending,
+		ClientOrderId: clientOrderId(p.Name, scheduled),
+	}
+	s.history = append(s.history, e)
+	if err := s.save(); err != nil {
+		s.history = s.history[:len(s.history)-1]
+		return err
+	}
+
+	runErr := s.execute(p, scheduled, &e)
+	if runErr != nil {
+		e.Status = StatusFailed
+		e.Reason = runErr.Error()
+	}
+	e.Timestamp = s.now().UnixMilli()
+	s.history[len(s.history)-1] = e
+
+	err := errors.Join(runErr, s.save())
+	if s.OnExecution != nil {
+		s.OnExecution(e)
+	}
+	return err
+}
+
+func (s *Scheduler) execute(p *plan, scheduled time.Time, e *Execution) error {
+	if reason := p.skip(scheduled); reason != "" {
+		e.Status, e.Reason = StatusSkipped, reason
+		return nil
+	}
+
+	ticker, err := s.Ticker.GetTicker(p.Pair.Name)
+	if err != nil {
+		return err
+	}
+	if ticker.Error {
+		return fmt.Errorf("ticker %s: %s", p.Pair.Name, ticker.ErrorMessage)
+	}
+	ask := ticker.Data.Ask
+	if ask <= 0 {
+		return fmt.Errorf("no ask for %s", p.Pair.Name)
+	}
+	if p.MaxPrice > 0 && ask > p.MaxPrice {
+		e.Status, e.Reason = StatusSkipped, fmt.Sprintf("ask %v above max price %v", ask, p.MaxPrice)
+		return nil
+	}
+
+	if p.LimitOffset > 0 {
+		price := round(ask*(1-p.LimitOffset), p.Pair.PriceDecimals)
+		amount := roundDown(p.Amount/price, p.Pair.LotDecimals)
+		if amount <= 0 || amount < p.Pair.MinAmount {
+			return fmt.Errorf("amount %v below minimum %v of %s", amount, p.Pair.MinAmount, p.Pair.Name)
+		}
+		r, err := s.Orders.BuyLimit(amount, price, 0, p.Pair.Name, false, false, e.ClientOrderId)
+		if err != nil {
+			return err
+		}
+		if r.Error {
+			return fmt.Errorf("order rejected: %s", r.ErrorMessage)
+		}
+		e.Status, e.OrderId, e.Price = StatusOpen, r.OrderId, price
+		return nil
+	}
+
+	r, err := s.Orders.BuyInstant(p.Amount, p.Pair.Name, e.ClientOrderId)
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return fmt.Errorf("order rejected: %s", r.ErrorMessage)
+	}
+	e.Status, e.OrderId = StatusFilled, r.OrderId
+	return s.fill(e, p.Amount/ask, ask)
+}
+
+// Set fills of an execution from trade history, or from amount and price
+// when there is none
+func (s *Scheduler) fill(e *Execution, amount, price float64) error {
+	if s.Trades == nil {
+		e.Amount, e.Total, e.Price = amount, amount*price, price
+		return nil
+	}
+
+	r, err := s.Trades.GetTradeHistory(secure.TradeHistoryParams{OrderId: e.OrderId})
+	if err != nil {
+		return err
+	}
+	if r.Error {
+		return fmt.Errorf("trade history: %s", r.ErrorMessage)
+	}
+	e.Amount, e.Total, e.Fee = 0, 0, 0
+	for _, t := range r.Data {
+		e.Amount += t.Amount
+		e.Total += t.Amount * t.Price
+		e.Fee += t.Fee
+	}
+	if e.Amount > 0 {
+		e.Price = e.Total / e.Amount
+	}
+	return nil
+}
+
+// Find the order of a pending run by its client order ID. Found orders are
+// picked up as open, the caller updates them from the order.
+func (s *Scheduler) resolve(e *Execution, currencyPair string) error {
+	if e.ClientOrderId == 0 {
+		e.Status = StatusFailed
+		e.Reason = "interrupted before the order was confirmed, check the order history"
+		return nil
+	}
+
+	var orders []secure.OrderHistoryData
+	if lookup, ok := s.Orders.(secure.OrderLookupInterface); ok {
+		r, err := lookup.GetOrderByClientOrderId(e.ClientOrderId)
+		if err != nil {
+			return err
+		}
+		if r.Error {
+			return fmt.Errorf("order lookup failed: %s", r.ErrorMessage)
+		}
+		orders = r.Data
+	} else {
+		r, err := s.Orders.GetHistory(currencyPair, historyLimit)
+		if err != nil {
+			return err
+		}
+		if r.Error {
+			return fmt.Errorf("order history failed: %s", r.ErrorMessage)
+		}
+		orders = r.Data
+	}
+
+	for _, o := range orders {
+		if o.ClientOrderId == e.ClientOrderId {
+			e.Status, e.OrderId, e.Price = StatusOpen, o.Id, o.Price
+			return nil
+		}
+	}
+	e.Status = StatusFailed
+	e.Reason = "interrupted before the order was sent"
+	return nil
+}
+
+func (s *Scheduler) lookup(orderId uint64, currencyPair string) (secure.OrderHistoryData, error) {
+	if lookup, ok := s.Orders.(secure.OrderLookupInterface); ok {
+		r, err := lookup.GetOrderById(orderId)
+		if err != nil {
+			return secure.OrderHistoryData{}, err
+		}
+		if r.Error {
+			return secure.OrderHistoryData{}, fmt.Errorf("order lookup failed: %s", r.ErrorMessage)
+		}
+		if r.Data == nil {
+			return secure.OrderHistoryData{}, fmt.Errorf("order %d not found", orderId)
+		}
+		return *r.Data, nil
+	}
+
+	r, err := s.Orders.GetHistory(currencyPair, historyLimit)
+	if err != nil {
+		return secure.OrderHistoryData{}, err
+	}
+	if r.Error {
+		return secure.OrderHistoryData{}, fmt.Errorf("order history failed: %s", r.ErrorMessage)
+	}
+	for _, o := range r.Data {
+		if o.Id == orderId {
+			return o, nil
+		}
+	}
+	return secure.OrderHistoryData{}, fmt.Errorf("order %d not found", orderId)
+}
+
+func (s *Scheduler) save() error {
+	if s.Store == nil {
+		return nil
+	}
+	return s.Store.Save(s.history)
+}
+
+// Client order ID of a run, the same for the same plan and scheduled time.
+// IDs fit into int64 and are never zero.
+func clientOrderId(plan string, scheduled time.Time) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s/%d", plan, scheduled.UnixMilli())
+	return h.Sum64()>>1 | 1
+}
+
+func round(value float64, decimals uint64) float64 {
+	scale := math.Pow10(int(decimals))
+	return math.Round(value*scale) / scale
+}
+
+func roundDown(value float64, decimals uint64) float64 {
+	scale := math.Pow10(int(decimals))
+	// Tolerate binary representation errors, e.g. 0.29999999999999999
+	return math.Floor(value*scale+1e-9) / scale
+}
+
+// Reason to skip a run, empty when it goes ahead
+func (p *plan) skip(scheduled time.Time) string {
+	local := scheduled.In(p.location)
+	if date := local.Format(time.DateOnly); p.skipDates[date] {
+		return "skipped date " + date
+	}
+	for _, d := range p.SkipWeekdays {
+		if local.Weekday() == d {
+			return "skipped " + d.String()
+		}
+	}
+	return ""
+}
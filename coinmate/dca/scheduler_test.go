@@ -0,0 +1,234 @@
+package dca
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+func newTestScheduler(t *testing.T, server *coinmatetest.Server, store Store, now *time.Time) *Scheduler {
+	t.Helper()
+	client := server.NewClient("1")
+	s, err := NewScheduler(&secure.Order{Client: client}, &public.Ticker{Client: client}, store)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	s.Trades = &secure.TradeHistory{Client: client}
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func newTestServer(t *testing.T) *coinmatetest.Server {
+	t.Helper()
+	server := coinmatetest.NewServer()
+	t.Cleanup(server.Close)
+
+	server.AddAccount("1", "public-key", "private-key")
+	server.Deposit("1", "EUR", 100000)
+	server.AddLiquidity("BTC_EUR", "SELL", 50000, 1)
+	return server
+}
+
+var btcEur = public.TradingPairsData{Name: "btc_eur", PriceDecimals: 2, LotDecimals: 8, MinAmount: 0.0002}
+
+var dailyPlan = Plan{Name: "btc", Pair: btcEur, Schedule: "0 9 * * *", Amount: 1000, Location: "UTC"}
+
+func TestInstantRuns(t *testing.T) {
+	server := newTestServer(t)
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	s := newTestScheduler(t, server, nil, &now)
+	var executions []Execution
+	s.OnExecution = func(e Execution) { executions = append(executions, e) }
+
+	if err := s.Add(dailyPlan); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if next, _ := s.Next("btc"); !next.Equal(time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected next run at 9:00, got %v", next)
+	}
+	s.RunDue()
+	if len(executions) != 0 {
+		t.Fatalf("Expected no run before 9:00, got %+v", executions)
+	}
+
+	now = time.Date(2024, 5, 1, 9, 0, 30, 0, time.UTC)
+	if err := s.RunDue(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	s.RunDue()
+	if len(executions) != 1 || executions[0].Status != StatusFilled || math.Abs(executions[0].Amount-0.02) > 1e-9 || executions[0].Price != 50000 {
+		t.Fatalf("Expected one filled run of 0.02 BTC, got %+v", executions)
+	}
+
+	server.AddLiquidity("BTC_EUR", "SELL", 40000, 1)
+	now = time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	s.RunDue()
+
+	summary := s.Summary("btc")
+	if summary.Runs != 2 || math.Abs(summary.Amount-0.045) > 1e-9 || math.Abs(summary.Invested-2000) > 1e-6 || math.Abs(summary.AverageCost-2000/0.045) > 1e-6 {
+		t.Errorf("Expected 2 runs averaging %v, got %+v", 2000/0.045, summary)
+	}
+}
+
+func TestSkipRules(t *testing.T) {
+	server := newTestServer(t)
+	now := time.Date(2024, 12, 23, 12, 0, 0, 0, time.UTC)
+	s := newTestScheduler(t, server, nil, &now)
+
+	plan := dailyPlan
+	plan.SkipDates = []string{"2024-12-24", "2024-12-25"}
+	plan.SkipWeekdays = []time.Weekday{time.Saturday, time.Sunday}
+	s.Add(plan)
+	cheap := dailyPlan
+	cheap.Name = "cheap"
+	cheap.MaxPrice = 45000
+	s.Add(cheap)
+
+	for day := 24; day <= 30; day++ {
+		now = time.Date(2024, 12, day, 10, 0, 0, 0, time.UTC)
+		s.RunDue()
+	}
+
+	var statuses []Status
+	for _, e := range s.History("btc") {
+		statuses = append(statuses, e.Status)
+	}
+	// Tue 24 and Wed 25 are holidays, Sat 28 and Sun 29 weekends
+	expected := []Status{StatusSkipped, StatusSkipped, StatusFilled, StatusFilled, StatusSkipped, StatusSkipped, StatusFilled}
+	if len(statuses) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, statuses)
+	}
+	for i := range expected {
+		if statuses[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, statuses)
+			break
+		}
+	}
+
+	if summary := s.Summary("cheap"); summary.Skipped != 7 || summary.Runs != 0 {
+		t.Errorf("Expected all runs above max price skipped, got %+v", summary)
+	}
+}
+
+func TestMissedRunsAfterRestart(t *testing.T) {
+	server := newTestServer(t)
+	store := NewFileStore(filepath.Join(t.TempDir(), "dca", "history.json"))
+	now := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+
+	first := newTestScheduler(t, server, store, &now)
+	first.Add(Plan{Name: "btc", Pair: btcEur, Schedule: "0 9 * * *", Amount: 1000, Location: "UTC"})
+	now = time.Date(2024, 5, 2, 9, 30, 0, 0, time.UTC)
+	first.RunDue()
+
+	// Down for three days, the missed runs are made up once
+	now = time.Date(2024, 5, 5, 10, 0, 0, 0, time.UTC)
+	second := newTestScheduler(t, server, store, &now)
+	second.Add(dailyPlan)
+	second.RunDue()
+
+	history := second.History("btc")
+	if len(history) != 2 || history[1].Scheduled != time.Date(2024, 5, 5, 9, 0, 0, 0, time.UTC).UnixMilli() {
+		t.Fatalf("Expected one made up run, got %+v", history)
+	}
+
+	// A run interrupted before its order was confirmed is not repeated
+	history[1].Status = StatusPending
+	store.Save(history)
+	third := newTestScheduler(t, server, store, &now)
+	third.Add(dailyPlan)
+	third.RunDue()
+	if history := third.History("btc"); len(history) != 2 || history[1].Status != StatusPending {
+		t.Errorf("Expected interrupted run kept pending, got %+v", history)
+	}
+}
+
+func TestInterruptedRunsResolvedByClientOrderId(t *testing.T) {
+	server := newTestServer(t)
+	store := NewFileStore(filepath.Join(t.TempDir(), "history.json"))
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+
+	first := newTestScheduler(t, server, store, &now)
+	first.Add(dailyPlan)
+	now = time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	first.RunDue()
+	placed := first.History("btc")[0]
+	if placed.Status != StatusFilled || placed.ClientOrderId == 0 {
+		t.Fatalf("Expected filled run with client order ID, got %+v", placed)
+	}
+
+	// Crash after the order was sent and before the next run's order was
+	sent := Execution{Plan: "btc", Scheduled: placed.Scheduled, Status: StatusPending, ClientOrderId: placed.ClientOrderId}
+	next := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	lost := Execution{Plan: "btc", Scheduled: next.UnixMilli(), Status: StatusPending, ClientOrderId: clientOrderId("btc", next)}
+	store.Save([]Execution{sent, lost})
+
+	now = time.Date(2024, 5, 2, 9, 30, 0, 0, time.UTC)
+	second := newTestScheduler(t, server, store, &now)
+	second.Add(dailyPlan)
+	if err := second.Reconcile(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	history := second.History("btc")
+	if history[0].Status != StatusFilled || history[0].OrderId != placed.OrderId || math.Abs(history[0].Amount-0.02) > 1e-9 {
+		t.Errorf("Expected sent run resolved as filled, got %+v", history[0])
+	}
+	if history[1].Status != StatusFailed {
+		t.Errorf("Expected run without order marked failed, got %+v", history[1])
+	}
+	if summary := second.Summary("btc"); summary.Runs != 1 || math.Abs(summary.AverageCost-50000) > 1e-6 {
+		t.Errorf("Expected resolved run in the average cost, got %+v", summary)
+	}
+}
+
+func TestClientOrderId(t *testing.T) {
+	scheduled := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	id := clientOrderId("btc", scheduled)
+	if id == 0 || id > math.MaxInt64 || id != clientOrderId("btc", scheduled) {
+		t.Errorf("Expected stable non-zero int64 ID, got %d", id)
+	}
+	if id == clientOrderId("btc", scheduled.Add(24*time.Hour)) || id == clientOrderId("eth", scheduled) {
+		t.Error("Expected distinct IDs for other runs")
+	}
+}
+
+func TestLimitOffset(t *testing.T) {
+	server := newTestServer(t)
+	server.AddAccount("2", "public-key-2", "private-key-2")
+	server.Deposit("2", "BTC", 1)
+	server.AddLiquidity("BTC_EUR", "BUY", 49000, 1)
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	s := newTestScheduler(t, server, nil, &now)
+
+	plan := dailyPlan
+	plan.LimitOffset = 0.01
+	s.Add(plan)
+	now = now.Add(time.Hour)
+	s.RunDue()
+
+	e := s.History("btc")[0]
+	if e.Status != StatusOpen || e.Price != 49500 || e.OrderId == 0 {
+		t.Fatalf("Expected open limit order at 49500, got %+v", e)
+	}
+	s.Reconcile()
+	if e := s.History("btc")[0]; e.Status != StatusOpen || e.Amount != 0 {
+		t.Errorf("Expected unfilled order to stay open, got %+v", e)
+	}
+
+	// The own bid at 49500 is filled before the bid at 49000
+	taker := &secure.Order{Client: server.NewClient("2")}
+	taker.SellLimit(0.5, 49000, 0, "BTC_EUR", false, false, 0)
+	if err := s.Reconcile(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	e = s.History("btc")[0]
+	if e.Status != StatusFilled || math.Abs(e.Amount-0.02020202) > 1e-9 || e.Price != 49500 {
+		t.Errorf("Expected filled limit order, got %+v", e)
+	}
+	if summary := s.Summary("btc"); math.Abs(summary.AverageCost-49500) > 1e-6 {
+		t.Errorf("Expected average cost 49500, got %+v", summary)
+	}
+}
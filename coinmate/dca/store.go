@@ -0,0 +1,17 @@
+package dca
+
+import "tourGo/coinmate/filestore"
+
+// Persistence of executions, the last run of every plan is taken from them
+type Store interface {
+	Load() ([]Execution, error)
+	Save(executions []Execution) error
+}
+
+// Store keeping all executions in one JSON file, see filestore.JSONFileStore
+type FileStore = filestore.JSONFileStore[Execution]
+
+// Return file store at path, the directory is created on first save
+func NewFileStore(path string) *FileStore {
+	return filestore.New[Execution](path, "execution")
+}
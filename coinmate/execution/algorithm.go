@@ -0,0 +1,495 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate"
+	"tourGo/coinmate/idempotent"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	buySide  = "BUY"
+	sellSide = "SELL"
+
+	// Child order types
+	OrderTypeInstant = "INSTANT"
+	OrderTypeLimit   = "LIMIT"
+
+	epsilon = 1e-9
+
+	// Longer than the client timeout, see Execution
+	defaultRetryDelay = coinmate.RequestTimeout + time.Second
+)
+
+// Execution algorithm
+type Algorithm string
+
+const (
+	// Equal slices over the window
+	AlgorithmTWAP Algorithm = "TWAP"
+	// Slices in proportion to the market volume traded during the window
+	AlgorithmVWAP Algorithm = "VWAP"
+)
+
+// State of an execution
+type Status string
+
+const (
+	StatusPending   Status = "PENDING"
+	StatusRunning   Status = "RUNNING"
+	StatusCompleted Status = "COMPLETED"
+	// All slices ran but part of the amount was held back, e.g. by the limit
+	// price or the participation cap. Remaining is the unfilled amount.
+	StatusIncomplete Status = "INCOMPLETE"
+	StatusCancelled  Status = "CANCELLED"
+	StatusFailed     Status = "FAILED"
+)
+
+// Parent order executed by an algorithm
+type Params struct {
+	CurrencyPair string
+	Side         string
+	// Base amount to execute
+	Amount   float64
+	Duration time.Duration
+	// Number of child orders, one at the start of every Duration/Slices
+	Slices int
+	// Buys never pay more, sells never receive less, zero for no limit
+	LimitPrice float64
+	// OrderTypeInstant (default) or OrderTypeLimit, immediate-or-cancel limit
+	// orders at the best price capped by LimitPrice
+	OrderType string
+	// Max share of the market volume of the previous slice, e.g. 0.1 for 10 %,
+	// zero for no cap
+	MaxParticipation float64
+	// Smaller child orders are postponed to the next slice
+	MinChildAmount float64
+}
+
+// Child order placed by an execution
+type Child struct {
+	Slice         int     `json:"slice"`
+	ClientOrderId uint64  `json:"clientOrderId"`
+	OrderId       uint64  `json:"orderId"`
+	Timestamp     int64   `json:"timestamp"`
+	Amount        float64 `json:"amount"`
+	Filled        float64 `json:"filled"`
+	Notional      float64 `json:"notional"`
+	Fee           float64 `json:"fee"`
+	Error         string  `json:"error,omitempty"`
+}
+
+// Execution progress
+type Progress struct {
+	Algorithm Algorithm `json:"algorithm"`
+	Status    Status    `json:"status"`
+	Slice     int       `json:"slice"`
+	Slices    int       `json:"slices"`
+	Executed  float64   `json:"executed"`
+	Remaining float64   `json:"remaining"`
+	// Quote amount paid or received
+	Notional     float64 `json:"notional"`
+	AveragePrice float64 `json:"averagePrice"`
+	Fees         float64 `json:"fees"`
+	Children     int     `json:"children"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// Execution slices a parent order into child orders placed over time.
+// Children are instant or immediate-or-cancel orders, so nothing rests on
+// the book between slices and an unfilled part carries over to the next
+// slice. Fills are read from trade history.
+//
+// Every child is sent with a generated client order ID. A send failing
+// without a definite answer is looked up by it after RetryDelay, a child
+// that was not placed counts as unfilled.
+type Execution struct {
+	Orders secure.OrderInterface
+	// Lookup of children, recent order history is searched when nil
+	Lookup       secure.OrderLookupInterface
+	Trades       secure.TradeHistoryInterface
+	Ticker       *public.Ticker
+	Transactions *public.Transactions
+	Algorithm    Algorithm
+	Params       Params
+	Generator    *idempotent.Generator
+	// Wait before looking up a child after a failed send, defaults to the
+	// client timeout and one second
+	RetryDelay time.Duration
+
+	// Called after every slice and when the execution ends
+	OnProgress func(Progress)
+
+	mu       sync.Mutex
+	progress Progress
+	children []Child
+	cancel   chan struct{}
+	once     sync.Once
+	now      func() time.Time
+	wait     func(ctx context.Context, d time.Duration) error
+}
+
+// Return TWAP execution of params
+func NewTWAP(orders secure.OrderInterface, trades secure.TradeHistoryInterface, ticker *public.Ticker, params Params) *Execution {
+	return newExecution(AlgorithmTWAP, orders, trades, ticker, nil, params)
+}
+
+// Return VWAP execution of params, market volume is read from transactions
+func NewVWAP(orders secure.OrderInterface, trades secure.TradeHistoryInterface, ticker *public.Ticker, transactions *public.Transactions, params Params) *Execution {
+	return newExecution(AlgorithmVWAP, orders, trades, ticker, transactions, params)
+}
+
+// Execute until all slices are done, the execution is cancelled or the
+// context is done. The final progress is returned, StatusIncomplete when
+// slices ended with part of the amount unfilled.
+func (x *Execution) Run(ctx context.Context) (Progress, error) {
+	if err := x.validate(); err != nil {
+		return x.finish(StatusFailed, err)
+	}
+	p := x.Params
+	interval := p.Duration / time.Duration(p.Slices)
+	start := x.now()
+
+	var expected float64
+	if x.Algorithm == AlgorithmVWAP {
+		// Expect as much volume as traded during the window before the start
+		volume, err := x.volume(start.Add(-p.Duration), start)
+		if err != nil {
+			return x.finish(StatusFailed, err)
+		}
+		expected = volume
+	}
+
+	x.update(func(pr *Progress) { pr.Status = StatusRunning })
+	for slice := 0; slice < p.Slices; slice++ {
+		if err := x.waitUntil(ctx, start.Add(time.Duration(slice)*interval)); err != nil {
+			return x.finish(StatusCancelled, nil)
+		}
+
+		now := x.now()
+		target := p.Amount * float64(slice+1) / float64(p.Slices)
+		if x.Algorithm == AlgorithmVWAP && expected > 0 && slice < p.Slices-1 {
+			traded, err := x.volume(start, now)
+			if err != nil {
+				return x.finish(StatusFailed, err)
+			}
+			target = p.Amount * math.Min(1, (traded-x.executed())/expected)
+		}
+		amount := target - x.executed()
+
+		if p.MaxParticipation > 0 {
+			// Market volume of the previous slice excluding own fills
+			volume, err := x.volume(now.Add(-interval), now)
+			if err != nil {
+				return x.finish(StatusFailed, err)
+			}
+			volume -= x.filledSince(now.Add(-interval))
+			amount = math.Min(amount, math.Max(0, volume)*p.MaxParticipation)
+		}
+		amount = math.Min(amount, p.Amount-x.executed())
+
+		if amount > epsilon && amount >= p.MinChildAmount {
+			if err := x.placeChild(ctx, slice, amount); err != nil {
+				return x.finish(StatusFailed, err)
+			}
+		}
+		x.update(func(pr *Progress) { pr.Slice = slice + 1 })
+		x.report()
+
+		if x.executed() >= p.Amount-epsilon {
+			return x.finish(StatusCompleted, nil)
+		}
+	}
+	return x.finish(StatusIncomplete, nil)
+}
+
+// Stop before the next slice
+func (x *Execution) Cancel() {
+	x.once.Do(func() { close(x.cancel) })
+}
+
+// Current progress
+func (x *Execution) Progress() Progress {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	return x.progress
+}
+
+// Child orders placed so far
+func (x *Execution) Children() []Child {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	return append([]Child(nil), x.children...)
+}
+
+// Helper functions
+
+func newExecution(algorithm Algorithm, orders secure.OrderInterface, trades secure.TradeHistoryInterface, ticker *public.Ticker, transactions *public.Transactions, params Params) *Execution {
+	params.CurrencyPair = strings.ToUpper(params.CurrencyPair)
+	params.Side = strings.ToUpper(params.Side)
+	if params.OrderType == "" {
+		params.OrderType = OrderTypeInstant
+	}
+	x := &Execution{
+		Orders:       orders,
+		Trades:       trades,
+		Ticker:       ticker,
+		Transactions: transactions,
+		Algorithm:    algorithm,
+		Params:       params,
+		Generator:    idempotent.NewGenerator(),
+		progress:     Progress{Algorithm: algorithm, Status: StatusPending, Slices: params.Slices, Remaining: params.Amount},
+		cancel:       make(chan struct{}),
+		now:          time.Now,
+		wait:         sleep,
+	}
+	if lookup, ok := orders.(secure.OrderLookupInterface); ok {
+		x.Lookup = lookup
+	}
+	return x
+}
+
+func (x *Execution) validate() error {
+	p := x.Params
+	switch {
+	case p.CurrencyPair == "":
+		return fmt.Errorf("currencyPair must not be empty")
+	case p.Side != buySide && p.Side != sellSide:
+		return fmt.Errorf("side must be %s or %s, got %q", buySide, sellSide, p.Side)
+	case p.Amount <= 0:
+		return fmt.Errorf("amount must be positive")
+	case p.Slices <= 0:
+		return fmt.Errorf("slices must be positive")
+	case p.Duration < 0:
+		return fmt.Errorf("duration must not be negative")
+	case p.OrderType != OrderTypeInstant && p.OrderType != OrderTypeLimit:
+		return fmt.Errorf("unknown order type %q", p.OrderType)
+	case (x.Algorithm == AlgorithmVWAP || p.MaxParticipation > 0) && x.Transactions == nil:
+		return fmt.Errorf("transactions are required for VWAP and participation caps")
+	}
+	return nil
+}
+
+// Place child order within the price limit and record its fills
+func (x *Execution) placeChild(ctx context.Context, slice int, amount float64) error {
+	p := x.Params
+	ticker, err := x.Ticker.GetTicker(p.CurrencyPair)
+	if err != nil {
+		return err
+	}
+	if ticker.Error {
+		return fmt.Errorf("ticker %s: %s", p.CurrencyPair, ticker.ErrorMessage)
+	}
+
+	price := ticker.Data.Ask
+	if p.Side == sellSide {
+		price = ticker.Data.Bid
+	}
+	if price <= 0 {
+		return nil
+	}
+	outside := p.LimitPrice > 0 && ((p.Side == buySide && price > p.LimitPrice) || (p.Side == sellSide && price < p.LimitPrice))
+	if outside && p.OrderType == OrderTypeInstant {
+		// Market beyond the limit, retry with the next slice
+		return nil
+	}
+	if outside {
+		price = p.LimitPrice
+	}
+
+	child := Child{Slice: slice, ClientOrderId: x.Generator.Next(), Timestamp: x.now().UnixMilli(), Amount: amount}
+	orderId, rejected, err := x.send(amount, price, child.ClientOrderId)
+	if err != nil && !errors.Is(err, secure.ErrNotSent) {
+		// The child may have reached the exchange
+		if err := x.wait(ctx, x.retryDelay()); err != nil {
+			return fmt.Errorf("child %d outcome unknown: %w", child.ClientOrderId, err)
+		}
+		o, found, lookupErr := idempotent.FindOrder(x.Orders, x.Lookup, child.ClientOrderId, p.CurrencyPair, historyLimit)
+		switch {
+		case lookupErr != nil:
+			return fmt.Errorf("child %d outcome unknown: %w, lookup failed: %w", child.ClientOrderId, err, lookupErr)
+		case found:
+			orderId, err = o.Id, nil
+		default:
+			// Not placed, the amount carries over to the next slice
+			rejected, err = err.Error(), nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	child.OrderId = orderId
+	child.Error = rejected
+
+	if orderId != 0 {
+		fills, err := x.Trades.GetTradeHistory(secure.TradeHistoryParams{OrderId: orderId})
+		if err != nil {
+			return err
+		}
+		if fills.Error {
+			return fmt.Errorf("trade history: %s", fills.ErrorMessage)
+		}
+		for _, f := range fills.Data {
+			child.Filled += f.Amount
+			child.Notional += f.Amount * f.Price
+			child.Fee += f.Fee
+		}
+	}
+
+	x.mu.Lock()
+	x.children = append(x.children, child)
+	pr := &x.progress
+	pr.Children = len(x.children)
+	pr.Executed += child.Filled
+	pr.Remaining = math.Max(0, p.Amount-pr.Executed)
+	pr.Notional += child.Notional
+	pr.Fees += child.Fee
+	if pr.Executed > 0 {
+		pr.AveragePrice = pr.Notional / pr.Executed
+	}
+	x.mu.Unlock()
+	return nil
+}
+
+// Send child order, returns the order ID or the message of a rejection
+func (x *Execution) send(amount, price float64, clientOrderId uint64) (uint64, string, error) {
+	p := x.Params
+	switch {
+	case p.OrderType == OrderTypeLimit && p.Side == buySide:
+		r, err := x.Orders.BuyLimit(amount, price, 0, p.CurrencyPair, false, true, clientOrderId)
+		return r.OrderId, errorMessage(r.Error, r.ErrorMessage), err
+	case p.OrderType == OrderTypeLimit:
+		r, err := x.Orders.SellLimit(amount, price, 0, p.CurrencyPair, false, true, clientOrderId)
+		return r.OrderId, errorMessage(r.Error, r.ErrorMessage), err
+	case p.Side == buySide:
+		r, err := x.Orders.BuyInstant(amount*price, p.CurrencyPair, clientOrderId)
+		return r.OrderId, errorMessage(r.Error, r.ErrorMessage), err
+	default:
+		r, err := x.Orders.SellInstant(amount, p.CurrencyPair, clientOrderId)
+		return r.OrderId, errorMessage(r.Error, r.ErrorMessage), err
+	}
+}
+
+// Public volume of the pair traded in (from, to]
+func (x *Execution) volume(from, to time.Time) (float64, error) {
+	minutes := uint64(math.Ceil(x.now().Sub(from).Minutes())) + 1
+	r, err := x.Transactions.GetTransactions(x.Params.CurrencyPair, minutes)
+	if err != nil {
+		return 0, err
+	}
+	if r.Error {
+		return 0, fmt.Errorf("transactions %s: %s", x.Params.CurrencyPair, r.ErrorMessage)
+	}
+
+	volume := 0.0
+	for _, t := range r.Data {
+		if t.Timestamp > from.UnixMilli() && t.Timestamp <= to.UnixMilli() {
+			volume += t.Amount
+		}
+	}
+	return volume, nil
+}
+
+func (x *Execution) executed() float64 {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	return x.progress.Executed
+}
+
+func (x *Execution) filledSince(t time.Time) float64 {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	filled := 0.0
+	for _, c := range x.children {
+		if c.Timestamp > t.UnixMilli() {
+			filled += c.Filled
+		}
+	}
+	return filled
+}
+
+func (x *Execution) waitUntil(ctx context.Context, t time.Time) error {
+	select {
+	case <-x.cancel:
+		return errors.New("cancelled")
+	default:
+	}
+
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	go func() {
+		select {
+		case <-x.cancel:
+			stop()
+		case <-ctx.Done():
+		}
+	}()
+	return x.wait(ctx, t.Sub(x.now()))
+}
+
+func (x *Execution) update(change func(*Progress)) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	change(&x.progress)
+}
+
+func (x *Execution) report() {
+	if x.OnProgress != nil {
+		x.OnProgress(x.Progress())
+	}
+}
+
+func (x *Execution) finish(status Status, err error) (Progress, error) {
+	x.update(func(pr *Progress) {
+		pr.Status = status
+		if err != nil {
+			pr.Error = err.Error()
+		}
+	})
+	x.report()
+	return x.Progress(), err
+}
+
+func errorMessage(rejected bool, message string) string {
+	if !rejected {
+		return ""
+	}
+	if message == "" {
+		return "order rejected"
+	}
+	return message
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (x *Execution) retryDelay() time.Duration {
+	if x.RetryDelay > 0 {
+		return x.RetryDelay
+	}
+	return defaultRetryDelay
+}
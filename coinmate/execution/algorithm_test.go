@@ -0,0 +1,257 @@
+package execution
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+// Clock advanced by waits of the execution
+type testClock struct {
+	mu sync.Mutex
+	t  time.Time
+	// Called after every wait, e.g. to add market trades
+	onWait func(now time.Time)
+}
+
+func (c *testClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.t
+}
+
+func (c *testClock) wait(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if d > 0 {
+		c.t = c.t.Add(d)
+	}
+	now := c.t
+	c.mu.Unlock()
+
+	if c.onWait != nil {
+		c.onWait(now)
+	}
+	return nil
+}
+
+func newTestServer(t *testing.T, clock *testClock) *coinmatetest.Server {
+	t.Helper()
+	server := coinmatetest.NewServer()
+	t.Cleanup(server.Close)
+
+	server.SetClock(clock.now)
+	server.AddAccount("1", "public-key", "private-key")
+	server.Deposit("1", "EUR", 100000)
+	server.Deposit("1", "BTC", 1)
+	return server
+}
+
+func newTestExecution(server *coinmatetest.Server, clock *testClock, algorithm Algorithm, params Params) *Execution {
+	client := server.NewClient("1")
+	x := newExecution(algorithm, &secure.Order{Client: client}, &secure.TradeHistory{Client: client}, &public.Ticker{Client: client}, &public.Transactions{Client: client}, params)
+	x.now = clock.now
+	x.wait = clock.wait
+	return x
+}
+
+func trade(server *coinmatetest.Server, at time.Time, amount float64) {
+	server.AddTransaction(public.TransactionsData{CurrencyPair: "BTC_EUR", Price: 50000, Amount: amount, TradeType: "BUY", Timestamp: at.UnixMilli()})
+}
+
+func TestTWAP(t *testing.T) {
+	clock := &testClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	server := newTestServer(t, clock)
+	server.AddLiquidity("BTC_EUR", "SELL", 50000, 10)
+
+	x := newTestExecution(server, clock, AlgorithmTWAP, Params{CurrencyPair: "btc_eur", Side: "buy", Amount: 1, Duration: 4 * time.Minute, Slices: 4})
+	var reports []Progress
+	x.OnProgress = func(p Progress) { reports = append(reports, p) }
+
+	start := clock.now()
+	p, err := x.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if p.Status != StatusCompleted || math.Abs(p.Executed-1) > 1e-6 || p.Remaining > 1e-6 || math.Abs(p.AveragePrice-50000) > 1e-3 {
+		t.Errorf("Expected completed execution at 50000, got %+v", p)
+	}
+
+	children := x.Children()
+	if len(children) != 4 {
+		t.Fatalf("Expected 4 children, got %+v", children)
+	}
+	for i, c := range children {
+		at := start.Add(time.Duration(i) * time.Minute).UnixMilli()
+		if c.Slice != i || c.Timestamp != at || c.OrderId == 0 || math.Abs(c.Filled-0.25) > 1e-6 {
+			t.Errorf("Expected child %d of 0.25 at %d, got %+v", i, at, c)
+		}
+	}
+	if len(reports) != 5 || reports[0].Slice != 1 || reports[0].Status != StatusRunning || reports[4].Status != StatusCompleted {
+		t.Errorf("Expected progress after every slice and at the end, got %+v", reports)
+	}
+}
+
+func TestPriceLimit(t *testing.T) {
+	clock := &testClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	server := newTestServer(t, clock)
+	server.AddLiquidity("BTC_EUR", "SELL", 50000, 0.3)
+	server.AddLiquidity("BTC_EUR", "SELL", 52000, 10)
+
+	x := newTestExecution(server, clock, AlgorithmTWAP, Params{CurrencyPair: "BTC_EUR", Side: "BUY", Amount: 1, Duration: 2 * time.Minute, Slices: 2, LimitPrice: 51000, OrderType: OrderTypeLimit})
+	p, err := x.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if p.Status != StatusIncomplete || math.Abs(p.Executed-0.3) > 1e-6 || math.Abs(p.Remaining-0.7) > 1e-6 || math.Abs(p.AveragePrice-50000) > 1e-3 {
+		t.Errorf("Expected only liquidity within the limit taken, got %+v", p)
+	}
+	if open, _ := x.Orders.GetOpenOrders("BTC_EUR"); len(open.Data) != 0 {
+		t.Errorf("Expected no resting children, got %+v", open.Data)
+	}
+
+	x = newTestExecution(server, clock, AlgorithmTWAP, Params{CurrencyPair: "BTC_EUR", Side: "BUY", Amount: 1, Duration: 2 * time.Minute, Slices: 2, LimitPrice: 51000})
+	if p, _ := x.Run(context.Background()); p.Status != StatusIncomplete || p.Executed != 0 || len(x.Children()) != 0 {
+		t.Errorf("Expected no instant children above the limit, got %+v", p)
+	}
+}
+
+func TestVWAP(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := &testClock{t: start}
+	server := newTestServer(t, clock)
+	server.AddLiquidity("BTC_EUR", "SELL", 50000, 10)
+	// Volume expected during the window
+	trade(server, start.Add(-3*time.Minute), 10)
+	// Market volume of the first two slices, none afterwards
+	volumes := []float64{2, 6}
+	clock.onWait = func(now time.Time) {
+		if n := int(now.Sub(start) / (2 * time.Minute)); n > 0 && n <= len(volumes) {
+			trade(server, now.Add(-time.Second), volumes[n-1])
+		}
+	}
+
+	x := newTestExecution(server, clock, AlgorithmVWAP, Params{CurrencyPair: "BTC_EUR", Side: "BUY", Amount: 1, Duration: 8 * time.Minute, Slices: 4})
+	p, err := x.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if p.Status != StatusCompleted || math.Abs(p.Executed-1) > 1e-6 {
+		t.Errorf("Expected completed execution, got %+v", p)
+	}
+
+	// Nothing traded before the first slice, then 20 % and 80 % of the
+	// expected volume, the last slice completes the order
+	children := x.Children()
+	expected := []float64{0.2, 0.6, 0.2}
+	if len(children) != len(expected) {
+		t.Fatalf("Expected %d children, got %+v", len(expected), children)
+	}
+	for i, c := range children {
+		if c.Slice != i+1 || math.Abs(c.Filled-expected[i]) > 1e-6 {
+			t.Errorf("Expected child of slice %d filled %v, got %+v", i+1, expected[i], c)
+		}
+	}
+}
+
+func TestMaxParticipation(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := &testClock{t: start}
+	server := newTestServer(t, clock)
+	server.AddLiquidity("BTC_EUR", "SELL", 50000, 10)
+	trade(server, start.Add(-time.Second), 1)
+	clock.onWait = func(now time.Time) {
+		if now.After(start) {
+			trade(server, now.Add(-time.Second), 2)
+		}
+	}
+
+	x := newTestExecution(server, clock, AlgorithmTWAP, Params{CurrencyPair: "BTC_EUR", Side: "BUY", Amount: 1, Duration: 2 * time.Minute, Slices: 2, MaxParticipation: 0.1})
+	p, _ := x.Run(context.Background())
+
+	// 10 % of the market volume of the previous slice, own fills excluded
+	children := x.Children()
+	if len(children) != 2 || math.Abs(children[0].Filled-0.1) > 1e-6 || math.Abs(children[1].Filled-0.2) > 1e-6 {
+		t.Fatalf("Expected children capped by participation, got %+v", children)
+	}
+	if p.Status != StatusIncomplete || math.Abs(p.Remaining-0.7) > 1e-6 {
+		t.Errorf("Expected unfilled remainder, got %+v", p)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	clock := &testClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	server := newTestServer(t, clock)
+	server.AddLiquidity("BTC_EUR", "BUY", 50000, 10)
+
+	x := newTestExecution(server, clock, AlgorithmTWAP, Params{CurrencyPair: "BTC_EUR", Side: "SELL", Amount: 1, Duration: 4 * time.Minute, Slices: 4})
+	x.OnProgress = func(p Progress) {
+		if p.Slice == 1 {
+			x.Cancel()
+		}
+	}
+	p, err := x.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if p.Status != StatusCancelled || len(x.Children()) != 1 || math.Abs(p.Executed-0.25) > 1e-6 {
+		t.Errorf("Expected execution cancelled after first slice, got %+v", p)
+	}
+	if btc, _ := server.Balance("1", "BTC"); math.Abs(btc-0.75) > 1e-6 {
+		t.Errorf("Expected 0.25 BTC sold, got balance %v", btc)
+	}
+}
+
+func TestChildLookedUpAfterFailedSend(t *testing.T) {
+	clock := &testClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	server := newTestServer(t, clock)
+	server.AddLiquidity("BTC_EUR", "SELL", 50000, 10)
+	// The first child executes but its response is lost, the second never
+	// reaches the exchange
+	server.InjectFault("/buyInstant", coinmatetest.Fault{StatusCode: http.StatusGatewayTimeout, AfterHandling: true, Times: 1})
+	server.InjectFault("/buyInstant", coinmatetest.Fault{StatusCode: http.StatusGatewayTimeout, Times: 1})
+
+	x := newTestExecution(server, clock, AlgorithmTWAP, Params{CurrencyPair: "BTC_EUR", Side: "BUY", Amount: 1, Duration: 3 * time.Minute, Slices: 3})
+	p, err := x.Run(context.Background())
+	if err != nil || p.Status != StatusCompleted || math.Abs(p.Executed-1) > 1e-6 {
+		t.Fatalf("Expected completed execution, got %+v (%v)", p, err)
+	}
+
+	children := x.Children()
+	if len(children) != 3 || children[0].OrderId == 0 || children[0].ClientOrderId == 0 {
+		t.Fatalf("Expected first child found by its client order ID, got %+v", children)
+	}
+	if children[1].OrderId != 0 || children[1].Error == "" || math.Abs(children[2].Filled-2.0/3) > 1e-6 {
+		t.Errorf("Expected second slice carried over to the third, got %+v", children)
+	}
+}
+
+func TestFailedChild(t *testing.T) {
+	clock := &testClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	server := newTestServer(t, clock)
+	server.AddLiquidity("BTC_EUR", "SELL", 50000, 10)
+	server.InjectFault("/buyInstant", coinmatetest.Fault{StatusCode: 500})
+	server.InjectFault("/order", coinmatetest.Fault{StatusCode: 500})
+
+	// Neither the child nor its lookup get an answer
+	x := newTestExecution(server, clock, AlgorithmTWAP, Params{CurrencyPair: "BTC_EUR", Side: "BUY", Amount: 1, Duration: time.Minute, Slices: 2})
+	p, err := x.Run(context.Background())
+	if err == nil || p.Status != StatusFailed || p.Error == "" {
+		t.Errorf("Expected failed execution, got %+v (%v)", p, err)
+	}
+
+	x = newTestExecution(server, clock, AlgorithmVWAP, Params{CurrencyPair: "BTC_EUR", Side: "BUY", Amount: 1, Slices: 2})
+	x.Transactions = nil
+	if _, err := x.Run(context.Background()); err == nil {
+		t.Error("Expected VWAP without transactions to fail")
+	}
+}
@@ -0,0 +1,328 @@
+package execution
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	orderStatusFilled    = "FILLED"
+	orderStatusCancelled = "CANCELLED"
+
+	// Amounts are sent with 8 decimals
+	amountPrecision = 1e8
+	historyLimit    = 100
+)
+
+// Iceberg order
+type IcebergParams struct {
+	CurrencyPair string
+	Side         string
+	// Total base amount
+	Amount float64
+	Price  float64
+	// Amount shown on the book at a time
+	DisplayAmount float64
+	// Random variation of displayed slices, e.g. 0.2 for ±20 %
+	Variance float64
+	// Smallest order accepted for the pair, smaller remainders are merged into
+	// the last slice
+	MinAmount float64
+}
+
+// Iceberg progress
+type IcebergStatus struct {
+	Status    Status  `json:"status"`
+	Filled    float64 `json:"filled"`
+	Remaining float64 `json:"remaining"`
+	// Visible order and its amount
+	OrderId uint64  `json:"orderId,omitempty"`
+	Visible float64 `json:"visible"`
+	// Slices placed so far
+	Slices int    `json:"slices"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Iceberg keeps a small limit order of a larger amount on the book and
+// places the next slice once it is filled. The hidden remainder is never
+// sent to the exchange. Fills are detected by Poll, which looks up the
+// visible order.
+type Iceberg struct {
+	Orders secure.OrderInterface
+	// Order lookup, order history is searched when nil
+	Lookup secure.OrderLookupInterface
+	Params IcebergParams
+
+	// Called when a slice is placed or fills and when the iceberg ends
+	OnProgress func(IcebergStatus)
+
+	mu      sync.Mutex
+	status  IcebergStatus
+	visible float64
+	done    float64
+	random  func() float64
+}
+
+// Return iceberg of params, the lookup is taken from orders when supported
+func NewIceberg(orders secure.OrderInterface, params IcebergParams) *Iceberg {
+	params.CurrencyPair = strings.ToUpper(params.CurrencyPair)
+	params.Side = strings.ToUpper(params.Side)
+	i := &Iceberg{
+		Orders: orders,
+		Params: params,
+		status: IcebergStatus{Status: StatusPending, Remaining: params.Amount},
+		random: rand.Float64,
+	}
+	if lookup, ok := orders.(secure.OrderLookupInterface); ok {
+		i.Lookup = lookup
+	}
+	return i
+}
+
+// Place the first slice
+func (i *Iceberg) Start() error {
+	if err := i.validate(); err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	if i.status.Status != StatusPending {
+		i.mu.Unlock()
+		return fmt.Errorf("iceberg already started")
+	}
+	i.status.Status = StatusRunning
+	err := i.placeSlice()
+	status := i.status
+	i.mu.Unlock()
+
+	i.report(status)
+	return err
+}
+
+// Check the visible order and refill it when filled
+func (i *Iceberg) Poll() error {
+	i.mu.Lock()
+	if i.status.Status != StatusRunning {
+		i.mu.Unlock()
+		return nil
+	}
+	before := i.status
+	o, err := i.lookup(i.status.OrderId)
+	if err == nil {
+		err = i.settle(o)
+	}
+	status := i.status
+	i.mu.Unlock()
+
+	if status.Filled != before.Filled || status.OrderId != before.OrderId || status.Status != before.Status {
+		i.report(status)
+	}
+	return err
+}
+
+// Poll periodically until the iceberg ends or the context is done
+func (i *Iceberg) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := i.Poll(); err != nil && onError != nil {
+			onError(err)
+		}
+		if s := i.Status().Status; s != StatusRunning && s != StatusPending {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cancel the visible order, fills up to the cancellation are kept
+func (i *Iceberg) Cancel() error {
+	i.mu.Lock()
+	switch i.status.Status {
+	case StatusPending:
+		i.status.Status = StatusCancelled
+	case StatusRunning:
+		r, err := i.Orders.CancelOrderWithInfo(i.status.OrderId)
+		if err != nil {
+			i.mu.Unlock()
+			return err
+		}
+		if r.Error {
+			i.mu.Unlock()
+			return fmt.Errorf("failed to cancel order %d: %s", i.status.OrderId, r.ErrorMessage)
+		}
+		if !r.Data.Success {
+			// Filled or cancelled in the meantime
+			o, err := i.lookup(i.status.OrderId)
+			if err != nil {
+				i.mu.Unlock()
+				return err
+			}
+			i.fill(o.OriginalAmount - o.RemainingAmount)
+		} else {
+			i.fill(i.visible - r.Data.RemainingAmount)
+		}
+		i.finish(StatusCancelled, "")
+	default:
+		i.mu.Unlock()
+		return nil
+	}
+	status := i.status
+	i.mu.Unlock()
+
+	i.report(status)
+	return nil
+}
+
+// Current progress
+func (i *Iceberg) Status() IcebergStatus {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.status
+}
+
+// Helper functions
+
+func (i *Iceberg) validate() error {
+	p := i.Params
+	switch {
+	case p.CurrencyPair == "":
+		return fmt.Errorf("currencyPair must not be empty")
+	case p.Side != buySide && p.Side != sellSide:
+		return fmt.Errorf("side must be %s or %s, got %q", buySide, sellSide, p.Side)
+	case p.Amount <= 0 || p.Price <= 0:
+		return fmt.Errorf("amount and price must be positive")
+	case p.DisplayAmount <= 0:
+		return fmt.Errorf("displayAmount must be positive")
+	case p.Variance < 0 || p.Variance >= 1:
+		return fmt.Errorf("variance must be in [0, 1)")
+	}
+	return nil
+}
+
+// Place the next visible order, the caller holds mu
+func (i *Iceberg) placeSlice() error {
+	remaining := i.Params.Amount - i.done
+	amount := i.sliceAmount(remaining)
+
+	var r secure.SellLimit
+	var err error
+	if i.Params.Side == buySide {
+		r, err = i.Orders.BuyLimit(amount, i.Params.Price, 0, i.Params.CurrencyPair, false, false, 0)
+	} else {
+		r, err = i.Orders.SellLimit(amount, i.Params.Price, 0, i.Params.CurrencyPair, false, false, 0)
+	}
+	if err == nil && r.Error {
+		err = fmt.Errorf("order rejected: %s", r.ErrorMessage)
+	}
+	if err != nil {
+		i.finish(StatusFailed, err.Error())
+		return err
+	}
+
+	i.visible = amount
+	i.status.OrderId = r.OrderId
+	i.status.Visible = amount
+	i.status.Slices++
+	return nil
+}
+
+// Randomized slice of the remaining amount
+func (i *Iceberg) sliceAmount(remaining float64) float64 {
+	p := i.Params
+	amount := p.DisplayAmount
+	if p.Variance > 0 {
+		amount *= 1 + p.Variance*(2*i.random()-1)
+	}
+	amount = math.Max(amount, p.MinAmount)
+	amount = math.Floor(amount*amountPrecision) / amountPrecision
+	if remaining-amount < math.Max(p.MinAmount, epsilon) {
+		return remaining
+	}
+	return amount
+}
+
+// Account the state of the visible order, the caller holds mu
+func (i *Iceberg) settle(o secure.OrderHistoryData) error {
+	executed := o.OriginalAmount - o.RemainingAmount
+	switch o.Status {
+	case orderStatusFilled:
+		i.fill(o.OriginalAmount)
+		if i.Params.Amount-i.done <= epsilon {
+			i.finish(StatusCompleted, "")
+			return nil
+		}
+		return i.placeSlice()
+	case orderStatusCancelled:
+		i.fill(executed)
+		i.finish(StatusCancelled, fmt.Sprintf("order %d cancelled outside the iceberg", o.Id))
+		return nil
+	}
+	i.status.Filled = i.done + executed
+	i.status.Remaining = math.Max(0, i.Params.Amount-i.status.Filled)
+	return nil
+}
+
+// Close the visible slice with amount filled
+func (i *Iceberg) fill(amount float64) {
+	i.done += amount
+	i.visible = 0
+	i.status.Filled = i.done
+	i.status.Remaining = math.Max(0, i.Params.Amount-i.done)
+	i.status.Visible = 0
+}
+
+func (i *Iceberg) finish(status Status, message string) {
+	i.status.Status = status
+	i.status.Error = message
+	i.status.Visible = 0
+}
+
+func (i *Iceberg) lookup(orderId uint64) (secure.OrderHistoryData, error) {
+	if i.Lookup != nil {
+		r, err := i.Lookup.GetOrderById(orderId)
+		if err != nil {
+			return secure.OrderHistoryData{}, err
+		}
+		if r.Error {
+			return secure.OrderHistoryData{}, fmt.Errorf("order lookup failed: %s", r.ErrorMessage)
+		}
+		if r.Data == nil {
+			return secure.OrderHistoryData{}, fmt.Errorf("order %d not found", orderId)
+		}
+		return *r.Data, nil
+	}
+
+	r, err := i.Orders.GetHistory(i.Params.CurrencyPair, historyLimit)
+	if err != nil {
+		return secure.OrderHistoryData{}, err
+	}
+	if r.Error {
+		return secure.OrderHistoryData{}, fmt.Errorf("order history failed: %s", r.ErrorMessage)
+	}
+	for _, o := range r.Data {
+		if o.Id == orderId {
+			return o, nil
+		}
+	}
+	return secure.OrderHistoryData{}, fmt.Errorf("order %d not found", orderId)
+}
+
+func (i *Iceberg) report(status IcebergStatus) {
+	if i.OnProgress != nil {
+		i.OnProgress(status)
+	}
+}
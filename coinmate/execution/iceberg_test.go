@@ -0,0 +1,103 @@
+package execution
+
+import (
+	"math"
+	"testing"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/secure"
+)
+
+func newTestIceberg(t *testing.T, params IcebergParams) (*Iceberg, *secure.Order, *coinmatetest.Server) {
+	t.Helper()
+	server := coinmatetest.NewServer()
+	t.Cleanup(server.Close)
+
+	server.AddAccount("1", "public-key", "private-key")
+	server.AddAccount("2", "public-key-2", "private-key-2")
+	server.Deposit("1", "EUR", 100000)
+	server.Deposit("2", "BTC", 2)
+
+	i := NewIceberg(&secure.Order{Client: server.NewClient("1")}, params)
+	return i, &secure.Order{Client: server.NewClient("2")}, server
+}
+
+func TestIcebergRefill(t *testing.T) {
+	i, taker, server := newTestIceberg(t, IcebergParams{CurrencyPair: "btc_eur", Side: "buy", Amount: 1, Price: 50000, DisplayAmount: 0.3})
+	var reports []IcebergStatus
+	i.OnProgress = func(s IcebergStatus) { reports = append(reports, s) }
+
+	if err := i.Start(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, amount := range []float64{0.3, 0.1, 0.2, 0.3, 0.1} {
+		open, _ := i.Orders.GetOpenOrders("BTC_EUR")
+		if len(open.Data) != 1 || open.Data[0].Amount > 0.3+1e-9 {
+			t.Fatalf("Expected one visible order of at most 0.3, got %+v", open.Data)
+		}
+		taker.SellInstant(amount, "BTC_EUR", 0)
+		if err := i.Poll(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	s := i.Status()
+	if s.Status != StatusCompleted || math.Abs(s.Filled-1) > 1e-9 || s.Remaining > 1e-9 || s.Slices != 4 || s.Visible != 0 {
+		t.Errorf("Expected completed iceberg of 4 slices, got %+v", s)
+	}
+	if btc, _ := server.Balance("1", "BTC"); math.Abs(btc-1) > 1e-6 {
+		t.Errorf("Expected 1 BTC bought, got %v", btc)
+	}
+	if open, _ := i.Orders.GetOpenOrders("BTC_EUR"); len(open.Data) != 0 {
+		t.Errorf("Expected no open orders, got %+v", open.Data)
+	}
+	// Start, five polls with fills and the completion of the last one
+	if len(reports) != 6 || math.Abs(reports[2].Filled-0.4) > 1e-9 || reports[2].Slices != 2 {
+		t.Errorf("Unexpected progress reports %+v", reports)
+	}
+}
+
+func TestIcebergSliceAmount(t *testing.T) {
+	i := NewIceberg(nil, IcebergParams{Amount: 1, DisplayAmount: 0.3, Variance: 0.2, MinAmount: 0.05})
+
+	i.random = func() float64 { return 1 }
+	if amount := i.sliceAmount(1); math.Abs(amount-0.36) > 1e-9 {
+		t.Errorf("Expected largest slice 0.36, got %v", amount)
+	}
+	i.random = func() float64 { return 0 }
+	if amount := i.sliceAmount(1); math.Abs(amount-0.24) > 1e-9 {
+		t.Errorf("Expected smallest slice 0.24, got %v", amount)
+	}
+	if amount := i.sliceAmount(0.28); amount != 0.28 {
+		t.Errorf("Expected remainder below min amount merged, got %v", amount)
+	}
+}
+
+func TestIcebergCancel(t *testing.T) {
+	i, taker, _ := newTestIceberg(t, IcebergParams{CurrencyPair: "BTC_EUR", Side: "BUY", Amount: 1, Price: 50000, DisplayAmount: 0.3})
+	i.Start()
+	taker.SellInstant(0.1, "BTC_EUR", 0)
+
+	if err := i.Cancel(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if s := i.Status(); s.Status != StatusCancelled || math.Abs(s.Filled-0.1) > 1e-9 || math.Abs(s.Remaining-0.9) > 1e-9 {
+		t.Errorf("Expected cancelled iceberg with partial fill, got %+v", s)
+	}
+	if open, _ := i.Orders.GetOpenOrders("BTC_EUR"); len(open.Data) != 0 {
+		t.Errorf("Expected visible order cancelled, got %+v", open.Data)
+	}
+	if err := i.Poll(); err != nil || i.Status().Slices != 1 {
+		t.Errorf("Expected no refill after cancel, got %+v (%v)", i.Status(), err)
+	}
+}
+
+func TestIcebergCancelledOutside(t *testing.T) {
+	i, _, _ := newTestIceberg(t, IcebergParams{CurrencyPair: "BTC_EUR", Side: "BUY", Amount: 1, Price: 50000, DisplayAmount: 0.3})
+	i.Start()
+	i.Orders.CancelOrder(i.Status().OrderId)
+
+	i.Poll()
+	if s := i.Status(); s.Status != StatusCancelled || s.Error == "" {
+		t.Errorf("Expected iceberg cancelled with its order, got %+v", s)
+	}
+}
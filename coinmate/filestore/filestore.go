@@ -0,0 +1,82 @@
+package filestore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Store keeping a list of records in one JSON file, shared by the order,
+// intent, condition and execution stores. Saves write a temporary file and
+// rename it, so a crash never leaves a partially written file behind.
+type JSONFileStore[T any] struct {
+	Path string
+	// Kind of records in error messages, e.g. "order"
+	Name string
+}
+
+// Return file store at path, the directory is created on first save
+func New[T any](path, name string) *JSONFileStore[T] {
+	return &JSONFileStore[T]{Path: path, Name: name}
+}
+
+// Load records, none when the file does not exist yet
+func (s *JSONFileStore[T]) Load() ([]T, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.name(), err)
+	}
+
+	var records []T
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode %s %s: %w", s.name(), s.Path, err)
+	}
+	return records, nil
+}
+
+// Replace stored records
+func (s *JSONFileStore[T]) Save(records []T) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", s.name(), err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", s.name(), err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", s.name(), err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", s.name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %w", s.name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", s.name(), err)
+	}
+	if err := os.Rename(tmp.Name(), s.Path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", s.name(), err)
+	}
+	return nil
+}
+
+// Helper functions
+
+func (s *JSONFileStore[T]) name() string {
+	if s.Name == "" {
+		return "store"
+	}
+	return s.Name + " store"
+}
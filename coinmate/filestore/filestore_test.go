@@ -0,0 +1,54 @@
+package filestore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type record struct {
+	Id   uint64 `json:"id"`
+	Note string `json:"note"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	store := New[record](filepath.Join(t.TempDir(), "state", "records.json"), "record")
+
+	records, err := store.Load()
+	if err != nil || len(records) != 0 {
+		t.Fatalf("Expected empty store, got %+v (%v)", records, err)
+	}
+
+	if err := store.Save([]record{{Id: 1, Note: "first"}, {Id: 2}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	records, err = store.Load()
+	if err != nil || len(records) != 2 || records[0].Note != "first" || records[1].Id != 2 {
+		t.Fatalf("Expected 2 records, got %+v (%v)", records, err)
+	}
+
+	// Saving replaces the previous records
+	if err := store.Save([]record{{Id: 3}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	records, _ = store.Load()
+	if len(records) != 1 || records[0].Id != 3 {
+		t.Errorf("Expected record 3 only, got %+v", records)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(store.Path))
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files left, got %d entries", len(entries))
+	}
+}
+
+func TestCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.json")
+	os.WriteFile(path, []byte("{broken"), 0o644)
+
+	_, err := New[record](path, "record").Load()
+	if err == nil || !strings.Contains(err.Error(), "record store") {
+		t.Errorf("Expected record store decode error, got %v", err)
+	}
+}
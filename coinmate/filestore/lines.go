@@ -0,0 +1,76 @@
+package filestore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Scanner over an append-only file of newline-delimited records, e.g. JSON
+// lines. A crash during append can leave a last line without newline, Torn
+// reports it so that readers can skip the record instead of failing.
+type LineScanner struct {
+	*bufio.Scanner
+	torn bool
+}
+
+// Return scanner reading lines of up to 1 MiB from r
+func NewLineScanner(r io.Reader) *LineScanner {
+	s := &LineScanner{Scanner: bufio.NewScanner(r)}
+	s.Buffer(nil, 1<<20)
+	s.Split(s.scanLines)
+	return s
+}
+
+// Whether the line read by the last Scan call ends the file without newline
+func (s *LineScanner) Torn() bool {
+	return s.torn
+}
+
+// Remove a trailing line without newline left by a crash during append, so
+// that new records do not continue it. Missing files are left alone.
+func TruncateTornLine(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	end := info.Size()
+	buf := make([]byte, 4096)
+	for offset := end; offset > 0; {
+		n := int64(len(buf))
+		if offset < n {
+			n = offset
+		}
+		offset -= n
+		if _, err := f.ReadAt(buf[:n], offset); err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if i := strings.LastIndexByte(string(buf[:n]), '\n'); i >= 0 {
+			if offset+int64(i)+1 == end {
+				return nil
+			}
+			return f.Truncate(offset + int64(i) + 1)
+		}
+	}
+	return f.Truncate(0)
+}
+
+// Helper functions
+
+// bufio.ScanLines remembering whether the line was terminated
+func (s *LineScanner) scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	s.torn = atEOF && advance == len(data) && advance > 0 && data[advance-1] != '\n'
+	return advance, token, err
+}
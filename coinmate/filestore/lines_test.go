@@ -0,0 +1,56 @@
+package filestore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLineScannerTorn(t *testing.T) {
+	s := NewLineScanner(strings.NewReader("{\"id\":1}\n{\"id\":2}\n{\"id\""))
+
+	var torn []bool
+	for s.Scan() {
+		torn = append(torn, s.Torn())
+	}
+	if len(torn) != 3 || torn[0] || torn[1] || !torn[2] {
+		t.Errorf("Expected only the last line to be torn, got %v", torn)
+	}
+
+	s = NewLineScanner(strings.NewReader("{\"id\":1}\n"))
+	if !s.Scan() || s.Torn() {
+		t.Error("Expected terminated last line not to be torn")
+	}
+}
+
+func TestTruncateTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.jsonl")
+	if err := TruncateTornLine(path); err != nil {
+		t.Fatalf("Expected missing file to be left alone, got %v", err)
+	}
+
+	os.WriteFile(path, []byte("{\"id\":1}\n{\"id\""), 0o644)
+	if err := TruncateTornLine(path); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "{\"id\":1}\n" {
+		t.Errorf("Expected torn line to be removed, got %q", data)
+	}
+
+	// Complete files are not changed
+	if err := TruncateTornLine(path); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if string(data) != "{\"id\":1}\n" {
+		t.Errorf("Expected file to be unchanged, got %q", data)
+	}
+
+	os.WriteFile(path, []byte("{\"id\""), 0o644)
+	TruncateTornLine(path)
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Errorf("Expected single torn line to be removed, got %q", data)
+	}
+}
@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"tourGo/coinmate"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	defaultRetries    = 2
+	defaultRetryDelay = 500 * time.Millisecond
+)
+
+// Permission of a token, trading includes reading
+type Permission int
+
+const (
+	// No token needed, e.g. the OpenAPI document
+	PermissionNone Permission = iota
+	// Market data and account state
+	PermissionRead
+	// Placing and cancelling orders
+	PermissionTrade
+)
+
+func (p Permission) String() string {
+	switch p {
+	case PermissionNone:
+		return "none"
+	case PermissionRead:
+		return "read"
+	case PermissionTrade:
+		return "trade"
+	}
+	return fmt.Sprintf("Permission(%d)", int(p))
+}
+
+// Error returned to the caller with its HTTP status
+type Error struct {
+	Status  int    `json:"-"`
+	Message string `json:"error"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Gateway serves the Coinmate API as a local REST/JSON API. The credentials
+// stay in the client, callers authenticate with bearer tokens, each allowed
+// either reading or trading. Every route requires a permission, see Routes.
+//
+// Responses are the data of the Coinmate response. Errors are
+// {"error": "..."} with the status 400 for invalid requests, 401 and 403 for
+// missing tokens and permissions, 422 when Coinmate refuses the request, 502
+// when it cannot be reached and 503 when an order gate refuses the order.
+//
+// Requests are signed by the client, hand in a ratelimit.Client to keep all
+// callers within the Coinmate rate limit. Read routes are retried when
+// Coinmate cannot be reached; orders and cancels are not, as a failed request
+// may still have been executed.
+type Gateway struct {
+	Client coinmate.ClientInterface
+	// Bearer tokens and their permissions
+	Tokens map[string]Permission
+	// Checked before orders are placed, the default gate when nil
+	Gate secure.Gate
+	// Extra attempts of read routes and the wait before each
+	Retries    int
+	RetryDelay time.Duration
+
+	sleep func(time.Duration)
+}
+
+// Return gateway using the client for all requests
+func NewGateway(client coinmate.ClientInterface, tokens map[string]Permission) (*Gateway, error) {
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("at least one token is required")
+	}
+	for token, permission := range tokens {
+		if strings.TrimSpace(token) == "" {
+			return nil, fmt.Errorf("token must not be empty")
+		}
+		if permission != PermissionRead && permission != PermissionTrade {
+			return nil, fmt.Errorf("invalid permission %v of token", permission)
+		}
+	}
+	return &Gateway{
+		Client:     client,
+		Tokens:     tokens,
+		Retries:    defaultRetries,
+		RetryDelay: defaultRetryDelay,
+		sleep:      time.Sleep,
+	}, nil
+}
+
+// HTTP handler serving all routes
+func (g *Gateway) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, r := range Routes() {
+		mux.Handle(r.Method+" "+r.Path, g.handle(r))
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, &Error{Status: http.StatusNotFound, Message: "no route " + r.Method + " " + r.URL.Path})
+	})
+	return mux
+}
+
+// Helper functions
+
+func (g *Gateway) handle(route Route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := g.authorize(r, route.Permission); err != nil {
+			writeError(w, err)
+			return
+		}
+		data, err := route.handle(g, r)
+		for attempt := 0; attempt < g.Retries && route.Permission == PermissionRead && unreachable(err); attempt++ {
+			g.wait(g.RetryDelay)
+			data, err = route.handle(g, r)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, data)
+	})
+}
+
+func (g *Gateway) authorize(r *http.Request, required Permission) error {
+	if required == PermissionNone {
+		return nil
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return &Error{Status: http.StatusUnauthorized, Message: "missing bearer token"}
+	}
+
+	granted := PermissionNone
+	for t, p := range g.Tokens {
+		// Every token is compared so that the time does not tell which one matched
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			granted = p
+		}
+	}
+	switch {
+	case granted == PermissionNone:
+		return &Error{Status: http.StatusUnauthorized, Message: "invalid bearer token"}
+	case granted < required:
+		return &Error{Status: http.StatusForbidden, Message: fmt.Sprintf("token lacks %s permission", required)}
+	}
+	return nil
+}
+
+func (g *Gateway) wait(d time.Duration) {
+	if g.sleep == nil {
+		time.Sleep(d)
+		return
+	}
+	g.sleep(d)
+}
+
+// Coinmate could not be reached, served as 502
+func unreachable(err error) bool {
+	var e *Error
+	return err != nil && !errors.As(err, &e) && !errors.Is(err, secure.ErrNotSent)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+	case errors.Is(err, secure.ErrNotSent):
+		e = &Error{Status: http.StatusServiceUnavailable, Message: err.Error()}
+	default:
+		e = &Error{Status: http.StatusBadGateway, Message: err.Error()}
+	}
+	if e.Status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	writeJSON(w, e.Status, e)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
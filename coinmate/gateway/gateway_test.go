@@ -0,0 +1,242 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	readToken  = "read-token"
+	tradeToken = "trade-token"
+)
+
+type refuseGate struct{}
+
+func (refuseGate) Allow() error {
+	return errors.New("halted")
+}
+
+func newTestGateway(t *testing.T) (*Gateway, *httptest.Server, *coinmatetest.Server) {
+	t.Helper()
+	server := coinmatetest.NewServer()
+	t.Cleanup(server.Close)
+
+	server.AddAccount("1", "public-key", "private-key")
+	server.Deposit("1", "EUR", 10000)
+	server.AddLiquidity("BTC_EUR", "SELL", 50000, 1)
+	server.AddLiquidity("BTC_EUR", "BUY", 49000, 1)
+
+	g, err := NewGateway(server.NewClient("1"), map[string]Permission{readToken: PermissionRead, tradeToken: PermissionTrade})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	gateway := httptest.NewServer(g.Handler())
+	t.Cleanup(gateway.Close)
+	return g, gateway, server
+}
+
+// Send request, decode the JSON response into v and return the status
+func request(t *testing.T, gateway *httptest.Server, method, path, token, body string, v interface{}) int {
+	t.Helper()
+	req, _ := http.NewRequest(method, gateway.URL+path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if v != nil {
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatalf("Expected JSON response, got %q", data)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestAuthorization(t *testing.T) {
+	_, gateway, _ := newTestGateway(t)
+
+	var e Error
+	if status := request(t, gateway, http.MethodGet, "/v1/balances", "", "", &e); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", status)
+	}
+	if status := request(t, gateway, http.MethodGet, "/v1/balances", "wrong", "", &e); status != http.StatusUnauthorized || e.Message != "invalid bearer token" {
+		t.Errorf("Expected 401 for a wrong token, got %d %q", status, e.Message)
+	}
+
+	var balances map[string]secure.BalanceCurrency
+	if status := request(t, gateway, http.MethodGet, "/v1/balances", readToken, "", &balances); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if balances["EUR"].Available != 10000 {
+		t.Errorf("Expected 10000 EUR available, got %+v", balances["EUR"])
+	}
+
+	order := `{"side":"BUY","currencyPair":"BTC_EUR","amount":0.01,"price":48000}`
+	if status := request(t, gateway, http.MethodPost, "/v1/orders", readToken, order, &e); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a read token, got %d", status)
+	}
+	if status := request(t, gateway, http.MethodDelete, "/v1/orders/1", readToken, "", &e); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a read token, got %d", status)
+	}
+
+	// The document needs no token
+	if status := request(t, gateway, http.MethodGet, "/openapi.json", "", "", nil); status != http.StatusOK {
+		t.Errorf("Expected 200 for the OpenAPI document, got %d", status)
+	}
+}
+
+func TestMarketData(t *testing.T) {
+	_, gateway, _ := newTestGateway(t)
+
+	var ticker struct{ Bid, Ask float64 }
+	if status := request(t, gateway, http.MethodGet, "/v1/ticker/btc_eur", readToken, "", &ticker); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if ticker.Bid != 49000 || ticker.Ask != 50000 {
+		t.Errorf("Expected bid 49000 and ask 50000, got %+v", ticker)
+	}
+
+	var book struct{ Asks, Bids []struct{ Price float64 } }
+	request(t, gateway, http.MethodGet, "/v1/orderbook/BTC_EUR?group=true", readToken, "", &book)
+	if len(book.Asks) != 1 || len(book.Bids) != 1 {
+		t.Errorf("Expected 1 ask and 1 bid, got %+v", book)
+	}
+
+	var e Error
+	if status := request(t, gateway, http.MethodGet, "/v1/orderbook/BTC_EUR?group=maybe", readToken, "", &e); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid flag, got %d", status)
+	}
+	if status := request(t, gateway, http.MethodGet, "/v1/ticker/XXX_YYY", readToken, "", &e); status != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for an unknown pair, got %d %q", status, e.Message)
+	}
+	if status := request(t, gateway, http.MethodGet, "/v1/nothing", readToken, "", &e); status != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", status)
+	}
+}
+
+func TestOrders(t *testing.T) {
+	g, gateway, _ := newTestGateway(t)
+
+	var placed OrderResponse
+	order := `{"side":"buy","currencyPair":"BTC_EUR","amount":0.01,"price":48000}`
+	if status := request(t, gateway, http.MethodPost, "/v1/orders", tradeToken, order, &placed); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+
+	var open []secure.OpenOrdersData
+	request(t, gateway, http.MethodGet, "/v1/orders?pair=BTC_EUR", readToken, "", &open)
+	if len(open) != 1 || open[0].Id != placed.OrderId || open[0].Price != 48000 {
+		t.Fatalf("Expected open order %d, got %+v", placed.OrderId, open)
+	}
+
+	var cancelled secure.CancelOrderWithInfoData
+	path := "/v1/orders/" + strconv.FormatUint(placed.OrderId, 10)
+	if status := request(t, gateway, http.MethodDelete, path, tradeToken, "", &cancelled); status != http.StatusOK || !cancelled.Success {
+		t.Errorf("Expected order cancelled, got %d %+v", status, cancelled)
+	}
+
+	var e Error
+	for _, body := range []string{
+		`{"side":"HOLD","currencyPair":"BTC_EUR","amount":1,"price":1}`,
+		`{"side":"BUY","currencyPair":"BTC_EUR","amount":1}`,
+		`{"side":"BUY","type":"INSTANT","currencyPair":"BTC_EUR","amount":1}`,
+		`{"side":"BUY","currencyPair":"BTC_EUR","amount":1,"price":1,"extra":true}`,
+		`not json`,
+	} {
+		if status := request(t, gateway, http.MethodPost, "/v1/orders", tradeToken, body, &e); status != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, status)
+		}
+	}
+
+	var instant OrderResponse
+	if status := request(t, gateway, http.MethodPost, "/v1/orders", tradeToken, `{"side":"BUY","type":"INSTANT","currencyPair":"BTC_EUR","total":500}`, &instant); status != http.StatusOK || instant.OrderId == 0 {
+		t.Errorf("Expected instant buy, got %d %+v", status, instant)
+	}
+	if status := request(t, gateway, http.MethodPost, "/v1/orders", tradeToken, `{"side":"SELL","currencyPair":"BTC_EUR","amount":5,"price":60000}`, &e); status != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for insufficient balance, got %d %q", status, e.Message)
+	}
+
+	g.Gate = refuseGate{}
+	if status := request(t, gateway, http.MethodPost, "/v1/orders", tradeToken, order, &e); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 when the gate refuses, got %d %q", status, e.Message)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	_, gateway, _ := newTestGateway(t)
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationId string `json:"operationId"`
+			Permission  string `json:"x-permission"`
+			Responses   map[string]struct {
+				Content map[string]struct {
+					Schema map[string]interface{} `json:"schema"`
+				} `json:"content"`
+			} `json:"responses"`
+		} `json:"paths"`
+	}
+	request(t, gateway, http.MethodGet, "/openapi.json", "", "", &doc)
+	if doc.OpenAPI != openAPIVersion {
+		t.Errorf("Expected version %s, got %q", openAPIVersion, doc.OpenAPI)
+	}
+	for _, r := range Routes() {
+		op, ok := doc.Paths[r.Path][strings.ToLower(r.Method)]
+		if !ok {
+			t.Errorf("Expected %s %s in the document", r.Method, r.Path)
+			continue
+		}
+		if r.Permission != PermissionNone && op.Permission != r.Permission.String() {
+			t.Errorf("Expected %s permission of %s %s, got %q", r.Permission, r.Method, r.Path, op.Permission)
+		}
+	}
+
+	post := doc.Paths["/v1/orders"]["post"]
+	if post.OperationId != "postOrders" || post.Permission != "trade" {
+		t.Errorf("Expected trading postOrders, got %+v", post)
+	}
+	ticker := doc.Paths["/v1/ticker/{pair}"]["get"].Responses["200"].Content["application/json"].Schema
+	if props, _ := ticker["properties"].(map[string]interface{}); props["last"] == nil {
+		t.Errorf("Expected ticker schema with last, got %v", ticker)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	g, gateway, server := newTestGateway(t)
+	var waits []time.Duration
+	g.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	server.InjectFault("/ticker", coinmatetest.Fault{StatusCode: http.StatusServiceUnavailable, Times: 2})
+	var ticker struct{ Ask float64 }
+	if status := request(t, gateway, http.MethodGet, "/v1/ticker/BTC_EUR", readToken, "", &ticker); status != http.StatusOK || ticker.Ask != 50000 {
+		t.Errorf("Expected ticker after retries, got %d %+v", status, ticker)
+	}
+	if len(waits) != 2 || waits[0] != defaultRetryDelay {
+		t.Errorf("Expected 2 retries after %v, got %v", defaultRetryDelay, waits)
+	}
+
+	// An order may have been executed, it is never sent twice
+	server.InjectFault("/buyLimit", coinmatetest.Fault{StatusCode: http.StatusServiceUnavailable, Times: 1})
+	var e Error
+	order := `{"side":"BUY","currencyPair":"BTC_EUR","amount":0.01,"price":48000}`
+	if status := request(t, gateway, http.MethodPost, "/v1/orders", tradeToken, order, &e); status != http.StatusBadGateway {
+		t.Errorf("Expected 502 without retry, got %d %q", status, e.Message)
+	}
+	if len(waits) != 2 {
+		t.Errorf("Expected no retry of the order, got %v", waits)
+	}
+}
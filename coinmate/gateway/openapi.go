@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+const openAPIVersion = "3.0.3"
+
+// OpenAPI document describing Routes, schemas are derived from the JSON tags
+// of the response types
+func OpenAPI() map[string]interface{} {
+	paths := map[string]map[string]interface{}{}
+	for _, r := range Routes() {
+		op := map[string]interface{}{
+			"summary":     r.Summary,
+			"operationId": operationId(r),
+			"responses":   responses(r),
+		}
+		if r.Permission != PermissionNone {
+			op["security"] = []map[string][]string{{"bearer": {}}}
+			op["x-permission"] = r.Permission.String()
+		}
+		var params []map[string]interface{}
+		for _, p := range r.Params {
+			params = append(params, map[string]interface{}{
+				"name":        p.Name,
+				"in":          p.In,
+				"required":    p.Required,
+				"description": p.Description,
+				"schema":      map[string]string{"type": p.Type},
+			})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+		if r.Body {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": schema(reflect.TypeOf(OrderRequest{}))}},
+			}
+		}
+		if paths[r.Path] == nil {
+			paths[r.Path] = map[string]interface{}{}
+		}
+		paths[r.Path][strings.ToLower(r.Method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]string{
+			"title":       "Coinmate gateway",
+			"version":     "1",
+			"description": "Local REST/JSON gateway to the Coinmate API. Read tokens may use the read routes, trade tokens all routes.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]string{"type": "http", "scheme": "bearer"},
+			},
+			"schemas": map[string]interface{}{
+				"Error": schema(reflect.TypeOf(Error{})),
+			},
+		},
+	}
+}
+
+// Helper functions
+
+func openAPI(g *Gateway, r *http.Request) (interface{}, error) {
+	return OpenAPI(), nil
+}
+
+// e.g. getTicker or deleteOrders
+func operationId(r Route) string {
+	id := strings.ToLower(r.Method)
+	for _, part := range strings.Split(r.Path, "/") {
+		if part == "" || part == "v1" || strings.HasPrefix(part, "{") {
+			continue
+		}
+		part = strings.TrimSuffix(part, ".json")
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	if strings.HasSuffix(r.Path, "}") {
+		param := strings.Trim(r.Path[strings.LastIndex(r.Path, "{"):], "{}")
+		id += "By" + strings.ToUpper(param[:1]) + param[1:]
+	}
+	return id
+}
+
+func responses(r Route) map[string]interface{} {
+	errorResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": map[string]string{"$ref": "#/components/schemas/Error"}}},
+		}
+	}
+	result := map[string]interface{}{
+		"200": map[string]interface{}{
+			"description": "OK",
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": schema(reflect.TypeOf(r.Response))}},
+		},
+	}
+	if r.Permission == PermissionNone {
+		return result
+	}
+	result["400"] = errorResponse("Invalid request")
+	result["401"] = errorResponse("Missing or invalid bearer token")
+	result["403"] = errorResponse("Token lacks the permission of the route")
+	result["422"] = errorResponse("Refused by Coinmate")
+	result["502"] = errorResponse("Coinmate not reachable")
+	if r.Permission == PermissionTrade {
+		result["503"] = errorResponse("Refused by an order gate, e.g. a kill switch")
+	}
+	return result
+}
+
+// JSON schema of a Go type as encoded by encoding/json
+func schema(t reflect.Type) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			properties[name] = schema(f.Type)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	}
+	return map[string]interface{}{}
+}
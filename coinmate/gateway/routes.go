@@ -0,0 +1,426 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	buySide  = "BUY"
+	sellSide = "SELL"
+
+	orderTypeLimit   = "LIMIT"
+	orderTypeInstant = "INSTANT"
+
+	// Larger order requests are refused
+	maxBodySize = 64 << 10
+)
+
+// Path or query parameter of a route
+type Param struct {
+	Name        string
+	In          string // path or query
+	Type        string // string, integer, number or boolean
+	Description string
+	Required    bool
+}
+
+// Gateway endpoint
+type Route struct {
+	Method     string
+	Path       string
+	Summary    string
+	Permission Permission
+	Params     []Param
+	// JSON request body, see OrderRequest
+	Body bool
+	// Zero value of the returned data, describes it in the OpenAPI document
+	Response interface{}
+
+	handle func(g *Gateway, r *http.Request) (interface{}, error)
+}
+
+// Body of POST /v1/orders
+type OrderRequest struct {
+	// BUY or SELL
+	Side string `json:"side"`
+	// LIMIT or INSTANT
+	Type         string `json:"type"`
+	CurrencyPair string `json:"currencyPair"`
+	// Base amount of limit orders and instant sells
+	Amount float64 `json:"amount"`
+	// Quote amount spent by instant buys
+	Total             float64 `json:"total"`
+	Price             float64 `json:"price"`
+	StopPrice         float64 `json:"stopPrice"`
+	Hidden            bool    `json:"hidden"`
+	ImmediateOrCancel bool    `json:"immediateOrCancel"`
+	ClientOrderId     uint64  `json:"clientOrderId"`
+}
+
+// Response of POST /v1/orders
+type OrderResponse struct {
+	OrderId uint64 `json:"orderId"`
+}
+
+var (
+	pairParam = Param{Name: "pair", In: "path", Type: "string", Description: "currency pair, e.g. BTC_EUR", Required: true}
+	idParam   = Param{Name: "id", In: "path", Type: "integer", Description: "order ID", Required: true}
+)
+
+// All gateway routes
+func Routes() []Route {
+	return []Route{
+		{Method: http.MethodGet, Path: "/openapi.json", Summary: "OpenAPI document of the gateway", Permission: PermissionNone, Response: map[string]interface{}{}, handle: openAPI},
+
+		{Method: http.MethodGet, Path: "/v1/ticker/{pair}", Summary: "Ticker of a pair", Permission: PermissionRead, Params: []Param{pairParam}, Response: public.TickerData{}, handle: ticker},
+		{Method: http.MethodGet, Path: "/v1/tickers", Summary: "Tickers of all pairs", Permission: PermissionRead, Response: map[string]public.TickerAllItem{}, handle: tickers},
+		{Method: http.MethodGet, Path: "/v1/orderbook/{pair}", Summary: "Order book of a pair", Permission: PermissionRead,
+			Params: []Param{pairParam, {Name: "group", In: "query", Type: "boolean", Description: "group orders by price"}}, Response: public.OrderBookData{}, handle: orderBook},
+		{Method: http.MethodGet, Path: "/v1/transactions/{pair}", Summary: "Recent public trades of a pair", Permission: PermissionRead,
+			Params: []Param{pairParam, {Name: "minutes", In: "query", Type: "integer", Description: "minutes into history, 10 by default"}}, Response: []public.TransactionsData{}, handle: transactions},
+		{Method: http.MethodGet, Path: "/v1/pairs", Summary: "Trading pairs", Permission: PermissionRead, Response: []public.TradingPairsData{}, handle: tradingPairs},
+		{Method: http.MethodGet, Path: "/v1/currencies", Summary: "Currencies", Permission: PermissionRead, Response: []string{}, handle: currencies},
+
+		{Method: http.MethodGet, Path: "/v1/balances", Summary: "Account balances", Permission: PermissionRead, Response: map[string]secure.BalanceCurrency{}, handle: balances},
+		{Method: http.MethodGet, Path: "/v1/orders", Summary: "Open orders", Permission: PermissionRead,
+			Params: []Param{{Name: "pair", In: "query", Type: "string", Description: "currency pair, all pairs when omitted"}}, Response: []secure.OpenOrdersData{}, handle: openOrders},
+		{Method: http.MethodGet, Path: "/v1/orders/{id}", Summary: "Order by ID", Permission: PermissionRead, Params: []Param{idParam}, Response: secure.OrderHistoryData{}, handle: orderById},
+		{Method: http.MethodGet, Path: "/v1/history/{pair}", Summary: "Order history of a pair", Permission: PermissionRead,
+			Params: []Param{pairParam, {Name: "limit", In: "query", Type: "integer", Description: "number of orders, 100 by default"}}, Response: []secure.OrderHistoryData{}, handle: orderHistory},
+		{Method: http.MethodGet, Path: "/v1/trades", Summary: "Own trades", Permission: PermissionRead, Params: []Param{
+			{Name: "pair", In: "query", Type: "string", Description: "currency pair"},
+			{Name: "limit", In: "query", Type: "integer", Description: "number of trades"},
+			{Name: "lastId", In: "query", Type: "integer", Description: "only trades after this ID"},
+			{Name: "sort", In: "query", Type: "string", Description: "ASC or DESC"},
+		}, Response: []secure.TradeHistoryData{}, handle: trades},
+		{Method: http.MethodGet, Path: "/v1/transfers", Summary: "Deposits and withdrawals", Permission: PermissionRead, Params: []Param{
+			{Name: "currency", In: "query", Type: "string", Description: "currency"},
+			{Name: "limit", In: "query", Type: "integer", Description: "number of transfers"},
+			{Name: "lastId", In: "query", Type: "integer", Description: "only transfers after this ID"},
+			{Name: "sort", In: "query", Type: "string", Description: "ASC or DESC"},
+		}, Response: []secure.TransferHistoryData{}, handle: transfers},
+
+		{Method: http.MethodPost, Path: "/v1/orders", Summary: "Place a limit or instant order", Permission: PermissionTrade, Body: true, Response: OrderResponse{}, handle: placeOrder},
+		{Method: http.MethodDelete, Path: "/v1/orders/{id}", Summary: "Cancel an order", Permission: PermissionTrade, Params: []Param{idParam}, Response: secure.CancelOrderWithInfoData{}, handle: cancelOrder},
+	}
+}
+
+// Public data
+
+func ticker(g *Gateway, r *http.Request) (interface{}, error) {
+	resp, err := (&public.Ticker{Client: g.Client}).GetTicker(pair(r))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error {
+		return nil, refused(resp.ErrorMessage)
+	}
+	return resp.Data, nil
+}
+
+func tickers(g *Gateway, r *http.Request) (interface{}, error) {
+	resp, err := (&public.TickerAll{Client: g.Client}).GetTickerAll()
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error {
+		return nil, refused(resp.ErrorMessage)
+	}
+	return resp.Data, nil
+}
+
+func orderBook(g *Gateway, r *http.Request) (interface{}, error) {
+	group, err := queryBool(r, "group")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := (&public.OrderBook{Client: g.Client}).GetOrderBook(pair(r), group)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error {
+		return nil, refused(resp.ErrorMessage)
+	}
+	return resp.Data, nil
+}
+
+func transactions(g *Gateway, r *http.Request) (interface{}, error) {
+	minutes, err := queryUint(r, "minutes", 10)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := (&public.Transactions{Client: g.Client}).GetTransactions(pair(r), minutes)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error {
+		return nil, refused(resp.ErrorMessage)
+	}
+	return resp.Data, nil
+}
+
+func tradingPairs(g *Gateway, r *http.Request) (interface{}, error) {
+	resp, err := (&public.TradingPairs{Client: g.Client}).GetTradingPairs()
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error {
+		return nil, refused(resp.ErrorMessage)
+	}
+	return resp.Data, nil
+}
+
+func currencies(g *Gateway, r *http.Request) (interface{}, error) {
+	resp, err := (&public.Currencies{Client: g.Client}).GetCurrencies()
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error {
+		return nil, refused(resp.ErrorMessage)
+	}
+	return resp.Data, nil
+}
+
+// Account data
+
+func balances(g *Gateway, r *http.Request) (interface{}, error) {
+	resp, err := (&secure.Balances{Client: g.Client}).GetBalances()
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error {
+		return nil, refused(resp.ErrorMessage)
+	}
+	return resp.Data, nil
+}
+
+func openOrders(g *Gateway, r *http.Request) (interface{}, error) {
+	resp, err := g.orders().GetOpenOrders(strings.ToUpper(r.URL.Query().Get("pair")))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error {
+		return nil, refused(resp.ErrorMessage)
+	}
+	return resp.Data, nil
+}
+
+func orderById(g *Gateway, r *http.Request) (interface{}, error) {
+	id, err := orderId(r)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.orders().GetOrderById(id)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error {
+		return nil, refused(resp.ErrorMessage)
+	}
+	if resp.Data == nil {
+		return nil, &Error{Status: http.StatusNotFound, Message: fmt.Sprintf("order %d not found", id)}
+	}
+	return resp.Data, nil
+}
+
+func orderHistory(g *Gateway, r *http.Request) (interface{}, error) {
+	limit, err := queryUint(r, "limit", 100)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.orders().GetHistory(pair(r), int64(limit))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error {
+		return nil, refused(resp.ErrorMessage)
+	}
+	return resp.Data, nil
+}
+
+func trades(g *Gateway, r *http.Request) (interface{}, error) {
+	limit, err := queryUint(r, "limit", 0)
+	if err != nil {
+		return nil, err
+	}
+	lastId, err := queryUint(r, "lastId", 0)
+	if err != nil {
+		return nil, err
+	}
+	q := r.URL.Query()
+	params := secure.TradeHistoryParams{Limit: int64(limit), LastId: lastId, Sort: q.Get("sort"), CurrencyPair: strings.ToUpper(q.Get("pair"))}
+	resp, err := (&secure.TradeHistory{Client: g.Client}).GetTradeHistory(params)
+	if err != nil {
+		return nil, invalidOr(err, params.Sort)
+	}
+	if resp.Error {
+		return nil, refused(resp.ErrorMessage)
+	}
+	return resp.Data, nil
+}
+
+func transfers(g *Gateway, r *http.Request) (interface{}, error) {
+	limit, err := queryUint(r, "limit", 0)
+	if err != nil {
+		return nil, err
+	}
+	lastId, err := queryUint(r, "lastId", 0)
+	if err != nil {
+		return nil, err
+	}
+	q := r.URL.Query()
+	params := secure.TransferHistoryParams{Limit: int64(limit), LastId: lastId, Sort: q.Get("sort"), Currency: strings.ToUpper(q.Get("currency"))}
+	resp, err := (&secure.TransferHistory{Client: g.Client}).GetTransferHistory(params)
+	if err != nil {
+		return nil, invalidOr(err, params.Sort)
+	}
+	if resp.Error {
+		return nil, refused(resp.ErrorMessage)
+	}
+	return resp.Data, nil
+}
+
+// Trading
+
+func placeOrder(g *Gateway, r *http.Request) (interface{}, error) {
+	var o OrderRequest
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&o); err != nil {
+		return nil, invalid("invalid order: %v", err)
+	}
+	o.Side, o.Type, o.CurrencyPair = strings.ToUpper(o.Side), strings.ToUpper(o.Type), strings.ToUpper(o.CurrencyPair)
+	if o.Type == "" {
+		o.Type = orderTypeLimit
+	}
+	if o.Side != buySide && o.Side != sellSide {
+		return nil, invalid("invalid side %q, expected BUY or SELL", o.Side)
+	}
+	if o.CurrencyPair == "" {
+		return nil, invalid("currencyPair must not be empty")
+	}
+
+	orders := g.orders()
+	switch o.Type {
+	case orderTypeLimit:
+		if o.Amount <= 0 || o.Price <= 0 {
+			return nil, invalid("limit orders need a positive amount and price")
+		}
+		var resp secure.SellLimit
+		var err error
+		if o.Side == buySide {
+			resp, err = orders.BuyLimit(o.Amount, o.Price, o.StopPrice, o.CurrencyPair, o.Hidden, o.ImmediateOrCancel, o.ClientOrderId)
+		} else {
+			resp, err = orders.SellLimit(o.Amount, o.Price, o.StopPrice, o.CurrencyPair, o.Hidden, o.ImmediateOrCancel, o.ClientOrderId)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error {
+			return nil, refused(resp.ErrorMessage)
+		}
+		return OrderResponse{OrderId: resp.OrderId}, nil
+
+	case orderTypeInstant:
+		var resp secure.BuyAndSellResponse
+		var err error
+		if o.Side == buySide {
+			if o.Total <= 0 {
+				return nil, invalid("instant buys need a positive total")
+			}
+			resp, err = orders.BuyInstant(o.Total, o.CurrencyPair, o.ClientOrderId)
+		} else {
+			if o.Amount <= 0 {
+				return nil, invalid("instant sells need a positive amount")
+			}
+			resp, err = orders.SellInstant(o.Amount, o.CurrencyPair, o.ClientOrderId)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error {
+			return nil, refused(resp.ErrorMessage)
+		}
+		return OrderResponse{OrderId: resp.OrderId}, nil
+	}
+	return nil, invalid("invalid type %q, expected LIMIT or INSTANT", o.Type)
+}
+
+func cancelOrder(g *Gateway, r *http.Request) (interface{}, error) {
+	id, err := orderId(r)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.orders().CancelOrderWithInfo(id)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error {
+		return nil, refused(resp.ErrorMessage)
+	}
+	return resp.Data, nil
+}
+
+// Helper functions
+
+func (g *Gateway) orders() *secure.Order {
+	return &secure.Order{Client: g.Client, Gate: g.Gate}
+}
+
+func pair(r *http.Request) string {
+	return strings.ToUpper(r.PathValue("pair"))
+}
+
+func orderId(r *http.Request) (uint64, error) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return 0, invalid("invalid order ID %q", r.PathValue("id"))
+	}
+	return id, nil
+}
+
+func queryUint(r *http.Request, name string, fallback uint64) (uint64, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return fallback, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, invalid("invalid %s %q", name, s)
+	}
+	return v, nil
+}
+
+func queryBool(r *http.Request, name string) (bool, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return false, nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, invalid("invalid %s %q", name, s)
+	}
+	return v, nil
+}
+
+func invalid(format string, args ...interface{}) error {
+	return &Error{Status: http.StatusBadRequest, Message: fmt.Sprintf(format, args...)}
+}
+
+// History requests fail before sending only for an invalid sort
+func invalidOr(err error, sort string) error {
+	if s := strings.ToUpper(sort); s != "" && s != "ASC" && s != "DESC" {
+		return invalid("invalid sort %q, expected ASC or DESC", sort)
+	}
+	return err
+}
+
+func refused(message string) error {
+	return &Error{Status: http.StatusUnprocessableEntity, Message: "coinmate: " + message}
+}
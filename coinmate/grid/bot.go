@@ -0,0 +1,358 @@
+package grid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	buySide  = "BUY"
+	sellSide = "SELL"
+
+	orderStatusFilled    = "FILLED"
+	orderStatusCancelled = "CANCELLED"
+	historyLimit         = 100
+)
+
+// Grid of a currency pair
+type Config struct {
+	// Lowest and highest level price
+	LowerPrice float64
+	UpperPrice float64
+	// Number of price levels including both bounds, at least 2
+	Levels int
+	// Base amount ordered at every level
+	Amount float64
+	// Fee rate deducted from realized profit, e.g. 0.004 for 0.4 %
+	FeeRate float64
+}
+
+// Price level of the grid
+type Level struct {
+	Index int     `json:"index"`
+	Price float64 `json:"price"`
+	// Side of the order resting at the level, empty for the free level
+	Side    string `json:"side,omitempty"`
+	OrderId uint64 `json:"orderId,omitempty"`
+	// Price of the fill the order replaces, zero for orders of the initial ladder
+	Entry float64 `json:"entry,omitempty"`
+}
+
+// Filled grid order
+type Fill struct {
+	Timestamp int64   `json:"timestamp"`
+	Level     int     `json:"level"`
+	Side      string  `json:"side"`
+	Price     float64 `json:"price"`
+	Amount    float64 `json:"amount"`
+	OrderId   uint64  `json:"orderId"`
+	// Profit of the round trip the fill completes, zero when it opens one
+	Profit float64 `json:"profit"`
+}
+
+// Realized grid profit
+type Report struct {
+	// Profit in quote currency after fees
+	Profit     float64 `json:"profit"`
+	RoundTrips int     `json:"roundTrips"`
+	Buys       int     `json:"buys"`
+	Sells      int     `json:"sells"`
+}
+
+// Bot keeps a ladder of buy orders below the price and sell orders above it.
+// A filled buy is replaced by a sell one level higher and a filled sell by a
+// buy one level lower, each such pair is a round trip earning the level
+// spacing. Prices and amounts are rounded to the decimals of the pair.
+//
+// Orders go through any secure.OrderInterface, e.g. a risk.Guard or a
+// paper.Trader.
+type Bot struct {
+	Orders secure.OrderInterface
+	Pair   public.TradingPairsData
+	Config Config
+
+	// Called for every filled grid order
+	OnFill func(Fill)
+
+	mu     sync.Mutex
+	levels []Level
+	amount float64
+	report Report
+	now    func() time.Time
+}
+
+// Return grid bot of pair, levels are rounded to its price decimals
+func NewBot(orders secure.OrderInterface, pair public.TradingPairsData, config Config) (*Bot, error) {
+	pair.Name = strings.ToUpper(pair.Name)
+	if pair.Name == "" {
+		return nil, fmt.Errorf("pair name must not be empty")
+	}
+	if config.Levels < 2 {
+		return nil, fmt.Errorf("levels must be at least 2")
+	}
+	if config.LowerPrice <= 0 || config.UpperPrice <= config.LowerPrice {
+		return nil, fmt.Errorf("price range must be positive and increasing")
+	}
+
+	amount := roundDown(config.Amount, pair.LotDecimals)
+	if amount <= 0 || amount < pair.MinAmount {
+		return nil, fmt.Errorf("amount %v below minimum %v of %s", config.Amount, pair.MinAmount, pair.Name)
+	}
+
+	step := (config.UpperPrice - config.LowerPrice) / float64(config.Levels-1)
+	levels := make([]Level, config.Levels)
+	for i := range levels {
+		levels[i] = Level{Index: i, Price: round(config.LowerPrice+float64(i)*step, pair.PriceDecimals)}
+		if i > 0 && levels[i].Price <= levels[i-1].Price {
+			return nil, fmt.Errorf("levels closer than price decimals of %s", pair.Name)
+		}
+	}
+
+	return &Bot{
+		Orders: orders,
+		Pair:   pair,
+		Config: config,
+		levels: levels,
+		amount: amount,
+		now:    time.Now,
+	}, nil
+}
+
+// Place the ladder around price: buys below, sells above, the level
+// closest to price stays free
+func (b *Bot) Start(price float64) error {
+	if price <= 0 {
+		return fmt.Errorf("price must be positive")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, l := range b.levels {
+		if l.Side != "" {
+			return fmt.Errorf("grid already started")
+		}
+	}
+
+	free := 0
+	for i, l := range b.levels {
+		if math.Abs(l.Price-price) < math.Abs(b.levels[free].Price-price) {
+			free = i
+		}
+	}
+	for i := range b.levels {
+		switch {
+		case i < free:
+			b.levels[i].Side = buySide
+		case i > free:
+			b.levels[i].Side = sellSide
+		}
+	}
+	return b.placePending()
+}
+
+// Detect filled orders, place their replacements and retry orders that
+// could not be placed before
+func (b *Bot) Poll() error {
+	b.mu.Lock()
+	fills, err := b.poll()
+	b.mu.Unlock()
+
+	if b.OnFill != nil {
+		for _, f := range fills {
+			b.OnFill(f)
+		}
+	}
+	return err
+}
+
+// Poll periodically until the context is done
+func (b *Bot) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.Poll(); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cancel all grid orders
+func (b *Bot) Stop() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var errs []error
+	for i := range b.levels {
+		l := &b.levels[i]
+		if l.OrderId != 0 {
+			r, err := b.Orders.CancelOrder(l.OrderId)
+			switch {
+			case err != nil:
+				errs = append(errs, fmt.Errorf("order %d: %w", l.OrderId, err))
+				continue
+			case r.Error:
+				errs = append(errs, fmt.Errorf("order %d: %s", l.OrderId, r.ErrorMessage))
+				continue
+			}
+		}
+		l.Side, l.OrderId, l.Entry = "", 0, 0
+	}
+	return errors.Join(errs...)
+}
+
+// Grid levels from the lowest price
+func (b *Bot) Levels() []Level {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Level(nil), b.levels...)
+}
+
+// Realized profit
+func (b *Bot) Report() Report {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.report
+}
+
+// Helper functions
+
+func (b *Bot) poll() ([]Fill, error) {
+	open, err := b.Orders.GetOpenOrders(b.Pair.Name)
+	if err != nil {
+		return nil, err
+	}
+	if open.Error {
+		return nil, fmt.Errorf("open orders failed: %s", open.ErrorMessage)
+	}
+	resting := make(map[uint64]bool, len(open.Data))
+	for _, o := range open.Data {
+		resting[o.Id] = true
+	}
+
+	var closed []int
+	for i, l := range b.levels {
+		if l.OrderId != 0 && !resting[l.OrderId] {
+			closed = append(closed, i)
+		}
+	}
+	if len(closed) == 0 {
+		return nil, b.placePending()
+	}
+
+	history, err := b.Orders.GetHistory(b.Pair.Name, historyLimit)
+	if err != nil {
+		return nil, err
+	}
+	if history.Error {
+		return nil, fmt.Errorf("order history failed: %s", history.ErrorMessage)
+	}
+	status := make(map[uint64]string, len(history.Data))
+	for _, o := range history.Data {
+		status[o.Id] = o.Status
+	}
+
+	var fills []Fill
+	var errs []error
+	for _, i := range closed {
+		l := &b.levels[i]
+		switch status[l.OrderId] {
+		case orderStatusFilled:
+			fills = append(fills, b.fill(i))
+		case orderStatusCancelled:
+			// Cancelled outside the bot, the level is placed again
+			l.OrderId = 0
+		default:
+			errs = append(errs, fmt.Errorf("order %d of level %d not found in history", l.OrderId, i))
+		}
+	}
+	// Levels are freed before replacing so that several levels filled at
+	// once move along the ladder
+	for _, f := range fills {
+		next, side := f.Level+1, sellSide
+		if f.Side == sellSide {
+			next, side = f.Level-1, buySide
+		}
+		if next >= 0 && next < len(b.levels) && b.levels[next].Side == "" {
+			b.levels[next].Side = side
+			b.levels[next].Entry = f.Price
+		}
+	}
+	errs = append(errs, b.placePending())
+	return fills, errors.Join(errs...)
+}
+
+// Record the fill of level i and free the level
+func (b *Bot) fill(i int) Fill {
+	l := b.levels[i]
+	f := Fill{Timestamp: b.now().UnixMilli(), Level: i, Side: l.Side, Price: l.Price, Amount: b.amount, OrderId: l.OrderId}
+	if l.Entry > 0 {
+		fees := (l.Entry + l.Price) * b.amount * b.Config.FeeRate
+		f.Profit = math.Abs(l.Price-l.Entry)*b.amount - fees
+		b.report.Profit += f.Profit
+		b.report.RoundTrips++
+	}
+	if l.Side == buySide {
+		b.report.Buys++
+	} else {
+		b.report.Sells++
+	}
+
+	b.levels[i].Side, b.levels[i].OrderId, b.levels[i].Entry = "", 0, 0
+	return f
+}
+
+// Place orders of levels with a side but no order
+func (b *Bot) placePending() error {
+	var errs []error
+	for i := range b.levels {
+		l := &b.levels[i]
+		if l.Side == "" || l.OrderId != 0 {
+			continue
+		}
+
+		var r secure.SellLimit
+		var err error
+		if l.Side == buySide {
+			r, err = b.Orders.BuyLimit(b.amount, l.Price, 0, b.Pair.Name, false, false, 0)
+		} else {
+			r, err = b.Orders.SellLimit(b.amount, l.Price, 0, b.Pair.Name, false, false, 0)
+		}
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("level %d: %w", i, err))
+		case r.Error:
+			errs = append(errs, fmt.Errorf("level %d: order rejected: %s", i, r.ErrorMessage))
+		default:
+			l.OrderId = r.OrderId
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func round(value float64, decimals uint64) float64 {
+	scale := math.Pow10(int(decimals))
+	return math.Round(value*scale) / scale
+}
+
+func roundDown(value float64, decimals uint64) float64 {
+	scale := math.Pow10(int(decimals))
+	// Tolerate binary representation errors, e.g. 0.29999999999999999
+	return math.Floor(value*scale+1e-9) / scale
+}
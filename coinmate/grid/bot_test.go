@@ -0,0 +1,258 @@
+package grid
+
+import (
+	"math"
+	"testing"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+// In-memory order book filled by trades of the test
+type fakeOrders struct {
+	orders []secure.OrderHistoryData
+	// Reject the next limit orders
+	reject int
+}
+
+func (f *fakeOrders) GetHistory(currencyPair string, limit int64) (secure.OrderHistoryResponse, error) {
+	return secure.OrderHistoryResponse{Data: append([]secure.OrderHistoryData(nil), f.orders...)}, nil
+}
+
+func (f *fakeOrders) GetOpenOrders(currencyPair string) (secure.OpenOrdersResponse, error) {
+	r := secure.OpenOrdersResponse{}
+	for _, o := range f.orders {
+		if o.Status == "OPEN" {
+			r.Data = append(r.Data, secure.OpenOrdersData{Id: o.Id, Type: o.Type, CurrencyPair: o.CurrencyPair, Price: o.Price, Amount: o.RemainingAmount})
+		}
+	}
+	return r, nil
+}
+
+func (f *fakeOrders) BuyLimit(amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (secure.SellLimit, error) {
+	return f.place(buySide, amount, price, currencyPair), nil
+}
+
+func (f *fakeOrders) SellLimit(amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (secure.SellLimit, error) {
+	return f.place(sellSide, amount, price, currencyPair), nil
+}
+
+func (f *fakeOrders) BuyInstant(total float64, cp string, clientOrderId uint64) (secure.BuyAndSellResponse, error) {
+	return secure.BuyAndSellResponse{Error: true, ErrorMessage: "not supported"}, nil
+}
+
+func (f *fakeOrders) SellInstant(total float64, cp string, clientOrderId uint64) (secure.BuyAndSellResponse, error) {
+	return secure.BuyAndSellResponse{Error: true, ErrorMessage: "not supported"}, nil
+}
+
+func (f *fakeOrders) CancelOrder(orderId uint64) (secure.CancelOrderResponse, error) {
+	for i := range f.orders {
+		if f.orders[i].Id == orderId && f.orders[i].Status == "OPEN" {
+			f.orders[i].Status = "CANCELLED"
+			return secure.CancelOrderResponse{Data: true}, nil
+		}
+	}
+	return secure.CancelOrderResponse{}, nil
+}
+
+func (f *fakeOrders) CancelOrderWithInfo(orderId uint64) (secure.CancelOrderWithInfoResponse, error) {
+	r, _ := f.CancelOrder(orderId)
+	return secure.CancelOrderWithInfoResponse{Data: secure.CancelOrderWithInfoData{Success: r.Data}}, nil
+}
+
+func (f *fakeOrders) place(side string, amount, price float64, pair string) secure.SellLimit {
+	if f.reject > 0 {
+		f.reject--
+		return secure.SellLimit{Error: true, ErrorMessage: "Insufficient balance"}
+	}
+	id := uint64(len(f.orders) + 1)
+	f.orders = append(f.orders, secure.OrderHistoryData{Id: id, Type: side, CurrencyPair: pair, Price: price, OriginalAmount: amount, RemainingAmount: amount, Status: "OPEN"})
+	return secure.SellLimit{OrderId: id}
+}
+
+// Fill open orders crossed by a trade at price
+func (f *fakeOrders) trade(price float64) {
+	for i := range f.orders {
+		o := &f.orders[i]
+		if o.Status == "OPEN" && ((o.Type == buySide && o.Price >= price) || (o.Type == sellSide && o.Price <= price)) {
+			o.Status, o.RemainingAmount = "FILLED", 0
+		}
+	}
+}
+
+func (f *fakeOrders) open() map[float64]string {
+	open := map[float64]string{}
+	for _, o := range f.orders {
+		if o.Status == "OPEN" {
+			open[o.Price] = o.Type
+		}
+	}
+	return open
+}
+
+var testPair = public.TradingPairsData{Name: "btc_czk", FirstCurrency: "BTC", SecondCurrency: "CZK", PriceDecimals: 0, LotDecimals: 4, MinAmount: 0.0002}
+
+func newTestBot(t *testing.T, orders *fakeOrders) *Bot {
+	t.Helper()
+	b, err := NewBot(orders, testPair, Config{LowerPrice: 1000000, UpperPrice: 1100000, Levels: 5, Amount: 0.123456789})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return b
+}
+
+func assertOpen(t *testing.T, orders *fakeOrders, expected map[float64]string) {
+	t.Helper()
+	open := orders.open()
+	if len(open) != len(expected) {
+		t.Fatalf("Expected open orders %v, got %v", expected, open)
+	}
+	for price, side := range expected {
+		if open[price] != side {
+			t.Errorf("Expected %s at %v, got %v", side, price, open)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	orders := &fakeOrders{}
+	b := newTestBot(t, orders)
+
+	if err := b.Start(1040000); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	assertOpen(t, orders, map[float64]string{1000000: buySide, 1025000: buySide, 1075000: sellSide, 1100000: sellSide})
+	for _, o := range orders.orders {
+		if o.OriginalAmount != 0.1234 || o.CurrencyPair != "BTC_CZK" {
+			t.Errorf("Expected amount rounded to lot decimals, got %+v", o)
+		}
+	}
+	if err := b.Start(1040000); err == nil {
+		t.Error("Expected error starting twice")
+	}
+}
+
+func TestRoundTrips(t *testing.T) {
+	orders := &fakeOrders{}
+	b := newTestBot(t, orders)
+	var fills []Fill
+	b.OnFill = func(f Fill) { fills = append(fills, f) }
+	b.Start(1040000)
+
+	// Buy at 1025000 is replaced by a sell one level higher
+	orders.trade(1025000)
+	b.Poll()
+	assertOpen(t, orders, map[float64]string{1000000: buySide, 1050000: sellSide, 1075000: sellSide, 1100000: sellSide})
+
+	// Selling it completes a round trip
+	orders.trade(1050000)
+	b.Poll()
+	assertOpen(t, orders, map[float64]string{1000000: buySide, 1025000: buySide, 1075000: sellSide, 1100000: sellSide})
+	if len(fills) != 2 || fills[0].Profit != 0 || math.Abs(fills[1].Profit-25000*0.1234) > 1e-6 {
+		t.Errorf("Expected profit of one level spacing, got %+v", fills)
+	}
+
+	// Two levels filled at once move along the ladder, buying back at
+	// 1025000 completes the second round trip
+	orders.trade(1000000)
+	b.Poll()
+	assertOpen(t, orders, map[float64]string{1025000: sellSide, 1050000: sellSide, 1075000: sellSide, 1100000: sellSide})
+
+	orders.trade(1060000)
+	b.Poll()
+	report := b.Report()
+	if report.RoundTrips != 4 || report.Buys != 3 || report.Sells != 3 || math.Abs(report.Profit-4*25000*0.1234) > 1e-6 {
+		t.Errorf("Expected 4 round trips, got %+v", report)
+	}
+}
+
+func TestFees(t *testing.T) {
+	orders := &fakeOrders{}
+	b, _ := NewBot(orders, testPair, Config{LowerPrice: 1000000, UpperPrice: 1100000, Levels: 5, Amount: 0.1, FeeRate: 0.004})
+	b.Start(1040000)
+
+	orders.trade(1025000)
+	b.Poll()
+	orders.trade(1050000)
+	b.Poll()
+
+	expected := 25000*0.1 - (1025000+1050000)*0.1*0.004
+	if profit := b.Report().Profit; math.Abs(profit-expected) > 1e-6 {
+		t.Errorf("Expected profit %v after fees, got %v", expected, profit)
+	}
+}
+
+func TestRejectedLevelRetried(t *testing.T) {
+	orders := &fakeOrders{reject: 1}
+	b := newTestBot(t, orders)
+
+	if err := b.Start(1040000); err == nil {
+		t.Error("Expected error for rejected level")
+	}
+	if len(orders.open()) != 3 {
+		t.Fatalf("Expected 3 of 4 levels placed, got %v", orders.open())
+	}
+	if err := b.Poll(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	assertOpen(t, orders, map[float64]string{1000000: buySide, 1025000: buySide, 1075000: sellSide, 1100000: sellSide})
+}
+
+func TestStop(t *testing.T) {
+	orders := &fakeOrders{}
+	b := newTestBot(t, orders)
+	b.Start(1040000)
+
+	if err := b.Stop(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(orders.open()) != 0 {
+		t.Errorf("Expected all grid orders cancelled, got %v", orders.open())
+	}
+	for _, l := range b.Levels() {
+		if l.Side != "" || l.OrderId != 0 {
+			t.Errorf("Expected free level, got %+v", l)
+		}
+	}
+}
+
+func TestPairDecimals(t *testing.T) {
+	if _, err := NewBot(&fakeOrders{}, testPair, Config{LowerPrice: 1000, UpperPrice: 1002, Levels: 10, Amount: 0.1}); err == nil {
+		t.Error("Expected error for levels closer than price decimals")
+	}
+	if _, err := NewBot(&fakeOrders{}, testPair, Config{LowerPrice: 1000, UpperPrice: 2000, Levels: 3, Amount: 0.00019}); err == nil {
+		t.Error("Expected error for amount below pair minimum")
+	}
+
+	b, _ := NewBot(&fakeOrders{}, testPair, Config{LowerPrice: 1000, UpperPrice: 2000, Levels: 4, Amount: 0.1})
+	levels := b.Levels()
+	if levels[1].Price != 1333 || levels[2].Price != 1667 {
+		t.Errorf("Expected prices rounded to whole CZK, got %+v", levels)
+	}
+}
+
+func TestSubPennyLevels(t *testing.T) {
+	xrpEur := public.TradingPairsData{Name: "XRP_EUR", FirstCurrency: "XRP", SecondCurrency: "EUR", PriceDecimals: 5, LotDecimals: 2, MinAmount: 1}
+	server := coinmatetest.NewFundedServer(t, xrpEur)
+	server.Deposit(coinmatetest.FundedClientId, "XRP", 100)
+	orders := &secure.Order{Client: server.NewClient(coinmatetest.FundedClientId)}
+
+	b, err := NewBot(orders, xrpEur, Config{LowerPrice: 0.5, UpperPrice: 0.50004, Levels: 5, Amount: 10})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := b.Start(0.50002); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	r, err := orders.GetOpenOrders("XRP_EUR")
+	if err != nil || len(r.Data) != 4 {
+		t.Fatalf("Expected 4 open orders, got %+v (%v)", r.Data, err)
+	}
+	expected := map[float64]string{0.5: buySide, 0.50001: buySide, 0.50003: sellSide, 0.50004: sellSide}
+	for _, o := range r.Data {
+		if expected[o.Price] != o.Type {
+			t.Errorf("Expected levels at 5 price decimals %v, got %+v", expected, r.Data)
+		}
+	}
+}
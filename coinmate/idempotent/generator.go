@@ -0,0 +1,49 @@
+package idempotent
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const (
+	// Low bits of an ID filled with a random value
+	sequenceBits = 22
+	sequenceMask = 1<<sequenceBits - 1
+)
+
+// Start of the ID timestamps, 41 bits of milliseconds last until 2093
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Generator of client order IDs. An ID holds the milliseconds since 2024 in
+// the high bits and a random sequence in the low 22 bits, so processes
+// generating IDs in the same millisecond collide with a chance of one in four
+// million. IDs of one generator are strictly increasing and fit into int64.
+type Generator struct {
+	mu   sync.Mutex
+	last uint64
+	now  func() time.Time
+}
+
+// Return generator using the system clock
+func NewGenerator() *Generator {
+	return &Generator{now: time.Now}
+}
+
+// Next client order ID
+func (g *Generator) Next() uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	millis := g.now().Sub(epoch).Milliseconds()
+	if millis < 0 {
+		millis = 0
+	}
+	id := uint64(millis)<<sequenceBits | rand.Uint64N(sequenceMask+1)
+	// Clock going backwards or many IDs within one millisecond
+	if id <= g.last {
+		id = g.last + 1
+	}
+	g.last = id
+	return id
+}
@@ -0,0 +1,45 @@
+package idempotent
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGeneratorIsUniqueAndIncreasing(t *testing.T) {
+	g := NewGenerator()
+	seen := make(map[uint64]bool)
+	var last uint64
+	for i := 0; i < 100000; i++ {
+		id := g.Next()
+		if seen[id] || id <= last {
+			t.Fatalf("Expected unique increasing IDs, got %d after %d", id, last)
+		}
+		if id > math.MaxInt64 {
+			t.Fatalf("Expected ID to fit into int64, got %d", id)
+		}
+		seen[id] = true
+		last = id
+	}
+}
+
+func TestGeneratorClockGoingBackwards(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	g := &Generator{now: func() time.Time { return now }}
+
+	first := g.Next()
+	now = now.Add(-time.Hour)
+	if second := g.Next(); second <= first {
+		t.Errorf("Expected IDs to keep increasing, got %d after %d", second, first)
+	}
+}
+
+func TestGeneratorEncodesTime(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	g := &Generator{now: func() time.Time { return now }}
+
+	millis := int64(g.Next() >> sequenceBits)
+	if millis != now.Sub(epoch).Milliseconds() {
+		t.Errorf("Expected milliseconds since epoch in high bits, got %d", millis)
+	}
+}
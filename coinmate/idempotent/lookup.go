@@ -0,0 +1,40 @@
+package idempotent
+
+import (
+	"fmt"
+	"tourGo/coinmate/secure"
+)
+
+// Oldest order placed with clientOrderId, looked up by lookup or searched in
+// the last historyLimit orders of currencyPair when lookup is nil. Used to
+// settle a send that failed without a definite answer.
+func FindOrder(orders secure.OrderInterface, lookup secure.OrderLookupInterface, clientOrderId uint64, currencyPair string, historyLimit int64) (secure.OrderHistoryData, bool, error) {
+	var history []secure.OrderHistoryData
+	if lookup != nil {
+		r, err := lookup.GetOrderByClientOrderId(clientOrderId)
+		if err != nil {
+			return secure.OrderHistoryData{}, false, err
+		}
+		if r.Error {
+			return secure.OrderHistoryData{}, false, fmt.Errorf("order lookup failed: %s", r.ErrorMessage)
+		}
+		history = r.Data
+	} else {
+		r, err := orders.GetHistory(currencyPair, historyLimit)
+		if err != nil {
+			return secure.OrderHistoryData{}, false, err
+		}
+		if r.Error {
+			return secure.OrderHistoryData{}, false, fmt.Errorf("order history failed: %s", r.ErrorMessage)
+		}
+		history = r.Data
+	}
+
+	var found secure.OrderHistoryData
+	for _, o := range history {
+		if o.ClientOrderId == clientOrderId && (found.Id == 0 || o.Id < found.Id) {
+			found = o
+		}
+	}
+	return found, found.Id != 0, nil
+}
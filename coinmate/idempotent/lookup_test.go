@@ -0,0 +1,23 @@
+package idempotent
+
+import (
+	"testing"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/secure"
+)
+
+func TestFindOrder(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	orders := &secure.Order{Client: server.NewClient("1")}
+	placed, _ := orders.BuyLimit(0.01, 40000, 0, "BTC_EUR", false, false, 42)
+
+	for _, lookup := range []secure.OrderLookupInterface{orders, nil} {
+		o, found, err := FindOrder(orders, lookup, 42, "BTC_EUR", 10)
+		if err != nil || !found || o.Id != placed.OrderId || o.Price != 40000 {
+			t.Errorf("Expected order %d, got %+v, %v (%v)", placed.OrderId, o, found, err)
+		}
+		if _, found, err := FindOrder(orders, lookup, 43, "BTC_EUR", 10); err != nil || found {
+			t.Errorf("Expected no order for unknown client order ID, got %v (%v)", found, err)
+		}
+	}
+}
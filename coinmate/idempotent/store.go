@@ -0,0 +1,17 @@
+package idempotent
+
+import "tourGo/coinmate/filestore"
+
+// Persistence of intents, used to avoid placing an order twice after a restart
+type Store interface {
+	Load() ([]Intent, error)
+	Save(intents []Intent) error
+}
+
+// Store keeping all intents in one JSON file, see filestore.JSONFileStore
+type FileStore = filestore.JSONFileStore[Intent]
+
+// Return file store at path, the directory is created on first save
+func NewFileStore(path string) *FileStore {
+	return filestore.New[Intent](path, "intent")
+}
@@ -0,0 +1,428 @@
+package idempotent
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	buySide         = "BUY"
+	sellSide        = "SELL"
+	limitOrderType  = "LIMIT"
+	marketOrderType = "MARKET"
+
+	defaultMaxAttempts = 3
+	// Longer than the client timeout, see Submitter
+	defaultRetryDelay   = coinmate.RequestTimeout + time.Second
+	defaultHistoryLimit = 100
+
+	epsilon = 1e-9
+)
+
+// Submission status of an intent
+type Status string
+
+const (
+	// Recorded, never sent
+	StatusNew Status = "NEW"
+	// Sent without a definite answer, the order may exist
+	StatusPending Status = "PENDING"
+	// Order exists on the exchange
+	StatusPlaced Status = "PLACED"
+	// Exchange refused the order
+	StatusRejected Status = "REJECTED"
+	// Lookup confirmed that no order exists, safe to send again
+	StatusNotPlaced Status = "NOT_PLACED"
+)
+
+var (
+	ErrAmbiguous      = errors.New("order placement outcome unknown")
+	ErrIntentMismatch = errors.New("intent key already used for a different order")
+	ErrInProgress     = errors.New("intent is being submitted")
+)
+
+// Order the caller wants placed exactly once, identified by its key
+type Intent struct {
+	Key               string  `json:"key"`
+	ClientOrderId     uint64  `json:"clientOrderId"`
+	CurrencyPair      string  `json:"currencyPair"`
+	Side              string  `json:"side"`
+	OrderType         string  `json:"orderType"`
+	Amount            float64 `json:"amount,omitempty"`
+	Total             float64 `json:"total,omitempty"`
+	Price             float64 `json:"price,omitempty"`
+	StopPrice         float64 `json:"stopPrice,omitempty"`
+	Hidden            bool    `json:"hidden,omitempty"`
+	ImmediateOrCancel bool    `json:"immediateOrCancel,omitempty"`
+	Status            Status  `json:"status"`
+	OrderId           uint64  `json:"orderId,omitempty"`
+	Attempts          int     `json:"attempts"`
+	Error             string  `json:"error,omitempty"`
+	CreatedAt         int64   `json:"createdAt"`
+	UpdatedAt         int64   `json:"updatedAt"`
+}
+
+// Submitter placing each intent at most once. Every intent gets a generated
+// client order ID, which is persisted before the order is sent. When a
+// request fails without a definite answer the order is looked up by its
+// client order ID, and sent again only when it does not exist.
+//
+// A request still travelling to the exchange is not visible to the lookup,
+// RetryDelay should therefore exceed the HTTP client timeout.
+type Submitter struct {
+	Orders secure.OrderInterface
+	// Lookup by client order ID, recent order history is searched when nil
+	Lookup    secure.OrderLookupInterface
+	Store     Store
+	Generator *Generator
+	// Sends per call, defaults to 3
+	MaxAttempts int
+	// Wait before looking up an order after a failed send, defaults to 3s
+	RetryDelay time.Duration
+	// Orders searched by the history lookup, defaults to 100
+	HistoryLimit int64
+
+	mu      sync.Mutex
+	intents map[string]*Intent
+	busy    map[string]bool
+	now     func() time.Time
+	sleep   func(time.Duration)
+}
+
+// Return submitter with intents loaded from store, which may be nil. Orders
+// implementing secure.OrderLookupInterface are also used for lookups.
+func NewSubmitter(orders secure.OrderInterface, store Store) (*Submitter, error) {
+	s := &Submitter{
+		Orders:    orders,
+		Store:     store,
+		Generator: NewGenerator(),
+		intents:   make(map[string]*Intent),
+		busy:      make(map[string]bool),
+		now:       time.Now,
+		sleep:     time.Sleep,
+	}
+	if lookup, ok := orders.(secure.OrderLookupInterface); ok {
+		s.Lookup = lookup
+	}
+	if store == nil {
+		return s, nil
+	}
+
+	intents, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range intents {
+		intent := intents[i]
+		s.intents[intent.Key] = &intent
+	}
+	return s, nil
+}
+
+// Place buy limit order once per key, an empty key creates a new intent
+func (s *Submitter) BuyLimit(key string, amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool) (Intent, error) {
+	intent := limitIntent(key, buySide, amount, price, stopPrice, currencyPair, hidden, immediateOrCancel)
+	return s.submit(intent, func(clientOrderId uint64) (uint64, bool, string, error) {
+		r, err := s.Orders.BuyLimit(amount, price, stopPrice, currencyPair, hidden, immediateOrCancel, clientOrderId)
+		return r.OrderId, r.Error, r.ErrorMessage, err
+	})
+}
+
+// Place sell limit order once per key, an empty key creates a new intent
+func (s *Submitter) SellLimit(key string, amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool) (Intent, error) {
+	intent := limitIntent(key, sellSide, amount, price, stopPrice, currencyPair, hidden, immediateOrCancel)
+	return s.submit(intent, func(clientOrderId uint64) (uint64, bool, string, error) {
+		r, err := s.Orders.SellLimit(amount, price, stopPrice, currencyPair, hidden, immediateOrCancel, clientOrderId)
+		return r.OrderId, r.Error, r.ErrorMessage, err
+	})
+}
+
+// Buy instantly for a total in the quote currency once per key
+func (s *Submitter) BuyInstant(key string, total float64, currencyPair string) (Intent, error) {
+	intent := Intent{Key: key, Side: buySide, OrderType: marketOrderType, CurrencyPair: strings.ToUpper(currencyPair), Total: total}
+	return s.submit(intent, func(clientOrderId uint64) (uint64, bool, string, error) {
+		r, err := s.Orders.BuyInstant(total, currencyPair, clientOrderId)
+		return r.OrderId, r.Error, r.ErrorMessage, err
+	})
+}
+
+// Sell instantly an amount in the base currency once per key
+func (s *Submitter) SellInstant(key string, amount float64, currencyPair string) (Intent, error) {
+	intent := Intent{Key: key, Side: sellSide, OrderType: marketOrderType, CurrencyPair: strings.ToUpper(currencyPair), Amount: amount}
+	return s.submit(intent, func(clientOrderId uint64) (uint64, bool, string, error) {
+		r, err := s.Orders.SellInstant(amount, currencyPair, clientOrderId)
+		return r.OrderId, r.Error, r.ErrorMessage, err
+	})
+}
+
+// Intent by key
+func (s *Submitter) Intent(key string) (Intent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	intent, ok := s.intents[key]
+	if !ok {
+		return Intent{}, false
+	}
+	return *intent, true
+}
+
+// All intents, oldest first
+func (s *Submitter) Intents() []Intent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sorted()
+}
+
+// Look up intents left pending, e.g. by a crash or an earlier ambiguous
+// failure. Intents whose order is not found become safe to send again.
+func (s *Submitter) Resolve() error {
+	s.mu.Lock()
+	var pending []*Intent
+	for _, intent := range s.sorted() {
+		if intent.Status == StatusPending && !s.busy[intent.Key] {
+			s.busy[intent.Key] = true
+			pending = append(pending, s.intents[intent.Key])
+		}
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for _, intent := range pending {
+		if err := s.resolve(intent); err != nil {
+			errs = append(errs, fmt.Errorf("intent %s: %w", intent.Key, err))
+		}
+		s.mu.Lock()
+		delete(s.busy, intent.Key)
+		s.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// Remove settled intents last updated before t, returns the number removed.
+// A removed key may be used again for a new order.
+func (s *Submitter) Prune(t time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for key, intent := range s.intents {
+		settled := intent.Status == StatusPlaced || intent.Status == StatusRejected || intent.Status == StatusNotPlaced
+		if settled && !s.busy[key] && intent.UpdatedAt < t.UnixMilli() {
+			delete(s.intents, key)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, s.save()
+}
+
+// Helper functions
+
+func limitIntent(key, side string, amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool) Intent {
+	return Intent{
+		Key:               key,
+		CurrencyPair:      strings.ToUpper(currencyPair),
+		Side:              side,
+		OrderType:         limitOrderType,
+		Amount:            amount,
+		Price:             price,
+		StopPrice:         stopPrice,
+		Hidden:            hidden,
+		ImmediateOrCancel: immediateOrCancel,
+	}
+}
+
+func (s *Submitter) submit(request Intent, send func(clientOrderId uint64) (uint64, bool, string, error)) (Intent, error) {
+	s.mu.Lock()
+	if request.Key == "" {
+		request.ClientOrderId = s.Generator.Next()
+		request.Key = strconv.FormatUint(request.ClientOrderId, 10)
+	}
+	if s.busy[request.Key] {
+		s.mu.Unlock()
+		return Intent{}, fmt.Errorf("%w: %s", ErrInProgress, request.Key)
+	}
+
+	intent, ok := s.intents[request.Key]
+	if ok && !intent.sameOrder(request) {
+		existing := *intent
+		s.mu.Unlock()
+		return existing, fmt.Errorf("%w: %s", ErrIntentMismatch, request.Key)
+	}
+	if !ok {
+		if request.ClientOrderId == 0 {
+			request.ClientOrderId = s.Generator.Next()
+		}
+		request.Status = StatusNew
+		request.CreatedAt = s.now().UnixMilli()
+		request.UpdatedAt = request.CreatedAt
+		intent = &request
+		s.intents[request.Key] = intent
+
+		if err := s.save(); err != nil {
+			delete(s.intents, request.Key)
+			s.mu.Unlock()
+			return Intent{}, err
+		}
+	}
+	s.busy[intent.Key] = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.busy, intent.Key)
+		s.mu.Unlock()
+	}()
+
+	for attempt := 1; ; attempt++ {
+		// A previous send may have reached the exchange
+		if s.snapshot(intent).Status == StatusPending {
+			if err := s.resolve(intent); err != nil {
+				return s.snapshot(intent), fmt.Errorf("%w: %v", ErrAmbiguous, err)
+			}
+		}
+		if current := s.snapshot(intent); current.Status == StatusPlaced || current.Status == StatusRejected {
+			return current, nil
+		}
+
+		// Never send an order that could not be looked up after a crash
+		if err := s.update(intent, func(i *Intent) {
+			i.Status = StatusPending
+			i.Attempts++
+		}); err != nil {
+			return s.snapshot(intent), err
+		}
+
+		orderId, rejected, message, sendErr := send(intent.ClientOrderId)
+		switch {
+		case sendErr == nil && rejected:
+			err := s.update(intent, func(i *Intent) {
+				i.Status = StatusRejected
+				i.Error = message
+			})
+			return s.snapshot(intent), err
+		case sendErr == nil:
+			err := s.update(intent, func(i *Intent) {
+				i.Status = StatusPlaced
+				i.OrderId = orderId
+				i.Error = ""
+			})
+			return s.snapshot(intent), err
+		case errors.Is(sendErr, secure.ErrNotSent):
+			s.update(intent, func(i *Intent) {
+				i.Status = StatusNotPlaced
+				i.Error = sendErr.Error()
+			})
+			return s.snapshot(intent), sendErr
+		}
+
+		s.update(intent, func(i *Intent) { i.Error = sendErr.Error() })
+		if attempt >= s.maxAttempts() {
+			return s.snapshot(intent), fmt.Errorf("%w: %v", ErrAmbiguous, sendErr)
+		}
+		s.sleep(s.retryDelay())
+	}
+}
+
+// Look up a pending intent and record the outcome
+func (s *Submitter) resolve(intent *Intent) error {
+	orderId, found, err := s.find(intent.ClientOrderId, intent.CurrencyPair)
+	if err != nil {
+		return err
+	}
+	return s.update(intent, func(i *Intent) {
+		if found {
+			i.Status = StatusPlaced
+			i.OrderId = orderId
+			i.Error = ""
+		} else {
+			i.Status = StatusNotPlaced
+		}
+	})
+}
+
+// Oldest order placed with client order ID
+func (s *Submitter) find(clientOrderId uint64, currencyPair string) (uint64, bool, error) {
+	o, found, err := FindOrder(s.Orders, s.Lookup, clientOrderId, currencyPair, s.historyLimit())
+	return o.Id, found, err
+}
+
+func (s *Submitter) update(intent *Intent, change func(*Intent)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change(intent)
+	intent.UpdatedAt = s.now().UnixMilli()
+	return s.save()
+}
+
+func (s *Submitter) snapshot(intent *Intent) Intent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return *intent
+}
+
+func (s *Submitter) save() error {
+	if s.Store == nil {
+		return nil
+	}
+	return s.Store.Save(s.sorted())
+}
+
+func (s *Submitter) sorted() []Intent {
+	intents := make([]Intent, 0, len(s.intents))
+	for _, intent := range s.intents {
+		intents = append(intents, *intent)
+	}
+	sort.Slice(intents, func(i, j int) bool {
+		if intents[i].CreatedAt != intents[j].CreatedAt {
+			return intents[i].CreatedAt < intents[j].CreatedAt
+		}
+		return intents[i].Key < intents[j].Key
+	})
+	return intents
+}
+
+func (i *Intent) sameOrder(o Intent) bool {
+	return i.CurrencyPair == o.CurrencyPair && i.Side == o.Side && i.OrderType == o.OrderType &&
+		sameValue(i.Amount, o.Amount) && sameValue(i.Total, o.Total) && sameValue(i.Price, o.Price) &&
+		sameValue(i.StopPrice, o.StopPrice) && i.Hidden == o.Hidden && i.ImmediateOrCancel == o.ImmediateOrCancel
+}
+
+func sameValue(a, b float64) bool {
+	return math.Abs(a-b) < epsilon
+}
+
+func (s *Submitter) maxAttempts() int {
+	if s.MaxAttempts > 0 {
+		return s.MaxAttempts
+	}
+	return defaultMaxAttempts
+}
+
+func (s *Submitter) retryDelay() time.Duration {
+	if s.RetryDelay > 0 {
+		return s.RetryDelay
+	}
+	return defaultRetryDelay
+}
+
+func (s *Submitter) historyLimit() int64 {
+	if s.HistoryLimit > 0 {
+		return s.HistoryLimit
+	}
+	return defaultHistoryLimit
+}
@@ -0,0 +1,276 @@
+package idempotent
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+	"tourGo/coinmate"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/secure"
+)
+
+func newTestSubmitter(t *testing.T, orders secure.OrderInterface, store Store) *Submitter {
+	t.Helper()
+	s, err := NewSubmitter(orders, store)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	s.sleep = func(time.Duration) {}
+	return s
+}
+
+func openOrders(t *testing.T, orders secure.OrderInterface) []secure.OpenOrdersData {
+	t.Helper()
+	r, err := orders.GetOpenOrders("BTC_EUR")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return r.Data
+}
+
+func TestSubmitOnce(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	orders := &secure.Order{Client: server.NewClient("1")}
+	s := newTestSubmitter(t, orders, nil)
+
+	intent, err := s.BuyLimit("rebalance-1", 0.1, 40000, 0, "BTC_EUR", false, false)
+	if err != nil || intent.Status != StatusPlaced || intent.OrderId == 0 || intent.ClientOrderId == 0 {
+		t.Fatalf("Expected placed intent, got %+v (%v)", intent, err)
+	}
+
+	again, err := s.BuyLimit("rebalance-1", 0.1, 40000, 0, "BTC_EUR", false, false)
+	if err != nil || again.OrderId != intent.OrderId {
+		t.Errorf("Expected the same order for the same key, got %+v (%v)", again, err)
+	}
+	open := openOrders(t, orders)
+	if len(open) != 1 || open[0].ClientOrderId != intent.ClientOrderId {
+		t.Errorf("Expected one order with the generated client order ID, got %+v", open)
+	}
+
+	if _, err := s.BuyLimit("rebalance-1", 0.2, 40000, 0, "BTC_EUR", false, false); !errors.Is(err, ErrIntentMismatch) {
+		t.Errorf("Expected intent mismatch, got %v", err)
+	}
+}
+
+func TestEmptyKeyCreatesNewIntent(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	orders := &secure.Order{Client: server.NewClient("1")}
+	s := newTestSubmitter(t, orders, nil)
+
+	first, _ := s.SellLimit("", 0.1, 60000, 0, "BTC_EUR", false, false)
+	second, _ := s.SellLimit("", 0.1, 60000, 0, "BTC_EUR", false, false)
+	if first.Key == second.Key || first.ClientOrderId == second.ClientOrderId {
+		t.Errorf("Expected distinct intents, got %+v and %+v", first, second)
+	}
+	if len(openOrders(t, orders)) != 2 {
+		t.Error("Expected two orders")
+	}
+}
+
+func TestLostResponseIsNotSentAgain(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	orders := &secure.Order{Client: server.NewClient("1")}
+	s := newTestSubmitter(t, orders, nil)
+
+	server.InjectFault("/buyLimit", coinmatetest.Fault{StatusCode: http.StatusGatewayTimeout, AfterHandling: true, Times: 1})
+	intent, err := s.BuyLimit("k", 0.1, 40000, 0, "BTC_EUR", false, false)
+	if err != nil || intent.Status != StatusPlaced || intent.Attempts != 1 {
+		t.Fatalf("Expected order found by lookup, got %+v (%v)", intent, err)
+	}
+	if open := openOrders(t, orders); len(open) != 1 || open[0].Id != intent.OrderId {
+		t.Errorf("Expected exactly one order, got %+v", open)
+	}
+}
+
+func TestLostRequestIsRetried(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	orders := &secure.Order{Client: server.NewClient("1")}
+	s := newTestSubmitter(t, orders, nil)
+
+	server.InjectFault("/sellInstant", coinmatetest.Fault{StatusCode: http.StatusServiceUnavailable, Times: 1})
+	server.AddLiquidity("BTC_EUR", "BUY", 50000, 1)
+	intent, err := s.SellInstant("k", 0.1, "BTC_EUR")
+	if err != nil || intent.Status != StatusPlaced || intent.Attempts != 2 {
+		t.Fatalf("Expected order placed on the second attempt, got %+v (%v)", intent, err)
+	}
+
+	history, _ := orders.GetHistory("BTC_EUR", 10)
+	if len(history.Data) != 1 || history.Data[0].ClientOrderId != intent.ClientOrderId {
+		t.Errorf("Expected exactly one order, got %+v", history.Data)
+	}
+}
+
+func TestFailedLookupIsAmbiguous(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	orders := &secure.Order{Client: server.NewClient("1")}
+	s := newTestSubmitter(t, orders, nil)
+
+	server.InjectFault("/buyLimit", coinmatetest.Fault{StatusCode: http.StatusGatewayTimeout, AfterHandling: true, Times: 1})
+	server.InjectFault("/order", coinmatetest.Fault{StatusCode: http.StatusServiceUnavailable, Times: 1})
+	intent, err := s.BuyLimit("k", 0.1, 40000, 0, "BTC_EUR", false, false)
+	if !errors.Is(err, ErrAmbiguous) || intent.Status != StatusPending {
+		t.Fatalf("Expected ambiguous pending intent, got %+v (%v)", intent, err)
+	}
+
+	intent, err = s.BuyLimit("k", 0.1, 40000, 0, "BTC_EUR", false, false)
+	if err != nil || intent.Status != StatusPlaced {
+		t.Fatalf("Expected order found on the next call, got %+v (%v)", intent, err)
+	}
+	if len(openOrders(t, orders)) != 1 {
+		t.Error("Expected exactly one order")
+	}
+}
+
+func TestAttemptsExhausted(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	orders := &secure.Order{Client: server.NewClient("1")}
+	s := newTestSubmitter(t, orders, nil)
+	s.MaxAttempts = 2
+
+	server.InjectFault("/buyLimit", coinmatetest.Fault{StatusCode: http.StatusServiceUnavailable})
+	intent, err := s.BuyLimit("k", 0.1, 40000, 0, "BTC_EUR", false, false)
+	if !errors.Is(err, ErrAmbiguous) || intent.Attempts != 2 || intent.Error == "" {
+		t.Errorf("Expected two failed attempts, got %+v (%v)", intent, err)
+	}
+
+	server.ClearFaults()
+	if err := s.Resolve(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if intent, _ := s.Intent("k"); intent.Status != StatusNotPlaced {
+		t.Errorf("Expected intent confirmed not placed, got %s", intent.Status)
+	}
+}
+
+func TestRejectedIntentIsNotSentAgain(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	orders := &secure.Order{Client: server.NewClient("1")}
+	s := newTestSubmitter(t, orders, nil)
+
+	intent, err := s.BuyInstant("k", 20000, "BTC_EUR")
+	if err != nil || intent.Status != StatusRejected || intent.Error == "" {
+		t.Fatalf("Expected rejected intent, got %+v (%v)", intent, err)
+	}
+
+	server.Deposit("1", "EUR", 20000)
+	if again, _ := s.BuyInstant("k", 20000, "BTC_EUR"); again.Status != StatusRejected || again.Attempts != 1 {
+		t.Errorf("Expected rejection to be final, got %+v", again)
+	}
+}
+
+func TestOrderNotSent(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	halted := true
+	orders := &secure.Order{Client: server.NewClient("1"), Gate: gateFunc(func() error {
+		if halted {
+			return errors.New("halted")
+		}
+		return nil
+	})}
+	s := newTestSubmitter(t, orders, nil)
+
+	intent, err := s.BuyLimit("k", 0.1, 40000, 0, "BTC_EUR", false, false)
+	if !errors.Is(err, secure.ErrNotSent) || intent.Status != StatusNotPlaced || intent.Attempts != 1 {
+		t.Fatalf("Expected intent not placed without retries, got %+v (%v)", intent, err)
+	}
+
+	halted = false
+	if intent, err := s.BuyLimit("k", 0.1, 40000, 0, "BTC_EUR", false, false); err != nil || intent.Status != StatusPlaced {
+		t.Errorf("Expected intent placed once allowed, got %+v (%v)", intent, err)
+	}
+}
+
+type gateFunc func() error
+
+func (f gateFunc) Allow() error {
+	return f()
+}
+
+func TestRecoveryAfterRestart(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	orders := &secure.Order{Client: server.NewClient("1")}
+	store := NewFileStore(filepath.Join(t.TempDir(), "intents.json"))
+
+	first := newTestSubmitter(t, orders, store)
+	first.MaxAttempts = 1
+	server.InjectFault("/sellLimit", coinmatetest.Fault{StatusCode: http.StatusGatewayTimeout, AfterHandling: true, Times: 1})
+	lost, _ := first.SellLimit("k", 0.2, 60000, 0, "BTC_EUR", false, false)
+
+	second := newTestSubmitter(t, orders, store)
+	if intents := second.Intents(); len(intents) != 1 || intents[0].Status != StatusPending {
+		t.Fatalf("Expected pending intent after restart, got %+v", intents)
+	}
+	if err := second.Resolve(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	intent, _ := second.Intent("k")
+	if intent.Status != StatusPlaced || intent.ClientOrderId != lost.ClientOrderId {
+		t.Errorf("Expected intent resolved as placed, got %+v", intent)
+	}
+
+	if removed, err := second.Prune(time.Now().Add(time.Minute)); err != nil || removed != 1 {
+		t.Errorf("Expected settled intent pruned, got %d (%v)", removed, err)
+	}
+	if loaded, _ := store.Load(); len(loaded) != 0 {
+		t.Errorf("Expected pruned store, got %+v", loaded)
+	}
+}
+
+func TestHistoryLookupWithoutLookupEndpoint(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	orders := struct{ secure.OrderInterface }{&secure.Order{Client: server.NewClient("1")}}
+	s := newTestSubmitter(t, orders, nil)
+	if s.Lookup != nil {
+		t.Fatal("Expected no lookup for orders without lookup endpoints")
+	}
+
+	server.InjectFault("/buyLimit", coinmatetest.Fault{StatusCode: http.StatusGatewayTimeout, AfterHandling: true, Times: 1})
+	intent, err := s.BuyLimit("k", 0.1, 40000, 0, "BTC_EUR", false, false)
+	if err != nil || intent.Status != StatusPlaced || intent.Attempts != 1 {
+		t.Errorf("Expected order found in history, got %+v (%v)", intent, err)
+	}
+}
+
+// Orders whose first buy times out on the client and reaches the exchange
+// one client timeout later
+type slowOrders struct {
+	*secure.Order
+	inFlight bool
+	elapsed  time.Duration
+	amount   float64
+	price    float64
+	id       uint64
+}
+
+func (o *slowOrders) BuyLimit(amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (secure.SellLimit, error) {
+	if o.id == 0 {
+		o.inFlight, o.amount, o.price, o.id = true, amount, price, clientOrderId
+		return secure.SellLimit{}, errors.New("Client.Timeout exceeded while awaiting headers")
+	}
+	return o.Order.BuyLimit(amount, price, stopPrice, currencyPair, hidden, immediateOrCancel, clientOrderId)
+}
+
+func (o *slowOrders) sleep(d time.Duration) {
+	o.elapsed += d
+	if o.inFlight && o.elapsed >= coinmate.RequestTimeout {
+		o.inFlight = false
+		o.Order.BuyLimit(o.amount, o.price, 0, "BTC_EUR", false, false, o.id)
+	}
+}
+
+func TestTimedOutRequestLandingLateIsNotSentAgain(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	orders := &slowOrders{Order: &secure.Order{Client: server.NewClient("1")}}
+	s := newTestSubmitter(t, orders, nil)
+	s.sleep = orders.sleep
+
+	intent, err := s.BuyLimit("k", 0.1, 40000, 0, "BTC_EUR", false, false)
+	if err != nil || intent.Status != StatusPlaced || intent.Attempts != 1 {
+		t.Fatalf("Expected late order found by lookup, got %+v (%v)", intent, err)
+	}
+	if open := openOrders(t, orders); len(open) != 1 || open[0].Id != intent.OrderId {
+		t.Errorf("Expected exactly one order, got %+v", open)
+	}
+}
@@ -0,0 +1,70 @@
+package indicators
+
+import (
+	"math"
+	"tourGo/coinmate/candles"
+)
+
+// Average true range using Wilder's smoothing
+type ATR struct {
+	average   *EMA
+	prevClose float64
+	started   bool
+}
+
+// Return average true range over period candles
+func NewATR(period int) (*ATR, error) {
+	average, err := newEMA(period, 1/float64(period))
+	if err != nil {
+		return nil, err
+	}
+	return &ATR{average: average}, nil
+}
+
+// Add candle, returns ATR once period candles were seen
+func (a *ATR) Update(c candles.Candle) (float64, bool) {
+	if !finite(c.High) || !finite(c.Low) || !finite(c.Close) {
+		return a.Value()
+	}
+
+	trueRange := c.High - c.Low
+	if a.started {
+		trueRange = math.Max(trueRange, math.Max(math.Abs(c.High-a.prevClose), math.Abs(c.Low-a.prevClose)))
+	}
+	a.prevClose = c.Close
+	a.started = true
+
+	return a.average.Update(trueRange)
+}
+
+// Return current ATR
+func (a *ATR) Value() (float64, bool) {
+	return a.average.Value()
+}
+
+// Return ATR of candles, NaN until period candles were seen
+func ATRSeries(bars []candles.Candle, period int) ([]float64, error) {
+	a, err := NewATR(period)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]float64, len(bars))
+	for i, c := range bars {
+		value, ok := a.Update(c)
+		if !ok {
+			value = math.NaN()
+		}
+		out[i] = value
+	}
+	return out, nil
+}
+
+// Return closing prices of candles
+func Closes(bars []candles.Candle) []float64 {
+	out := make([]float64, len(bars))
+	for i, c := range bars {
+		out[i] = c.Close
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+	"tourGo/coinmate/candles"
+)
+
+func bar(high, low, close float64) candles.Candle {
+	return candles.Candle{Open: close, High: high, Low: low, Close: close, Volume: 1}
+}
+
+func TestATRSeries(t *testing.T) {
+	bars := []candles.Candle{
+		bar(10, 8, 9),
+		bar(11, 9, 10.5),
+		bar(12, 10, 11),
+		bar(11.5, 9, 9.5),
+	}
+
+	out, err := ATRSeries(bars, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !math.IsNaN(out[0]) {
+		t.Errorf("Expected NaN during warm-up, got %f", out[0])
+	}
+	expected := []float64{2, 2, 2.25}
+	for i, e := range expected {
+		if !almostEqual(out[i+1], e, 1e-12) {
+			t.Errorf("Expected ATR %f at %d, got %f", e, i+1, out[i+1])
+		}
+	}
+}
+
+func TestATRUsesPreviousCloseOnGaps(t *testing.T) {
+	a, _ := NewATR(1)
+	a.Update(bar(10, 8, 9))
+	value, ok := a.Update(bar(15, 14, 14.5))
+	if !ok || value != 6 {
+		t.Errorf("Expected true range 6 across the gap, got %f", value)
+	}
+
+	if _, err := NewATR(0); err == nil {
+		t.Error("Expected error for zero period")
+	}
+}
+
+func TestCloses(t *testing.T) {
+	closes := Closes([]candles.Candle{bar(2, 1, 1.5), bar(3, 2, 2.5)})
+	if len(closes) != 2 || closes[0] != 1.5 || closes[1] != 2.5 {
+		t.Errorf("Unexpected closes %v", closes)
+	}
+}
@@ -0,0 +1,84 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+)
+
+// Bollinger bands output
+type BollingerValue struct {
+	Upper  float64 `json:"upper"`
+	Middle float64 `json:"middle"`
+	Lower  float64 `json:"lower"`
+}
+
+// Bollinger bands, simple moving average plus/minus a multiple of the
+// population standard deviation over the same window
+type Bollinger struct {
+	sma        *SMA
+	multiplier float64
+	value      BollingerValue
+	ready      bool
+}
+
+// Return Bollinger bands over period values, commonly 20 and 2
+func NewBollinger(period int, multiplier float64) (*Bollinger, error) {
+	if multiplier <= 0 {
+		return nil, fmt.Errorf("multiplier must be positive")
+	}
+	sma, err := NewSMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return &Bollinger{sma: sma, multiplier: multiplier}, nil
+}
+
+// Add closing price, returns bands once period prices were seen.
+// Non-finite values are ignored.
+func (b *Bollinger) Update(price float64) (BollingerValue, bool) {
+	if !finite(price) {
+		return b.Value()
+	}
+
+	mean, ok := b.sma.Update(price)
+	if !ok {
+		return b.Value()
+	}
+
+	variance := 0.0
+	for _, v := range b.sma.window {
+		variance += (v - mean) * (v - mean)
+	}
+	deviation := math.Sqrt(variance / float64(b.sma.period))
+
+	b.value = BollingerValue{
+		Upper:  mean + b.multiplier*deviation,
+		Middle: mean,
+		Lower:  mean - b.multiplier*deviation,
+	}
+	b.ready = true
+	return b.Value()
+}
+
+// Return current bands
+func (b *Bollinger) Value() (BollingerValue, bool) {
+	return b.value, b.ready
+}
+
+// Return Bollinger bands of prices, all fields NaN until period prices were seen
+func BollingerSeries(prices []float64, period int, multiplier float64) ([]BollingerValue, error) {
+	b, err := NewBollinger(period, multiplier)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]BollingerValue, len(prices))
+	for i, p := range prices {
+		value, ok := b.Update(p)
+		if !ok {
+			value = BollingerValue{Upper: math.NaN(), Middle: math.NaN(), Lower: math.NaN()}
+		}
+		out[i] = value
+	}
+	return out, nil
+}
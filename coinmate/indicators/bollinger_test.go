@@ -0,0 +1,42 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBollingerSeries(t *testing.T) {
+	out, err := BollingerSeries(referenceCloses, 20, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !math.IsNaN(out[18].Middle) {
+		t.Errorf("Expected NaN before period values, got %+v", out[18])
+	}
+
+	last := out[len(out)-1]
+	if !almostEqual(last.Middle, 45.24261, 1e-9) {
+		t.Errorf("Expected middle band 45.24261, got %f", last.Middle)
+	}
+	if !almostEqual(last.Upper, 47.62346641557823, 1e-9) {
+		t.Errorf("Expected upper band 47.623466, got %f", last.Upper)
+	}
+	if !almostEqual(last.Lower, 42.86175358442177, 1e-9) {
+		t.Errorf("Expected lower band 42.861754, got %f", last.Lower)
+	}
+}
+
+func TestBollingerFlatPrices(t *testing.T) {
+	b, _ := NewBollinger(3, 2)
+	b.Update(10)
+	b.Update(10)
+	value, ok := b.Update(10)
+	if !ok || value.Upper != 10 || value.Lower != 10 {
+		t.Errorf("Expected collapsed bands at 10, got %+v", value)
+	}
+
+	if _, err := NewBollinger(20, 0); err == nil {
+		t.Error("Expected error for zero multiplier")
+	}
+}
@@ -0,0 +1,89 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+)
+
+// Moving average convergence divergence output
+type MACDValue struct {
+	MACD      float64 `json:"macd"`
+	Signal    float64 `json:"signal"`
+	Histogram float64 `json:"histogram"`
+}
+
+// Moving average convergence divergence
+type MACD struct {
+	fast   *EMA
+	slow   *EMA
+	signal *EMA
+	value  MACDValue
+	ready  bool
+}
+
+// Return MACD with the given EMA periods, commonly 12, 26 and 9
+func NewMACD(fast, slow, signal int) (*MACD, error) {
+	if fast >= slow {
+		return nil, fmt.Errorf("fast period must be shorter than slow period")
+	}
+	f, err := NewEMA(fast)
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewEMA(slow)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := NewEMA(signal)
+	if err != nil {
+		return nil, err
+	}
+	return &MACD{fast: f, slow: s, signal: sig}, nil
+}
+
+// Add closing price, returns MACD once the signal line is ready.
+// Non-finite values are ignored.
+func (m *MACD) Update(price float64) (MACDValue, bool) {
+	if !finite(price) {
+		return m.Value()
+	}
+
+	fast, _ := m.fast.Update(price)
+	slow, ok := m.slow.Update(price)
+	if !ok {
+		return m.Value()
+	}
+
+	macd := fast - slow
+	signal, ok := m.signal.Update(macd)
+	if !ok {
+		return m.Value()
+	}
+
+	m.value = MACDValue{MACD: macd, Signal: signal, Histogram: macd - signal}
+	m.ready = true
+	return m.Value()
+}
+
+// Return current MACD
+func (m *MACD) Value() (MACDValue, bool) {
+	return m.value, m.ready
+}
+
+// Return MACD of prices, all fields NaN until the signal line is ready
+func MACDSeries(prices []float64, fast, slow, signal int) ([]MACDValue, error) {
+	m, err := NewMACD(fast, slow, signal)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]MACDValue, len(prices))
+	for i, p := range prices {
+		value, ok := m.Update(p)
+		if !ok {
+			value = MACDValue{MACD: math.NaN(), Signal: math.NaN(), Histogram: math.NaN()}
+		}
+		out[i] = value
+	}
+	return out, nil
+}
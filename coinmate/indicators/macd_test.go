@@ -0,0 +1,41 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMACDSeries(t *testing.T) {
+	out, err := MACDSeries(referenceCloses, 3, 6, 4)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Slow EMA is ready at index 5, signal needs 4 MACD values
+	if !math.IsNaN(out[7].MACD) {
+		t.Errorf("Expected NaN before signal is ready, got %+v", out[7])
+	}
+	if math.IsNaN(out[8].Signal) {
+		t.Error("Expected signal to be ready at index 8")
+	}
+
+	last := out[len(out)-1]
+	if !almostEqual(last.MACD, -0.4373015993412821, 1e-9) {
+		t.Errorf("Expected MACD -0.437302, got %f", last.MACD)
+	}
+	if !almostEqual(last.Signal, -0.43518007484491433, 1e-9) {
+		t.Errorf("Expected signal -0.435180, got %f", last.Signal)
+	}
+	if !almostEqual(last.Histogram, last.MACD-last.Signal, 1e-12) {
+		t.Errorf("Expected histogram to be MACD - signal, got %f", last.Histogram)
+	}
+}
+
+func TestMACDInvalidPeriods(t *testing.T) {
+	if _, err := NewMACD(26, 12, 9); err == nil {
+		t.Error("Expected error when fast period is not shorter than slow")
+	}
+	if _, err := NewMACD(0, 12, 9); err == nil {
+		t.Error("Expected error for zero period")
+	}
+}
@@ -0,0 +1,140 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+)
+
+// Simple moving average
+type SMA struct {
+	period int
+	window []float64
+	next   int
+	count  int
+	value  float64
+}
+
+// Return simple moving average over period values
+func NewSMA(period int) (*SMA, error) {
+	if period < 1 {
+		return nil, fmt.Errorf("period must be positive")
+	}
+	return &SMA{period: period, window: make([]float64, period)}, nil
+}
+
+// Add value, returns the average once period values were seen.
+// Non-finite values are ignored.
+func (s *SMA) Update(value float64) (float64, bool) {
+	if !finite(value) {
+		return s.Value()
+	}
+
+	s.window[s.next] = value
+	s.next = (s.next + 1) % s.period
+	if s.count < s.period {
+		s.count++
+	}
+	if s.count == s.period {
+		// Summing the window instead of keeping a running sum avoids drift
+		s.value = sum(s.window) / float64(s.period)
+	}
+	return s.Value()
+}
+
+// Return current average
+func (s *SMA) Value() (float64, bool) {
+	return s.value, s.count == s.period
+}
+
+// Exponential moving average, seeded with the simple average of the first
+// period values and smoothed with 2 / (period + 1) afterwards
+type EMA struct {
+	period int
+	alpha  float64
+	seed   *SMA
+	value  float64
+	ready  bool
+}
+
+// Return exponential moving average over period values
+func NewEMA(period int) (*EMA, error) {
+	return newEMA(period, 2/float64(period+1))
+}
+
+// Add value, returns the average once period values were seen.
+// Non-finite values are ignored.
+func (e *EMA) Update(value float64) (float64, bool) {
+	if !finite(value) {
+		return e.Value()
+	}
+
+	if !e.ready {
+		if avg, ok := e.seed.Update(value); ok {
+			e.value = avg
+			e.ready = true
+		}
+		return e.Value()
+	}
+
+	e.value += e.alpha * (value - e.value)
+	return e.Value()
+}
+
+// Return current average
+func (e *EMA) Value() (float64, bool) {
+	return e.value, e.ready
+}
+
+// Return simple moving average of values, NaN until period values were seen
+func SMASeries(values []float64, period int) ([]float64, error) {
+	s, err := NewSMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return series(values, s.Update), nil
+}
+
+// Return exponential moving average of values, NaN until period values were seen
+func EMASeries(values []float64, period int) ([]float64, error) {
+	e, err := NewEMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return series(values, e.Update), nil
+}
+
+// Helper functions
+
+// EMA with custom smoothing factor, Wilder's smoothing uses 1 / period
+func newEMA(period int, alpha float64) (*EMA, error) {
+	seed, err := NewSMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return &EMA{period: period, alpha: alpha, seed: seed}, nil
+}
+
+// Apply streaming update to every value, NaN where the indicator is not ready
+func series(values []float64, update func(float64) (float64, bool)) []float64 {
+	out := make([]float64, len(values))
+	for i, v := range values {
+		value, ok := update(v)
+		if !ok {
+			value = math.NaN()
+		}
+		out[i] = value
+	}
+	return out
+}
+
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
@@ -0,0 +1,79 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+)
+
+// Reference closes from the StockCharts RSI worksheet
+var referenceCloses = []float64{
+	44.3389, 44.0902, 44.1497, 43.6124, 44.3278, 44.8264, 45.0955, 45.4245, 45.8433, 46.0826,
+	45.8931, 46.0328, 45.6140, 46.2820, 46.2820, 46.0028, 46.0328, 46.4116, 46.2222, 45.6439,
+	46.2122, 46.2521, 45.7137, 46.4515, 45.7835, 45.3548, 44.0288, 44.1783, 44.2181, 44.5672,
+	43.4205, 42.6628, 43.1314,
+}
+
+func almostEqual(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestSMA(t *testing.T) {
+	s, err := NewSMA(3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, ok := s.Update(1); ok {
+		t.Error("Expected SMA not to be ready after 1 value")
+	}
+	s.Update(2)
+	value, ok := s.Update(3)
+	if !ok || value != 2 {
+		t.Errorf("Expected SMA 2, got %f (ready=%v)", value, ok)
+	}
+	value, _ = s.Update(10)
+	if value != 5 {
+		t.Errorf("Expected SMA 5, got %f", value)
+	}
+	value, _ = s.Update(math.NaN())
+	if value != 5 {
+		t.Errorf("Expected NaN input to be ignored, got %f", value)
+	}
+}
+
+func TestSMASeries(t *testing.T) {
+	out, err := SMASeries([]float64{1, 2, 3, 4}, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !math.IsNaN(out[0]) {
+		t.Errorf("Expected NaN during warm-up, got %f", out[0])
+	}
+	expected := []float64{1.5, 2.5, 3.5}
+	for i, e := range expected {
+		if out[i+1] != e {
+			t.Errorf("Expected %f at %d, got %f", e, i+1, out[i+1])
+		}
+	}
+
+	if _, err := SMASeries(nil, 0); err == nil {
+		t.Error("Expected error for zero period")
+	}
+}
+
+func TestEMASeries(t *testing.T) {
+	out, err := EMASeries(referenceCloses, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !math.IsNaN(out[8]) {
+		t.Errorf("Expected NaN before period values, got %f", out[8])
+	}
+	if !almostEqual(out[9], 44.77913, 1e-9) {
+		t.Errorf("Expected EMA seeded with SMA 44.77913, got %f", out[9])
+	}
+	if !almostEqual(out[len(out)-1], 44.120148356901375, 1e-9) {
+		t.Errorf("Expected last EMA 44.120148, got %f", out[len(out)-1])
+	}
+}
@@ -0,0 +1,77 @@
+package indicators
+
+import "fmt"
+
+// Relative strength index using Wilder's smoothing
+type RSI struct {
+	gain  *EMA
+	loss  *EMA
+	prev  float64
+	count int
+	value float64
+	ready bool
+}
+
+// Return relative strength index over period price changes
+func NewRSI(period int) (*RSI, error) {
+	if period < 1 {
+		return nil, fmt.Errorf("period must be positive")
+	}
+	gain, _ := newEMA(period, 1/float64(period))
+	loss, _ := newEMA(period, 1/float64(period))
+	return &RSI{gain: gain, loss: loss}, nil
+}
+
+// Add closing price, returns RSI in range <0, 100> once period + 1 prices
+// were seen. Non-finite values are ignored.
+func (r *RSI) Update(price float64) (float64, bool) {
+	if !finite(price) {
+		return r.Value()
+	}
+
+	r.count++
+	if r.count == 1 {
+		r.prev = price
+		return r.Value()
+	}
+
+	change := price - r.prev
+	r.prev = price
+	gain, loss := 0.0, 0.0
+	if change > 0 {
+		gain = change
+	} else {
+		loss = -change
+	}
+
+	avgGain, ok := r.gain.Update(gain)
+	avgLoss, _ := r.loss.Update(loss)
+	if !ok {
+		return r.Value()
+	}
+
+	r.ready = true
+	switch {
+	case avgLoss == 0 && avgGain == 0:
+		r.value = 50
+	case avgLoss == 0:
+		r.value = 100
+	default:
+		r.value = 100 - 100/(1+avgGain/avgLoss)
+	}
+	return r.Value()
+}
+
+// Return current RSI
+func (r *RSI) Value() (float64, bool) {
+	return r.value, r.ready
+}
+
+// Return RSI of prices, NaN until period + 1 prices were seen
+func RSISeries(prices []float64, period int) ([]float64, error) {
+	r, err := NewRSI(period)
+	if err != nil {
+		return nil, err
+	}
+	return series(prices, r.Update), nil
+}
@@ -0,0 +1,51 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRSISeriesMatchesReference(t *testing.T) {
+	expected := []float64{
+		70.53, 66.32, 66.55, 69.41, 66.36, 57.97, 62.93, 63.26, 56.06, 62.38,
+		54.71, 50.42, 39.99, 41.46, 41.87, 45.46, 37.30, 33.08, 37.77,
+	}
+
+	out, err := RSISeries(referenceCloses, 14)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for i := 0; i < 14; i++ {
+		if !math.IsNaN(out[i]) {
+			t.Errorf("Expected NaN at %d, got %f", i, out[i])
+		}
+	}
+	for i, e := range expected {
+		if !almostEqual(out[i+14], e, 0.005) {
+			t.Errorf("Expected RSI %.2f at %d, got %f", e, i+14, out[i+14])
+		}
+	}
+}
+
+func TestRSIBounds(t *testing.T) {
+	r, _ := NewRSI(2)
+	r.Update(1)
+	r.Update(2)
+	value, ok := r.Update(3)
+	if !ok || value != 100 {
+		t.Errorf("Expected RSI 100 for only gains, got %f", value)
+	}
+
+	r, _ = NewRSI(2)
+	r.Update(1)
+	r.Update(1)
+	value, _ = r.Update(1)
+	if value != 50 {
+		t.Errorf("Expected RSI 50 for flat prices, got %f", value)
+	}
+
+	if _, err := NewRSI(0); err == nil {
+		t.Error("Expected error for zero period")
+	}
+}
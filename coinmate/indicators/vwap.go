@@ -0,0 +1,63 @@
+package indicators
+
+import (
+	"math"
+	"tourGo/coinmate/candles"
+)
+
+// Cumulative volume weighted average price. Candles built from trades carry
+// the exact traded quote volume, which is used when present; otherwise the
+// typical price (high + low + close) / 3 weights the candle volume.
+type VWAP struct {
+	volume      float64
+	quoteVolume float64
+}
+
+// Return VWAP accumulating from the first candle
+func NewVWAP() *VWAP {
+	return &VWAP{}
+}
+
+// Add candle, returns VWAP once any volume was seen
+func (v *VWAP) Update(c candles.Candle) (float64, bool) {
+	if !finite(c.Volume) || c.Volume <= 0 {
+		return v.Value()
+	}
+
+	quote := c.QuoteVolume
+	if quote <= 0 || !finite(quote) {
+		quote = (c.High + c.Low + c.Close) / 3 * c.Volume
+	}
+	v.volume += c.Volume
+	v.quoteVolume += quote
+	return v.Value()
+}
+
+// Return current VWAP
+func (v *VWAP) Value() (float64, bool) {
+	if v.volume == 0 {
+		return 0, false
+	}
+	return v.quoteVolume / v.volume, true
+}
+
+// Start a new session, e.g. at the beginning of each trading day
+func (v *VWAP) Reset() {
+	v.volume = 0
+	v.quoteVolume = 0
+}
+
+// Return cumulative VWAP of candles, NaN until any volume was seen
+func VWAPSeries(bars []candles.Candle) []float64 {
+	v := NewVWAP()
+
+	out := make([]float64, len(bars))
+	for i, c := range bars {
+		value, ok := v.Update(c)
+		if !ok {
+			value = math.NaN()
+		}
+		out[i] = value
+	}
+	return out
+}
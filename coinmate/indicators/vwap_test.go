@@ -0,0 +1,43 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+	"tourGo/coinmate/candles"
+)
+
+func TestVWAPUsesQuoteVolume(t *testing.T) {
+	v := NewVWAP()
+	if _, ok := v.Value(); ok {
+		t.Error("Expected VWAP not to be ready without volume")
+	}
+
+	v.Update(candles.Candle{High: 11, Low: 9, Close: 10, Volume: 2, QuoteVolume: 19})
+	value, ok := v.Update(candles.Candle{High: 12, Low: 10, Close: 11, Volume: 1, QuoteVolume: 11})
+	if !ok || !almostEqual(value, 10, 1e-12) {
+		t.Errorf("Expected VWAP 10, got %f", value)
+	}
+
+	v.Reset()
+	if _, ok := v.Value(); ok {
+		t.Error("Expected VWAP to reset")
+	}
+}
+
+func TestVWAPSeriesTypicalPrice(t *testing.T) {
+	out := VWAPSeries([]candles.Candle{
+		{High: 10, Low: 10, Close: 10},
+		{High: 12, Low: 9, Close: 9, Volume: 1},
+		{High: 13, Low: 13, Close: 13, Volume: 3},
+	})
+
+	if !math.IsNaN(out[0]) {
+		t.Errorf("Expected NaN without volume, got %f", out[0])
+	}
+	if !almostEqual(out[1], 10, 1e-12) {
+		t.Errorf("Expected typical price 10, got %f", out[1])
+	}
+	if !almostEqual(out[2], 12.25, 1e-12) {
+		t.Errorf("Expected VWAP 12.25, got %f", out[2])
+	}
+}
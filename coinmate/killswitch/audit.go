@@ -0,0 +1,99 @@
+package killswitch
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Record of transitions between trading and halted
+type AuditLog interface {
+	Record(t Transition) error
+	// Latest transition, false when none was recorded yet
+	Last() (Transition, bool, error)
+}
+
+// Audit log appending one JSON line per transition to a file
+type FileAuditLog struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// Return audit log at path, the directory is created on first record
+func NewFileAuditLog(path string) *FileAuditLog {
+	return &FileAuditLog{Path: path}
+}
+
+// Append transition and sync it to disk
+func (l *FileAuditLog) Record(t Transition) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to encode transition: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0o755); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	f, err := os.OpenFile(l.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+	return f.Close()
+}
+
+// All recorded transitions, oldest first
+func (l *FileAuditLog) Transitions() ([]Transition, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var transitions []Transition
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var t Transition
+		if err := json.Unmarshal(scanner.Bytes(), &t); err != nil {
+			return nil, fmt.Errorf("failed to decode audit log %s line %d: %w", l.Path, line, err)
+		}
+		transitions = append(transitions, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return transitions, nil
+}
+
+// Latest transition
+func (l *FileAuditLog) Last() (Transition, bool, error) {
+	transitions, err := l.Transitions()
+	if err != nil || len(transitions) == 0 {
+		return Transition{}, false, err
+	}
+	return transitions[len(transitions)-1], true, nil
+}
@@ -0,0 +1,282 @@
+package killswitch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate/risk"
+	"tourGo/coinmate/secure"
+)
+
+// What tripped or resumed trading
+type Source string
+
+const (
+	SourceManual Source = "MANUAL"
+	SourceRisk   Source = "RISK"
+	SourceFile   Source = "FILE"
+	SourceSignal Source = "SIGNAL"
+)
+
+var ErrHalted = errors.New("trading halted")
+
+// Change between trading and halted
+type Transition struct {
+	Timestamp int64  `json:"timestamp"`
+	Halted    bool   `json:"halted"`
+	Source    Source `json:"source"`
+	Reason    string `json:"reason"`
+	// Orders cancelled when tripping
+	Cancelled []uint64 `json:"cancelled,omitempty"`
+	// Failures cancelling open orders
+	Error string `json:"error,omitempty"`
+}
+
+// Switch halts trading process-wide. It is a secure.Gate, installed with
+// secure.SetDefaultGate it makes every order placement on secure.Order fail
+// with secure.ErrNotSent and ErrHalted while tripped:
+//
+//	sw, err := killswitch.NewSwitch(killswitch.NewFileAuditLog("state/halt.log"))
+//	secure.SetDefaultGate(sw)
+//
+// A tripped switch stays halted until Resume, also across restarts when the
+// audit log shows a halt as the last transition.
+type Switch struct {
+	Audit AuditLog
+
+	// Cancel all open orders of Orders when tripping
+	CancelOnTrip bool
+	Orders       secure.OrderInterface
+
+	// Called after every recorded transition while the switch is locked, it
+	// must not call methods of the switch
+	OnTransition func(Transition)
+
+	mu     sync.Mutex
+	halted bool
+	last   Transition
+	// Incremented by every transition
+	version uint64
+	now     func() time.Time
+}
+
+// Return switch restoring its state from the audit log, which may be nil
+func NewSwitch(audit AuditLog) (*Switch, error) {
+	s := &Switch{Audit: audit, now: time.Now}
+	if audit == nil {
+		return s, nil
+	}
+
+	last, ok, err := audit.Last()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		s.halted = last.Halted
+		s.last = last
+	}
+	return s, nil
+}
+
+// Refuse orders while halted, implements secure.Gate
+func (s *Switch) Allow() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.halted {
+		return fmt.Errorf("%w by %s: %s", ErrHalted, s.last.Source, s.last.Reason)
+	}
+	return nil
+}
+
+// Whether trading is halted
+func (s *Switch) Halted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.halted
+}
+
+// Latest transition
+func (s *Switch) Status() Transition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.last
+}
+
+// Halt trading. Orders are refused from this moment and the halt is recorded
+// before open orders are cancelled when CancelOnTrip is set, the result of
+// the cancellation is recorded as a second transition. Tripping a halted
+// switch does nothing.
+func (s *Switch) Trip(source Source, reason string) error {
+	s.mu.Lock()
+	if s.halted {
+		s.mu.Unlock()
+		return nil
+	}
+	t := Transition{Timestamp: s.now().UnixMilli(), Halted: true, Source: source, Reason: reason}
+	s.halted = true
+	s.last = t
+	s.version++
+	version := s.version
+	// Trading stays halted even when the transition cannot be recorded
+	err := s.record(t, "halt")
+	s.mu.Unlock()
+
+	if !s.CancelOnTrip || s.Orders == nil {
+		return err
+	}
+	cancelled, cancelErr := s.cancelAll()
+	t.Timestamp = s.now().UnixMilli()
+	t.Cancelled = cancelled
+	if cancelErr != nil {
+		t.Error = cancelErr.Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Resumed while cancelling, the result must not replace the newer state
+	if s.version != version {
+		return errors.Join(err, cancelErr)
+	}
+	s.last = t
+	return errors.Join(err, cancelErr, s.record(t, "cancelled orders"))
+}
+
+// Re-enable trading after a halt. The transition is recorded first, trading
+// stays halted when that fails.
+func (s *Switch) Resume(reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.halted {
+		return nil
+	}
+	t := Transition{Timestamp: s.now().UnixMilli(), Halted: false, Source: SourceManual, Reason: reason}
+	if s.Audit != nil {
+		if err := s.Audit.Record(t); err != nil {
+			return fmt.Errorf("failed to record resume: %w", err)
+		}
+	}
+	s.halted = false
+	s.last = t
+	s.version++
+	if s.OnTransition != nil {
+		s.OnTransition(t)
+	}
+	return nil
+}
+
+// Return risk.Guard violation handler tripping the switch on the given
+// rules, on every rule when none given
+func (s *Switch) TripOnViolation(rules ...risk.Rule) func(*risk.Violation) {
+	return func(v *risk.Violation) {
+		if len(rules) > 0 && !containsRule(rules, v.Rule) {
+			return
+		}
+		s.Trip(SourceRisk, v.Error())
+	}
+}
+
+// Trip when a file appears at path, e.g. created with touch. The file
+// contents are used as reason. Trading is tripped again after Resume while
+// the file exists.
+func (s *Switch) WatchFile(ctx context.Context, path string, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if data, err := os.ReadFile(path); err == nil && !s.Halted() {
+			reason := strings.TrimSpace(string(data))
+			if reason == "" {
+				reason = "halt file " + path
+			}
+			if err := s.Trip(SourceFile, reason); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Trip when the process receives one of the signals, e.g. syscall.SIGUSR1
+func (s *Switch) WatchSignals(ctx context.Context, onError func(error), signals ...os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-ch:
+			if err := s.Trip(SourceSignal, "received "+sig.String()); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Helper functions
+
+// Record transition while holding mu, so that the audit log keeps the order
+// of the transitions
+func (s *Switch) record(t Transition, what string) error {
+	var err error
+	if s.Audit != nil {
+		if err = s.Audit.Record(t); err != nil {
+			err = fmt.Errorf("failed to record %s: %w", what, err)
+		}
+	}
+	if s.OnTransition != nil {
+		s.OnTransition(t)
+	}
+	return err
+}
+
+// Cancel open orders across all pairs
+func (s *Switch) cancelAll() ([]uint64, error) {
+	open, err := s.Orders.GetOpenOrders("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open orders: %w", err)
+	}
+	if open.Error {
+		return nil, fmt.Errorf("failed to list open orders: %s", open.ErrorMessage)
+	}
+
+	var cancelled []uint64
+	var errs []error
+	for _, o := range open.Data {
+		r, err := s.Orders.CancelOrder(o.Id)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("order %d: %w", o.Id, err))
+		case r.Error:
+			errs = append(errs, fmt.Errorf("order %d: %s", o.Id, r.ErrorMessage))
+		case r.Data:
+			cancelled = append(cancelled, o.Id)
+		}
+	}
+	return cancelled, errors.Join(errs...)
+}
+
+func containsRule(rules []risk.Rule, rule risk.Rule) bool {
+	for _, r := range rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
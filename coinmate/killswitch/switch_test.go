@@ -0,0 +1,223 @@
+package killswitch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/risk"
+	"tourGo/coinmate/secure"
+)
+
+func newTestSwitch(t *testing.T, audit AuditLog) *Switch {
+	t.Helper()
+	s, err := NewSwitch(audit)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return s
+}
+
+func TestTripAndResume(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	sw := newTestSwitch(t, nil)
+	order := &secure.Order{Client: server.NewClient("1"), Gate: sw}
+
+	if _, err := order.BuyLimit(0.01, 40000, 0, "BTC_EUR", false, false, 0); err != nil {
+		t.Fatalf("Expected order before halt, got %v", err)
+	}
+
+	sw.Trip(SourceManual, "maintenance")
+	_, err := order.SellInstant(0.01, "BTC_EUR", 0)
+	if !errors.Is(err, ErrHalted) || !errors.Is(err, secure.ErrNotSent) {
+		t.Errorf("Expected halted order not sent, got %v", err)
+	}
+	if _, err := order.BuyLimit(0.01, 40000, 0, "BTC_EUR", false, false, 0); !errors.Is(err, ErrHalted) {
+		t.Errorf("Expected halted buy limit, got %v", err)
+	}
+	if r, err := order.GetOpenOrders("BTC_EUR"); err != nil || len(r.Data) != 1 {
+		t.Errorf("Expected reads to work while halted, got %+v (%v)", r.Data, err)
+	}
+
+	if err := sw.Resume("maintenance done"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := order.BuyLimit(0.01, 40000, 0, "BTC_EUR", false, false, 0); err != nil {
+		t.Errorf("Expected orders after resume, got %v", err)
+	}
+}
+
+func TestCancelOnTrip(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	sw := newTestSwitch(t, nil)
+	order := &secure.Order{Client: server.NewClient("1"), Gate: sw}
+	sw.Orders = order
+	sw.CancelOnTrip = true
+
+	server.Deposit("1", "ETH", 1)
+	order.BuyLimit(0.01, 40000, 0, "BTC_EUR", false, false, 0)
+	order.SellLimit(0.01, 3000, 0, "ETH_EUR", false, false, 0)
+
+	var transitions []Transition
+	sw.OnTransition = func(t Transition) { transitions = append(transitions, t) }
+	if err := sw.Trip(SourceManual, "panic"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if open, _ := order.GetOpenOrders(""); len(open.Data) != 0 {
+		t.Errorf("Expected all open orders cancelled, got %+v", open.Data)
+	}
+	if len(transitions) != 2 || len(transitions[0].Cancelled) != 0 || len(transitions[1].Cancelled) != 2 {
+		t.Errorf("Expected halt followed by 2 cancelled orders, got %+v", transitions)
+	}
+	if status := sw.Status(); !status.Halted || len(status.Cancelled) != 2 {
+		t.Errorf("Expected status with cancelled orders, got %+v", status)
+	}
+
+	if err := sw.Trip(SourceManual, "again"); err != nil || len(transitions) != 2 {
+		t.Errorf("Expected tripping a halted switch to do nothing, got %+v (%v)", transitions, err)
+	}
+}
+
+// Lists no open orders after calling onList
+type listingOrders struct {
+	secure.OrderInterface
+	onList func()
+}
+
+func (o *listingOrders) GetOpenOrders(currencyPair string) (secure.OpenOrdersResponse, error) {
+	o.onList()
+	return secure.OpenOrdersResponse{}, nil
+}
+
+func TestHaltRecordedBeforeCancel(t *testing.T) {
+	audit := NewFileAuditLog(filepath.Join(t.TempDir(), "halt.log"))
+	sw := newTestSwitch(t, audit)
+	sw.CancelOnTrip = true
+
+	var recorded Transition
+	sw.Orders = &listingOrders{onList: func() { recorded, _, _ = audit.Last() }}
+	sw.Trip(SourceManual, "incident")
+	if !recorded.Halted || recorded.Reason != "incident" {
+		t.Errorf("Expected halt recorded before cancelling, got %+v", recorded)
+	}
+
+	// Resumed while the orders are cancelled
+	sw = newTestSwitch(t, audit)
+	sw.Resume("resolved")
+	sw.CancelOnTrip = true
+	sw.Orders = &listingOrders{onList: func() { sw.Resume("resolved again") }}
+	sw.Trip(SourceManual, "second incident")
+
+	if sw.Halted() || sw.Status().Reason != "resolved again" {
+		t.Errorf("Expected resume to stay the latest transition, got %+v", sw.Status())
+	}
+	if restarted := newTestSwitch(t, audit); restarted.Halted() {
+		t.Errorf("Expected trading after restart, got %+v", restarted.Status())
+	}
+}
+
+func TestHaltSurvivesRestart(t *testing.T) {
+	audit := NewFileAuditLog(filepath.Join(t.TempDir(), "audit", "halt.log"))
+
+	first := newTestSwitch(t, audit)
+	first.Trip(SourceManual, "incident")
+
+	second := newTestSwitch(t, audit)
+	if !second.Halted() || second.Status().Reason != "incident" {
+		t.Fatalf("Expected halt restored, got %+v", second.Status())
+	}
+	second.Resume("resolved")
+
+	third := newTestSwitch(t, audit)
+	if third.Halted() {
+		t.Error("Expected trading after recorded resume")
+	}
+
+	transitions, err := audit.Transitions()
+	if err != nil || len(transitions) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %+v (%v)", transitions, err)
+	}
+	if !transitions[0].Halted || transitions[1].Halted || transitions[1].Reason != "resolved" {
+		t.Errorf("Unexpected audit entries %+v", transitions)
+	}
+}
+
+func TestTripOnViolation(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	sw := newTestSwitch(t, nil)
+	client := server.NewClient("1")
+	guard, err := risk.NewGuard(&secure.Order{Client: client, Gate: sw}, nil, nil, risk.Limits{MaxNotional: map[string]float64{"BTC_EUR": 1000}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	guard.OnViolation = sw.TripOnViolation(risk.RuleMaxNotional)
+
+	guard.BuyLimit(1, 50000, 0, "BTC_EUR", false, false, 0)
+	if status := sw.Status(); !sw.Halted() || status.Source != SourceRisk {
+		t.Fatalf("Expected switch tripped by risk rule, got %+v", status)
+	}
+	if _, err := guard.BuyLimit(0.01, 50000, 0, "BTC_EUR", false, false, 0); !errors.Is(err, ErrHalted) {
+		t.Errorf("Expected orders within limits halted, got %v", err)
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	sw := newTestSwitch(t, nil)
+	path := filepath.Join(t.TempDir(), "HALT")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sw.WatchFile(ctx, path, 5*time.Millisecond, nil)
+
+	time.Sleep(20 * time.Millisecond)
+	if sw.Halted() {
+		t.Fatal("Expected trading without halt file")
+	}
+	os.WriteFile(path, []byte("ops request\n"), 0o644)
+	waitHalted(t, sw)
+	if status := sw.Status(); status.Source != SourceFile || status.Reason != "ops request" {
+		t.Errorf("Expected halt by file, got %+v", status)
+	}
+}
+
+func TestWatchSignals(t *testing.T) {
+	sw := newTestSwitch(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sw.WatchSignals(ctx, nil, syscall.SIGUSR1)
+
+	time.Sleep(20 * time.Millisecond)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	waitHalted(t, sw)
+	if sw.Status().Source != SourceSignal {
+		t.Errorf("Expected halt by signal, got %+v", sw.Status())
+	}
+}
+
+func TestDefaultGate(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	sw := newTestSwitch(t, nil)
+	secure.SetDefaultGate(sw)
+	t.Cleanup(func() { secure.SetDefaultGate(nil) })
+
+	sw.Trip(SourceManual, "test")
+	order := &secure.Order{Client: server.NewClient("1")}
+	if _, err := order.BuyInstant(100, "BTC_EUR", 0); !errors.Is(err, ErrHalted) {
+		t.Errorf("Expected default gate to halt orders, got %v", err)
+	}
+}
+
+func waitHalted(t *testing.T, sw *Switch) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !sw.Halted() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected switch to trip")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
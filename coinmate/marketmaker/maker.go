@@ -0,0 +1,533 @@
+package marketmaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate"
+	"tourGo/coinmate/analytics"
+	"tourGo/coinmate/idempotent"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	buySide  = "BUY"
+	sellSide = "SELL"
+
+	epsilon = 1e-9
+
+	// Time after which a quote sent without an answer is no longer expected
+	// among open orders
+	unansweredTimeout = coinmate.RequestTimeout + time.Second
+)
+
+// Quoting parameters
+type Config struct {
+	// Distance between bid and ask relative to the center price, e.g. 0.004
+	// quotes 0.2 % below and above it
+	Spread float64
+	// Base amount of each quote
+	Size float64
+
+	// Base balance aimed for and the deviation from it at which the side
+	// adding to the deviation is no longer quoted, zero disables skewing
+	TargetInventory float64
+	MaxInventory    float64
+	// Relative shift of both quotes at the maximal deviation, e.g. 0.002
+	// moves them 0.2 % down when long and up when short
+	Skew float64
+
+	// Relative price change that makes a quote move, e.g. 0.0005
+	RequoteThreshold float64
+	// Quotes are cancelled when the order book is older, zero disables the check
+	MaxDataAge time.Duration
+}
+
+// Resting quote
+type Quote struct {
+	Side    string  `json:"side"`
+	OrderId uint64  `json:"orderId"`
+	Price   float64 `json:"price"`
+	Amount  float64 `json:"amount"`
+}
+
+// Maker state
+type Status struct {
+	// Mid price of the book excluding own quotes
+	Mid       float64 `json:"mid"`
+	Inventory float64 `json:"inventory"`
+	Bid       *Quote  `json:"bid,omitempty"`
+	Ask       *Quote  `json:"ask,omitempty"`
+	// Time of the last order book
+	Updated int64  `json:"updated"`
+	Halted  bool   `json:"halted"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Maker keeps a bid and an ask around the mid of the order book. Quotes are
+// centered on the mid shifted against the inventory deviation from its
+// target, so a long position is sold down and a short one bought back.
+// Quotes move when their price changes by more than the re-quote threshold,
+// with one replace request when Orders supports secure.OrderReplaceInterface.
+//
+// Quotes are cancelled while the order book is stale. When an order is
+// refused with secure.ErrNotSent, e.g. by a risk.Guard or a kill switch, all
+// quotes are cancelled and the maker stays halted until Resume.
+//
+// Quotes carry client order IDs. A quote sent without an answer is looked for
+// among open orders and adopted when its side has no quote, otherwise it is
+// cancelled.
+type Maker struct {
+	Orders   secure.OrderInterface
+	Balances secure.BalancesInterface
+	// Order book polled by Step, nil when books are pushed with Update
+	Book   *public.OrderBook
+	Pair   public.TradingPairsData
+	Config Config
+	// Client order IDs of quotes
+	Generator *idempotent.Generator
+
+	// Called when the maker halts
+	OnHalt func(reason string)
+
+	mu      sync.Mutex
+	replace secure.OrderReplaceInterface
+	book    public.OrderBookData
+	updated time.Time
+	quotes  map[string]*Quote
+	// Send times by client order ID of quotes sent without an answer
+	unanswered map[uint64]time.Time
+	status     Status
+	now        func() time.Time
+}
+
+// Return maker quoting pair
+func NewMaker(orders secure.OrderInterface, balances secure.BalancesInterface, book *public.OrderBook, pair public.TradingPairsData, config Config) (*Maker, error) {
+	pair.Name = strings.ToUpper(pair.Name)
+	if pair.Name == "" || pair.FirstCurrency == "" {
+		return nil, fmt.Errorf("pair name and currencies must not be empty")
+	}
+	if config.Spread <= 0 || config.Size <= 0 {
+		return nil, fmt.Errorf("spread and size must be positive")
+	}
+	if config.Size < pair.MinAmount {
+		return nil, fmt.Errorf("size %v below minimum %v of %s", config.Size, pair.MinAmount, pair.Name)
+	}
+
+	m := &Maker{
+		Orders:     orders,
+		Balances:   balances,
+		Book:       book,
+		Pair:       pair,
+		Config:     config,
+		Generator:  idempotent.NewGenerator(),
+		quotes:     make(map[string]*Quote),
+		unanswered: make(map[uint64]time.Time),
+		now:        time.Now,
+	}
+	if replace, ok := orders.(secure.OrderReplaceInterface); ok {
+		m.replace = replace
+	}
+	return m, nil
+}
+
+// Feed order book, e.g. from a stream
+func (m *Maker) Update(book public.OrderBookData) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.book = book
+	m.updated = m.now()
+}
+
+// Run one quoting cycle: read the book when polling, drop filled quotes,
+// then place, move or cancel quotes
+func (m *Maker) Step() error {
+	if m.Book != nil {
+		r, err := m.Book.GetOrderBook(m.Pair.Name, false)
+		if err != nil {
+			return m.cancelOnError(err)
+		}
+		if r.Error {
+			return m.cancelOnError(fmt.Errorf("order book %s: %s", m.Pair.Name, r.ErrorMessage))
+		}
+		m.Update(r.Data)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.status.Halted {
+		return nil
+	}
+	if err := m.dropClosed(); err != nil {
+		return err
+	}
+	if m.updated.IsZero() || (m.Config.MaxDataAge > 0 && m.now().Sub(m.updated) > m.Config.MaxDataAge) {
+		return m.cancelAll()
+	}
+
+	inventory, err := m.inventory()
+	if err != nil {
+		return errors.Join(err, m.cancelAll())
+	}
+	m.status.Inventory = inventory
+
+	book := m.ownBookRemoved()
+	mid, err := analytics.MidPrice(book)
+	if err != nil {
+		return errors.Join(err, m.cancelAll())
+	}
+	spread, _, _ := analytics.Spread(book)
+	m.status.Mid = mid
+
+	bid, ask := m.prices(mid, spread, inventory)
+	sides := []string{buySide, sellSide}
+	prices := map[string]float64{buySide: bid, sellSide: ask}
+	if q := m.quotes[sellSide]; q != nil && bid >= q.Price {
+		// Move the ask away first so that the new bid does not trade with it
+		sides[0], sides[1] = sellSide, buySide
+	}
+
+	var errs []error
+	for _, side := range sides {
+		errs = append(errs, m.sync(side, prices[side]))
+		if m.status.Halted {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Step periodically until the context is done, quotes are cancelled on exit
+func (m *Maker) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Step(); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			if err := m.Cancel(); err != nil && onError != nil {
+				onError(err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cancel quotes and stop quoting until Resume
+func (m *Maker) Halt(reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.halt(reason)
+}
+
+// Quote again after a halt
+func (m *Maker) Resume() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.status.Halted = false
+	m.status.Reason = ""
+}
+
+// Cancel resting quotes, they are placed again by the next Step
+func (m *Maker) Cancel() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.cancelAll()
+}
+
+// Current state
+func (m *Maker) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.status
+	if !m.updated.IsZero() {
+		s.Updated = m.updated.UnixMilli()
+	}
+	if q := m.quotes[buySide]; q != nil {
+		bid := *q
+		s.Bid = &bid
+	}
+	if q := m.quotes[sellSide]; q != nil {
+		ask := *q
+		s.Ask = &ask
+	}
+	return s
+}
+
+// Helper functions
+
+// Bid and ask for the book and inventory, zero for a side not quoted.
+// Quotes never cross the book of others.
+func (m *Maker) prices(mid, spread, inventory float64) (float64, float64) {
+	c := m.Config
+	deviation := 0.0
+	if c.MaxInventory > 0 {
+		deviation = math.Max(-1, math.Min(1, (inventory-c.TargetInventory)/c.MaxInventory))
+	}
+	center := mid * (1 - c.Skew*deviation)
+
+	scale := math.Pow10(int(m.Pair.PriceDecimals))
+	bid := math.Floor(center*(1-c.Spread/2)*scale+epsilon) / scale
+	ask := math.Ceil(center*(1+c.Spread/2)*scale-epsilon) / scale
+	if bestAsk := mid + spread/2; bid >= bestAsk {
+		bid = math.Round((bestAsk-1/scale)*scale) / scale
+	}
+	if bestBid := mid - spread/2; ask <= bestBid {
+		ask = math.Round((bestBid+1/scale)*scale) / scale
+	}
+	if deviation >= 1 {
+		bid = 0
+	}
+	if deviation <= -1 {
+		ask = 0
+	}
+	return bid, ask
+}
+
+// Place, move or cancel the quote of a side
+func (m *Maker) sync(side string, price float64) error {
+	q := m.quotes[side]
+	if price <= 0 {
+		if q == nil {
+			return nil
+		}
+		return m.cancelQuote(q)
+	}
+	if q != nil && math.Abs(price-q.Price) <= q.Price*m.Config.RequoteThreshold {
+		return nil
+	}
+
+	var orderId uint64
+	var err error
+	switch {
+	case q != nil && m.replace != nil:
+		orderId, err = m.replaceQuote(q, price)
+	case q != nil:
+		if err = m.cancelQuote(q); err == nil {
+			orderId, err = m.place(side, price)
+		}
+	default:
+		orderId, err = m.place(side, price)
+	}
+	if errors.Is(err, secure.ErrNotSent) {
+		return errors.Join(err, m.halt(err.Error()))
+	}
+	if err != nil {
+		return err
+	}
+	if orderId != 0 {
+		m.quotes[side] = &Quote{Side: side, OrderId: orderId, Price: price, Amount: m.Config.Size}
+	}
+	return nil
+}
+
+func (m *Maker) place(side string, price float64) (uint64, error) {
+	clientOrderId := m.Generator.Next()
+	var r secure.SellLimit
+	var err error
+	if side == buySide {
+		r, err = m.Orders.BuyLimit(m.Config.Size, price, 0, m.Pair.Name, false, false, clientOrderId)
+	} else {
+		r, err = m.Orders.SellLimit(m.Config.Size, price, 0, m.Pair.Name, false, false, clientOrderId)
+	}
+	if err != nil {
+		m.sentWithoutAnswer(clientOrderId, err)
+		return 0, err
+	}
+	if r.Error {
+		return 0, fmt.Errorf("%s quote rejected: %s", strings.ToLower(side), r.ErrorMessage)
+	}
+	return r.OrderId, nil
+}
+
+// Replace quote in one request, a quote that was already closed is dropped
+// and placed again by the next Step
+func (m *Maker) replaceQuote(q *Quote, price float64) (uint64, error) {
+	clientOrderId := m.Generator.Next()
+	var r secure.ReplaceOrderResponse
+	var err error
+	if q.Side == buySide {
+		r, err = m.replace.ReplaceByBuyLimit(q.OrderId, m.Config.Size, price, 0, m.Pair.Name, false, false, clientOrderId)
+	} else {
+		r, err = m.replace.ReplaceBySellLimit(q.OrderId, m.Config.Size, price, 0, m.Pair.Name, false, false, clientOrderId)
+	}
+	if err != nil {
+		m.sentWithoutAnswer(clientOrderId, err)
+		return 0, err
+	}
+	if r.Error {
+		// The old quote may be cancelled, it is dropped from open orders
+		return 0, fmt.Errorf("%s quote rejected: %s", strings.ToLower(q.Side), r.ErrorMessage)
+	}
+	if !r.Data.Success {
+		delete(m.quotes, q.Side)
+	}
+	return r.Data.CreatedOrderId, nil
+}
+
+func (m *Maker) cancelQuote(q *Quote) error {
+	if err := m.cancelOrder(q.OrderId); err != nil {
+		return err
+	}
+	// Not cancelled means already filled or cancelled
+	delete(m.quotes, q.Side)
+	return nil
+}
+
+func (m *Maker) cancelOrder(orderId uint64) error {
+	r, err := m.Orders.CancelOrder(orderId)
+	if err != nil {
+		return fmt.Errorf("failed to cancel quote %d: %w", orderId, err)
+	}
+	if r.Error {
+		return fmt.Errorf("failed to cancel quote %d: %s", orderId, r.ErrorMessage)
+	}
+	return nil
+}
+
+// Cancel quotes including those sent without an answer that rest by now
+func (m *Maker) cancelAll() error {
+	var errs []error
+	if len(m.unanswered) > 0 {
+		errs = append(errs, m.dropClosed())
+	}
+	for _, side := range []string{buySide, sellSide} {
+		if q := m.quotes[side]; q != nil {
+			errs = append(errs, m.cancelQuote(q))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Maker) cancelOnError(err error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Config.MaxDataAge > 0 && m.now().Sub(m.updated) > m.Config.MaxDataAge {
+		return errors.Join(err, m.cancelAll())
+	}
+	return err
+}
+
+func (m *Maker) halt(reason string) error {
+	wasHalted := m.status.Halted
+	m.status.Halted = true
+	m.status.Reason = reason
+	err := m.cancelAll()
+	if !wasHalted && m.OnHalt != nil {
+		m.OnHalt(reason)
+	}
+	return err
+}
+
+// Drop quotes no longer open, filled quotes change the inventory. Open
+// quotes sent without an answer are adopted when their side has no quote and
+// cancelled otherwise.
+func (m *Maker) dropClosed() error {
+	if len(m.quotes) == 0 && len(m.unanswered) == 0 {
+		return nil
+	}
+	open, err := m.Orders.GetOpenOrders(m.Pair.Name)
+	if err != nil {
+		return err
+	}
+	if open.Error {
+		return fmt.Errorf("open orders failed: %s", open.ErrorMessage)
+	}
+	resting := make(map[uint64]bool, len(open.Data))
+	for _, o := range open.Data {
+		resting[o.Id] = true
+	}
+	for side, q := range m.quotes {
+		if !resting[q.OrderId] {
+			delete(m.quotes, side)
+		}
+	}
+
+	var errs []error
+	for _, o := range open.Data {
+		if _, ok := m.unanswered[o.ClientOrderId]; !ok || o.ClientOrderId == 0 {
+			continue
+		}
+		side := strings.ToUpper(o.Type)
+		if m.quotes[side] == nil && (side == buySide || side == sellSide) {
+			m.quotes[side] = &Quote{Side: side, OrderId: o.Id, Price: o.Price, Amount: o.Amount}
+			delete(m.unanswered, o.ClientOrderId)
+			continue
+		}
+		if err := m.cancelOrder(o.Id); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(m.unanswered, o.ClientOrderId)
+	}
+	for clientOrderId, sent := range m.unanswered {
+		if m.now().Sub(sent) > unansweredTimeout {
+			delete(m.unanswered, clientOrderId)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Remember a quote whose send failed without an answer, it may rest anyway
+func (m *Maker) sentWithoutAnswer(clientOrderId uint64, err error) {
+	if !errors.Is(err, secure.ErrNotSent) {
+		m.unanswered[clientOrderId] = m.now()
+	}
+}
+
+func (m *Maker) inventory() (float64, error) {
+	if m.Balances == nil {
+		return m.Config.TargetInventory, nil
+	}
+	r, err := m.Balances.GetBalances()
+	if err != nil {
+		return 0, err
+	}
+	if r.Error {
+		return 0, fmt.Errorf("balances failed: %s", r.ErrorMessage)
+	}
+	return float64(r.Data[strings.ToUpper(m.Pair.FirstCurrency)].Balance), nil
+}
+
+// Order book without own quotes, which would otherwise define the mid
+func (m *Maker) ownBookRemoved() public.OrderBookData {
+	book := public.OrderBookData{
+		Bids: withoutQuote(m.book.Bids, m.quotes[buySide]),
+		Asks: withoutQuote(m.book.Asks, m.quotes[sellSide]),
+	}
+	return book
+}
+
+func withoutQuote(levels []public.OrderBookAsksBids, q *Quote) []public.OrderBookAsksBids {
+	if q == nil {
+		return levels
+	}
+	result := make([]public.OrderBookAsksBids, 0, len(levels))
+	for _, l := range levels {
+		if math.Abs(l.Price-q.Price) < epsilon {
+			l.Amount -= q.Amount
+			if l.Amount <= epsilon {
+				continue
+			}
+		}
+		result = append(result, l)
+	}
+	return result
+}
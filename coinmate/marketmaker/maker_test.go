@@ -0,0 +1,256 @@
+package marketmaker
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+type gateFunc func() error
+
+func (f gateFunc) Allow() error {
+	return f()
+}
+
+func newTestMaker(t *testing.T, config Config) (*Maker, *coinmatetest.Server) {
+	t.Helper()
+	server := coinmatetest.NewServer()
+	t.Cleanup(server.Close)
+
+	server.AddAccount("1", "public-key", "private-key")
+	server.Deposit("1", "EUR", 100000)
+	server.Deposit("1", "BTC", 1)
+
+	client := server.NewClient("1")
+	m, err := NewMaker(&secure.Order{Client: client}, &secure.Balances{Client: client}, nil, coinmatetest.DefaultTradingPairs()[0], config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return m, server
+}
+
+func book(bid, ask float64) public.OrderBookData {
+	return public.OrderBookData{
+		Bids: []public.OrderBookAsksBids{{Price: bid, Amount: 1}},
+		Asks: []public.OrderBookAsksBids{{Price: ask, Amount: 1}},
+	}
+}
+
+func openQuotes(t *testing.T, m *Maker) map[string]secure.OpenOrdersData {
+	t.Helper()
+	open, err := m.Orders.GetOpenOrders("BTC_EUR")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	quotes := map[string]secure.OpenOrdersData{}
+	for _, o := range open.Data {
+		if _, ok := quotes[o.Type]; ok {
+			t.Fatalf("Expected one quote per side, got %+v", open.Data)
+		}
+		quotes[o.Type] = o
+	}
+	return quotes
+}
+
+func TestQuotesAroundMid(t *testing.T) {
+	m, _ := newTestMaker(t, Config{Spread: 0.002, Size: 0.01, RequoteThreshold: 0.001})
+
+	m.Update(book(49900, 50100))
+	if err := m.Step(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	quotes := openQuotes(t, m)
+	if quotes[buySide].Price != 49950 || quotes[sellSide].Price != 50050 || quotes[buySide].Amount != 0.01 {
+		t.Fatalf("Expected quotes 49950/50050, got %+v", quotes)
+	}
+
+	// Own quotes are part of the book but do not move the mid
+	m.Update(public.OrderBookData{
+		Bids: []public.OrderBookAsksBids{{Price: 49950, Amount: 0.01}, {Price: 49900, Amount: 1}},
+		Asks: []public.OrderBookAsksBids{{Price: 50050, Amount: 0.01}, {Price: 50100, Amount: 1}},
+	})
+	m.Step()
+	if again := openQuotes(t, m); again[buySide].Id != quotes[buySide].Id || again[sellSide].Id != quotes[sellSide].Id {
+		t.Errorf("Expected quotes kept, got %+v", again)
+	}
+	if s := m.Status(); s.Mid != 50000 || s.Bid == nil || s.Ask == nil {
+		t.Errorf("Expected mid 50000 with both quotes, got %+v", s)
+	}
+
+	// Below the re-quote threshold
+	m.Update(book(49940, 50100))
+	m.Step()
+	if again := openQuotes(t, m); again[buySide].Id != quotes[buySide].Id {
+		t.Errorf("Expected small move ignored, got %+v", again)
+	}
+
+	m.Update(book(50900, 51100))
+	if err := m.Step(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	moved := openQuotes(t, m)
+	if moved[buySide].Price != 50949 || moved[sellSide].Price != 51051 || moved[buySide].Id == quotes[buySide].Id {
+		t.Errorf("Expected quotes replaced around 51000, got %+v", moved)
+	}
+}
+
+func TestInventorySkew(t *testing.T) {
+	m, _ := newTestMaker(t, Config{Spread: 0.002, Size: 0.01, TargetInventory: 0.5, MaxInventory: 1, Skew: 0.002})
+
+	m.Update(book(49900, 50100))
+	m.Step()
+
+	// Half of the max deviation long moves both quotes 0.1 % down
+	quotes := openQuotes(t, m)
+	if math.Abs(quotes[buySide].Price-49900.05) > 1e-6 || math.Abs(quotes[sellSide].Price-49999.95) > 1e-6 {
+		t.Errorf("Expected skewed quotes 49900.05/49999.95, got %+v", quotes)
+	}
+	if inventory := m.Status().Inventory; inventory != 1 {
+		t.Errorf("Expected inventory 1, got %v", inventory)
+	}
+
+	m.Config.TargetInventory = 0
+	m.Step()
+	quotes = openQuotes(t, m)
+	if _, ok := quotes[buySide]; ok || len(quotes) != 1 {
+		t.Errorf("Expected only an ask at the max inventory, got %+v", quotes)
+	}
+}
+
+func TestFilledQuoteReplaced(t *testing.T) {
+	m, server := newTestMaker(t, Config{Spread: 0.002, Size: 0.01})
+	server.AddAccount("2", "public-key-2", "private-key-2")
+	server.Deposit("2", "BTC", 1)
+
+	m.Update(book(49900, 50100))
+	m.Step()
+	filled := openQuotes(t, m)[buySide]
+
+	taker := &secure.Order{Client: server.NewClient("2")}
+	taker.SellInstant(0.01, "BTC_EUR", 0)
+
+	m.Step()
+	quotes := openQuotes(t, m)
+	if quotes[buySide].Id == filled.Id || quotes[buySide].Price != 49950 {
+		t.Errorf("Expected new bid after fill, got %+v", quotes)
+	}
+	if inventory := m.Status().Inventory; math.Abs(inventory-1.01) > 1e-6 {
+		t.Errorf("Expected inventory 1.01, got %v", inventory)
+	}
+}
+
+func TestUnansweredQuoteAdopted(t *testing.T) {
+	m, server := newTestMaker(t, Config{Spread: 0.002, Size: 0.01})
+	// The bid rests but its response is lost
+	server.InjectFault("/buyLimit", coinmatetest.Fault{StatusCode: http.StatusGatewayTimeout, AfterHandling: true, Times: 1})
+
+	m.Update(book(49900, 50100))
+	if err := m.Step(); err == nil {
+		t.Fatal("Expected error of the lost bid")
+	}
+	if m.Status().Bid != nil {
+		t.Fatal("Expected no bid tracked without an answer")
+	}
+
+	if err := m.Step(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	quotes := openQuotes(t, m)
+	if bid := m.Status().Bid; bid == nil || bid.OrderId != quotes[buySide].Id {
+		t.Errorf("Expected resting bid adopted, got %+v and %+v", bid, quotes)
+	}
+	if quotes[buySide].ClientOrderId == 0 {
+		t.Error("Expected bid with a client order ID")
+	}
+
+	if err := m.Cancel(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if quotes := openQuotes(t, m); len(quotes) != 0 {
+		t.Errorf("Expected all quotes cancelled, got %+v", quotes)
+	}
+}
+
+func TestUnansweredQuoteCancelled(t *testing.T) {
+	m, server := newTestMaker(t, Config{Spread: 0.002, Size: 0.01})
+	server.InjectFault("/sellLimit", coinmatetest.Fault{StatusCode: http.StatusGatewayTimeout, AfterHandling: true, Times: 1})
+
+	m.Update(book(49900, 50100))
+	m.Step()
+	if len(openQuotes(t, m)) != 2 {
+		t.Fatal("Expected the lost ask resting")
+	}
+
+	// Cancelling before the next Step must not leave the untracked ask behind
+	if err := m.Cancel(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if quotes := openQuotes(t, m); len(quotes) != 0 {
+		t.Errorf("Expected all quotes cancelled, got %+v", quotes)
+	}
+}
+
+func TestStaleData(t *testing.T) {
+	m, _ := newTestMaker(t, Config{Spread: 0.002, Size: 0.01, MaxDataAge: time.Minute})
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	m.Update(book(49900, 50100))
+	m.Step()
+	if len(openQuotes(t, m)) != 2 {
+		t.Fatal("Expected two quotes")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := m.Step(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if quotes := openQuotes(t, m); len(quotes) != 0 {
+		t.Errorf("Expected quotes cancelled on stale book, got %+v", quotes)
+	}
+
+	m.Update(book(49900, 50100))
+	m.Step()
+	if len(openQuotes(t, m)) != 2 {
+		t.Error("Expected quotes placed again on fresh book")
+	}
+}
+
+func TestRiskBreachHalts(t *testing.T) {
+	m, _ := newTestMaker(t, Config{Spread: 0.002, Size: 0.01})
+	refused := error(nil)
+	m.Orders.(*secure.Order).Gate = gateFunc(func() error { return refused })
+	var halts []string
+	m.OnHalt = func(reason string) { halts = append(halts, reason) }
+
+	m.Update(book(49900, 50100))
+	m.Step()
+
+	refused = errors.New("max notional exceeded")
+	m.Update(book(50900, 51100))
+	if err := m.Step(); !errors.Is(err, secure.ErrNotSent) {
+		t.Errorf("Expected refused order, got %v", err)
+	}
+	if s := m.Status(); !s.Halted || len(halts) != 1 {
+		t.Fatalf("Expected maker halted, got %+v", s)
+	}
+	if quotes := openQuotes(t, m); len(quotes) != 0 {
+		t.Errorf("Expected all quotes cancelled, got %+v", quotes)
+	}
+
+	refused = nil
+	m.Step()
+	if len(openQuotes(t, m)) != 0 {
+		t.Error("Expected no quotes while halted")
+	}
+	m.Resume()
+	m.Step()
+	if len(openQuotes(t, m)) != 2 {
+		t.Error("Expected quotes after resume")
+	}
+}
@@ -0,0 +1,386 @@
+package matching
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	Buy  = "BUY"
+	Sell = "SELL"
+
+	StatusOpen            = "OPEN"
+	StatusPartiallyFilled = "PARTIALLY_FILLED"
+	StatusFilled          = "FILLED"
+	StatusCancelled       = "CANCELLED"
+
+	Limit  = "LIMIT"
+	Market = "MARKET"
+
+	// Amounts below are treated as zero
+	Epsilon = 1e-12
+)
+
+// Returned when an account cannot cover an order, the message matches the
+// Coinmate API error
+var ErrInsufficientBalance = errors.New("Not enough account balance available")
+
+// Balance of one currency
+type Balance struct {
+	Total    float64
+	Reserved float64
+}
+
+// Balances of one account, shared by the API emulator, the paper trader and
+// the backtest broker
+type Account struct {
+	// Owner of the balances, e.g. the client ID
+	Id string
+	// Balances are neither checked nor moved, e.g. for market liquidity
+	Unlimited bool
+
+	balances map[string]*Balance
+}
+
+// Order of an account. Buys reserve quote currency, sells base currency;
+// Settle releases the reservation as the order fills.
+type Order struct {
+	Id            uint64
+	ClientOrderId uint64
+	Account       *Account
+	Pair          string
+	Base          string
+	Quote         string
+	Side          string
+	Type          string
+	Price         float64
+	StopPrice     float64
+	Original      float64
+	Remaining     float64
+	Hidden        bool
+	Status        string
+	Timestamp     int64
+	// Funds still reserved and reserved amount per filled unit, buys without
+	// it release the cost of each fill instead
+	Reserved       float64
+	ReservePerUnit float64
+}
+
+// Execution of an order
+type Fill struct {
+	OrderId       uint64  `json:"orderId"`
+	ClientOrderId uint64  `json:"clientOrderId"`
+	CurrencyPair  string  `json:"currencyPair"`
+	Side          string  `json:"side"`
+	Price         float64 `json:"price"`
+	Amount        float64 `json:"amount"`
+	Fee           float64 `json:"fee"`
+	FeeCurrency   string  `json:"feeCurrency"`
+	Maker         bool    `json:"maker"`
+	Timestamp     int64   `json:"timestamp"`
+}
+
+// Resting orders of a pair in price-time priority, best price first
+type Book struct {
+	Bids []*Order
+	Asks []*Order
+}
+
+// Return account with initial balances, e.g. {"EUR": 10000}
+func NewAccount(id string, balances map[string]float64) *Account {
+	a := &Account{Id: id, balances: make(map[string]*Balance)}
+	for currency, amount := range balances {
+		a.Balance(strings.ToUpper(currency)).Total = amount
+	}
+	return a
+}
+
+// Return balance of a currency, created when missing
+func (a *Account) Balance(currency string) *Balance {
+	if a.balances == nil {
+		a.balances = make(map[string]*Balance)
+	}
+	b, ok := a.balances[currency]
+	if !ok {
+		b = &Balance{}
+		a.balances[currency] = b
+	}
+	return b
+}
+
+// Return balance not reserved by orders
+func (a *Account) Available(currency string) float64 {
+	if a.Unlimited {
+		return math.MaxFloat64
+	}
+	b := a.Balance(currency)
+	return b.Total - b.Reserved
+}
+
+// Return total balance per currency
+func (a *Account) Totals() map[string]float64 {
+	totals := make(map[string]float64, len(a.balances))
+	for currency, b := range a.balances {
+		totals[currency] = b.Total
+	}
+	return totals
+}
+
+// Return balances as served by the balances endpoint
+func (a *Account) Data() map[string]secure.BalanceCurrency {
+	data := map[string]secure.BalanceCurrency{}
+	for currency, b := range a.balances {
+		data[currency] = secure.BalanceCurrency{
+			Currency:  currency,
+			Balance:   float32(b.Total),
+			Reserved:  float32(b.Reserved),
+			Available: float32(b.Total - b.Reserved),
+		}
+	}
+	return data
+}
+
+// Return order of the account, timestamp in Unix milliseconds
+func (a *Account) NewOrder(id uint64, side, currencyPair, orderType string, clientOrderId uint64, timestamp int64) (*Order, error) {
+	base, quote, err := SplitPair(currencyPair)
+	if err != nil {
+		return nil, err
+	}
+	return &Order{
+		Id:            id,
+		ClientOrderId: clientOrderId,
+		Account:       a,
+		Pair:          base + "_" + quote,
+		Base:          base,
+		Quote:         quote,
+		Side:          side,
+		Type:          orderType,
+		Status:        StatusOpen,
+		Timestamp:     timestamp,
+	}, nil
+}
+
+// Order is open or partially filled
+func (o *Order) Open() bool {
+	return o.Status == StatusOpen || o.Status == StatusPartiallyFilled
+}
+
+// Return currency the order spends
+func (o *Order) Spends() string {
+	if o.Side == Buy {
+		return o.Quote
+	}
+	return o.Base
+}
+
+// Account can spend amount on the order
+func (o *Order) Affords(amount float64) bool {
+	return o.Account.Available(o.Spends()) >= amount-Epsilon
+}
+
+// Reserve amount for the order, released by perUnit for every filled unit.
+// Unlimited accounts reserve nothing.
+func (o *Order) Reserve(amount, perUnit float64) error {
+	if !o.Affords(amount) {
+		return ErrInsufficientBalance
+	}
+	if o.Account.Unlimited {
+		return nil
+	}
+	o.Account.Balance(o.Spends()).Reserved += amount
+	o.Reserved += amount
+	o.ReservePerUnit = perUnit
+	return nil
+}
+
+// Release funds reserved for the order
+func (o *Order) Release(amount float64) {
+	if amount <= 0 {
+		return
+	}
+	b := o.Account.Balance(o.Spends())
+	b.Reserved -= amount
+	if b.Reserved < Epsilon {
+		b.Reserved = 0
+	}
+	o.Reserved -= amount
+	if o.Reserved < Epsilon {
+		o.Reserved = 0
+	}
+}
+
+// Move the funds of a fill, fee in the quote currency, and update the order
+// state. The whole reservation is released once the order is filled.
+func (o *Order) Settle(amount, price, fee float64, maker bool, timestamp int64) Fill {
+	if !o.Account.Unlimited {
+		base, quote := o.Account.Balance(o.Base), o.Account.Balance(o.Quote)
+		release := amount * o.ReservePerUnit
+		if o.Side == Buy {
+			base.Total += amount
+			quote.Total -= amount*price + fee
+			if o.ReservePerUnit == 0 {
+				release = amount*price + fee
+			}
+		} else {
+			base.Total -= amount
+			quote.Total += amount*price - fee
+		}
+		o.Release(math.Min(o.Reserved, release))
+	}
+
+	o.Remaining -= amount
+	if o.Remaining <= Epsilon {
+		o.Remaining = 0
+		o.Status = StatusFilled
+		o.Release(o.Reserved)
+	} else {
+		o.Status = StatusPartiallyFilled
+	}
+
+	return Fill{
+		OrderId:       o.Id,
+		ClientOrderId: o.ClientOrderId,
+		CurrencyPair:  o.Pair,
+		Side:          o.Side,
+		Price:         price,
+		Amount:        amount,
+		Fee:           fee,
+		FeeCurrency:   o.Quote,
+		Maker:         maker,
+		Timestamp:     timestamp,
+	}
+}
+
+// Cancel the order and release its funds
+func (o *Order) Cancel() {
+	o.Release(o.Reserved)
+	o.Status = StatusCancelled
+}
+
+// Close a market order after it took liquidity, the original amount becomes
+// the executed one and orders executing nothing are cancelled
+func (o *Order) Complete() {
+	o.Original -= o.Remaining
+	o.Remaining = 0
+	o.Status = StatusFilled
+	if o.Original <= Epsilon {
+		o.Status = StatusCancelled
+	}
+	o.Release(o.Reserved)
+}
+
+// Limit price allows trading at price
+func (o *Order) Crosses(price float64) bool {
+	if o.Side == Buy {
+		return price <= o.Price
+	}
+	return price >= o.Price
+}
+
+// Stop price is reached by a trade at price
+func (o *Order) StopReached(price float64) bool {
+	if o.Side == Buy {
+		return price >= o.StopPrice
+	}
+	return price <= o.StopPrice
+}
+
+// Resting order fills against a public trade: a sell-initiated trade hits
+// bids, a buy-initiated trade lifts asks
+func (o *Order) FilledBy(tx public.TransactionsData) bool {
+	return tx.TradeType != o.Side && o.Crosses(tx.Price)
+}
+
+// Return order as served by the order history endpoints
+func (o *Order) HistoryData() secure.OrderHistoryData {
+	return secure.OrderHistoryData{
+		Id:              o.Id,
+		Timestamp:       o.Timestamp,
+		Type:            o.Side,
+		CurrencyPair:    o.Pair,
+		Price:           o.Price,
+		RemainingAmount: o.Remaining,
+		OriginalAmount:  o.Original,
+		Status:          o.Status,
+		StopPrice:       o.StopPrice,
+		OrderTradeType:  o.Type,
+		Hidden:          o.Hidden,
+		ClientOrderId:   o.ClientOrderId,
+	}
+}
+
+// Return order as served by the open orders endpoint
+func (o *Order) OpenData() secure.OpenOrdersData {
+	return secure.OpenOrdersData{
+		Id:             o.Id,
+		Timestamp:      o.Timestamp,
+		Type:           o.Side,
+		CurrencyPair:   o.Pair,
+		Price:          o.Price,
+		Amount:         o.Remaining,
+		OrderTradeType: o.Type,
+		StopPrice:      o.StopPrice,
+		Hidden:         o.Hidden,
+		ClientOrderId:  o.ClientOrderId,
+	}
+}
+
+// Rest order behind orders of the same price
+func (b *Book) Insert(o *Order) {
+	if o.Side == Buy {
+		b.Bids = append(b.Bids, o)
+		sort.SliceStable(b.Bids, func(i, j int) bool { return b.Bids[i].Price > b.Bids[j].Price })
+		return
+	}
+	b.Asks = append(b.Asks, o)
+	sort.SliceStable(b.Asks, func(i, j int) bool { return b.Asks[i].Price < b.Asks[j].Price })
+}
+
+// Remove resting order
+func (b *Book) Remove(o *Order) {
+	levels := &b.Asks
+	if o.Side == Buy {
+		levels = &b.Bids
+	}
+	for i, resting := range *levels {
+		if resting == o {
+			*levels = append((*levels)[:i], (*levels)[i+1:]...)
+			return
+		}
+	}
+}
+
+// Return resting orders an incoming order of the given side trades against
+func (b *Book) Opposite(side string) []*Order {
+	if side == Buy {
+		return b.Asks
+	}
+	return b.Bids
+}
+
+// Return copy of the snapshot levels an order of the given side trades
+// against, best price first
+func Levels(book public.OrderBookData, side string) []public.OrderBookAsksBids {
+	if side == Buy {
+		asks := append([]public.OrderBookAsksBids(nil), book.Asks...)
+		sort.SliceStable(asks, func(i, j int) bool { return asks[i].Price < asks[j].Price })
+		return asks
+	}
+	bids := append([]public.OrderBookAsksBids(nil), book.Bids...)
+	sort.SliceStable(bids, func(i, j int) bool { return bids[i].Price > bids[j].Price })
+	return bids
+}
+
+// Split currency pair like BTC_EUR into base and quote currency
+func SplitPair(currencyPair string) (string, string, error) {
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(currencyPair)), "_")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid currencyPair %q", currencyPair)
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,782 @@
+package oms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate/idempotent"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	buySide         = "BUY"
+	sellSide        = "SELL"
+	limitOrderType  = "LIMIT"
+	marketOrderType = "MARKET"
+
+	defaultHistoryLimit   = 100
+	defaultUnknownTimeout = 5 * time.Minute
+	tradeHistoryPageSize  = 1000
+
+	// Tolerated difference between local and exchange clocks when matching
+	// orders without an exchange ID
+	clockSkew = time.Minute
+
+	epsilon = 1e-9
+)
+
+var (
+	ErrUnknownOrder    = errors.New("unknown order")
+	ErrNotAcknowledged = errors.New("order not acknowledged by the exchange yet")
+	ErrOrderCompleted  = errors.New("order already completed")
+)
+
+// Execution of a tracked order, from the trade history
+type Fill struct {
+	TransactionId uint64  `json:"transactionId"`
+	Price         float64 `json:"price"`
+	Amount        float64 `json:"amount"`
+	Fee           float64 `json:"fee"`
+	FeeType       string  `json:"feeType"`
+	Timestamp     int64   `json:"timestamp"`
+}
+
+// Order tracked by the manager. LocalId identifies the order from the moment
+// it is created, OrderId once the exchange has acknowledged it.
+type Order struct {
+	LocalId           uint64  `json:"localId"`
+	OrderId           uint64  `json:"orderId"`
+	ClientOrderId     uint64  `json:"clientOrderId"`
+	CurrencyPair      string  `json:"currencyPair"`
+	Side              string  `json:"side"`
+	OrderType         string  `json:"orderType"`
+	Price             float64 `json:"price"`
+	StopPrice         float64 `json:"stopPrice"`
+	Amount            float64 `json:"amount"` // unknown for instant buys until executed
+	Total             float64 `json:"total"`  // quote total of instant buys
+	Hidden            bool    `json:"hidden"`
+	ImmediateOrCancel bool    `json:"immediateOrCancel"`
+
+	State        State   `json:"state"`
+	FilledAmount float64 `json:"filledAmount"`
+	Fills        []Fill  `json:"fills"`
+	Error        string  `json:"error"`
+	CreatedAt    int64   `json:"createdAt"`
+	UpdatedAt    int64   `json:"updatedAt"`
+}
+
+// Amount still to be executed
+func (o Order) RemainingAmount() float64 {
+	if o.State.Terminal() {
+		return 0
+	}
+	return math.Max(0, o.Amount-o.FilledAmount)
+}
+
+// Volume weighted price of known fills, 0 without fills
+func (o Order) AveragePrice() float64 {
+	amount, notional := 0.0, 0.0
+	for _, f := range o.Fills {
+		amount += f.Amount
+		notional += f.Amount * f.Price
+	}
+	if amount == 0 {
+		return 0
+	}
+	return notional / amount
+}
+
+// Sum of fees of known fills
+func (o Order) Fees() float64 {
+	fees := 0.0
+	for _, f := range o.Fills {
+		fees += f.Fee
+	}
+	return fees
+}
+
+// Lifecycle event. Fill is set when the event reports a new execution, the
+// state may stay the same in that case.
+type Event struct {
+	Order    Order
+	Previous State
+	Fill     *Fill
+}
+
+// Manager places orders through an order interface and tracks each of them
+// through its lifecycle. Every order is persisted before the request is
+// sent, so an order whose request was interrupted, or which was still
+// pending when the process stopped, is found again by Reconcile.
+//
+// Reconcile compares tracked orders with open orders, order history and,
+// when Trades is set, trade history, and moves them to the state reported
+// by the exchange. Transitions and fills are passed to OnEvent.
+type Manager struct {
+	Orders secure.OrderInterface
+	Trades secure.TradeHistoryInterface
+	Store  Store
+
+	// Called for every transition and fill, outside of the manager lock
+	OnEvent func(Event)
+
+	// Orders requested from order history per pair when reconciling
+	HistoryLimit int64
+
+	// Orders without exchange ID not found after this time since creation
+	// are considered rejected
+	UnknownTimeout time.Duration
+
+	// Generates client order IDs for orders placed without one, which lets
+	// reconciliation identify orders whose response was lost
+	ClientOrderIds *idempotent.Generator
+
+	mu          sync.Mutex
+	orders      map[uint64]*Order
+	inFlight    map[uint64]bool
+	nextLocalId uint64
+	now         func() time.Time
+}
+
+// Return manager with orders recovered from store, which may be nil
+func NewManager(orders secure.OrderInterface, trades secure.TradeHistoryInterface, store Store) (*Manager, error) {
+	m := &Manager{
+		Orders:   orders,
+		Trades:   trades,
+		Store:    store,
+		orders:   make(map[uint64]*Order),
+		inFlight: make(map[uint64]bool),
+		now:      time.Now,
+	}
+	if store == nil {
+		return m, nil
+	}
+
+	recovered, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range recovered {
+		o := recovered[i]
+		m.orders[o.LocalId] = &o
+		if o.LocalId > m.nextLocalId {
+			m.nextLocalId = o.LocalId
+		}
+	}
+	return m, nil
+}
+
+// Place buy limit order
+func (m *Manager) BuyLimit(amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (Order, error) {
+	clientOrderId = m.clientOrderId(clientOrderId)
+	o := limitOrder(buySide, amount, price, stopPrice, currencyPair, hidden, immediateOrCancel, clientOrderId)
+	return m.submit(o, func() (uint64, bool, string, error) {
+		r, err := m.Orders.BuyLimit(amount, price, stopPrice, currencyPair, hidden, immediateOrCancel, clientOrderId)
+		return r.OrderId, r.Error, r.ErrorMessage, err
+	})
+}
+
+// Place sell limit order
+func (m *Manager) SellLimit(amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (Order, error) {
+	clientOrderId = m.clientOrderId(clientOrderId)
+	o := limitOrder(sellSide, amount, price, stopPrice, currencyPair, hidden, immediateOrCancel, clientOrderId)
+	return m.submit(o, func() (uint64, bool, string, error) {
+		r, err := m.Orders.SellLimit(amount, price, stopPrice, currencyPair, hidden, immediateOrCancel, clientOrderId)
+		return r.OrderId, r.Error, r.ErrorMessage, err
+	})
+}
+
+// Buy instantly for a total in the quote currency
+func (m *Manager) BuyInstant(total float64, currencyPair string, clientOrderId uint64) (Order, error) {
+	clientOrderId = m.clientOrderId(clientOrderId)
+	o := Order{Side: buySide, OrderType: marketOrderType, CurrencyPair: strings.ToUpper(currencyPair), Total: total, ClientOrderId: clientOrderId}
+	return m.submit(o, func() (uint64, bool, string, error) {
+		r, err := m.Orders.BuyInstant(total, currencyPair, clientOrderId)
+		return r.OrderId, r.Error, r.ErrorMessage, err
+	})
+}
+
+// Sell instantly an amount in the base currency
+func (m *Manager) SellInstant(amount float64, currencyPair string, clientOrderId uint64) (Order, error) {
+	clientOrderId = m.clientOrderId(clientOrderId)
+	o := Order{Side: sellSide, OrderType: marketOrderType, CurrencyPair: strings.ToUpper(currencyPair), Amount: amount, ClientOrderId: clientOrderId}
+	return m.submit(o, func() (uint64, bool, string, error) {
+		r, err := m.Orders.SellInstant(amount, currencyPair, clientOrderId)
+		return r.OrderId, r.Error, r.ErrorMessage, err
+	})
+}
+
+// Cancel order. A cancel the exchange does not confirm leaves the order
+// untouched, the next Reconcile finds out whether it was filled meanwhile.
+func (m *Manager) Cancel(localId uint64) (Order, error) {
+	m.mu.Lock()
+	o, ok := m.orders[localId]
+	if !ok {
+		m.mu.Unlock()
+		return Order{}, ErrUnknownOrder
+	}
+	if o.State.Terminal() {
+		current := o.copy()
+		m.mu.Unlock()
+		return current, ErrOrderCompleted
+	}
+	if o.OrderId == 0 {
+		current := o.copy()
+		m.mu.Unlock()
+		return current, ErrNotAcknowledged
+	}
+	orderId := o.OrderId
+	m.mu.Unlock()
+
+	response, err := m.Orders.CancelOrderWithInfo(orderId)
+	if err != nil {
+		return m.order(localId), fmt.Errorf("cancel of order %d failed: %w", orderId, err)
+	}
+	if response.Error {
+		return m.order(localId), fmt.Errorf("cancel of order %d failed: %s", orderId, response.ErrorMessage)
+	}
+	if !response.Data.Success {
+		return m.order(localId), fmt.Errorf("cancel of order %d was not accepted", orderId)
+	}
+
+	m.mu.Lock()
+	var events []Event
+	if o.Amount > 0 {
+		o.FilledAmount = math.Max(o.FilledAmount, o.Amount-response.Data.RemainingAmount)
+	}
+	m.transition(o, StateCancelled, &events)
+	err = m.save()
+	current := o.copy()
+	m.mu.Unlock()
+
+	m.emit(events)
+	return current, err
+}
+
+// Return tracked order by local ID
+func (m *Manager) Order(localId uint64) (Order, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.orders[localId]
+	if !ok {
+		return Order{}, false
+	}
+	return o.copy(), true
+}
+
+// Return tracked order by exchange order ID
+func (m *Manager) OrderById(orderId uint64) (Order, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, o := range m.orders {
+		if o.OrderId == orderId && orderId != 0 {
+			return o.copy(), true
+		}
+	}
+	return Order{}, false
+}
+
+// Return all tracked orders, oldest first
+func (m *Manager) All() []Order {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sorted(func(o *Order) bool { return true })
+}
+
+// Return orders not in a terminal state, oldest first
+func (m *Manager) Active() []Order {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sorted(func(o *Order) bool { return !o.State.Terminal() })
+}
+
+// Forget orders in a terminal state last updated before t, returns how many
+// were removed
+func (m *Manager) Prune(t time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for id, o := range m.orders {
+		if o.State.Terminal() && o.UpdatedAt < t.UnixMilli() {
+			delete(m.orders, id)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, m.save()
+}
+
+// Bring tracked orders in line with the exchange
+func (m *Manager) Reconcile() error {
+	m.mu.Lock()
+	candidates := map[uint64]bool{}
+	since := int64(math.MaxInt64)
+	for id, o := range m.orders {
+		if m.inFlight[id] || !m.needsReconcile(o) {
+			continue
+		}
+		candidates[id] = true
+		since = min(since, o.CreatedAt)
+	}
+	m.mu.Unlock()
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	view, err := m.fetch(since)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	var events []Event
+	claimed := map[uint64]bool{}
+	for _, o := range m.orders {
+		if o.OrderId != 0 {
+			claimed[o.OrderId] = true
+		}
+	}
+	for _, o := range m.sortedPointers() {
+		if candidates[o.LocalId] && !m.inFlight[o.LocalId] {
+			m.reconcileOrder(o, view, claimed, &events)
+		}
+	}
+	err = m.save()
+	m.mu.Unlock()
+
+	m.emit(events)
+	return err
+}
+
+// Reconcile every interval until ctx is cancelled, errors are passed to onError
+func (m *Manager) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := m.Reconcile(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Helper functions
+
+// Order as reported by the exchange, from open orders or order history
+type exchangeOrder struct {
+	id            uint64
+	clientOrderId uint64
+	pair          string
+	side          string
+	orderType     string
+	price         float64
+	original      float64
+	remaining     float64
+	status        string
+	timestamp     int64
+}
+
+// Exchange state fetched for one reconciliation
+type exchangeView struct {
+	open    map[uint64]exchangeOrder
+	history map[uint64]exchangeOrder
+	fills   map[uint64][]secure.TradeHistoryData
+	pairs   map[string]bool
+}
+
+func limitOrder(side string, amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) Order {
+	return Order{
+		Side:              side,
+		OrderType:         limitOrderType,
+		CurrencyPair:      strings.ToUpper(currencyPair),
+		Amount:            amount,
+		Price:             price,
+		StopPrice:         stopPrice,
+		Hidden:            hidden,
+		ImmediateOrCancel: immediateOrCancel,
+		ClientOrderId:     clientOrderId,
+	}
+}
+
+func (m *Manager) submit(o Order, send func() (uint64, bool, string, error)) (Order, error) {
+	m.mu.Lock()
+	m.nextLocalId++
+	o.LocalId = m.nextLocalId
+	o.State = StatePendingNew
+	o.CreatedAt = m.now().UnixMilli()
+	o.UpdatedAt = o.CreatedAt
+	m.orders[o.LocalId] = &o
+
+	// Never send an order that could not be recovered after a crash
+	if err := m.save(); err != nil {
+		delete(m.orders, o.LocalId)
+		m.mu.Unlock()
+		return Order{}, err
+	}
+	m.inFlight[o.LocalId] = true
+	m.mu.Unlock()
+
+	orderId, rejected, message, sendErr := send()
+
+	m.mu.Lock()
+	delete(m.inFlight, o.LocalId)
+	var events []Event
+	switch {
+	case errors.Is(sendErr, secure.ErrNotSent):
+		o.Error = sendErr.Error()
+		m.transition(&o, StateRejected, &events)
+	case sendErr != nil:
+		o.Error = sendErr.Error()
+		m.transition(&o, StateUnknown, &events)
+	case rejected:
+		o.Error = message
+		m.transition(&o, StateRejected, &events)
+	default:
+		o.OrderId = orderId
+		m.transition(&o, StateOpen, &events)
+	}
+	err := m.save()
+	current := o.copy()
+	m.mu.Unlock()
+
+	m.emit(events)
+	if errors.Is(sendErr, secure.ErrNotSent) {
+		return current, sendErr
+	}
+	if sendErr != nil {
+		return current, fmt.Errorf("order %d state unknown: %w", current.LocalId, sendErr)
+	}
+	return current, err
+}
+
+func (m *Manager) needsReconcile(o *Order) bool {
+	if !o.State.Terminal() {
+		return true
+	}
+	// Completed orders stay until their fills are known
+	return m.Trades != nil && o.OrderId != 0 && o.State != StateRejected && o.filledFromTrades() < o.FilledAmount-epsilon
+}
+
+func (m *Manager) fetch(since int64) (exchangeView, error) {
+	view := exchangeView{
+		open:    map[uint64]exchangeOrder{},
+		history: map[uint64]exchangeOrder{},
+		fills:   map[uint64][]secure.TradeHistoryData{},
+		pairs:   map[string]bool{},
+	}
+
+	open, err := m.Orders.GetOpenOrders("")
+	if err != nil {
+		return view, err
+	}
+	if open.Error {
+		return view, fmt.Errorf("open orders request failed: %s", open.ErrorMessage)
+	}
+	for _, d := range open.Data {
+		view.open[d.Id] = exchangeOrder{
+			id:            d.Id,
+			clientOrderId: d.ClientOrderId,
+			pair:          strings.ToUpper(d.CurrencyPair),
+			side:          d.Type,
+			orderType:     d.OrderTradeType,
+			price:         d.Price,
+			remaining:     d.Amount,
+			status:        "OPEN",
+			timestamp:     d.Timestamp,
+		}
+	}
+
+	m.mu.Lock()
+	for _, o := range m.orders {
+		if m.needsReconcile(o) {
+			view.pairs[o.CurrencyPair] = true
+		}
+	}
+	m.mu.Unlock()
+
+	pairs := make([]string, 0, len(view.pairs))
+	for pair := range view.pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+	for _, pair := range pairs {
+		history, err := m.Orders.GetHistory(pair, m.historyLimit())
+		if err != nil {
+			return view, err
+		}
+		if history.Error {
+			return view, fmt.Errorf("order history request failed: %s", history.ErrorMessage)
+		}
+		for _, d := range history.Data {
+			view.history[d.Id] = exchangeOrder{
+				id:            d.Id,
+				clientOrderId: d.ClientOrderId,
+				pair:          pair,
+				side:          d.Type,
+				orderType:     d.OrderTradeType,
+				price:         d.Price,
+				original:      d.OriginalAmount,
+				remaining:     d.RemainingAmount,
+				status:        d.Status,
+				timestamp:     d.Timestamp,
+			}
+		}
+	}
+
+	if m.Trades == nil {
+		return view, nil
+	}
+	params := secure.TradeHistoryParams{
+		TimestampFrom: since - clockSkew.Milliseconds(),
+		Sort:          "ASC",
+		Limit:         tradeHistoryPageSize,
+	}
+	for {
+		trades, err := m.Trades.GetTradeHistory(params)
+		if err != nil {
+			return view, err
+		}
+		if trades.Error {
+			return view, fmt.Errorf("trade history request failed: %s", trades.ErrorMessage)
+		}
+		for _, t := range trades.Data {
+			view.fills[t.OrderId] = append(view.fills[t.OrderId], t)
+			params.LastId = max(params.LastId, t.TransactionId)
+		}
+		if len(trades.Data) < tradeHistoryPageSize {
+			return view, nil
+		}
+	}
+}
+
+func (m *Manager) reconcileOrder(o *Order, view exchangeView, claimed map[uint64]bool, events *[]Event) {
+	if o.OrderId == 0 {
+		match, ok := m.identify(o, view, claimed)
+		if !ok {
+			if m.now().Sub(time.UnixMilli(o.CreatedAt)) > m.unknownTimeout() {
+				o.Error = "order not found on exchange"
+				m.transition(o, StateRejected, events)
+			} else {
+				m.transition(o, StateUnknown, events)
+			}
+			return
+		}
+		o.OrderId = match.id
+		claimed[match.id] = true
+	}
+
+	// Fills first, so events report them before the state they lead to
+	for _, t := range view.fills[o.OrderId] {
+		if o.hasFill(t.TransactionId) {
+			continue
+		}
+		fill := Fill{
+			TransactionId: t.TransactionId,
+			Price:         t.Price,
+			Amount:        t.Amount,
+			Fee:           t.Fee,
+			FeeType:       t.FeeType,
+			Timestamp:     t.CreatedTimestamp,
+		}
+		previous := o.State
+		o.Fills = append(o.Fills, fill)
+		o.FilledAmount = math.Max(o.FilledAmount, o.filledFromTrades())
+		o.UpdatedAt = m.now().UnixMilli()
+		*events = append(*events, Event{Order: o.copy(), Previous: previous, Fill: &fill})
+	}
+
+	state, filled, found := exchangeState(o, view)
+	if !found {
+		// Neither open nor in recent history
+		if !o.State.Terminal() {
+			m.transition(o, StateUnknown, events)
+		}
+		return
+	}
+	if o.Amount == 0 && state.Terminal() {
+		o.Amount = filled
+	}
+	o.FilledAmount = math.Max(o.FilledAmount, filled)
+	if state == StateOpen && o.FilledAmount > epsilon {
+		state = StatePartiallyFilled
+	}
+	m.transition(o, state, events)
+}
+
+// State and executed amount of an order according to the exchange
+func exchangeState(o *Order, view exchangeView) (State, float64, bool) {
+	if e, ok := view.open[o.OrderId]; ok {
+		filled := 0.0
+		if o.Amount > 0 {
+			filled = math.Max(0, o.Amount-e.remaining)
+		}
+		return StateOpen, filled, true
+	}
+	if e, ok := view.history[o.OrderId]; ok {
+		state, known := stateFromStatus(e.status)
+		if !known {
+			return StateUnknown, 0, true
+		}
+		// Open in history but not in open orders, it completed in between
+		if state == StateOpen || state == StatePartiallyFilled {
+			return StateUnknown, math.Max(0, e.original-e.remaining), true
+		}
+		return state, math.Max(0, e.original-e.remaining), true
+	}
+	return "", 0, false
+}
+
+// Find the exchange order created by a request whose response was lost
+func (m *Manager) identify(o *Order, view exchangeView, claimed map[uint64]bool) (exchangeOrder, bool) {
+	var candidates []exchangeOrder
+	for _, source := range []map[uint64]exchangeOrder{view.history, view.open} {
+		for id, e := range source {
+			if claimed[id] || e.pair != o.CurrencyPair || e.side != o.Side {
+				continue
+			}
+			if o.ClientOrderId != 0 {
+				if e.clientOrderId == o.ClientOrderId {
+					candidates = append(candidates, e)
+				}
+				continue
+			}
+			if e.timestamp < o.CreatedAt-clockSkew.Milliseconds() || (e.orderType != "" && e.orderType != o.OrderType) {
+				continue
+			}
+			switch {
+			case o.OrderType == limitOrderType && !sameAmount(e.price, o.Price):
+				continue
+			case o.Amount > 0 && e.original > 0 && !sameAmount(e.original, o.Amount):
+				continue
+			case o.Amount > 0 && e.original == 0 && e.remaining > o.Amount+epsilon:
+				continue
+			}
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		return exchangeOrder{}, false
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].timestamp != candidates[j].timestamp {
+			return candidates[i].timestamp < candidates[j].timestamp
+		}
+		return candidates[i].id < candidates[j].id
+	})
+	return candidates[0], true
+}
+
+func sameAmount(a, b float64) bool {
+	return math.Abs(a-b) <= epsilon*math.Max(1, math.Abs(b))
+}
+
+// Move order to the next state, invalid transitions are ignored
+func (m *Manager) transition(o *Order, next State, events *[]Event) {
+	if o.State == next || !o.State.CanTransition(next) {
+		return
+	}
+	previous := o.State
+	o.State = next
+	o.UpdatedAt = m.now().UnixMilli()
+	*events = append(*events, Event{Order: o.copy(), Previous: previous})
+}
+
+func (m *Manager) emit(events []Event) {
+	if m.OnEvent == nil {
+		return
+	}
+	for _, e := range events {
+		m.OnEvent(e)
+	}
+}
+
+func (m *Manager) clientOrderId(id uint64) uint64 {
+	if id == 0 && m.ClientOrderIds != nil {
+		return m.ClientOrderIds.Next()
+	}
+	return id
+}
+
+func (m *Manager) save() error {
+	if m.Store == nil {
+		return nil
+	}
+	return m.Store.Save(m.sorted(func(o *Order) bool { return true }))
+}
+
+func (m *Manager) order(localId uint64) Order {
+	o, _ := m.Order(localId)
+	return o
+}
+
+func (m *Manager) sortedPointers() []*Order {
+	orders := make([]*Order, 0, len(m.orders))
+	for _, o := range m.orders {
+		orders = append(orders, o)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].LocalId < orders[j].LocalId })
+	return orders
+}
+
+func (m *Manager) sorted(keep func(o *Order) bool) []Order {
+	var orders []Order
+	for _, o := range m.sortedPointers() {
+		if keep(o) {
+			orders = append(orders, o.copy())
+		}
+	}
+	return orders
+}
+
+func (m *Manager) historyLimit() int64 {
+	if m.HistoryLimit <= 0 {
+		return defaultHistoryLimit
+	}
+	return m.HistoryLimit
+}
+
+func (m *Manager) unknownTimeout() time.Duration {
+	if m.UnknownTimeout <= 0 {
+		return defaultUnknownTimeout
+	}
+	return m.UnknownTimeout
+}
+
+func (o *Order) copy() Order {
+	c := *o
+	c.Fills = append([]Fill(nil), o.Fills...)
+	return c
+}
+
+func (o *Order) hasFill(transactionId uint64) bool {
+	for _, f := range o.Fills {
+		if f.TransactionId == transactionId {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Order) filledFromTrades() float64 {
+	filled := 0.0
+	for _, f := range o.Fills {
+		filled += f.Amount
+	}
+	return filled
+}
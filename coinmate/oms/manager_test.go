@@ -0,0 +1,255 @@
+package oms
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/idempotent"
+	"tourGo/coinmate/secure"
+)
+
+func newTestManager(t *testing.T, server *coinmatetest.Server, store Store) (*Manager, *[]Event) {
+	t.Helper()
+	client := server.NewClient("1")
+	m, err := NewManager(&secure.Order{Client: client}, &secure.TradeHistory{Client: client}, store)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	events := &[]Event{}
+	m.OnEvent = func(e Event) { *events = append(*events, e) }
+	return m, events
+}
+
+func states(events []Event) []State {
+	var result []State
+	for _, e := range events {
+		if e.Fill == nil {
+			result = append(result, e.Order.State)
+		}
+	}
+	return result
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	m, events := newTestManager(t, server, nil)
+
+	o, err := m.BuyLimit(0.1, 49000, 0, "BTC_EUR", false, false, 0)
+	if err != nil || o.State != StateOpen || o.OrderId == 0 {
+		t.Fatalf("Expected open order, got %+v (%v)", o, err)
+	}
+
+	server.AddLiquidity("BTC_EUR", "SELL", 49000, 0.04)
+	if err := m.Reconcile(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	o, _ = m.Order(o.LocalId)
+	if o.State != StatePartiallyFilled || math.Abs(o.FilledAmount-0.04) > 1e-9 || len(o.Fills) != 1 {
+		t.Fatalf("Expected partial fill of 0.04, got %+v", o)
+	}
+	if math.Abs(o.RemainingAmount()-0.06) > 1e-9 || o.AveragePrice() != 49000 {
+		t.Errorf("Unexpected remaining %f or average price %f", o.RemainingAmount(), o.AveragePrice())
+	}
+
+	o, err = m.Cancel(o.LocalId)
+	if err != nil || o.State != StateCancelled {
+		t.Fatalf("Expected cancelled order, got %+v (%v)", o, err)
+	}
+	if _, err := m.Cancel(o.LocalId); !errors.Is(err, ErrOrderCompleted) {
+		t.Errorf("Expected completed order error, got %v", err)
+	}
+
+	expected := []State{StateOpen, StatePartiallyFilled, StateCancelled}
+	if got := states(*events); len(got) != 3 || got[0] != expected[0] || got[1] != expected[1] || got[2] != expected[2] {
+		t.Errorf("Expected transitions %v, got %v", expected, got)
+	}
+	if fills := len(*events) - 3; fills != 1 {
+		t.Errorf("Expected one fill event, got %d", fills)
+	}
+	if len(m.Active()) != 0 || len(m.All()) != 1 {
+		t.Error("Expected no active orders left")
+	}
+}
+
+func TestRejectedOrder(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	m, _ := newTestManager(t, server, nil)
+
+	o, err := m.BuyLimit(1, 49000, 0, "BTC_EUR", false, false, 0)
+	if err != nil {
+		t.Fatalf("Expected no transport error, got %v", err)
+	}
+	if o.State != StateRejected || o.Error == "" {
+		t.Errorf("Expected rejected order with reason, got %+v", o)
+	}
+}
+
+func TestInstantOrderIsFilledOnReconcile(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	server.AddLiquidity("BTC_EUR", "SELL", 50000, 1)
+	m, _ := newTestManager(t, server, nil)
+
+	o, _ := m.BuyInstant(5000, "BTC_EUR", 0)
+	if err := m.Reconcile(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	o, _ = m.OrderById(o.OrderId)
+	if o.State != StateFilled || math.Abs(o.Amount-0.1) > 1e-9 || math.Abs(o.FilledAmount-0.1) > 1e-9 {
+		t.Errorf("Expected instant buy of 0.1 BTC filled, got %+v", o)
+	}
+}
+
+func TestLostResponseIsReconciled(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	m, _ := newTestManager(t, server, nil)
+
+	server.InjectFault("/sellLimit", coinmatetest.Fault{StatusCode: http.StatusGatewayTimeout, AfterHandling: true, Times: 1})
+	o, err := m.SellLimit(0.2, 51000, 0, "BTC_EUR", false, false, 0)
+	if err == nil || o.State != StateUnknown || o.OrderId != 0 {
+		t.Fatalf("Expected unknown order, got %+v (%v)", o, err)
+	}
+
+	// Another order with different attributes must not be mistaken for it
+	m.Orders.SellLimit(0.3, 51000, 0, "BTC_EUR", false, false, 0)
+
+	if err := m.Reconcile(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	o, _ = m.Order(o.LocalId)
+	open, _ := m.Orders.GetOpenOrders("BTC_EUR")
+	var expected uint64
+	for _, d := range open.Data {
+		if d.Amount == 0.2 {
+			expected = d.Id
+		}
+	}
+	if o.State != StateOpen || o.OrderId != expected {
+		t.Errorf("Expected order identified as the 0.2 BTC order %d, got %+v", expected, o)
+	}
+}
+
+func TestGeneratedClientOrderIds(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	m, _ := newTestManager(t, server, nil)
+	m.ClientOrderIds = idempotent.NewGenerator()
+
+	first, _ := m.BuyLimit(0.1, 49000, 0, "BTC_EUR", false, false, 0)
+	second, _ := m.BuyLimit(0.1, 49000, 0, "BTC_EUR", false, false, 0)
+	if first.ClientOrderId == 0 || first.ClientOrderId == second.ClientOrderId {
+		t.Errorf("Expected distinct generated client order IDs, got %d and %d", first.ClientOrderId, second.ClientOrderId)
+	}
+	if explicit, _ := m.BuyLimit(0.1, 49000, 0, "BTC_EUR", false, false, 42); explicit.ClientOrderId != 42 {
+		t.Errorf("Expected explicit client order ID kept, got %d", explicit.ClientOrderId)
+	}
+}
+
+func TestLostRequestIsRejectedAfterTimeout(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	m, _ := newTestManager(t, server, nil)
+	m.UnknownTimeout = time.Minute
+
+	server.InjectFault("/buyLimit", coinmatetest.Fault{StatusCode: http.StatusServiceUnavailable, Times: 1})
+	o, _ := m.BuyLimit(0.1, 49000, 0, "BTC_EUR", false, false, 42)
+
+	m.Reconcile()
+	if current, _ := m.Order(o.LocalId); current.State != StateUnknown {
+		t.Errorf("Expected order to stay unknown within timeout, got %s", current.State)
+	}
+
+	m.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	m.Reconcile()
+	if current, _ := m.Order(o.LocalId); current.State != StateRejected {
+		t.Errorf("Expected order rejected after timeout, got %s", current.State)
+	}
+}
+
+func TestRecoveryAfterRestart(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	store := NewFileStore(filepath.Join(t.TempDir(), "orders.json"))
+
+	first, _ := newTestManager(t, server, store)
+	placed, _ := first.BuyLimit(0.1, 49000, 0, "BTC_EUR", false, false, 0)
+
+	// Crash after persisting an order but before its response arrived
+	pending := Order{LocalId: 2, ClientOrderId: 77, CurrencyPair: "BTC_EUR", Side: sellSide, OrderType: limitOrderType,
+		Amount: 0.5, Price: 52000, State: StatePendingNew, CreatedAt: time.Now().UnixMilli()}
+	store.Save(append(first.All(), pending))
+	first.Orders.SellLimit(0.5, 52000, 0, "BTC_EUR", false, false, 77)
+
+	server.AddLiquidity("BTC_EUR", "SELL", 48000, 0.1)
+
+	second, events := newTestManager(t, server, store)
+	if len(second.Active()) != 2 {
+		t.Fatalf("Expected 2 recovered orders, got %+v", second.All())
+	}
+	if err := second.Reconcile(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	filled, _ := second.Order(placed.LocalId)
+	if filled.State != StateFilled || len(filled.Fills) != 1 || filled.Fills[0].FeeType != "MAKER" {
+		t.Errorf("Expected recovered order filled as maker, got %+v", filled)
+	}
+	recovered, _ := second.Order(2)
+	if recovered.State != StateOpen || recovered.OrderId == 0 {
+		t.Errorf("Expected pending order identified by client order ID, got %+v", recovered)
+	}
+	if len(*events) != 3 {
+		t.Errorf("Expected fill and two transitions, got %+v", *events)
+	}
+
+	next, _ := second.BuyLimit(0.01, 40000, 0, "BTC_EUR", false, false, 0)
+	if next.LocalId != 3 {
+		t.Errorf("Expected local IDs to continue after restart, got %d", next.LocalId)
+	}
+}
+
+func TestCancelBeforeAcknowledgement(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	m, _ := newTestManager(t, server, nil)
+
+	server.InjectFault("/buyLimit", coinmatetest.Fault{StatusCode: http.StatusServiceUnavailable, Times: 1})
+	o, _ := m.BuyLimit(0.1, 49000, 0, "BTC_EUR", false, false, 0)
+
+	if _, err := m.Cancel(o.LocalId); !errors.Is(err, ErrNotAcknowledged) {
+		t.Errorf("Expected not acknowledged error, got %v", err)
+	}
+	if _, err := m.Cancel(999); !errors.Is(err, ErrUnknownOrder) {
+		t.Errorf("Expected unknown order error, got %v", err)
+	}
+}
+
+func TestOrderNotSentIsRejected(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	m, _ := newTestManager(t, server, nil)
+	m.Orders.(*secure.Order).Gate = gateFunc(func() error { return errors.New("halted") })
+
+	o, err := m.BuyLimit(0.1, 49000, 0, "BTC_EUR", false, false, 0)
+	if !errors.Is(err, secure.ErrNotSent) || o.State != StateRejected {
+		t.Errorf("Expected order rejected without sending, got %+v (%v)", o, err)
+	}
+}
+
+type gateFunc func() error
+
+func (f gateFunc) Allow() error {
+	return f()
+}
+
+func TestPrune(t *testing.T) {
+	server := coinmatetest.NewFundedServer(t)
+	m, _ := newTestManager(t, server, nil)
+
+	m.BuyLimit(1, 49000, 0, "BTC_EUR", false, false, 0)
+	m.BuyLimit(0.1, 49000, 0, "BTC_EUR", false, false, 0)
+
+	removed, err := m.Prune(time.Now().Add(time.Minute))
+	if err != nil || removed != 1 || len(m.All()) != 1 {
+		t.Errorf("Expected rejected order pruned, got %d (%v)", removed, err)
+	}
+}
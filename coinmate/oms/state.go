@@ -0,0 +1,60 @@
+package oms
+
+// Lifecycle state of a tracked order
+type State string
+
+const (
+	// Persisted locally, request not answered yet
+	StatePendingNew State = "PENDING_NEW"
+	// Acknowledged and resting on the book
+	StateOpen State = "OPEN"
+	// Some amount executed, rest still on the book
+	StatePartiallyFilled State = "PARTIALLY_FILLED"
+	// Whole amount executed
+	StateFilled State = "FILLED"
+	// Removed from the book, possibly after partial fills
+	StateCancelled State = "CANCELLED"
+	// Refused by the exchange or never reached it
+	StateRejected State = "REJECTED"
+	// Outcome of the request is not known, reconciliation decides
+	StateUnknown State = "UNKNOWN"
+)
+
+var transitions = map[State][]State{
+	StatePendingNew:      {StateOpen, StatePartiallyFilled, StateFilled, StateCancelled, StateRejected, StateUnknown},
+	StateUnknown:         {StateOpen, StatePartiallyFilled, StateFilled, StateCancelled, StateRejected},
+	StateOpen:            {StatePartiallyFilled, StateFilled, StateCancelled, StateUnknown},
+	StatePartiallyFilled: {StatePartiallyFilled, StateFilled, StateCancelled, StateUnknown},
+}
+
+// Whether no further transition is possible
+func (s State) Terminal() bool {
+	return s == StateFilled || s == StateCancelled || s == StateRejected
+}
+
+// Whether the order can move from s to next
+func (s State) CanTransition(next State) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Map exchange order status to a state, false for unrecognized statuses
+func stateFromStatus(status string) (State, bool) {
+	switch status {
+	case "OPEN":
+		return StateOpen, true
+	case "PARTIALLY_FILLED":
+		return StatePartiallyFilled, true
+	case "FILLED":
+		return StateFilled, true
+	case "CANCELLED", "EXPIRED":
+		return StateCancelled, true
+	case "REJECTED":
+		return StateRejected, true
+	}
+	return "", false
+}
@@ -0,0 +1,54 @@
+package oms
+
+import "testing"
+
+func TestStateTransitions(t *testing.T) {
+	allowed := []struct{ from, to State }{
+		{StatePendingNew, StateOpen},
+		{StatePendingNew, StateRejected},
+		{StatePendingNew, StateUnknown},
+		{StateUnknown, StateFilled},
+		{StateOpen, StatePartiallyFilled},
+		{StatePartiallyFilled, StateCancelled},
+	}
+	for _, c := range allowed {
+		if !c.from.CanTransition(c.to) {
+			t.Errorf("Expected %s -> %s to be allowed", c.from, c.to)
+		}
+	}
+
+	denied := []struct{ from, to State }{
+		{StateFilled, StateOpen},
+		{StateCancelled, StateFilled},
+		{StateRejected, StateOpen},
+		{StateOpen, StatePendingNew},
+		{StatePartiallyFilled, StateOpen},
+	}
+	for _, c := range denied {
+		if c.from.CanTransition(c.to) {
+			t.Errorf("Expected %s -> %s to be denied", c.from, c.to)
+		}
+	}
+}
+
+func TestTerminalStates(t *testing.T) {
+	for _, s := range []State{StateFilled, StateCancelled, StateRejected} {
+		if !s.Terminal() {
+			t.Errorf("Expected %s to be terminal", s)
+		}
+	}
+	for _, s := range []State{StatePendingNew, StateOpen, StatePartiallyFilled, StateUnknown} {
+		if s.Terminal() {
+			t.Errorf("Expected %s not to be terminal", s)
+		}
+	}
+}
+
+func TestStateFromStatus(t *testing.T) {
+	if s, ok := stateFromStatus("EXPIRED"); !ok || s != StateCancelled {
+		t.Errorf("Expected expired order to be cancelled, got %s", s)
+	}
+	if _, ok := stateFromStatus("SOMETHING"); ok {
+		t.Error("Expected unknown status not to be mapped")
+	}
+}
@@ -0,0 +1,17 @@
+package oms
+
+import "tourGo/coinmate/filestore"
+
+// Persistence of tracked orders, used to recover after a restart
+type Store interface {
+	Load() ([]Order, error)
+	Save(orders []Order) error
+}
+
+// Store keeping all orders in one JSON file, see filestore.JSONFileStore
+type FileStore = filestore.JSONFileStore[Order]
+
+// Return file store at path, the directory is created on first save
+func NewFileStore(path string) *FileStore {
+	return filestore.New[Order](path, "order")
+}
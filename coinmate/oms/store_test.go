@@ -0,0 +1,49 @@
+package oms
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "state", "orders.json"))
+
+	orders, err := store.Load()
+	if err != nil || len(orders) != 0 {
+		t.Fatalf("Expected empty store, got %+v (%v)", orders, err)
+	}
+
+	saved := []Order{
+		{LocalId: 1, OrderId: 1001, CurrencyPair: "BTC_EUR", State: StateFilled, Fills: []Fill{{TransactionId: 5, Amount: 0.1, Price: 50000}}},
+		{LocalId: 2, CurrencyPair: "BTC_EUR", State: StatePendingNew},
+	}
+	if err := store.Save(saved); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	loaded, err := store.Load()
+	if err != nil || len(loaded) != 2 {
+		t.Fatalf("Expected 2 orders, got %+v (%v)", loaded, err)
+	}
+	if loaded[0].Fills[0].TransactionId != 5 || loaded[1].State != StatePendingNew {
+		t.Errorf("Unexpected orders %+v", loaded)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(store.Path))
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files left, got %d entries", len(entries))
+	}
+}
+
+func TestFileStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.json")
+	os.WriteFile(path, []byte("{broken"), 0o644)
+
+	if _, err := NewFileStore(path).Load(); err == nil {
+		t.Error("Expected error for corrupt store")
+	}
+	if _, err := NewManager(nil, nil, NewFileStore(path)); err == nil {
+		t.Error("Expected manager to fail on corrupt store")
+	}
+}
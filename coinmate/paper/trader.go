@@ -0,0 +1,446 @@
+package paper
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate/matching"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	// minutesIntoHistory requested when syncing trades
+	syncLookback = 60
+)
+
+// Simulated execution reported by the trader
+type Fill = matching.Fill
+
+type order struct {
+	*matching.Order
+	// Stop orders rest once a trade reaches the stop price
+	triggered bool
+}
+
+// Trader simulates order placement against live public market data and
+// keeps virtual balances. It implements secure.OrderInterface and
+// secure.BalancesInterface, so a strategy can switch between live and paper
+// trading by swapping the dependency.
+//
+// Orders crossing the spread fill immediately against the current order book
+// as taker. Resting orders fill as maker when Sync sees public trades printing
+// at or through their limit price, never for more than the traded amount.
+// Stop orders activate when a public trade reaches the stop price.
+type Trader struct {
+	OrderBook    *public.OrderBook
+	Transactions *public.Transactions
+
+	// Fee rates, e.g. 0.0025 for 0.25 %
+	MakerFee float64
+	TakerFee float64
+
+	// Called for every simulated fill, after the trader is unlocked so that
+	// it may place or cancel orders
+	OnFill func(Fill)
+
+	mu          sync.Mutex
+	account     *matching.Account
+	orders      map[uint64]*order
+	fills       []Fill
+	unreported  []Fill
+	seen        map[string]map[string]bool
+	nextOrderId uint64
+	now         func() time.Time
+}
+
+// Return paper trader with initial balances, e.g. {"EUR": 10000}
+func NewTrader(orderBook *public.OrderBook, transactions *public.Transactions, balances map[string]float64) *Trader {
+	return &Trader{
+		OrderBook:    orderBook,
+		Transactions: transactions,
+		account:      matching.NewAccount("", balances),
+		orders:       make(map[uint64]*order),
+		seen:         make(map[string]map[string]bool),
+		now:          time.Now,
+	}
+}
+
+// Balances endpoint
+func (t *Trader) GetBalances() (secure.BalancesResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return secure.BalancesResponse{Data: t.account.Data()}, nil
+}
+
+// Order history
+func (t *Trader) GetHistory(currencyPair string, limit int64) (secure.OrderHistoryResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	response := secure.OrderHistoryResponse{Data: []secure.OrderHistoryData{}}
+	for _, o := range t.sortedOrders(currencyPair) {
+		if limit > 0 && int64(len(response.Data)) == limit {
+			break
+		}
+		response.Data = append(response.Data, o.HistoryData())
+	}
+	return response, nil
+}
+
+// Open orders
+func (t *Trader) GetOpenOrders(currencyPair string) (secure.OpenOrdersResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	response := secure.OpenOrdersResponse{Data: []secure.OpenOrdersData{}}
+	for _, o := range t.sortedOrders(currencyPair) {
+		if !o.Open() {
+			continue
+		}
+		response.Data = append(response.Data, o.OpenData())
+	}
+	return response, nil
+}
+
+// Buy limit
+func (t *Trader) BuyLimit(amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (secure.SellLimit, error) {
+	return t.limit(matching.Buy, amount, price, stopPrice, currencyPair, hidden, immediateOrCancel, clientOrderId)
+}
+
+// Sell limit
+func (t *Trader) SellLimit(amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (secure.SellLimit, error) {
+	return t.limit(matching.Sell, amount, price, stopPrice, currencyPair, hidden, immediateOrCancel, clientOrderId)
+}
+
+// Buy instantly for a total in the quote currency
+func (t *Trader) BuyInstant(total float64, cp string, clientOrderId uint64) (secure.BuyAndSellResponse, error) {
+	return t.instant(matching.Buy, total, cp, clientOrderId)
+}
+
+// Sell instantly an amount in the base currency
+func (t *Trader) SellInstant(total float64, cp string, clientOrderId uint64) (secure.BuyAndSellResponse, error) {
+	return t.instant(matching.Sell, total, cp, clientOrderId)
+}
+
+// Cancel order
+func (t *Trader) CancelOrder(orderId uint64) (secure.CancelOrderResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.cancel(orderId)
+	return secure.CancelOrderResponse{Data: ok}, nil
+}
+
+// Cancel order with info
+func (t *Trader) CancelOrderWithInfo(orderId uint64) (secure.CancelOrderWithInfoResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	remaining, ok := t.cancel(orderId)
+	return secure.CancelOrderWithInfoResponse{Data: secure.CancelOrderWithInfoData{Success: ok, RemainingAmount: remaining}}, nil
+}
+
+// Return all simulated fills, oldest first
+func (t *Trader) Fills() []Fill {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]Fill(nil), t.fills...)
+}
+
+// Fetch recent public trades of every pair with open orders and fill resting
+// orders they cross
+func (t *Trader) Sync() error {
+	t.mu.Lock()
+	pairs := map[string]bool{}
+	for _, o := range t.orders {
+		if o.Open() {
+			pairs[o.Pair] = true
+		}
+	}
+	t.mu.Unlock()
+
+	names := make([]string, 0, len(pairs))
+	for pair := range pairs {
+		names = append(names, pair)
+	}
+	sort.Strings(names)
+
+	for _, pair := range names {
+		if t.Transactions == nil {
+			return fmt.Errorf("transactions service must be set")
+		}
+		response, err := t.Transactions.GetTransactions(pair, syncLookback)
+		if err != nil {
+			return err
+		}
+		if response.Error {
+			return fmt.Errorf("transactions request failed: %s", response.ErrorMessage)
+		}
+		t.ProcessTrades(pair, response.Data)
+	}
+	return nil
+}
+
+// Sync every interval until ctx is cancelled, errors are passed to onError
+func (t *Trader) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := t.Sync(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Fill resting orders against public trades, e.g. from a trade stream.
+// Trades already processed are ignored.
+func (t *Trader) ProcessTrades(currencyPair string, trades []public.TransactionsData) {
+	pair := strings.ToUpper(currencyPair)
+	sorted := append([]public.TransactionsData(nil), trades...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp < sorted[j].Timestamp })
+
+	t.mu.Lock()
+	if t.seen[pair] == nil {
+		t.seen[pair] = map[string]bool{}
+	}
+	for _, tx := range sorted {
+		if t.seen[pair][tx.TransactionId] {
+			continue
+		}
+		t.seen[pair][tx.TransactionId] = true
+		t.processTrade(pair, tx)
+	}
+	t.unlock()
+}
+
+// Helper functions
+
+// Unlock and report the fills of the locked section
+func (t *Trader) unlock() {
+	fills := t.unreported
+	t.unreported = nil
+	t.mu.Unlock()
+
+	if t.OnFill != nil {
+		for _, f := range fills {
+			t.OnFill(f)
+		}
+	}
+}
+
+func (t *Trader) limit(side string, amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (secure.SellLimit, error) {
+	response := secure.SellLimit{}
+
+	if _, _, err := matching.SplitPair(currencyPair); err != nil {
+		return response, err
+	}
+	if amount <= 0 || price <= 0 {
+		return rejected(response, "Amount and price must be positive"), nil
+	}
+
+	// Market data is fetched before taking the lock, crossing orders need it
+	var book public.OrderBookData
+	var err error
+	if stopPrice <= 0 {
+		if book, err = t.orderBook(currencyPair); err != nil {
+			return response, err
+		}
+	}
+
+	t.mu.Lock()
+	defer t.unlock()
+
+	o := t.newOrder(side, currencyPair, matching.Limit, clientOrderId)
+	o.Price = price
+	o.StopPrice = stopPrice
+	o.Original = amount
+	o.Remaining = amount
+	o.Hidden = hidden
+	o.triggered = stopPrice <= 0
+
+	reservePerUnit := 1.0
+	if side == matching.Buy {
+		reservePerUnit = price * (1 + t.TakerFee)
+	}
+	if err := o.Reserve(amount*reservePerUnit, reservePerUnit); err != nil {
+		return rejected(response, err.Error()), nil
+	}
+	t.add(o)
+
+	if o.triggered {
+		t.takeLiquidity(o, matching.Levels(book, side), false)
+		if o.Open() && immediateOrCancel {
+			o.Cancel()
+		}
+	}
+
+	response.OrderId = o.Id
+	return response, nil
+}
+
+func (t *Trader) instant(side string, quantity float64, currencyPair string, clientOrderId uint64) (secure.BuyAndSellResponse, error) {
+	response := secure.BuyAndSellResponse{}
+
+	if _, _, err := matching.SplitPair(currencyPair); err != nil {
+		return response, err
+	}
+	if quantity <= 0 {
+		response.Error = true
+		response.ErrorMessage = "Amount must be positive"
+		return response, nil
+	}
+
+	book, err := t.orderBook(currencyPair)
+	if err != nil {
+		return response, err
+	}
+
+	t.mu.Lock()
+	defer t.unlock()
+
+	o := t.newOrder(side, currencyPair, matching.Market, clientOrderId)
+	required := quantity
+	if side == matching.Buy {
+		required = quantity * (1 + t.TakerFee)
+	}
+	if !o.Affords(required) {
+		response.Error = true
+		response.ErrorMessage = matching.ErrInsufficientBalance.Error()
+		return response, nil
+	}
+	t.add(o)
+
+	levels := matching.Levels(book, side)
+	if side == matching.Buy {
+		// Convert the quote total into a base amount while walking the book
+		budget := quantity
+		for _, level := range levels {
+			if budget <= matching.Epsilon {
+				break
+			}
+			amount := math.Min(level.Amount, budget/level.Price)
+			budget -= amount * level.Price
+			o.Original += amount
+			o.Remaining += amount
+		}
+	} else {
+		o.Original = quantity
+		o.Remaining = quantity
+	}
+	t.takeLiquidity(o, levels, true)
+	o.Complete()
+
+	response.OrderId = o.Id
+	return response, nil
+}
+
+// Fill order as taker against book levels within its limit price
+func (t *Trader) takeLiquidity(o *order, book []public.OrderBookAsksBids, market bool) {
+	for _, level := range book {
+		if o.Remaining <= matching.Epsilon {
+			break
+		}
+		if !market && !o.Crosses(level.Price) {
+			break
+		}
+		t.fill(o, math.Min(o.Remaining, level.Amount), level.Price, false)
+	}
+}
+
+// Apply public trade to resting orders in time priority
+func (t *Trader) processTrade(pair string, tx public.TransactionsData) {
+	for _, o := range t.sortedOrders(pair) {
+		if o.Open() && !o.triggered && tx.Timestamp >= o.Timestamp && o.StopReached(tx.Price) {
+			o.triggered = true
+		}
+	}
+
+	available := tx.Amount
+	orders := t.sortedOrders(pair)
+	for i := len(orders) - 1; i >= 0 && available > matching.Epsilon; i-- {
+		o := orders[i]
+		if !o.Open() || !o.triggered || tx.Timestamp < o.Timestamp || !o.FilledBy(tx) {
+			continue
+		}
+		amount := math.Min(available, o.Remaining)
+		available -= amount
+		t.fill(o, amount, o.Price, true)
+	}
+}
+
+// Settle fill of an order, it is reported when the trader is unlocked
+func (t *Trader) fill(o *order, amount, price float64, maker bool) {
+	feeRate := t.TakerFee
+	if maker {
+		feeRate = t.MakerFee
+	}
+	f := o.Settle(amount, price, amount*price*feeRate, maker, t.now().UnixMilli())
+	t.fills = append(t.fills, f)
+	t.unreported = append(t.unreported, f)
+}
+
+func (t *Trader) cancel(orderId uint64) (float64, bool) {
+	o, ok := t.orders[orderId]
+	if !ok || !o.Open() {
+		return 0, false
+	}
+	o.Cancel()
+	return o.Remaining, true
+}
+
+// Return order with the next ID, the pair is valid
+func (t *Trader) newOrder(side, currencyPair, orderType string, clientOrderId uint64) *order {
+	o, _ := t.account.NewOrder(t.nextOrderId+1, side, currencyPair, orderType, clientOrderId, t.now().UnixMilli())
+	return &order{Order: o, triggered: true}
+}
+
+func (t *Trader) add(o *order) {
+	t.nextOrderId = o.Id
+	t.orders[o.Id] = o
+}
+
+// Return orders of a pair (all pairs when empty), newest first
+func (t *Trader) sortedOrders(currencyPair string) []*order {
+	pair := strings.ToUpper(currencyPair)
+	var orders []*order
+	for _, o := range t.orders {
+		if pair == "" || o.Pair == pair {
+			orders = append(orders, o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].Id > orders[j].Id })
+	return orders
+}
+
+func (t *Trader) orderBook(currencyPair string) (public.OrderBookData, error) {
+	if t.OrderBook == nil {
+		return public.OrderBookData{}, fmt.Errorf("order book service must be set")
+	}
+	response, err := t.OrderBook.GetOrderBook(currencyPair, false)
+	if err != nil {
+		return public.OrderBookData{}, err
+	}
+	if response.Error {
+		return public.OrderBookData{}, fmt.Errorf("order book request failed: %s", response.ErrorMessage)
+	}
+	return response.Data, nil
+}
+
+func rejected(response secure.SellLimit, message string) secure.SellLimit {
+	response.Error = true
+	response.ErrorMessage = message
+	return response
+}
@@ -0,0 +1,235 @@
+package paper
+
+import (
+	"math"
+	"testing"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/matching"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+func newTestTrader(t *testing.T) (*Trader, *coinmatetest.Server) {
+	t.Helper()
+	server := coinmatetest.NewServer()
+	t.Cleanup(server.Close)
+
+	server.AddLiquidity("BTC_EUR", "SELL", 50000, 0.1)
+	server.AddLiquidity("BTC_EUR", "SELL", 50100, 0.2)
+	server.AddLiquidity("BTC_EUR", "BUY", 49900, 0.1)
+	server.AddLiquidity("BTC_EUR", "BUY", 49800, 0.2)
+
+	client := server.NewClient("")
+	trader := NewTrader(&public.OrderBook{Client: client}, &public.Transactions{Client: client}, map[string]float64{
+		"EUR": 10000,
+		"BTC": 1,
+	})
+	return trader, server
+}
+
+func balances(t *testing.T, trader *Trader) map[string]secure.BalanceCurrency {
+	t.Helper()
+	response, err := trader.GetBalances()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return response.Data
+}
+
+func TestTraderImplementsSecureInterfaces(t *testing.T) {
+	var _ secure.OrderInterface = &Trader{}
+	var _ secure.BalancesInterface = &Trader{}
+}
+
+func TestBuyInstantWalksOrderBook(t *testing.T) {
+	trader, _ := newTestTrader(t)
+	trader.TakerFee = 0.001
+
+	response, err := trader.BuyInstant(7010, "BTC_EUR", 0)
+	if err != nil || response.Error {
+		t.Fatalf("Expected instant buy, got %+v (%v)", response, err)
+	}
+
+	fills := trader.Fills()
+	if len(fills) != 2 || fills[0].Price != 50000 || fills[1].Price != 50100 {
+		t.Fatalf("Expected fills at 50000 and 50100, got %+v", fills)
+	}
+	if math.Abs(fills[1].Amount-2010.0/50100) > 1e-9 {
+		t.Errorf("Expected remaining 2010 EUR spent at 50100, got %f BTC", fills[1].Amount)
+	}
+
+	b := balances(t, trader)
+	if math.Abs(float64(b["BTC"].Balance)-(1.1+2010.0/50100)) > 1e-6 {
+		t.Errorf("Unexpected BTC balance %f", b["BTC"].Balance)
+	}
+	if math.Abs(float64(b["EUR"].Balance)-(10000-7010*1.001)) > 1e-2 {
+		t.Errorf("Expected EUR minus total and fee, got %f", b["EUR"].Balance)
+	}
+}
+
+func TestSellInstant(t *testing.T) {
+	trader, _ := newTestTrader(t)
+
+	response, _ := trader.SellInstant(0.15, "BTC_EUR", 0)
+	if response.Error {
+		t.Fatalf("Expected instant sell, got %+v", response)
+	}
+
+	b := balances(t, trader)
+	if math.Abs(float64(b["EUR"].Balance)-(10000+0.1*49900+0.05*49800)) > 1e-2 {
+		t.Errorf("Unexpected EUR balance %f", b["EUR"].Balance)
+	}
+
+	response, _ = trader.SellInstant(5, "BTC_EUR", 0)
+	if !response.Error {
+		t.Error("Expected error for insufficient BTC")
+	}
+}
+
+func TestCrossingLimitOrderTakesLiquidity(t *testing.T) {
+	trader, _ := newTestTrader(t)
+
+	response, err := trader.BuyLimit(0.15, 50050, 0, "BTC_EUR", false, false, 9)
+	if err != nil || response.Error {
+		t.Fatalf("Expected buy limit, got %+v (%v)", response, err)
+	}
+
+	open, _ := trader.GetOpenOrders("BTC_EUR")
+	if len(open.Data) != 1 || math.Abs(open.Data[0].Amount-0.05) > 1e-9 {
+		t.Fatalf("Expected 0.05 BTC left on the book, got %+v", open.Data)
+	}
+
+	b := balances(t, trader)
+	if math.Abs(float64(b["EUR"].Reserved)-0.05*50050) > 1e-2 {
+		t.Errorf("Expected reservation for the remainder, got %f", b["EUR"].Reserved)
+	}
+	if fills := trader.Fills(); len(fills) != 1 || fills[0].Maker || fills[0].ClientOrderId != 9 {
+		t.Errorf("Expected one taker fill, got %+v", fills)
+	}
+}
+
+func TestImmediateOrCancelDoesNotRest(t *testing.T) {
+	trader, _ := newTestTrader(t)
+
+	trader.BuyLimit(0.2, 50050, 0, "BTC_EUR", false, true, 0)
+
+	open, _ := trader.GetOpenOrders("")
+	if len(open.Data) != 0 {
+		t.Errorf("Expected no open orders, got %+v", open.Data)
+	}
+	if b := balances(t, trader); b["EUR"].Reserved != 0 {
+		t.Errorf("Expected no reservation, got %f", b["EUR"].Reserved)
+	}
+}
+
+func TestRestingOrderFillsFromPublicTrades(t *testing.T) {
+	trader, server := newTestTrader(t)
+	trader.MakerFee = 0.0005
+
+	var reported []Fill
+	trader.OnFill = func(f Fill) { reported = append(reported, f) }
+
+	placed, _ := trader.BuyLimit(0.2, 49000, 0, "BTC_EUR", false, false, 0)
+
+	server.AddTransaction(public.TransactionsData{Price: 49500, Amount: 1, CurrencyPair: "BTC_EUR", TradeType: "SELL"})
+	server.AddTransaction(public.TransactionsData{Price: 48900, Amount: 0.5, CurrencyPair: "BTC_EUR", TradeType: "BUY"})
+	server.AddTransaction(public.TransactionsData{Price: 49000, Amount: 0.1, CurrencyPair: "BTC_EUR", TradeType: "SELL"})
+	if err := trader.Sync(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	history, _ := trader.GetHistory("BTC_EUR", 0)
+	if history.Data[0].Id != placed.OrderId || history.Data[0].Status != matching.StatusPartiallyFilled {
+		t.Fatalf("Expected partially filled order, got %+v", history.Data)
+	}
+	if math.Abs(history.Data[0].RemainingAmount-0.1) > 1e-9 {
+		t.Errorf("Expected fill limited by trade amount, got %f remaining", history.Data[0].RemainingAmount)
+	}
+	if len(reported) != 1 || !reported[0].Maker || reported[0].Price != 49000 {
+		t.Errorf("Expected maker fill at 49000, got %+v", reported)
+	}
+
+	// Trades are processed once
+	trader.Sync()
+	if len(trader.Fills()) != 1 {
+		t.Errorf("Expected trades not to be applied twice, got %d fills", len(trader.Fills()))
+	}
+}
+
+func TestOnFillMayPlaceOrders(t *testing.T) {
+	trader, _ := newTestTrader(t)
+
+	// Take profit placed from the callback of the buy fill
+	var placed []uint64
+	trader.OnFill = func(f Fill) {
+		if f.Side != matching.Buy {
+			return
+		}
+		response, err := trader.SellLimit(f.Amount, 60000, 0, f.CurrencyPair, false, false, 0)
+		if err != nil || response.Error {
+			t.Errorf("Expected sell to be placed, got %+v (%v)", response, err)
+		}
+		placed = append(placed, response.OrderId)
+	}
+
+	if _, err := trader.BuyLimit(0.05, 50000, 0, "BTC_EUR", false, false, 0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	open, _ := trader.GetOpenOrders("BTC_EUR")
+	if len(placed) != 1 || len(open.Data) != 1 || open.Data[0].Id != placed[0] {
+		t.Errorf("Expected take profit to rest, got %+v", open.Data)
+	}
+}
+
+func TestStopOrderActivatesOnTrade(t *testing.T) {
+	trader, server := newTestTrader(t)
+
+	placed, _ := trader.SellLimit(0.5, 48000, 48500, "BTC_EUR", false, false, 0)
+
+	server.AddTransaction(public.TransactionsData{Price: 48400, Amount: 0.2, CurrencyPair: "BTC_EUR", TradeType: "BUY"})
+	server.AddTransaction(public.TransactionsData{Price: 48100, Amount: 1, CurrencyPair: "BTC_EUR", TradeType: "BUY"})
+	trader.Sync()
+
+	history, _ := trader.GetHistory("BTC_EUR", 1)
+	if history.Data[0].Id != placed.OrderId || history.Data[0].Status != matching.StatusFilled {
+		t.Errorf("Expected stop order to trigger and fill, got %+v", history.Data)
+	}
+}
+
+func TestCancelReleasesReservation(t *testing.T) {
+	trader, _ := newTestTrader(t)
+
+	placed, _ := trader.SellLimit(0.4, 60000, 0, "BTC_EUR", true, false, 0)
+	if b := balances(t, trader); math.Abs(float64(b["BTC"].Reserved)-0.4) > 1e-6 {
+		t.Errorf("Expected 0.4 BTC reserved, got %f", b["BTC"].Reserved)
+	}
+
+	response, _ := trader.CancelOrderWithInfo(placed.OrderId)
+	if !response.Data.Success || response.Data.RemainingAmount != 0.4 {
+		t.Errorf("Expected cancel with 0.4 remaining, got %+v", response.Data)
+	}
+	if b := balances(t, trader); b["BTC"].Reserved != 0 {
+		t.Errorf("Expected reservation released, got %f", b["BTC"].Reserved)
+	}
+
+	again, _ := trader.CancelOrder(placed.OrderId)
+	if again.Data {
+		t.Error("Expected second cancel to fail")
+	}
+}
+
+func TestLimitOrderValidation(t *testing.T) {
+	trader, _ := newTestTrader(t)
+
+	response, _ := trader.BuyLimit(1, 50000, 0, "BTC_EUR", false, false, 0)
+	if !response.Error {
+		t.Error("Expected error for insufficient EUR")
+	}
+	if _, err := trader.BuyLimit(1, 1, 0, "BTCEUR", false, false, 0); err == nil {
+		t.Error("Expected error for malformed currency pair")
+	}
+	response, _ = trader.SellLimit(0, 50000, 0, "BTC_EUR", false, false, 0)
+	if !response.Error {
+		t.Error("Expected error for zero amount")
+	}
+}
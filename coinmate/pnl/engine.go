@@ -0,0 +1,208 @@
+package pnl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+const tradeHistoryPageSize = 1000
+
+// Gains of a period with the positions open now
+type Report struct {
+	Currency string `json:"currency"`
+	Method   Method `json:"method"`
+	From     int64  `json:"from,omitempty"`
+	To       int64  `json:"to,omitempty"`
+
+	Realizations []Realization `json:"realizations"`
+	Realized     float64       `json:"realized"`
+	// Trade fees, already part of costs and proceeds, and transfer fees
+	Fees      float64 `json:"fees"`
+	Deposited float64 `json:"deposited"`
+	Withdrawn float64 `json:"withdrawn"`
+
+	Positions  []Position `json:"positions"`
+	Unrealized float64    `json:"unrealized"`
+	// Currencies held without a price, left out of the unrealized total
+	Unpriced []string `json:"unpriced,omitempty"`
+}
+
+// Engine computes PnL from the full trade history of the account
+type Engine struct {
+	Trades secure.TradeHistoryInterface
+	// Prices positions at the last price of <currency>_<ledger currency>
+	Ticker   *public.Ticker
+	Currency string
+	Method   Method
+	// Needed for pairs and transfers not in the ledger currency
+	Convert Converter
+	// Deposits and withdrawals are fetched from the transfer history when
+	// set. Transfers with the Id of a history entry set its Price, e.g. a
+	// known deposit price, others are added to the history.
+	TransferHistory secure.TransferHistoryInterface
+	Transfers       []Transfer
+}
+
+// Return engine valued in currency
+func NewEngine(trades secure.TradeHistoryInterface, ticker *public.Ticker, currency string, method Method) *Engine {
+	return &Engine{
+		Trades:   trades,
+		Ticker:   ticker,
+		Currency: strings.ToUpper(currency),
+		Method:   method,
+	}
+}
+
+// Ledger of the full trade and transfer history
+func (e *Engine) Ledger() (*Ledger, error) {
+	trades, err := e.history()
+	if err != nil {
+		return nil, err
+	}
+	transfers, err := e.transfers()
+	if err != nil {
+		return nil, err
+	}
+	l := NewLedger(e.Currency, e.Method)
+	l.Convert = e.Convert
+	if err := l.Apply(trades, transfers); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Report gains realized in [from, to), zero times leave the range open.
+// Lots are built from the full history, so costs carry over from earlier
+// periods.
+func (e *Engine) Report(from, to time.Time) (Report, error) {
+	l, err := e.Ledger()
+	if err != nil {
+		return Report{}, err
+	}
+
+	r := Report{Currency: e.Currency, Method: e.Method, Realizations: l.Realizations(from, to)}
+	if !from.IsZero() {
+		r.From = from.UnixMilli()
+	}
+	if !to.IsZero() {
+		r.To = to.UnixMilli()
+	}
+	for _, realization := range r.Realizations {
+		r.Realized += realization.PnL
+	}
+	for _, f := range l.Flows(from, to) {
+		switch f.Kind {
+		case KindDeposit:
+			r.Deposited += f.Value
+		case KindWithdrawal:
+			r.Withdrawn += f.Value
+		case KindFee:
+			r.Fees += f.Value
+		}
+	}
+	prices, err := e.prices(l.Positions(nil))
+	if err != nil {
+		return Report{}, err
+	}
+	r.Positions = l.Positions(prices)
+	for _, p := range r.Positions {
+		if p.Price == 0 {
+			r.Unpriced = append(r.Unpriced, p.Currency)
+			continue
+		}
+		r.Unrealized += p.Unrealized
+	}
+	return r, nil
+}
+
+// Helper functions
+
+// Full trade history, oldest first
+func (e *Engine) history() ([]secure.TradeHistoryData, error) {
+	var trades []secure.TradeHistoryData
+	params := secure.TradeHistoryParams{Sort: "ASC", Limit: tradeHistoryPageSize}
+	for {
+		r, err := e.Trades.GetTradeHistory(params)
+		if err != nil {
+			return nil, err
+		}
+		if r.Error {
+			return nil, fmt.Errorf("trade history request failed: %s", r.ErrorMessage)
+		}
+		for _, t := range r.Data {
+			trades = append(trades, t)
+			params.LastId = max(params.LastId, t.TransactionId)
+		}
+		if len(r.Data) < tradeHistoryPageSize {
+			return trades, nil
+		}
+	}
+}
+
+// Transfers from the transfer history merged with the given ones
+func (e *Engine) transfers() ([]Transfer, error) {
+	if e.TransferHistory == nil {
+		return e.Transfers, nil
+	}
+
+	var history []secure.TransferHistoryData
+	params := secure.TransferHistoryParams{Sort: "ASC", Limit: tradeHistoryPageSize}
+	for {
+		r, err := e.TransferHistory.GetTransferHistory(params)
+		if err != nil {
+			return nil, err
+		}
+		if r.Error {
+			return nil, fmt.Errorf("transfer history request failed: %s", r.ErrorMessage)
+		}
+		for _, t := range r.Data {
+			history = append(history, t)
+			params.LastId = max(params.LastId, t.TransactionId)
+		}
+		if len(r.Data) < tradeHistoryPageSize {
+			return mergeTransfers(FromTransferHistory(history), e.Transfers), nil
+		}
+	}
+}
+
+// Last prices of the position currencies, currencies without a pair to the
+// ledger currency are left out
+func (e *Engine) prices(positions []Position) (map[string]float64, error) {
+	prices := map[string]float64{}
+	if e.Ticker == nil {
+		return prices, nil
+	}
+	for _, p := range positions {
+		t, err := e.Ticker.GetTicker(p.Currency + "_" + e.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if !t.Error && t.Data.Last > 0 {
+			prices[p.Currency] = t.Data.Last
+		}
+	}
+	return prices, nil
+}
+
+// Set prices of history entries from the given transfers with their Id,
+// given transfers without an entry are added
+func mergeTransfers(history, given []Transfer) []Transfer {
+	index := map[uint64]int{}
+	for i, t := range history {
+		index[t.Id] = i
+	}
+	for _, t := range given {
+		i, ok := index[t.Id]
+		if !ok || t.Id == 0 {
+			history = append(history, t)
+			continue
+		}
+		if t.Price > 0 {
+			history[i].Price = t.Price
+		}
+	}
+	return history
+}
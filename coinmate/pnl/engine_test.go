@@ -0,0 +1,84 @@
+package pnl
+
+import (
+	"math"
+	"testing"
+	"time"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+func TestEngineReport(t *testing.T) {
+	server := coinmatetest.NewServer()
+	t.Cleanup(server.Close)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	server.SetClock(func() time.Time { return now })
+	server.SetFees(0.001, 0.002)
+
+	server.AddAccount("1", "public-key", "private-key")
+	server.AddTransfer("1", "EUR", secure.TransferDeposit, 10000, 0)
+	client := server.NewClient("1")
+	orders := &secure.Order{Client: client}
+
+	server.AddLiquidity("BTC_EUR", "SELL", 50000, 1)
+	orders.BuyInstant(5000, "BTC_EUR", 0)
+	now = now.Add(24 * time.Hour)
+	server.AddLiquidity("BTC_EUR", "BUY", 60000, 1)
+	orders.SellInstant(0.05, "BTC_EUR", 0)
+
+	engine := NewEngine(&secure.TradeHistory{Client: client}, &public.Ticker{Client: client}, "EUR", FIFO)
+	engine.TransferHistory = &secure.TransferHistory{Client: client}
+	r, err := engine.Report(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Bought 0.1 BTC for 5000 + 10 fee, sold half for 3000 - 6 fee
+	if len(r.Realizations) != 1 || math.Abs(r.Realized-489) > 1e-6 || math.Abs(r.Fees-16) > 1e-6 || r.Deposited != 10000 {
+		t.Errorf("Expected realized 489 with 16 fees, got %+v", r)
+	}
+	if len(r.Positions) != 1 || math.Abs(r.Positions[0].AverageCost-50100) > 1e-6 || math.Abs(r.Unrealized-495) > 1e-6 {
+		t.Errorf("Expected 0.05 BTC at 50100 marked at 60000, got %+v", r.Positions)
+	}
+
+	// Costs carry over into a later period
+	r, _ = engine.Report(now.Add(-time.Hour), now.Add(time.Hour))
+	if len(r.Realizations) != 1 || math.Abs(r.Realized-489) > 1e-6 || math.Abs(r.Fees-6) > 1e-6 || r.Deposited != 0 {
+		t.Errorf("Expected only the sell in the period, got %+v", r)
+	}
+	r, _ = engine.Report(now.Add(time.Hour), time.Time{})
+	if len(r.Realizations) != 0 || r.Realized != 0 || math.Abs(r.Unrealized-495) > 1e-6 {
+		t.Errorf("Expected nothing realized after the sell, got %+v", r)
+	}
+}
+
+func TestEngineTransferPrices(t *testing.T) {
+	server := coinmatetest.NewServer()
+	t.Cleanup(server.Close)
+	server.AddAccount("1", "public-key", "private-key")
+	deposit, _ := server.AddTransfer("1", "BTC", secure.TransferDeposit, 0.5, 0)
+	client := server.NewClient("1")
+
+	engine := NewEngine(&secure.TradeHistory{Client: client}, nil, "EUR", FIFO)
+	engine.TransferHistory = &secure.TransferHistory{Client: client}
+	engine.Transfers = []Transfer{
+		// Price of the deposit in the history, no Convert needed
+		{Id: deposit, Price: 42000},
+		// Deposit missing in the history, e.g. from before the account
+		{Timestamp: 1, Currency: "EUR", Type: KindDeposit, Amount: 1000},
+	}
+	l, err := engine.Ledger()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	lots := l.Lots("BTC")
+	if len(lots) != 1 || lots[0].Amount != 0.5 || lots[0].Cost != 21000 || lots[0].Source != deposit {
+		t.Errorf("Expected one deposit lot at 42000, got %+v", lots)
+	}
+	r, _ := engine.Report(time.Time{}, time.Time{})
+	if r.Deposited != 22000 {
+		t.Errorf("Expected deposits of 22000 EUR, got %+v", r)
+	}
+}
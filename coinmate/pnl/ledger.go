@@ -0,0 +1,409 @@
+package pnl
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	buySide  = "BUY"
+	sellSide = "SELL"
+	epsilon  = 1e-9
+)
+
+// Lot matching method for disposals
+type Method string
+
+const (
+	// Oldest lots first
+	FIFO Method = "FIFO"
+	// Newest lots first
+	LIFO Method = "LIFO"
+	// Same share of every lot, each unit costs the average
+	AverageCost Method = "AVERAGE"
+)
+
+// Kind of disposal or cash flow
+type Kind string
+
+const (
+	// Sold for the ledger currency
+	KindSell Kind = "SELL"
+	// Paid for another currency in a pair not quoted in the ledger currency
+	KindSwap Kind = "SWAP"
+	// Trade or transfer fee
+	KindFee        Kind = "FEE"
+	KindDeposit    Kind = "DEPOSIT"
+	KindWithdrawal Kind = "WITHDRAWAL"
+)
+
+// Value of amount of from in to at a point in time
+type Converter func(amount float64, from, to string, at time.Time) (float64, error)
+
+// Deposit or withdrawal
+type Transfer struct {
+	Id        uint64
+	Timestamp int64
+	Currency  string
+	// KindDeposit or KindWithdrawal
+	Type Kind
+	// Amount credited or debited, the fee is charged on top
+	Amount float64
+	Fee    float64
+	// Value of one unit of a deposit in the ledger currency, converted at the
+	// time of the deposit when zero
+	Price float64
+}
+
+// Units acquired at once, cost includes fees
+type Lot struct {
+	Currency string  `json:"currency"`
+	Acquired int64   `json:"acquired"`
+	Amount   float64 `json:"amount"`
+	Cost     float64 `json:"cost"`
+	// Trade or transfer id
+	Source uint64 `json:"source,omitempty"`
+}
+
+// Part of a lot used by a disposal
+type Match struct {
+	Acquired int64   `json:"acquired"`
+	Amount   float64 `json:"amount"`
+	Cost     float64 `json:"cost"`
+}
+
+// Realized gain or loss of one disposal in the ledger currency
+type Realization struct {
+	Kind      Kind    `json:"kind"`
+	Source    uint64  `json:"source,omitempty"`
+	Timestamp int64   `json:"timestamp"`
+	Currency  string  `json:"currency"`
+	Amount    float64 `json:"amount"`
+	// Net of fees
+	Proceeds float64 `json:"proceeds"`
+	Cost     float64 `json:"cost"`
+	PnL      float64 `json:"pnl"`
+	Matches  []Match `json:"matches"`
+	// Amount without lots, e.g. bought before the history starts, counted
+	// at zero cost
+	Unmatched float64 `json:"unmatched,omitempty"`
+}
+
+// Deposit, withdrawal or fee valued in the ledger currency
+type Flow struct {
+	Kind      Kind    `json:"kind"`
+	Timestamp int64   `json:"timestamp"`
+	Currency  string  `json:"currency"`
+	Amount    float64 `json:"amount"`
+	Value     float64 `json:"value"`
+}
+
+// Open lots of a currency valued at a price
+type Position struct {
+	Currency    string  `json:"currency"`
+	Amount      float64 `json:"amount"`
+	Cost        float64 `json:"cost"`
+	AverageCost float64 `json:"averageCost"`
+	// Zero when no price is known
+	Price      float64 `json:"price"`
+	Value      float64 `json:"value"`
+	Unrealized float64 `json:"unrealized"`
+}
+
+// Ledger builds lots from trades and transfers applied in time order and
+// realizes gains against them. Amounts of the ledger currency are cash and
+// have no lots.
+type Ledger struct {
+	Currency string
+	Method   Method
+	// Needed for pairs and transfers not in the ledger currency
+	Convert Converter
+
+	lots         map[string][]Lot
+	realizations []Realization
+	flows        []Flow
+}
+
+// Return empty ledger valued in currency
+func NewLedger(currency string, method Method) *Ledger {
+	return &Ledger{
+		Currency: strings.ToUpper(currency),
+		Method:   method,
+		lots:     map[string][]Lot{},
+	}
+}
+
+// Apply trades and transfers in time order, transfers first on ties
+func (l *Ledger) Apply(trades []secure.TradeHistoryData, transfers []Transfer) error {
+	trades = append([]secure.TradeHistoryData{}, trades...)
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].CreatedTimestamp < trades[j].CreatedTimestamp })
+	transfers = append([]Transfer{}, transfers...)
+	sort.SliceStable(transfers, func(i, j int) bool { return transfers[i].Timestamp < transfers[j].Timestamp })
+
+	for len(trades) > 0 || len(transfers) > 0 {
+		if len(transfers) > 0 && (len(trades) == 0 || transfers[0].Timestamp <= trades[0].CreatedTimestamp) {
+			if err := l.AddTransfer(transfers[0]); err != nil {
+				return err
+			}
+			transfers = transfers[1:]
+			continue
+		}
+		if err := l.AddTrade(trades[0]); err != nil {
+			return err
+		}
+		trades = trades[1:]
+	}
+	return nil
+}
+
+// Apply trade, fees in the quote currency are added to the cost of a buy and
+// taken from the proceeds of a sell
+func (l *Ledger) AddTrade(t secure.TradeHistoryData) error {
+	base, quote, ok := strings.Cut(strings.ToUpper(t.CurrencyPair), "_")
+	if !ok {
+		return fmt.Errorf("trade %d: invalid currency pair %q", t.TransactionId, t.CurrencyPair)
+	}
+	at := time.UnixMilli(t.CreatedTimestamp)
+	total := t.Amount * t.Price
+	value, err := l.convert(total, quote, at)
+	if err != nil {
+		return fmt.Errorf("trade %d: %w", t.TransactionId, err)
+	}
+	fee, err := l.convert(t.Fee, quote, at)
+	if err != nil {
+		return fmt.Errorf("trade %d: %w", t.TransactionId, err)
+	}
+
+	if t.Fee > 0 {
+		l.flows = append(l.flows, Flow{Kind: KindFee, Timestamp: t.CreatedTimestamp, Currency: quote, Amount: t.Fee, Value: fee})
+	}
+
+	switch strings.ToUpper(t.Type) {
+	case buySide:
+		if quote != l.Currency {
+			l.dispose(KindSwap, t.TransactionId, quote, total+t.Fee, value+fee, t.CreatedTimestamp)
+		}
+		l.acquire(Lot{Currency: base, Acquired: t.CreatedTimestamp, Amount: t.Amount, Cost: value + fee, Source: t.TransactionId})
+	case sellSide:
+		l.dispose(KindSell, t.TransactionId, base, t.Amount, value-fee, t.CreatedTimestamp)
+		if quote != l.Currency {
+			l.acquire(Lot{Currency: quote, Acquired: t.CreatedTimestamp, Amount: total - t.Fee, Cost: value - fee, Source: t.TransactionId})
+		}
+	default:
+		return fmt.Errorf("trade %d: invalid type %q", t.TransactionId, t.Type)
+	}
+	return nil
+}
+
+// Apply deposit or withdrawal. Deposits are lots at their value, withdrawals
+// remove lots at their cost without realizing a gain, transfer fees are
+// realized losses.
+func (l *Ledger) AddTransfer(t Transfer) error {
+	if t.Type != KindDeposit && t.Type != KindWithdrawal {
+		return fmt.Errorf("transfer %d: invalid type %q", t.Id, t.Type)
+	}
+	currency := strings.ToUpper(t.Currency)
+	if currency == l.Currency {
+		l.flows = append(l.flows, Flow{Kind: t.Type, Timestamp: t.Timestamp, Currency: currency, Amount: t.Amount, Value: t.Amount})
+		if t.Fee > 0 {
+			l.flows = append(l.flows, Flow{Kind: KindFee, Timestamp: t.Timestamp, Currency: currency, Amount: t.Fee, Value: t.Fee})
+		}
+		return nil
+	}
+
+	value := 0.0
+	if t.Type == KindDeposit {
+		price := t.Price
+		if price == 0 {
+			var err error
+			if price, err = l.convert(1, currency, time.UnixMilli(t.Timestamp)); err != nil {
+				return fmt.Errorf("transfer %d: %w", t.Id, err)
+			}
+		}
+		value = t.Amount * price
+		l.acquire(Lot{Currency: currency, Acquired: t.Timestamp, Amount: t.Amount, Cost: value, Source: t.Id})
+	} else {
+		for _, m := range l.match(currency, t.Amount) {
+			value += m.Cost
+		}
+	}
+	l.flows = append(l.flows, Flow{Kind: t.Type, Timestamp: t.Timestamp, Currency: currency, Amount: t.Amount, Value: value})
+	if t.Fee > 0 {
+		r := l.dispose(KindFee, t.Id, currency, t.Fee, 0, t.Timestamp)
+		l.flows = append(l.flows, Flow{Kind: KindFee, Timestamp: t.Timestamp, Currency: currency, Amount: t.Fee, Value: r.Cost})
+	}
+	return nil
+}
+
+// Transfers from completed deposits and withdrawals of the transfer history
+func FromTransferHistory(history []secure.TransferHistoryData) []Transfer {
+	var transfers []Transfer
+	for _, t := range history {
+		if t.TransferStatus != secure.TransferCompleted {
+			continue
+		}
+		transfer := Transfer{Id: t.TransactionId, Timestamp: t.Timestamp, Currency: t.AmountCurrency, Amount: t.Amount, Fee: t.Fee}
+		switch t.TransferType {
+		case secure.TransferDeposit:
+			transfer.Type = KindDeposit
+		case secure.TransferWithdrawal:
+			transfer.Type = KindWithdrawal
+		default:
+			continue
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers
+}
+
+// Open lots of a currency, oldest first
+func (l *Ledger) Lots(currency string) []Lot {
+	return append([]Lot{}, l.lots[strings.ToUpper(currency)]...)
+}
+
+// Realizations in [from, to), zero times leave the range open
+func (l *Ledger) Realizations(from, to time.Time) []Realization {
+	var realizations []Realization
+	for _, r := range l.realizations {
+		if inRange(r.Timestamp, from, to) {
+			realizations = append(realizations, r)
+		}
+	}
+	return realizations
+}
+
+// Deposits, withdrawals and fees in [from, to)
+func (l *Ledger) Flows(from, to time.Time) []Flow {
+	var flows []Flow
+	for _, f := range l.flows {
+		if inRange(f.Timestamp, from, to) {
+			flows = append(flows, f)
+		}
+	}
+	return flows
+}
+
+// Open positions valued at prices in the ledger currency keyed by currency
+func (l *Ledger) Positions(prices map[string]float64) []Position {
+	currencies := make([]string, 0, len(l.lots))
+	for currency := range l.lots {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	var positions []Position
+	for _, currency := range currencies {
+		p := Position{Currency: currency}
+		for _, lot := range l.lots[currency] {
+			p.Amount += lot.Amount
+			p.Cost += lot.Cost
+		}
+		if p.Amount <= epsilon {
+			continue
+		}
+		p.AverageCost = p.Cost / p.Amount
+		if price := prices[currency]; price > 0 {
+			p.Price, p.Value = price, p.Amount*price
+			p.Unrealized = p.Value - p.Cost
+		}
+		positions = append(positions, p)
+	}
+	return positions
+}
+
+// Helper functions
+
+func (l *Ledger) convert(amount float64, from string, at time.Time) (float64, error) {
+	if from == l.Currency || amount == 0 {
+		return amount, nil
+	}
+	if l.Convert == nil {
+		return 0, fmt.Errorf("no conversion from %s to %s", from, l.Currency)
+	}
+	return l.Convert(amount, from, l.Currency, at)
+}
+
+func (l *Ledger) acquire(lot Lot) {
+	if lot.Amount <= epsilon {
+		return
+	}
+	l.lots[lot.Currency] = append(l.lots[lot.Currency], lot)
+}
+
+func (l *Ledger) dispose(kind Kind, source uint64, currency string, amount, proceeds float64, timestamp int64) Realization {
+	matches := l.match(currency, amount)
+	r := Realization{Kind: kind, Source: source, Timestamp: timestamp, Currency: currency, Amount: amount, Proceeds: proceeds, Matches: matches}
+	matched := 0.0
+	for _, m := range matches {
+		matched += m.Amount
+		r.Cost += m.Cost
+	}
+	if unmatched := amount - matched; unmatched > epsilon {
+		r.Unmatched = unmatched
+	}
+	r.PnL = r.Proceeds - r.Cost
+	l.realizations = append(l.realizations, r)
+	return r
+}
+
+// Remove amount from the lots of a currency by the ledger method
+func (l *Ledger) match(currency string, amount float64) []Match {
+	lots := l.lots[currency]
+	var matches []Match
+
+	if l.Method == AverageCost {
+		held := 0.0
+		for _, lot := range lots {
+			held += lot.Amount
+		}
+		if held <= epsilon {
+			return nil
+		}
+		share := math.Min(1, amount/held)
+		for i := range lots {
+			m := Match{Acquired: lots[i].Acquired, Amount: lots[i].Amount * share, Cost: lots[i].Cost * share}
+			lots[i].Amount -= m.Amount
+			lots[i].Cost -= m.Cost
+			matches = append(matches, m)
+		}
+		l.lots[currency] = compact(lots)
+		return matches
+	}
+
+	for amount > epsilon && len(lots) > 0 {
+		i := 0
+		if l.Method == LIFO {
+			i = len(lots) - 1
+		}
+		take := math.Min(amount, lots[i].Amount)
+		m := Match{Acquired: lots[i].Acquired, Amount: take, Cost: lots[i].Cost * take / lots[i].Amount}
+		lots[i].Amount -= take
+		lots[i].Cost -= m.Cost
+		amount -= take
+		matches = append(matches, m)
+		lots = compact(lots)
+	}
+	l.lots[currency] = lots
+	return matches
+}
+
+func compact(lots []Lot) []Lot {
+	kept := lots[:0]
+	for _, lot := range lots {
+		if lot.Amount > epsilon {
+			kept = append(kept, lot)
+		}
+	}
+	return kept
+}
+
+func inRange(timestamp int64, from, to time.Time) bool {
+	if !from.IsZero() && timestamp < from.UnixMilli() {
+		return false
+	}
+	return to.IsZero() || timestamp < to.UnixMilli()
+}
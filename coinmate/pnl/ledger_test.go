@@ -0,0 +1,124 @@
+package pnl
+
+import (
+	"math"
+	"testing"
+	"time"
+	"tourGo/coinmate/secure"
+)
+
+func trade(id uint64, timestamp int64, pair, side string, amount, price, fee float64) secure.TradeHistoryData {
+	return secure.TradeHistoryData{TransactionId: id, CreatedTimestamp: timestamp, CurrencyPair: pair, Type: side, Amount: amount, Price: price, Fee: fee}
+}
+
+var methodTrades = []secure.TradeHistoryData{
+	trade(1, 1000, "BTC_EUR", "BUY", 1, 100, 1),
+	trade(2, 2000, "BTC_EUR", "BUY", 1, 200, 2),
+	trade(3, 3000, "BTC_EUR", "SELL", 1, 300, 3),
+}
+
+func TestMethods(t *testing.T) {
+	tests := []struct {
+		method    Method
+		cost, pnl float64
+		acquired  []int64
+	}{
+		{FIFO, 101, 196, []int64{1000}},
+		{LIFO, 202, 95, []int64{2000}},
+		{AverageCost, 151.5, 145.5, []int64{1000, 2000}},
+	}
+
+	for _, tt := range tests {
+		l := NewLedger("eur", tt.method)
+		if err := l.Apply(methodTrades, nil); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		realizations := l.Realizations(time.Time{}, time.Time{})
+		if len(realizations) != 1 {
+			t.Fatalf("Expected one realization, got %+v", realizations)
+		}
+		r := realizations[0]
+		if r.Proceeds != 297 || math.Abs(r.Cost-tt.cost) > 1e-9 || math.Abs(r.PnL-tt.pnl) > 1e-9 || len(r.Matches) != len(tt.acquired) {
+			t.Errorf("Expected %s cost %v and PnL %v, got %+v", tt.method, tt.cost, tt.pnl, r)
+		}
+		for i, m := range r.Matches {
+			if m.Acquired != tt.acquired[i] {
+				t.Errorf("Expected %s to match lots %v, got %+v", tt.method, tt.acquired, r.Matches)
+			}
+		}
+
+		positions := l.Positions(map[string]float64{"BTC": 250})
+		if len(positions) != 1 || math.Abs(positions[0].Cost-(303-tt.cost)) > 1e-9 || math.Abs(positions[0].Unrealized-(250-303+tt.cost)) > 1e-9 {
+			t.Errorf("Expected %s position at cost %v, got %+v", tt.method, 303-tt.cost, positions)
+		}
+	}
+}
+
+func TestSwapsAndTransfers(t *testing.T) {
+	l := NewLedger("EUR", FIFO)
+	l.Convert = func(amount float64, from, to string, at time.Time) (float64, error) {
+		if from != "BTC" || to != "EUR" {
+			t.Fatalf("Unexpected conversion from %s to %s", from, to)
+		}
+		return amount * 50000, nil
+	}
+
+	err := l.Apply(
+		[]secure.TradeHistoryData{trade(1, 2000, "ETH_BTC", "BUY", 10, 0.05, 0.001)},
+		[]Transfer{
+			{Id: 7, Timestamp: 1000, Currency: "BTC", Type: KindDeposit, Amount: 1, Price: 40000},
+			{Id: 8, Timestamp: 3000, Currency: "BTC", Type: KindWithdrawal, Amount: 0.2, Fee: 0.0005},
+			{Id: 9, Timestamp: 3000, Currency: "EUR", Type: KindDeposit, Amount: 1000},
+		},
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	realizations := l.Realizations(time.Time{}, time.Time{})
+	if len(realizations) != 2 {
+		t.Fatalf("Expected swap and fee realizations, got %+v", realizations)
+	}
+	// 0.501 BTC paid for ETH, worth 25050 EUR, deposited at 40000
+	if swap := realizations[0]; swap.Kind != KindSwap || math.Abs(swap.Cost-20040) > 1e-6 || math.Abs(swap.PnL-5010) > 1e-6 {
+		t.Errorf("Expected swap gain 5010, got %+v", swap)
+	}
+	if fee := realizations[1]; fee.Kind != KindFee || math.Abs(fee.PnL+20) > 1e-6 {
+		t.Errorf("Expected fee loss 20, got %+v", fee)
+	}
+	if eth := l.Lots("ETH"); len(eth) != 1 || math.Abs(eth[0].Cost-25050) > 1e-6 {
+		t.Errorf("Expected ETH lot at 25050, got %+v", eth)
+	}
+	if btc := l.Lots("btc"); len(btc) != 1 || math.Abs(btc[0].Amount-0.2985) > 1e-9 {
+		t.Errorf("Expected 0.2985 BTC left, got %+v", btc)
+	}
+
+	var deposited float64
+	for _, f := range l.Flows(time.Time{}, time.Time{}) {
+		if f.Kind == KindDeposit {
+			deposited += f.Value
+		}
+	}
+	if deposited != 41000 {
+		t.Errorf("Expected 41000 EUR deposited, got %v", deposited)
+	}
+}
+
+func TestUnmatchedAndErrors(t *testing.T) {
+	l := NewLedger("EUR", FIFO)
+	l.AddTrade(trade(1, 1000, "BTC_EUR", "BUY", 0.5, 100, 0))
+	l.AddTrade(trade(2, 2000, "BTC_EUR", "SELL", 1, 100, 0))
+	if r := l.Realizations(time.Time{}, time.Time{})[0]; r.Unmatched != 0.5 || r.PnL != 50 {
+		t.Errorf("Expected half unmatched at zero cost, got %+v", r)
+	}
+
+	if err := l.AddTrade(trade(3, 3000, "ETH_BTC", "BUY", 1, 0.05, 0)); err == nil {
+		t.Error("Expected error without a converter")
+	}
+	if err := l.AddTrade(trade(4, 3000, "BTCEUR", "BUY", 1, 100, 0)); err == nil {
+		t.Error("Expected error for invalid pair")
+	}
+	if err := l.AddTransfer(Transfer{Currency: "EUR", Type: KindSell, Amount: 1}); err == nil {
+		t.Error("Expected error for invalid transfer type")
+	}
+}
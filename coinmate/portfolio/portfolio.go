@@ -0,0 +1,183 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+// Balance of one currency valued in the snapshot currency
+type Holding struct {
+	Currency  string  `json:"currency"`
+	Balance   float64 `json:"balance"`
+	Available float64 `json:"available"`
+	Reserved  float64 `json:"reserved"`
+	// Price of one unit and value of the balance, zero when unpriced
+	Price float64 `json:"price"`
+	Value float64 `json:"value"`
+	// Percent of the total value
+	Allocation float64 `json:"allocation"`
+	// Currencies converted through, e.g. [ETH BTC EUR]
+	Route []string `json:"route,omitempty"`
+}
+
+// Valued balances at one point in time
+type Snapshot struct {
+	Timestamp int64   `json:"timestamp"`
+	Currency  string  `json:"currency"`
+	Total     float64 `json:"total"`
+	// Largest value first
+	Holdings []Holding `json:"holdings"`
+	// Currencies held without a route to the snapshot currency, left out
+	// of the total
+	Unpriced []string `json:"unpriced,omitempty"`
+}
+
+// Portfolio values balances with the tickers of all currency pairs
+type Portfolio struct {
+	Balances secure.BalancesInterface
+	Tickers  *public.TickerAll
+	// Currency of snapshots
+	Currency string
+	// Mid price when empty
+	Source PriceSource
+	// Snapshots are not kept when nil
+	Store Store
+
+	// Called after every snapshot
+	OnSnapshot func(Snapshot)
+
+	now func() time.Time
+}
+
+// Return portfolio taking snapshots in currency
+func NewPortfolio(balances secure.BalancesInterface, tickers *public.TickerAll, currency string) *Portfolio {
+	return &Portfolio{
+		Balances: balances,
+		Tickers:  tickers,
+		Currency: strings.ToUpper(currency),
+		now:      time.Now,
+	}
+}
+
+// Current rates
+func (p *Portfolio) Rates() (*Rates, error) {
+	r, err := p.Tickers.GetTickerAll()
+	if err != nil {
+		return nil, err
+	}
+	if r.Error {
+		return nil, fmt.Errorf("ticker-all: %s", r.ErrorMessage)
+	}
+	source := p.Source
+	if source == "" {
+		source = PriceMid
+	}
+	return NewRates(r.Data, source), nil
+}
+
+// Value balances in currency, which may differ from the snapshot currency
+func (p *Portfolio) Value(currency string) (Snapshot, error) {
+	balances, err := p.Balances.GetBalances()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if balances.Error {
+		return Snapshot{}, fmt.Errorf("balances: %s", balances.ErrorMessage)
+	}
+	rates, err := p.Rates()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return Value(balances.Data, rates, currency, p.now()), nil
+}
+
+// Value balances in the snapshot currency and keep the snapshot in the store
+func (p *Portfolio) Snapshot() (Snapshot, error) {
+	s, err := p.Value(p.Currency)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if p.Store != nil {
+		if err := p.Store.Append(s); err != nil {
+			return s, err
+		}
+	}
+	if p.OnSnapshot != nil {
+		p.OnSnapshot(s)
+	}
+	return s, nil
+}
+
+// Stored snapshots in [from, to), zero times leave the range open
+func (p *Portfolio) Snapshots(from, to time.Time) ([]Snapshot, error) {
+	if p.Store == nil {
+		return nil, fmt.Errorf("no snapshot store")
+	}
+	return p.Store.Load(from, to)
+}
+
+// Take snapshots periodically until the context is done
+func (p *Portfolio) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.Snapshot(); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Value balances in currency at rates, zero balances are left out
+func Value(balances map[string]secure.BalanceCurrency, rates *Rates, currency string, at time.Time) Snapshot {
+	currency = strings.ToUpper(currency)
+	s := Snapshot{Timestamp: at.UnixMilli(), Currency: currency}
+
+	for name, b := range balances {
+		if b.Balance == 0 {
+			continue
+		}
+		if b.Currency != "" {
+			name = b.Currency
+		}
+		h := Holding{
+			Currency:  strings.ToUpper(name),
+			Balance:   float64(b.Balance),
+			Available: float64(b.Available),
+			Reserved:  float64(b.Reserved),
+		}
+		rate, route, err := rates.Rate(h.Currency, currency)
+		if err != nil {
+			s.Unpriced = append(s.Unpriced, h.Currency)
+		} else {
+			h.Price, h.Value, h.Route = rate, h.Balance*rate, route
+			s.Total += h.Value
+		}
+		s.Holdings = append(s.Holdings, h)
+	}
+
+	for i := range s.Holdings {
+		if s.Total > 0 {
+			s.Holdings[i].Allocation = s.Holdings[i].Value / s.Total * 100
+		}
+	}
+	sort.Slice(s.Holdings, func(i, j int) bool {
+		if s.Holdings[i].Value != s.Holdings[j].Value {
+			return s.Holdings[i].Value > s.Holdings[j].Value
+		}
+		return s.Holdings[i].Currency < s.Holdings[j].Currency
+	})
+	sort.Strings(s.Unpriced)
+	return s
+}
@@ -0,0 +1,136 @@
+package portfolio
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+func newTestPortfolio(t *testing.T) (*Portfolio, *coinmatetest.Server) {
+	t.Helper()
+	server := coinmatetest.NewServer()
+	t.Cleanup(server.Close)
+
+	server.AddAccount("1", "public-key", "private-key")
+	server.Deposit("1", "EUR", 5000)
+	server.Deposit("1", "BTC", 0.1)
+	server.Deposit("1", "ETH", 2)
+	server.AddLiquidity("BTC_EUR", "BUY", 49000, 1)
+	server.AddLiquidity("BTC_EUR", "SELL", 51000, 1)
+	server.AddLiquidity("BTC_CZK", "BUY", 1240000, 1)
+	server.AddLiquidity("BTC_CZK", "SELL", 1260000, 1)
+	server.AddLiquidity("ETH_EUR", "BUY", 2400, 1)
+	server.AddLiquidity("ETH_EUR", "SELL", 2600, 1)
+
+	client := server.NewClient("1")
+	return NewPortfolio(&secure.Balances{Client: client}, &public.TickerAll{Client: client}, "eur"), server
+}
+
+func TestValue(t *testing.T) {
+	p, _ := newTestPortfolio(t)
+
+	s, err := p.Value("EUR")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// 0.1 BTC at 50000, 2 ETH at 2500 and 5000 EUR
+	if math.Abs(s.Total-15000) > 1e-3 || len(s.Holdings) != 3 {
+		t.Fatalf("Expected 15000 EUR in 3 holdings, got %+v", s)
+	}
+	expected := []string{"BTC", "ETH", "EUR"}
+	for i, h := range s.Holdings {
+		if h.Currency != expected[i] || math.Abs(h.Allocation-100.0/3) > 1e-3 {
+			t.Errorf("Expected %s with a third of the value, got %+v", expected[i], h)
+		}
+	}
+
+	// ETH has no CZK pair and is valued through EUR and BTC
+	s, _ = p.Value("CZK")
+	var eth Holding
+	for _, h := range s.Holdings {
+		if h.Currency == "ETH" {
+			eth = h
+		}
+	}
+	if !reflect.DeepEqual(eth.Route, []string{"ETH", "EUR", "BTC", "CZK"}) || math.Abs(eth.Value-2*2500/50000.0*1250000) > 1e-3 {
+		t.Errorf("Expected ETH routed through EUR and BTC, got %+v", eth)
+	}
+}
+
+func TestUnpricedCurrency(t *testing.T) {
+	p, server := newTestPortfolio(t)
+	server.Deposit("1", "LTC", 3)
+
+	s, err := p.Value("EUR")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(s.Unpriced, []string{"LTC"}) || math.Abs(s.Total-15000) > 1e-3 {
+		t.Errorf("Expected LTC left out of the total, got %+v", s)
+	}
+	if ltc := s.Holdings[len(s.Holdings)-1]; ltc.Currency != "LTC" || ltc.Balance != 3 || ltc.Value != 0 {
+		t.Errorf("Expected LTC listed without value, got %+v", ltc)
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	p, server := newTestPortfolio(t)
+	p.Store = NewFileStore(filepath.Join(t.TempDir(), "portfolio", "snapshots.jsonl"))
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	var taken []Snapshot
+	p.OnSnapshot = func(s Snapshot) { taken = append(taken, s) }
+
+	p.Snapshot()
+	now = now.Add(time.Hour)
+	// The bid takes the ask at 51000, leaving the book at 49000/61000
+	server.AddLiquidity("BTC_EUR", "BUY", 59000, 1)
+	server.AddLiquidity("BTC_EUR", "SELL", 61000, 1)
+	p.Snapshot()
+
+	snapshots, err := p.Snapshots(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(snapshots) != 2 || len(taken) != 2 || math.Abs(snapshots[1].Total-snapshots[0].Total-500) > 1e-3 {
+		t.Fatalf("Expected two snapshots 500 EUR apart, got %+v", snapshots)
+	}
+
+	later, _ := p.Snapshots(now, time.Time{})
+	if len(later) != 1 || later[0].Timestamp != now.UnixMilli() || later[0].Currency != "EUR" {
+		t.Errorf("Expected the later snapshot, got %+v", later)
+	}
+}
+
+func TestFileStoreTornTrailingLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshots.jsonl")
+	store := NewFileStore(path)
+	store.Append(Snapshot{Timestamp: 1, Currency: "EUR", Total: 100})
+
+	// Crash in the middle of appending the second snapshot
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"timestamp":2,"curr`)
+	f.Close()
+
+	snapshots, err := store.Load(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Expected torn line to be skipped, got %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].Total != 100 {
+		t.Errorf("Expected first snapshot only, got %+v", snapshots)
+	}
+
+	if err := store.Append(Snapshot{Timestamp: 3, Currency: "EUR", Total: 300}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	snapshots, err = store.Load(time.Time{}, time.Time{})
+	if err != nil || len(snapshots) != 2 || snapshots[1].Timestamp != 3 {
+		t.Errorf("Expected torn line to be replaced by snapshot 3, got %+v, %v", snapshots, err)
+	}
+}
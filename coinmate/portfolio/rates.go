@@ -0,0 +1,127 @@
+package portfolio
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"tourGo/coinmate/public"
+)
+
+// Price used to value holdings
+type PriceSource string
+
+const (
+	// Middle of bid and ask
+	PriceMid PriceSource = "MID"
+	// What selling would get: the bid selling the base currency of a pair,
+	// the ask buying it
+	PriceBid  PriceSource = "BID"
+	PriceLast PriceSource = "LAST"
+)
+
+// Conversion rates between currencies from tickers of all currency pairs.
+// Currencies without a direct pair are converted through intermediate
+// currencies, taking the route with the fewest pairs.
+type Rates struct {
+	// Amount of the second currency for one unit of the first
+	edges map[string]map[string]float64
+}
+
+type route struct {
+	rate float64
+	path []string
+}
+
+// Return rates from tickers keyed by currency pair like "BTC_EUR". Prices
+// missing for the source fall back to the last price, pairs without any
+// price are left out.
+func NewRates(tickers map[string]public.TickerAllItem, source PriceSource) *Rates {
+	r := &Rates{edges: map[string]map[string]float64{}}
+	for pair, t := range tickers {
+		base, quote, ok := strings.Cut(strings.ToUpper(pair), "_")
+		if !ok || base == "" || quote == "" {
+			continue
+		}
+
+		sell, buy := t.Last, t.Last
+		switch source {
+		case PriceBid:
+			if t.Bid > 0 {
+				sell = t.Bid
+			}
+			if t.Ask > 0 {
+				buy = t.Ask
+			}
+		case PriceLast:
+		default:
+			if t.Bid > 0 && t.Ask > 0 {
+				sell = (t.Bid + t.Ask) / 2
+				buy = sell
+			}
+		}
+		if sell > 0 {
+			r.add(base, quote, sell)
+		}
+		if buy > 0 {
+			r.add(quote, base, 1/buy)
+		}
+	}
+	return r
+}
+
+// Amount of to for one unit of from and the currencies converted through,
+// starting with from and ending with to
+func (r *Rates) Rate(from, to string) (float64, []string, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return 1, []string{from}, nil
+	}
+
+	// Breadth-first search, neighbours in name order keep routes stable
+	visited := map[string]route{from: {rate: 1, path: []string{from}}}
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range r.neighbours(current) {
+			if _, ok := visited[next]; ok {
+				continue
+			}
+			prev := visited[current]
+			path := append(append([]string{}, prev.path...), next)
+			visited[next] = route{rate: prev.rate * r.edges[current][next], path: path}
+			if next == to {
+				return visited[next].rate, path, nil
+			}
+			queue = append(queue, next)
+		}
+	}
+	return 0, nil, fmt.Errorf("no rate from %s to %s", from, to)
+}
+
+// Convert amount of from to to
+func (r *Rates) Convert(amount float64, from, to string) (float64, error) {
+	rate, _, err := r.Rate(from, to)
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
+}
+
+// Helper functions
+
+func (r *Rates) add(from, to string, rate float64) {
+	if r.edges[from] == nil {
+		r.edges[from] = map[string]float64{}
+	}
+	r.edges[from][to] = rate
+}
+
+func (r *Rates) neighbours(currency string) []string {
+	names := make([]string, 0, len(r.edges[currency]))
+	for name := range r.edges[currency] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
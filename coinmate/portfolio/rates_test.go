@@ -0,0 +1,62 @@
+package portfolio
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"tourGo/coinmate/public"
+)
+
+var testTickers = map[string]public.TickerAllItem{
+	"BTC_EUR": {Last: 50000, Bid: 49900, Ask: 50100},
+	"BTC_CZK": {Last: 1250000, Bid: 1240000, Ask: 1260000},
+	"ETH_BTC": {Last: 0.05},
+	"SOL_USD": {Bid: 100, Ask: 102},
+}
+
+func TestRates(t *testing.T) {
+	rates := NewRates(testTickers, PriceMid)
+
+	tests := []struct {
+		from, to string
+		rate     float64
+		route    []string
+	}{
+		{"BTC", "EUR", 50000, []string{"BTC", "EUR"}},
+		{"eur", "btc", 1.0 / 50000, []string{"EUR", "BTC"}},
+		{"ETH", "CZK", 0.05 * 1250000, []string{"ETH", "BTC", "CZK"}},
+		{"EUR", "CZK", 1250000.0 / 50000, []string{"EUR", "BTC", "CZK"}},
+		{"CZK", "CZK", 1, []string{"CZK"}},
+	}
+	for _, tt := range tests {
+		rate, route, err := rates.Rate(tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("Expected rate %s/%s, got %v", tt.from, tt.to, err)
+		}
+		if math.Abs(rate-tt.rate) > 1e-9*tt.rate || !reflect.DeepEqual(route, tt.route) {
+			t.Errorf("Expected %s/%s %v via %v, got %v via %v", tt.from, tt.to, tt.rate, tt.route, rate, route)
+		}
+	}
+
+	if _, _, err := rates.Rate("SOL", "EUR"); err == nil {
+		t.Error("Expected no route from SOL to EUR")
+	}
+	if v, _ := rates.Convert(2, "SOL", "USD"); v != 202 {
+		t.Errorf("Expected 2 SOL at mid 101, got %v", v)
+	}
+}
+
+func TestRatesBidSource(t *testing.T) {
+	rates := NewRates(testTickers, PriceBid)
+
+	if rate, _, _ := rates.Rate("BTC", "EUR"); rate != 49900 {
+		t.Errorf("Expected BTC sold at the bid, got %v", rate)
+	}
+	if rate, _, _ := rates.Rate("EUR", "BTC"); rate != 1.0/50100 {
+		t.Errorf("Expected BTC bought at the ask, got %v", rate)
+	}
+	// Pairs without a book are valued at the last price
+	if rate, _, _ := rates.Rate("ETH", "BTC"); rate != 0.05 {
+		t.Errorf("Expected last price, got %v", rate)
+	}
+}
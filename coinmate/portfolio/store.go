@@ -0,0 +1,108 @@
+package portfolio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate/filestore"
+)
+
+// Persistence of snapshots
+type Store interface {
+	Append(snapshot Snapshot) error
+	// Snapshots taken in [from, to), zero times leave the range open
+	Load(from, to time.Time) ([]Snapshot, error)
+}
+
+// Append-only store writing snapshots as newline-delimited JSON into one file.
+// A last line torn by a crash during append is skipped when loading and
+// replaced by the next append.
+type FileStore struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Return file store at path, the directory is created on first append
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+// Append snapshot
+func (s *FileStore) Append(snapshot Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot store directory: %w", err)
+	}
+	if err := filestore.TruncateTornLine(s.Path); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", s.Path, err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", s.Path, err)
+	}
+	return f.Close()
+}
+
+// Load snapshots in [from, to), none when the file does not exist yet
+func (s *FileStore) Load(from, to time.Time) ([]Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", s.Path, err)
+	}
+	defer f.Close()
+
+	var snapshots []Snapshot
+	scanner := filestore.NewLineScanner(f)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		snapshot := Snapshot{}
+		if err := json.Unmarshal(line, &snapshot); err != nil {
+			// Torn by a crash during append, the snapshot was never stored
+			if scanner.Torn() {
+				continue
+			}
+			return nil, fmt.Errorf("failed to decode snapshot in %s: %w", s.Path, err)
+		}
+		if inRange(snapshot.Timestamp, from, to) {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.Path, err)
+	}
+	return snapshots, nil
+}
+
+// Helper functions
+
+func inRange(timestamp int64, from, to time.Time) bool {
+	if !from.IsZero() && timestamp < from.UnixMilli() {
+		return false
+	}
+	return to.IsZero() || timestamp < to.UnixMilli()
+}
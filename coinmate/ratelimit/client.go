@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+	"tourGo/coinmate"
+)
+
+// Requests per minute allowed by Coinmate
+const CoinmateRequestsPerMinute = 100
+
+// Client decorates a ClientInterface and delays requests so that at most
+// Burst requests are sent at once and Rate per second on average. Public
+// and secure requests share the budget, so one client can be handed to all
+// services talking to Coinmate:
+//
+//	limited := ratelimit.NewClient(client, ratelimit.CoinmateRequestsPerMinute/60.0, 10)
+//	ticker := &public.Ticker{Client: limited}
+type Client struct {
+	coinmate.ClientInterface
+
+	// Called when a request had to wait
+	OnWait func(d time.Duration)
+
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+	sleep  func(time.Duration)
+}
+
+// Return client allowing rate requests per second with bursts of burst
+// requests, a burst below one is one
+func NewClient(client coinmate.ClientInterface, rate float64, burst int) *Client {
+	b := float64(max(1, burst))
+	return &Client{
+		ClientInterface: client,
+		rate:            rate,
+		burst:           b,
+		tokens:          b,
+		now:             time.Now,
+		sleep:           time.Sleep,
+	}
+}
+
+// Make public request once the limit allows it
+func (c *Client) MakePublicRequest(r coinmate.Request) (coinmate.Response, error) {
+	c.wait()
+	return c.ClientInterface.MakePublicRequest(r)
+}
+
+// Make secure request once the limit allows it
+func (c *Client) MakeSecureRequest(r coinmate.Request) (coinmate.Response, error) {
+	c.wait()
+	return c.ClientInterface.MakeSecureRequest(r)
+}
+
+// Helper functions
+
+// Take a token, waiting for it when the bucket is empty. Tokens are
+// reserved in order, so concurrent callers are served first come first
+// served.
+func (c *Client) wait() {
+	if c.rate <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	now := c.now()
+	if !c.last.IsZero() {
+		c.tokens = min(c.burst, c.tokens+now.Sub(c.last).Seconds()*c.rate)
+	}
+	c.last = now
+	c.tokens--
+	var d time.Duration
+	if c.tokens < 0 {
+		d = time.Duration(-c.tokens / c.rate * float64(time.Second))
+	}
+	c.mu.Unlock()
+
+	if d > 0 {
+		if c.OnWait != nil {
+			c.OnWait(d)
+		}
+		c.sleep(d)
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+	"tourGo/coinmate"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/public"
+)
+
+func newTestClient(t *testing.T, rate float64, burst int) (*Client, *time.Time, *[]time.Duration) {
+	t.Helper()
+	server := coinmatetest.NewServer()
+	t.Cleanup(server.Close)
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var waits []time.Duration
+	c := NewClient(server.NewClient(""), rate, burst)
+	c.now = func() time.Time { return now }
+	c.sleep = func(d time.Duration) {
+		waits = append(waits, d)
+		now = now.Add(d)
+	}
+	return c, &now, &waits
+}
+
+func TestBurstThenRate(t *testing.T) {
+	c, now, waits := newTestClient(t, 2, 3)
+	ticker := &public.Ticker{Client: c}
+
+	for i := 0; i < 5; i++ {
+		if _, err := ticker.GetTicker("BTC_EUR"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	// Three requests in the burst, then one every half second
+	if len(*waits) != 2 || (*waits)[0] != 500*time.Millisecond || (*waits)[1] != 500*time.Millisecond {
+		t.Errorf("Expected two waits of 500ms, got %v", *waits)
+	}
+
+	// The bucket refills while idle, up to the burst
+	*now = now.Add(time.Hour)
+	*waits = nil
+	for i := 0; i < 3; i++ {
+		c.MakePublicRequest(coinmate.Request{HTTPMethod: "GET", URL: c.GetBaseUrl() + "/currencies"})
+	}
+	if len(*waits) != 0 {
+		t.Errorf("Expected no waits after idling, got %v", *waits)
+	}
+}
+
+func TestSecureRequestsShareLimit(t *testing.T) {
+	c, _, waits := newTestClient(t, 1, 1)
+
+	c.MakePublicRequest(coinmate.Request{HTTPMethod: "GET", URL: c.GetBaseUrl() + "/currencies"})
+	c.MakeSecureRequest(coinmate.Request{HTTPMethod: "POST", URL: c.GetBaseUrl() + "/balances", Body: c.GetRequestBody(nil)})
+	if len(*waits) != 1 || (*waits)[0] != time.Second {
+		t.Errorf("Expected secure request to wait 1s, got %v", *waits)
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	c, _, waits := newTestClient(t, 0, 1)
+	for i := 0; i < 10; i++ {
+		c.MakePublicRequest(coinmate.Request{HTTPMethod: "GET", URL: c.GetBaseUrl() + "/currencies"})
+	}
+	if len(*waits) != 0 {
+		t.Errorf("Expected no waits without a rate, got %v", *waits)
+	}
+}
@@ -0,0 +1,199 @@
+package recorder
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate/public"
+)
+
+const (
+	defaultPollInterval = 30 * time.Second
+	defaultLookback     = 60
+)
+
+// Recorder captures trades of the configured currency pairs into a Store.
+// Trades can be polled from the transactions endpoint or fed from any other
+// source through Record; either way they are deduplicated by transaction ID,
+// also across restarts as long as the lookback window overlaps stored data.
+type Recorder struct {
+	Transactions *public.Transactions
+	Store        *Store
+	Pairs        []string
+
+	// Time between polls in Run
+	PollInterval time.Duration
+	// minutesIntoHistory requested on every poll
+	Lookback uint64
+	// Called for every failed poll in Run, polling continues afterwards
+	OnError func(currencyPair string, err error)
+
+	mu     sync.Mutex
+	seen   map[string]map[string]int64
+	loaded map[string]bool
+	now    func() time.Time
+}
+
+// Return recorder with default poll interval and lookback
+func NewRecorder(transactions *public.Transactions, store *Store, pairs []string) *Recorder {
+	return &Recorder{
+		Transactions: transactions,
+		Store:        store,
+		Pairs:        pairs,
+		PollInterval: defaultPollInterval,
+		Lookback:     defaultLookback,
+		now:          time.Now,
+	}
+}
+
+// Poll all pairs once, returns number of newly stored trades
+func (r *Recorder) Poll() (int, error) {
+	total := 0
+	for _, pair := range r.Pairs {
+		n, err := r.PollPair(pair)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Poll single pair once, returns number of newly stored trades
+func (r *Recorder) PollPair(currencyPair string) (int, error) {
+	if r.Transactions == nil {
+		return 0, fmt.Errorf("transactions service must be set")
+	}
+
+	response, err := r.Transactions.GetTransactions(currencyPair, r.lookback())
+	if err != nil {
+		return 0, err
+	}
+	if response.Error {
+		return 0, fmt.Errorf("transactions request failed: %s", response.ErrorMessage)
+	}
+
+	return r.Record(currencyPair, response.Data)
+}
+
+// Store trades not seen before, returns number of newly stored trades
+func (r *Recorder) Record(currencyPair string, trades []public.TransactionsData) (int, error) {
+	if r.Store == nil {
+		return 0, fmt.Errorf("store must be set")
+	}
+	pair := strings.ToUpper(currencyPair)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.loadSeen(pair); err != nil {
+		return 0, err
+	}
+	seen := r.seen[pair]
+
+	fresh := make([]public.TransactionsData, 0, len(trades))
+	batch := map[string]bool{}
+	for _, tx := range trades {
+		if tx.TransactionId == "" {
+			return 0, fmt.Errorf("transactionId must not be empty")
+		}
+		if _, ok := seen[tx.TransactionId]; ok || batch[tx.TransactionId] {
+			continue
+		}
+		batch[tx.TransactionId] = true
+		fresh = append(fresh, tx)
+	}
+	sort.SliceStable(fresh, func(i, j int) bool { return fresh[i].Timestamp < fresh[j].Timestamp })
+
+	if err := r.Store.Append(pair, fresh); err != nil {
+		return 0, err
+	}
+	for _, tx := range fresh {
+		seen[tx.TransactionId] = tx.Timestamp
+	}
+	r.prune(pair)
+
+	return len(fresh), nil
+}
+
+// Poll all pairs every PollInterval until ctx is cancelled
+func (r *Recorder) Run(ctx context.Context) error {
+	interval := r.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, pair := range r.Pairs {
+			if _, err := r.PollPair(pair); err != nil && r.OnError != nil {
+				r.OnError(pair, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Helper functions
+
+func (r *Recorder) lookback() uint64 {
+	if r.Lookback == 0 {
+		return defaultLookback
+	}
+	return r.Lookback
+}
+
+// Window for which transaction IDs are remembered, twice the lookback so
+// that trades returned by overlapping polls are always recognised
+func (r *Recorder) window() time.Duration {
+	return 2 * time.Duration(r.lookback()) * time.Minute
+}
+
+func (r *Recorder) clock() time.Time {
+	if r.now == nil {
+		return time.Now()
+	}
+	return r.now()
+}
+
+// Seed deduplication state from trades already in the store
+func (r *Recorder) loadSeen(pair string) error {
+	if r.seen == nil {
+		r.seen = map[string]map[string]int64{}
+		r.loaded = map[string]bool{}
+	}
+	if r.loaded[pair] {
+		return nil
+	}
+
+	seen := map[string]int64{}
+	trades, err := r.Store.Load(pair, r.clock().Add(-r.window()), time.Time{})
+	if err != nil {
+		return fmt.Errorf("failed to load recorded trades: %w", err)
+	}
+	for _, tx := range trades {
+		seen[tx.TransactionId] = tx.Timestamp
+	}
+
+	r.seen[pair] = seen
+	r.loaded[pair] = true
+	return nil
+}
+
+func (r *Recorder) prune(pair string) {
+	cutoff := r.clock().Add(-r.window()).UnixMilli()
+	for id, timestamp := range r.seen[pair] {
+		if timestamp < cutoff {
+			delete(r.seen[pair], id)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package recorder
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+	"tourGo/coinmate"
+	"tourGo/coinmate/public"
+)
+
+// Mock client for testing
+type MockClient struct {
+	coinmate.ClientInterface
+	response *coinmate.Response
+	err      error
+	requests int
+}
+
+func (m *MockClient) GetBaseUrl() string {
+	return "https://coinmate.io/api"
+}
+
+func (m *MockClient) MakePublicRequest(r coinmate.Request) (coinmate.Response, error) {
+	m.requests++
+	if m.err != nil {
+		return coinmate.Response{}, m.err
+	}
+	return *m.response, nil
+}
+
+func transactionsResponse(body string) *coinmate.Response {
+	return &coinmate.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Body:       []byte(body),
+	}
+}
+
+const twoTrades = `{
+	"error": false,
+	"errorMessage": null,
+	"data": [
+		{"timestamp": 1704070800000, "transactionId": "2", "price": 101.0, "amount": 0.2, "currencyPair": "BTC_EUR", "tradeType": "SELL"},
+		{"timestamp": 1704067260000, "transactionId": "1", "price": 100.0, "amount": 0.1, "currencyPair": "BTC_EUR", "tradeType": "BUY"}
+	]
+}`
+
+func newTestRecorder(t *testing.T, client *MockClient, store *Store) *Recorder {
+	r := NewRecorder(&public.Transactions{Client: client}, store, []string{"BTC_EUR"})
+	r.now = func() time.Time { return time.UnixMilli(baseTime).Add(2 * time.Hour) }
+	return r
+}
+
+func TestRecorderPollDeduplicates(t *testing.T) {
+	client := &MockClient{response: transactionsResponse(twoTrades)}
+	store, _ := NewStore(t.TempDir())
+	r := newTestRecorder(t, client, store)
+
+	n, err := r.Poll()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 new trades, got %d", n)
+	}
+
+	n, err = r.Poll()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Expected no new trades on second poll, got %d", n)
+	}
+
+	trades, _ := store.Load("BTC_EUR", time.Time{}, time.Time{})
+	if len(trades) != 2 {
+		t.Fatalf("Expected 2 stored trades, got %d", len(trades))
+	}
+	if trades[0].TransactionId != "1" {
+		t.Errorf("Expected trades stored oldest first, got %s", trades[0].TransactionId)
+	}
+}
+
+func TestRecorderDeduplicatesAcrossRestart(t *testing.T) {
+	client := &MockClient{response: transactionsResponse(twoTrades)}
+	store, _ := NewStore(t.TempDir())
+
+	if _, err := newTestRecorder(t, client, store).Poll(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	n, err := newTestRecorder(t, client, store).Poll()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Expected restarted recorder to skip stored trades, got %d", n)
+	}
+}
+
+func TestRecorderRecordStream(t *testing.T) {
+	store, _ := NewStore(t.TempDir())
+	r := newTestRecorder(t, &MockClient{}, store)
+
+	n, err := r.Record("BTC_EUR", []public.TransactionsData{
+		trade("7", time.Hour, 100),
+		trade("7", time.Hour, 100),
+		trade("8", time.Hour, 100),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected duplicates within a batch to be dropped, got %d", n)
+	}
+
+	if _, err := r.Record("BTC_EUR", []public.TransactionsData{{Timestamp: baseTime}}); err == nil {
+		t.Error("Expected error for missing transaction ID")
+	}
+}
+
+func TestRecorderPollErrors(t *testing.T) {
+	store, _ := NewStore(t.TempDir())
+
+	r := newTestRecorder(t, &MockClient{err: &http.ProtocolError{}}, store)
+	if _, err := r.Poll(); err == nil {
+		t.Error("Expected error for network failure")
+	}
+
+	r = newTestRecorder(t, &MockClient{response: transactionsResponse(`{"error": true, "errorMessage": "Invalid currency pair", "data": null}`)}, store)
+	if _, err := r.Poll(); err == nil {
+		t.Error("Expected error for API error response")
+	}
+}
+
+func TestRecorderRun(t *testing.T) {
+	client := &MockClient{response: transactionsResponse(twoTrades)}
+	store, _ := NewStore(t.TempDir())
+	r := newTestRecorder(t, client, store)
+	r.PollInterval = time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := r.Run(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if client.requests < 2 {
+		t.Errorf("Expected repeated polling, got %d requests", client.requests)
+	}
+
+	trades, _ := store.Load("BTC_EUR", time.Time{}, time.Time{})
+	if len(trades) != 2 {
+		t.Errorf("Expected 2 stored trades, got %d", len(trades))
+	}
+}
@@ -0,0 +1,283 @@
+package recorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate/filestore"
+	"tourGo/coinmate/public"
+)
+
+const (
+	fileExtension = ".jsonl"
+	dayLayout     = "2006-01-02"
+)
+
+// Append-only trade store. Trades are written as newline-delimited JSON into
+// one file per currency pair and UTC day of the trade timestamp:
+//
+//	<dir>/BTC_EUR/2024-01-01.jsonl
+//
+// A last line torn by a crash during append is skipped when reading and
+// replaced by the next append.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Return store rooted at dir, the directory is created when missing
+func NewStore(dir string) (*Store, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, fmt.Errorf("dir must not be empty")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Append trades of a currency pair to the daily files
+func (s *Store) Append(currencyPair string, trades []public.TransactionsData) error {
+	pairDir, err := s.pairDir(currencyPair)
+	if err != nil {
+		return err
+	}
+	if len(trades) == 0 {
+		return nil
+	}
+
+	byDay := map[string][]public.TransactionsData{}
+	for _, tx := range trades {
+		day := time.UnixMilli(tx.Timestamp).UTC().Format(dayLayout)
+		byDay[day] = append(byDay[day], tx)
+	}
+	days := make([]string, 0, len(byDay))
+	for day := range byDay {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(pairDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create pair directory: %w", err)
+	}
+	for _, day := range days {
+		if err := appendFile(filepath.Join(pairDir, day+fileExtension), byDay[day]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return UTC days for which trades of a currency pair are stored, oldest first
+func (s *Store) Days(currencyPair string) ([]time.Time, error) {
+	pairDir, err := s.pairDir(currencyPair)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(pairDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", pairDir, err)
+	}
+
+	var days []time.Time
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, fileExtension) {
+			continue
+		}
+		day, err := time.Parse(dayLayout, strings.TrimSuffix(name, fileExtension))
+		if err != nil {
+			continue
+		}
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	return days, nil
+}
+
+// Return iterator over stored trades with from <= timestamp < to. A zero
+// from or to leaves that side of the range open.
+func (s *Store) Iterator(currencyPair string, from, to time.Time) (*Iterator, error) {
+	days, err := s.Days(currencyPair)
+	if err != nil {
+		return nil, err
+	}
+	pairDir, _ := s.pairDir(currencyPair)
+
+	it := &Iterator{from: from, to: to}
+	for _, day := range days {
+		if !from.IsZero() && !day.Add(24*time.Hour).After(from) {
+			continue
+		}
+		if !to.IsZero() && !day.Before(to) {
+			continue
+		}
+		it.files = append(it.files, filepath.Join(pairDir, day.Format(dayLayout)+fileExtension))
+	}
+	return it, nil
+}
+
+// Load all stored trades of a currency pair within the range
+func (s *Store) Load(currencyPair string, from, to time.Time) ([]public.TransactionsData, error) {
+	it, err := s.Iterator(currencyPair, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var trades []public.TransactionsData
+	for it.Next() {
+		trades = append(trades, it.Trade())
+	}
+	return trades, it.Err()
+}
+
+// Iterator over stored trades, used as:
+//
+//	for it.Next() {
+//		tx := it.Trade()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type Iterator struct {
+	files   []string
+	from    time.Time
+	to      time.Time
+	file    *os.File
+	scanner *filestore.LineScanner
+	current public.TransactionsData
+	err     error
+}
+
+// Advance to the next trade, returns false when done or on error
+func (it *Iterator) Next() bool {
+	for it.err == nil {
+		if it.scanner == nil {
+			if len(it.files) == 0 {
+				return false
+			}
+			if !it.open(it.files[0]) {
+				return false
+			}
+			it.files = it.files[1:]
+		}
+
+		if !it.scanner.Scan() {
+			if err := it.scanner.Err(); err != nil {
+				it.err = fmt.Errorf("failed to read %s: %w", it.file.Name(), err)
+			}
+			it.closeFile()
+			continue
+		}
+
+		line := it.scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		tx := public.TransactionsData{}
+		if err := json.Unmarshal(line, &tx); err != nil {
+			// Torn by a crash during append, the trade was never stored
+			if it.scanner.Torn() {
+				continue
+			}
+			it.err = fmt.Errorf("failed to decode trade in %s: %w", it.file.Name(), err)
+			return false
+		}
+		if !it.from.IsZero() && tx.Timestamp < it.from.UnixMilli() {
+			continue
+		}
+		if !it.to.IsZero() && tx.Timestamp >= it.to.UnixMilli() {
+			continue
+		}
+		it.current = tx
+		return true
+	}
+	return false
+}
+
+// Return trade read by the last Next call
+func (it *Iterator) Trade() public.TransactionsData {
+	return it.current
+}
+
+// Return first error hit while iterating
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Release the open file, safe to call more than once
+func (it *Iterator) Close() error {
+	it.files = nil
+	return it.closeFile()
+}
+
+// Helper functions
+
+func (it *Iterator) open(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		it.err = fmt.Errorf("failed to open %s: %w", path, err)
+		return false
+	}
+	it.file = f
+	it.scanner = filestore.NewLineScanner(f)
+	return true
+}
+
+func (it *Iterator) closeFile() error {
+	it.scanner = nil
+	if it.file == nil {
+		return nil
+	}
+	err := it.file.Close()
+	it.file = nil
+	return err
+}
+
+func (s *Store) pairDir(currencyPair string) (string, error) {
+	pair := strings.ToUpper(strings.TrimSpace(currencyPair))
+	if pair == "" {
+		return "", fmt.Errorf("currencyPair must not be empty")
+	}
+	if strings.ContainsAny(pair, `/\.`) {
+		return "", fmt.Errorf("invalid currencyPair %q", currencyPair)
+	}
+	return filepath.Join(s.dir, pair), nil
+}
+
+func appendFile(path string, trades []public.TransactionsData) error {
+	if err := filestore.TruncateTornLine(path); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, tx := range trades {
+		if err := enc.Encode(tx); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to encode trade %s: %w", tx.TransactionId, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,160 @@
+package recorder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"tourGo/coinmate/public"
+)
+
+// 2024-01-01T00:00:00Z in milliseconds
+const baseTime int64 = 1704067200000
+
+func trade(id string, offset time.Duration, price float64) public.TransactionsData {
+	return public.TransactionsData{
+		Timestamp:     baseTime + offset.Milliseconds(),
+		TransactionId: id,
+		Price:         price,
+		Amount:        0.1,
+		CurrencyPair:  "BTC_EUR",
+		TradeType:     "BUY",
+	}
+}
+
+func TestStoreAppendRotatesPerDay(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	err = store.Append("btc_eur", []public.TransactionsData{
+		trade("1", time.Hour, 100),
+		trade("2", 25*time.Hour, 101),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	err = store.Append("BTC_EUR", []public.TransactionsData{trade("3", 26*time.Hour, 102)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, name := range []string{"2024-01-01.jsonl", "2024-01-02.jsonl"} {
+		if _, err := os.Stat(filepath.Join(dir, "BTC_EUR", name)); err != nil {
+			t.Errorf("Expected file %s to exist: %v", name, err)
+		}
+	}
+
+	days, err := store.Days("BTC_EUR")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(days) != 2 {
+		t.Errorf("Expected 2 days, got %d", len(days))
+	}
+}
+
+func TestStoreIteratorRange(t *testing.T) {
+	store, _ := NewStore(t.TempDir())
+	store.Append("BTC_EUR", []public.TransactionsData{
+		trade("1", time.Hour, 100),
+		trade("2", 25*time.Hour, 101),
+		trade("3", 49*time.Hour, 102),
+	})
+
+	all, err := store.Load("BTC_EUR", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(all) != 3 || all[0].TransactionId != "1" || all[2].TransactionId != "3" {
+		t.Errorf("Unexpected trades %+v", all)
+	}
+
+	from := time.UnixMilli(baseTime).Add(2 * time.Hour)
+	to := time.UnixMilli(baseTime).Add(49 * time.Hour)
+	it, err := store.Iterator("BTC_EUR", from, to)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer it.Close()
+
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Trade().TransactionId)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(ids) != 1 || ids[0] != "2" {
+		t.Errorf("Expected only trade 2 in range, got %v", ids)
+	}
+}
+
+func TestStoreUnknownPairIsEmpty(t *testing.T) {
+	store, _ := NewStore(t.TempDir())
+
+	trades, err := store.Load("LTC_EUR", time.Time{}, time.Time{})
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if len(trades) != 0 {
+		t.Errorf("Expected no trades, got %d", len(trades))
+	}
+}
+
+func TestStoreCorruptLine(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := NewStore(dir)
+	store.Append("BTC_EUR", []public.TransactionsData{trade("1", 0, 100)})
+
+	f, _ := os.OpenFile(filepath.Join(dir, "BTC_EUR", "2024-01-01.jsonl"), os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString("not json\n")
+	f.Close()
+
+	if _, err := store.Load("BTC_EUR", time.Time{}, time.Time{}); err == nil {
+		t.Error("Expected error for corrupt line")
+	}
+}
+
+func TestStoreTornTrailingLine(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := NewStore(dir)
+	store.Append("BTC_EUR", []public.TransactionsData{trade("1", 0, 100), trade("2", 25*time.Hour, 101)})
+
+	// Crash in the middle of appending to the first day
+	f, _ := os.OpenFile(filepath.Join(dir, "BTC_EUR", "2024-01-01.jsonl"), os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"timestamp":1704067`)
+	f.Close()
+
+	trades, err := store.Load("BTC_EUR", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Expected torn line to be skipped, got %v", err)
+	}
+	if len(trades) != 2 || trades[1].TransactionId != "2" {
+		t.Errorf("Expected trades of both days, got %+v", trades)
+	}
+
+	if err := store.Append("BTC_EUR", []public.TransactionsData{trade("3", time.Hour, 102)}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	trades, err = store.Load("BTC_EUR", time.Time{}, time.Time{})
+	if err != nil || len(trades) != 3 || trades[1].TransactionId != "3" {
+		t.Errorf("Expected torn line to be replaced by trade 3, got %+v, %v", trades, err)
+	}
+}
+
+func TestStoreInvalidInput(t *testing.T) {
+	if _, err := NewStore(""); err == nil {
+		t.Error("Expected error for empty dir")
+	}
+
+	store, _ := NewStore(t.TempDir())
+	if err := store.Append("", nil); err == nil {
+		t.Error("Expected error for empty currency pair")
+	}
+	if err := store.Append("../etc", nil); err == nil {
+		t.Error("Expected error for currency pair with path separator")
+	}
+}
@@ -0,0 +1,439 @@
+package risk
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	buySide  = "BUY"
+	sellSide = "SELL"
+
+	transferPageSize = 1000
+)
+
+// Guard wraps an order interface and checks every new order against
+// Limits before it is sent. Violations are returned as *Violation errors
+// and the order never reaches the wrapped interface. Reads and cancels are
+// passed through unchecked. Market data needed by the configured limits is
+// fetched from Ticker and Balances, a failure to fetch it rejects the order
+// as well, with an error matching secure.ErrNotSent.
+//
+// Orders are checked and sent one at a time, so concurrent orders cannot
+// together exceed a limit.
+type Guard struct {
+	Orders   secure.OrderInterface
+	Ticker   *public.Ticker
+	Balances secure.BalancesInterface
+	Limits   Limits
+	// Deposits and withdrawals left out of the daily loss, optional
+	Transfers secure.TransferHistoryInterface
+
+	// Called for every rejected order
+	OnViolation func(*Violation)
+
+	mu       sync.Mutex
+	day      string
+	dayStart float64
+	// Time of the first valuation of the day in milliseconds
+	dayStartTime int64
+	now          func() time.Time
+}
+
+// Return guard enforcing limits on orders
+func NewGuard(orders secure.OrderInterface, ticker *public.Ticker, balances secure.BalancesInterface, limits Limits) (*Guard, error) {
+	if err := limits.validate(); err != nil {
+		return nil, err
+	}
+	return &Guard{
+		Orders:   orders,
+		Ticker:   ticker,
+		Balances: balances,
+		Limits:   limits,
+		now:      time.Now,
+	}, nil
+}
+
+// Order to be checked
+type request struct {
+	pair  string
+	side  string
+	limit bool
+	// Base amount, zero for instant buys
+	amount float64
+	price  float64
+	// Quote amount of instant buys
+	total float64
+}
+
+// Order history
+func (g *Guard) GetHistory(currencyPair string, limit int64) (secure.OrderHistoryResponse, error) {
+	return g.Orders.GetHistory(currencyPair, limit)
+}
+
+// Open orders
+func (g *Guard) GetOpenOrders(currencyPair string) (secure.OpenOrdersResponse, error) {
+	return g.Orders.GetOpenOrders(currencyPair)
+}
+
+// Buy limit
+func (g *Guard) BuyLimit(amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (secure.SellLimit, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.check(request{pair: currencyPair, side: buySide, limit: true, amount: amount, price: price}); err != nil {
+		return secure.SellLimit{}, err
+	}
+	return g.Orders.BuyLimit(amount, price, stopPrice, currencyPair, hidden, immediateOrCancel, clientOrderId)
+}
+
+// Sell limit
+func (g *Guard) SellLimit(amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (secure.SellLimit, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.check(request{pair: currencyPair, side: sellSide, limit: true, amount: amount, price: price}); err != nil {
+		return secure.SellLimit{}, err
+	}
+	return g.Orders.SellLimit(amount, price, stopPrice, currencyPair, hidden, immediateOrCancel, clientOrderId)
+}
+
+// Buy instantly for a total in the quote currency
+func (g *Guard) BuyInstant(total float64, cp string, clientOrderId uint64) (secure.BuyAndSellResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.check(request{pair: cp, side: buySide, total: total}); err != nil {
+		return secure.BuyAndSellResponse{}, err
+	}
+	return g.Orders.BuyInstant(total, cp, clientOrderId)
+}
+
+// Sell instantly an amount in the base currency
+func (g *Guard) SellInstant(amount float64, cp string, clientOrderId uint64) (secure.BuyAndSellResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.check(request{pair: cp, side: sellSide, amount: amount}); err != nil {
+		return secure.BuyAndSellResponse{}, err
+	}
+	return g.Orders.SellInstant(amount, cp, clientOrderId)
+}
+
+// Cancel order
+func (g *Guard) CancelOrder(orderId uint64) (secure.CancelOrderResponse, error) {
+	return g.Orders.CancelOrder(orderId)
+}
+
+// Cancel order with info
+func (g *Guard) CancelOrderWithInfo(orderId uint64) (secure.CancelOrderWithInfoResponse, error) {
+	return g.Orders.CancelOrderWithInfo(orderId)
+}
+
+// Loss in LossCurrency since the first valuation of the UTC day, taken by
+// the first order checked or DailyLoss call of the day. Negative for a gain.
+func (g *Guard) DailyLoss() (float64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.dailyLoss()
+}
+
+// Helper functions
+
+// Run all configured checks. Failures other than violations are wrapped in
+// secure.ErrNotSent, the order was not sent either way.
+func (g *Guard) check(r request) error {
+	err := g.checks(r)
+	var v *Violation
+	if err == nil || errors.As(err, &v) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", secure.ErrNotSent, err)
+}
+
+// Run all configured checks, cheapest first
+func (g *Guard) checks(r request) error {
+	r.pair = strings.ToUpper(r.pair)
+	l := g.Limits
+	if err := l.validate(); err != nil {
+		return fmt.Errorf("risk check failed: %w", err)
+	}
+
+	var ticker *public.TickerData
+	needsTicker := (l.PriceCollar > 0 && r.limit) || (!r.limit && (l.MaxNotional[r.pair] > 0 || len(l.MaxPosition) > 0))
+	if needsTicker {
+		t, err := g.ticker(r.pair)
+		if err != nil {
+			return err
+		}
+		ticker = &t
+	}
+
+	// Price instant orders at the side they take, limits at their limit
+	price := r.price
+	if !r.limit && ticker != nil {
+		price = reference(*ticker, r.side)
+		if r.side == buySide && price > 0 {
+			r.amount = r.total / price
+		}
+	}
+
+	if limit := l.MaxNotional[r.pair]; limit > 0 {
+		notional := r.amount * price
+		if r.side == buySide && !r.limit {
+			notional = r.total
+		}
+		if notional > limit {
+			return g.reject(&Violation{Rule: RuleMaxNotional, CurrencyPair: r.pair, Limit: limit, Value: notional})
+		}
+	}
+
+	if l.PriceCollar > 0 && r.limit {
+		ref := reference(*ticker, r.side)
+		if ref > 0 {
+			deviation := math.Abs(r.price/ref - 1)
+			if deviation > l.PriceCollar {
+				return g.reject(&Violation{Rule: RulePriceCollar, CurrencyPair: r.pair, Limit: l.PriceCollar, Value: deviation})
+			}
+		}
+	}
+
+	if l.MaxOpenOrders > 0 || len(l.MaxPosition) > 0 {
+		open, err := g.Orders.GetOpenOrders("")
+		if err != nil {
+			return fmt.Errorf("risk check failed: %w", err)
+		}
+		if open.Error {
+			return fmt.Errorf("risk check failed: %s", open.ErrorMessage)
+		}
+
+		if l.MaxOpenOrders > 0 {
+			count := 0
+			for _, o := range open.Data {
+				if strings.EqualFold(o.CurrencyPair, r.pair) {
+					count++
+				}
+			}
+			if count >= l.MaxOpenOrders {
+				return g.reject(&Violation{Rule: RuleMaxOpenOrders, CurrencyPair: r.pair, Limit: float64(l.MaxOpenOrders), Value: float64(count)})
+			}
+		}
+		if len(l.MaxPosition) > 0 {
+			if err := g.checkPosition(r, price, open.Data); err != nil {
+				return err
+			}
+		}
+	}
+
+	if l.MaxDailyLoss > 0 {
+		loss, err := g.dailyLoss()
+		if err != nil {
+			return err
+		}
+		if loss > l.MaxDailyLoss {
+			return g.reject(&Violation{Rule: RuleDailyLoss, CurrencyPair: r.pair, Currency: l.LossCurrency, Limit: l.MaxDailyLoss, Value: loss})
+		}
+	}
+	return nil
+}
+
+// Check the currency an order buys, including what open orders may buy
+func (g *Guard) checkPosition(r request, price float64, open []secure.OpenOrdersData) error {
+	base, quote, ok := strings.Cut(r.pair, "_")
+	if !ok {
+		return fmt.Errorf("risk check failed: invalid currency pair %s", r.pair)
+	}
+	bought, added := base, r.amount
+	if r.side == sellSide {
+		bought, added = quote, r.amount*price
+	}
+	limit := g.Limits.MaxPosition[bought]
+	if limit <= 0 {
+		return nil
+	}
+
+	balances, err := g.Balances.GetBalances()
+	if err != nil {
+		return fmt.Errorf("risk check failed: %w", err)
+	}
+	if balances.Error {
+		return fmt.Errorf("risk check failed: %s", balances.ErrorMessage)
+	}
+
+	position := float64(balances.Data[bought].Balance) + added
+	for _, o := range open {
+		b, q, _ := strings.Cut(strings.ToUpper(o.CurrencyPair), "_")
+		switch {
+		case o.Type == buySide && b == bought:
+			position += o.Amount
+		case o.Type == sellSide && q == bought:
+			position += o.Amount * o.Price
+		}
+	}
+	if position > limit {
+		return g.reject(&Violation{Rule: RuleMaxPosition, CurrencyPair: r.pair, Currency: bought, Limit: limit, Value: position})
+	}
+	return nil
+}
+
+// Current equity compared with the first valuation of the UTC day, without
+// the transfers since
+func (g *Guard) dailyLoss() (float64, error) {
+	if err := g.Limits.validate(); err != nil {
+		return 0, err
+	}
+	now := g.now()
+	equity, err := g.equity()
+	if err != nil {
+		return 0, err
+	}
+	day := now.UTC().Format(time.DateOnly)
+	if day != g.day {
+		g.day = day
+		g.dayStart = equity
+		g.dayStartTime = now.UnixMilli()
+		return 0, nil
+	}
+	transferred, err := g.transferred(g.dayStartTime)
+	if err != nil {
+		return 0, err
+	}
+	return g.dayStart - equity + transferred, nil
+}
+
+// Completed deposits less withdrawals since from valued in LossCurrency,
+// zero without Transfers. Fees are not included, they are losses.
+func (g *Guard) transferred(from int64) (float64, error) {
+	if g.Transfers == nil {
+		return 0, nil
+	}
+	quote := strings.ToUpper(g.Limits.LossCurrency)
+	total := 0.0
+	params := secure.TransferHistoryParams{Sort: "ASC", Limit: transferPageSize, TimestampFrom: from}
+	for {
+		r, err := g.Transfers.GetTransferHistory(params)
+		if err != nil {
+			return 0, fmt.Errorf("risk check failed: %w", err)
+		}
+		if r.Error {
+			return 0, fmt.Errorf("risk check failed: %s", r.ErrorMessage)
+		}
+		for _, t := range r.Data {
+			params.LastId = max(params.LastId, t.TransactionId)
+			if t.TransferStatus != secure.TransferCompleted || t.Timestamp < from {
+				continue
+			}
+			amount := t.Amount
+			switch t.TransferType {
+			case secure.TransferDeposit:
+			case secure.TransferWithdrawal:
+				amount = -amount
+			default:
+				continue
+			}
+			currency := strings.ToUpper(t.AmountCurrency)
+			if currency == quote {
+				total += amount
+				continue
+			}
+			value, err := g.value(currency, quote, amount)
+			if err != nil {
+				return 0, err
+			}
+			total += value
+		}
+		if len(r.Data) < transferPageSize {
+			return total, nil
+		}
+	}
+}
+
+// Total balances valued in LossCurrency at the last price. Currencies
+// without a pair to LossCurrency, e.g. dust of a delisted coin, are priced
+// through the inverse pair or left out.
+func (g *Guard) equity() (float64, error) {
+	quote := strings.ToUpper(g.Limits.LossCurrency)
+	balances, err := g.Balances.GetBalances()
+	if err != nil {
+		return 0, fmt.Errorf("risk check failed: %w", err)
+	}
+	if balances.Error {
+		return 0, fmt.Errorf("risk check failed: %s", balances.ErrorMessage)
+	}
+
+	equity := 0.0
+	for currency, b := range balances.Data {
+		amount := float64(b.Balance)
+		if amount == 0 {
+			continue
+		}
+		if strings.EqualFold(currency, quote) {
+			equity += amount
+			continue
+		}
+		value, err := g.value(strings.ToUpper(currency), quote, amount)
+		if err != nil {
+			return 0, err
+		}
+		equity += value
+	}
+	return equity, nil
+}
+
+// Value amount of currency in quote, zero when no pair connects them
+func (g *Guard) value(currency, quote string, amount float64) (float64, error) {
+	t, ok, err := g.lookup(currency + "_" + quote)
+	if err != nil || ok {
+		return amount * t.Last, err
+	}
+	t, ok, err = g.lookup(quote + "_" + currency)
+	if err != nil || !ok || t.Last <= 0 {
+		return 0, err
+	}
+	return amount / t.Last, nil
+}
+
+func (g *Guard) ticker(pair string) (public.TickerData, error) {
+	r, err := g.Ticker.GetTicker(pair)
+	if err != nil {
+		return public.TickerData{}, fmt.Errorf("risk check failed: %w", err)
+	}
+	if r.Error {
+		return public.TickerData{}, fmt.Errorf("risk check failed: ticker %s: %s", pair, r.ErrorMessage)
+	}
+	return r.Data, nil
+}
+
+// Ticker of pair, false when the exchange refuses the pair
+func (g *Guard) lookup(pair string) (public.TickerData, bool, error) {
+	r, err := g.Ticker.GetTicker(pair)
+	if err != nil {
+		return public.TickerData{}, false, fmt.Errorf("risk check failed: %w", err)
+	}
+	return r.Data, !r.Error, nil
+}
+
+func (g *Guard) reject(v *Violation) error {
+	if g.OnViolation != nil {
+		g.OnViolation(v)
+	}
+	return v
+}
+
+// Price an order on side trades against
+func reference(t public.TickerData, side string) float64 {
+	if side == buySide && t.Ask > 0 {
+		return t.Ask
+	}
+	if side == sellSide && t.Bid > 0 {
+		return t.Bid
+	}
+	return t.Last
+}
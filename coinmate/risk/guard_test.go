@@ -0,0 +1,211 @@
+package risk
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+func newTestGuard(t *testing.T, limits Limits) (*Guard, *coinmatetest.Server, *[]*Violation) {
+	t.Helper()
+	server := coinmatetest.NewServer()
+	t.Cleanup(server.Close)
+
+	server.AddAccount("1", "public-key", "private-key")
+	server.Deposit("1", "EUR", 10000)
+	server.Deposit("1", "BTC", 0.2)
+	server.AddLiquidity("BTC_EUR", "SELL", 50100, 1)
+	server.AddLiquidity("BTC_EUR", "BUY", 49900, 1)
+	server.AddTransaction(public.TransactionsData{CurrencyPair: "BTC_EUR", Price: 50000, Amount: 0.1, TradeType: "BUY"})
+
+	client := server.NewClient("1")
+	g, err := NewGuard(&secure.Order{Client: client}, &public.Ticker{Client: client}, &secure.Balances{Client: client}, limits)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	violations := &[]*Violation{}
+	g.OnViolation = func(v *Violation) { *violations = append(*violations, v) }
+	return g, server, violations
+}
+
+func violation(t *testing.T, err error, rule Rule) *Violation {
+	t.Helper()
+	var v *Violation
+	if !errors.As(err, &v) || v.Rule != rule {
+		t.Fatalf("Expected %s violation, got %v", rule, err)
+	}
+	return v
+}
+
+func TestMaxNotional(t *testing.T) {
+	g, _, violations := newTestGuard(t, Limits{MaxNotional: map[string]float64{"BTC_EUR": 5000}})
+
+	if _, err := g.BuyLimit(1000, 50000, 0, "BTC_EUR", false, false, 0); err == nil {
+		t.Fatal("Expected fat finger order to be rejected")
+	} else if v := violation(t, err, RuleMaxNotional); v.Value != 50000000 {
+		t.Errorf("Expected notional 50000000, got %f", v.Value)
+	}
+	if _, err := g.BuyInstant(6000, "BTC_EUR", 0); err == nil {
+		t.Error("Expected instant buy over limit to be rejected")
+	}
+	if _, err := g.SellInstant(0.11, "BTC_EUR", 0); err == nil {
+		t.Error("Expected instant sell valued at the bid to be rejected")
+	}
+
+	r, err := g.BuyLimit(0.09, 50000, 0, "BTC_EUR", false, false, 0)
+	if err != nil || r.Error || r.OrderId == 0 {
+		t.Errorf("Expected order within limit to be placed, got %+v (%v)", r, err)
+	}
+	if len(*violations) != 3 {
+		t.Errorf("Expected 3 violations reported, got %d", len(*violations))
+	}
+}
+
+func TestRejectedOrderMakesNoRequest(t *testing.T) {
+	g, server, _ := newTestGuard(t, Limits{MaxNotional: map[string]float64{"BTC_EUR": 5000}})
+
+	server.InjectFault("/buyLimit", coinmatetest.Fault{StatusCode: http.StatusInternalServerError})
+	_, err := g.BuyLimit(1000, 50000, 0, "BTC_EUR", false, false, 0)
+	violation(t, err, RuleMaxNotional)
+}
+
+func TestPriceCollar(t *testing.T) {
+	g, _, _ := newTestGuard(t, Limits{PriceCollar: 0.05})
+
+	if _, err := g.BuyLimit(0.01, 60000, 0, "BTC_EUR", false, false, 0); err == nil {
+		t.Fatal("Expected buy far above the ask to be rejected")
+	} else if v := violation(t, err, RulePriceCollar); math.Abs(v.Value-(60000.0/50100-1)) > 1e-9 {
+		t.Errorf("Expected deviation from the ask, got %f", v.Value)
+	}
+	if _, err := g.SellLimit(0.01, 40000, 0, "BTC_EUR", false, false, 0); err == nil {
+		t.Error("Expected sell far below the bid to be rejected")
+	}
+	if _, err := g.SellLimit(0.01, 51000, 0, "BTC_EUR", false, false, 0); err != nil {
+		t.Errorf("Expected sell near the bid to pass, got %v", err)
+	}
+}
+
+func TestMaxOpenOrders(t *testing.T) {
+	g, _, _ := newTestGuard(t, Limits{MaxOpenOrders: 2})
+
+	g.BuyLimit(0.01, 45000, 0, "BTC_EUR", false, false, 0)
+	g.BuyLimit(0.01, 45000, 0, "BTC_EUR", false, false, 0)
+	_, err := g.BuyLimit(0.01, 45000, 0, "BTC_EUR", false, false, 0)
+	violation(t, err, RuleMaxOpenOrders)
+
+	if _, err := g.BuyLimit(0.01, 2000, 0, "ETH_EUR", false, false, 0); err != nil {
+		t.Errorf("Expected orders on other pairs to pass, got %v", err)
+	}
+}
+
+func TestMaxPosition(t *testing.T) {
+	g, _, _ := newTestGuard(t, Limits{MaxPosition: map[string]float64{"BTC": 0.5, "EUR": 12000}})
+
+	if _, err := g.BuyLimit(0.2, 45000, 0, "BTC_EUR", false, false, 0); err != nil {
+		t.Fatalf("Expected order within position limit, got %v", err)
+	}
+	// 0.2 held, 0.2 on open buy order
+	_, err := g.BuyInstant(7000, "BTC_EUR", 0)
+	if v := violation(t, err, RuleMaxPosition); v.Currency != "BTC" || math.Abs(v.Value-(0.4+7000/50100.0)) > 1e-6 {
+		t.Errorf("Expected BTC position including open orders, got %+v", v)
+	}
+
+	_, err = g.SellLimit(0.1, 50000, 0, "BTC_EUR", false, false, 0)
+	if v := violation(t, err, RuleMaxPosition); v.Currency != "EUR" {
+		t.Errorf("Expected EUR position limit, got %+v", v)
+	}
+}
+
+func TestDailyLoss(t *testing.T) {
+	g, server, _ := newTestGuard(t, Limits{MaxDailyLoss: 1000, LossCurrency: "EUR"})
+
+	if _, err := g.BuyLimit(0.01, 45000, 0, "BTC_EUR", false, false, 0); err != nil {
+		t.Fatalf("Expected order to pass, got %v", err)
+	}
+
+	// BTC drops by 10000, the 0.2 BTC held lose 2000
+	server.AddTransaction(public.TransactionsData{CurrencyPair: "BTC_EUR", Price: 40000, Amount: 0.1, TradeType: "SELL"})
+	loss, err := g.DailyLoss()
+	if err != nil || math.Abs(loss-2000) > 1e-3 {
+		t.Fatalf("Expected loss of 2000, got %f (%v)", loss, err)
+	}
+	_, err = g.BuyLimit(0.01, 40000, 0, "BTC_EUR", false, false, 0)
+	violation(t, err, RuleDailyLoss)
+
+	if _, err := g.CancelOrder(1001); err != nil {
+		t.Errorf("Expected cancels to pass, got %v", err)
+	}
+}
+
+func TestDailyLossWithoutTransfers(t *testing.T) {
+	g, server, _ := newTestGuard(t, Limits{MaxDailyLoss: 1000, LossCurrency: "EUR"})
+	g.Transfers = &secure.TransferHistory{Client: server.NewClient("1")}
+	g.DailyLoss()
+
+	server.AddTransfer("1", "EUR", secure.TransferWithdrawal, 5000, 1)
+	server.AddTransfer("1", "BTC", secure.TransferDeposit, 0.1, 0)
+	loss, err := g.DailyLoss()
+	if err != nil || math.Abs(loss-1) > 1e-2 {
+		t.Fatalf("Expected only the withdrawal fee as loss, got %f (%v)", loss, err)
+	}
+	if _, err := g.BuyLimit(0.01, 45000, 0, "BTC_EUR", false, false, 0); err != nil {
+		t.Errorf("Expected order to pass, got %v", err)
+	}
+}
+
+func TestLossCurrencyRequired(t *testing.T) {
+	if _, err := NewGuard(nil, nil, nil, Limits{MaxDailyLoss: 1000}); err == nil {
+		t.Error("Expected error for MaxDailyLoss without LossCurrency")
+	}
+
+	g, _, _ := newTestGuard(t, Limits{})
+	g.Limits.MaxDailyLoss = 1000
+	if _, err := g.BuyLimit(0.01, 45000, 0, "BTC_EUR", false, false, 0); !errors.Is(err, secure.ErrNotSent) {
+		t.Errorf("Expected order not sent, got %v", err)
+	}
+}
+
+func TestMarketDataFailureRejectsOrder(t *testing.T) {
+	g, server, _ := newTestGuard(t, Limits{PriceCollar: 0.05})
+
+	server.InjectFault("/ticker", coinmatetest.Fault{StatusCode: http.StatusServiceUnavailable, Times: 1})
+	_, err := g.BuyLimit(0.01, 50000, 0, "BTC_EUR", false, false, 0)
+	var v *Violation
+	if err == nil || errors.As(err, &v) || !strings.Contains(err.Error(), "risk check failed") {
+		t.Errorf("Expected market data error, got %v", err)
+	}
+	if !errors.Is(err, secure.ErrNotSent) {
+		t.Errorf("Expected order not sent, got %v", err)
+	}
+
+	server.InjectFault("/balances", coinmatetest.Fault{StatusCode: http.StatusServiceUnavailable, Times: 1})
+	g.Limits = Limits{MaxDailyLoss: 1000, LossCurrency: "EUR"}
+	if _, err := g.BuyLimit(0.01, 50000, 0, "BTC_EUR", false, false, 0); !errors.Is(err, secure.ErrNotSent) {
+		t.Errorf("Expected order not sent, got %v", err)
+	}
+}
+
+func TestDailyLossSkipsUnpricedCurrencies(t *testing.T) {
+	g, server, _ := newTestGuard(t, Limits{MaxDailyLoss: 1000, LossCurrency: "EUR"})
+	server.Deposit("1", "DUST", 0.001)
+
+	loss, err := g.DailyLoss()
+	if err != nil || loss != 0 {
+		t.Fatalf("Expected no loss, got %f (%v)", loss, err)
+	}
+	if _, err := g.BuyLimit(0.01, 45000, 0, "BTC_EUR", false, false, 0); err != nil {
+		t.Errorf("Expected order to pass, got %v", err)
+	}
+}
+
+func TestGuardImplementsOrderInterface(t *testing.T) {
+	var o secure.OrderInterface = &Guard{}
+	if o == nil {
+		t.Error("Expected Guard to implement OrderInterface")
+	}
+}
@@ -0,0 +1,78 @@
+package risk
+
+import (
+	"fmt"
+	"strings"
+	"tourGo/coinmate/secure"
+)
+
+// Risk rule violated by an order
+type Rule string
+
+const (
+	RuleMaxNotional   Rule = "MAX_NOTIONAL"
+	RuleMaxPosition   Rule = "MAX_POSITION"
+	RulePriceCollar   Rule = "PRICE_COLLAR"
+	RuleMaxOpenOrders Rule = "MAX_OPEN_ORDERS"
+	RuleDailyLoss     Rule = "DAILY_LOSS"
+)
+
+// Trading limits, zero values and missing map entries are not enforced
+type Limits struct {
+	// Max order value in the quote currency per pair, e.g. {"BTC_EUR": 5000}
+	MaxNotional map[string]float64
+	// Max balance per currency including open buy orders and the new order,
+	// e.g. {"BTC": 0.5}
+	MaxPosition map[string]float64
+	// Max relative distance of a limit price from the ask for buys and the
+	// bid for sells, the last price when that side is empty. 0.05 allows 5 %.
+	PriceCollar float64
+	// Max open orders per pair
+	MaxOpenOrders int
+	// Max decrease of account equity since the first valuation of the UTC
+	// day, valued in LossCurrency, which must be set with it. Completed
+	// deposits and withdrawals are left out when the guard has Transfers,
+	// their fees count as losses.
+	MaxDailyLoss float64
+	LossCurrency string
+}
+
+// Error of limits that cannot be enforced
+func (l Limits) validate() error {
+	if l.MaxDailyLoss > 0 && strings.TrimSpace(l.LossCurrency) == "" {
+		return fmt.Errorf("LossCurrency must be set with MaxDailyLoss")
+	}
+	return nil
+}
+
+// Order rejected by a risk rule, returned as error by Guard before the
+// order is sent
+type Violation struct {
+	Rule         Rule
+	CurrencyPair string
+	// Currency of a position limit
+	Currency string
+	Limit    float64
+	Value    float64
+}
+
+func (v *Violation) Error() string {
+	switch v.Rule {
+	case RuleMaxNotional:
+		return fmt.Sprintf("risk: order value %.8g on %s exceeds limit %.8g", v.Value, v.CurrencyPair, v.Limit)
+	case RuleMaxPosition:
+		return fmt.Sprintf("risk: %s position %.8g would exceed limit %.8g", v.Currency, v.Value, v.Limit)
+	case RulePriceCollar:
+		return fmt.Sprintf("risk: price on %s deviates %.2f %% from market, limit %.2f %%", v.CurrencyPair, v.Value*100, v.Limit*100)
+	case RuleMaxOpenOrders:
+		return fmt.Sprintf("risk: %s has %.0f open orders, limit %.0f", v.CurrencyPair, v.Value, v.Limit)
+	case RuleDailyLoss:
+		return fmt.Sprintf("risk: daily loss %.8g exceeds limit %.8g", v.Value, v.Limit)
+	}
+	return fmt.Sprintf("risk: rule %s violated", v.Rule)
+}
+
+// Violations match secure.ErrNotSent, the order was never sent
+func (v *Violation) Is(target error) bool {
+	return target == secure.ErrNotSent
+}
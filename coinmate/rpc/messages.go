@@ -0,0 +1,188 @@
+package rpc
+
+// Messages of proto/coinmate/v1/coinmate.proto, field numbers in the
+// protobuf tags must match the definitions there
+
+type TickerRequest struct {
+	CurrencyPair string `protobuf:"1"`
+}
+
+type Ticker struct {
+	CurrencyPair string  `protobuf:"1"`
+	Last         float64 `protobuf:"2"`
+	High         float64 `protobuf:"3"`
+	Low          float64 `protobuf:"4"`
+	Amount       float64 `protobuf:"5"`
+	Bid          float64 `protobuf:"6"`
+	Ask          float64 `protobuf:"7"`
+	Change       float64 `protobuf:"8"`
+	Open         float64 `protobuf:"9"`
+	Timestamp    int64   `protobuf:"10"`
+}
+
+type OrderBookRequest struct {
+	CurrencyPair string `protobuf:"1"`
+	GroupByPrice bool   `protobuf:"2"`
+	Depth        uint32 `protobuf:"3"`
+}
+
+type PriceLevel struct {
+	Price  float64 `protobuf:"1"`
+	Amount float64 `protobuf:"2"`
+}
+
+type OrderBook struct {
+	CurrencyPair string       `protobuf:"1"`
+	Asks         []PriceLevel `protobuf:"2"`
+	Bids         []PriceLevel `protobuf:"3"`
+}
+
+type TradesRequest struct {
+	CurrencyPair       string `protobuf:"1"`
+	MinutesIntoHistory uint64 `protobuf:"2"`
+}
+
+type Trade struct {
+	TransactionId string  `protobuf:"1"`
+	Timestamp     int64   `protobuf:"2"`
+	CurrencyPair  string  `protobuf:"3"`
+	TradeType     string  `protobuf:"4"`
+	Price         float64 `protobuf:"5"`
+	Amount        float64 `protobuf:"6"`
+}
+
+type Trades struct {
+	Trades []Trade `protobuf:"1"`
+}
+
+type BalancesRequest struct{}
+
+type Balance struct {
+	Currency  string  `protobuf:"1"`
+	Balance   float64 `protobuf:"2"`
+	Reserved  float64 `protobuf:"3"`
+	Available float64 `protobuf:"4"`
+}
+
+type Balances struct {
+	Balances []Balance `protobuf:"1"`
+}
+
+type OpenOrdersRequest struct {
+	CurrencyPair string `protobuf:"1"`
+}
+
+type OrderHistoryRequest struct {
+	CurrencyPair string `protobuf:"1"`
+	Limit        int64  `protobuf:"2"`
+}
+
+type GetOrderRequest struct {
+	OrderId uint64 `protobuf:"1"`
+}
+
+type Order struct {
+	Id              uint64  `protobuf:"1"`
+	Timestamp       int64   `protobuf:"2"`
+	Type            string  `protobuf:"3"`
+	CurrencyPair    string  `protobuf:"4"`
+	Price           float64 `protobuf:"5"`
+	OriginalAmount  float64 `protobuf:"6"`
+	RemainingAmount float64 `protobuf:"7"`
+	Status          string  `protobuf:"8"`
+	StopPrice       float64 `protobuf:"9"`
+	OrderTradeType  string  `protobuf:"10"`
+	Hidden          bool    `protobuf:"11"`
+	ClientOrderId   uint64  `protobuf:"12"`
+}
+
+type Orders struct {
+	Orders []Order `protobuf:"1"`
+}
+
+type TradeHistoryRequest struct {
+	CurrencyPair  string `protobuf:"1"`
+	Limit         int64  `protobuf:"2"`
+	LastId        uint64 `protobuf:"3"`
+	Sort          string `protobuf:"4"`
+	TimestampFrom int64  `protobuf:"5"`
+	TimestampTo   int64  `protobuf:"6"`
+	OrderId       uint64 `protobuf:"7"`
+}
+
+type OwnTrade struct {
+	TransactionId    uint64  `protobuf:"1"`
+	CreatedTimestamp int64   `protobuf:"2"`
+	CurrencyPair     string  `protobuf:"3"`
+	Type             string  `protobuf:"4"`
+	OrderType        string  `protobuf:"5"`
+	OrderId          uint64  `protobuf:"6"`
+	Amount           float64 `protobuf:"7"`
+	Price            float64 `protobuf:"8"`
+	Fee              float64 `protobuf:"9"`
+	FeeType          string  `protobuf:"10"`
+}
+
+type OwnTrades struct {
+	Trades []OwnTrade `protobuf:"1"`
+}
+
+type Side int32
+
+const (
+	SideUnspecified Side = 0
+	SideBuy         Side = 1
+	SideSell        Side = 2
+)
+
+type OrderType int32
+
+const (
+	OrderTypeUnspecified OrderType = 0
+	OrderTypeLimit       OrderType = 1
+	OrderTypeInstant     OrderType = 2
+)
+
+type PlaceOrderRequest struct {
+	Side              Side      `protobuf:"1"`
+	Type              OrderType `protobuf:"2"`
+	CurrencyPair      string    `protobuf:"3"`
+	Amount            float64   `protobuf:"4"`
+	Total             float64   `protobuf:"5"`
+	Price             float64   `protobuf:"6"`
+	StopPrice         float64   `protobuf:"7"`
+	Hidden            bool      `protobuf:"8"`
+	ImmediateOrCancel bool      `protobuf:"9"`
+	ClientOrderId     uint64    `protobuf:"10"`
+}
+
+type PlaceOrderResponse struct {
+	OrderId uint64 `protobuf:"1"`
+}
+
+type CancelOrderRequest struct {
+	OrderId uint64 `protobuf:"1"`
+}
+
+type CancelOrderResponse struct {
+	Success         bool    `protobuf:"1"`
+	RemainingAmount float64 `protobuf:"2"`
+}
+
+type StreamTradesRequest struct {
+	CurrencyPair  string `protobuf:"1"`
+	IncludeRecent bool   `protobuf:"2"`
+}
+
+type StreamOrderBookRequest struct {
+	CurrencyPair string `protobuf:"1"`
+	Depth        uint32 `protobuf:"2"`
+}
+
+type OrderBookUpdate struct {
+	CurrencyPair string       `protobuf:"1"`
+	Snapshot     bool         `protobuf:"2"`
+	Asks         []PriceLevel `protobuf:"3"`
+	Bids         []PriceLevel `protobuf:"4"`
+	Timestamp    int64        `protobuf:"5"`
+}
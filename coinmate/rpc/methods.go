@@ -0,0 +1,304 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"tourGo/coinmate/gateway"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+// Methods by name, see the Coinmate service of the proto file
+var methods = map[string]method{
+	"GetTicker":       unary(gateway.PermissionRead, (*Server).getTicker),
+	"GetOrderBook":    unary(gateway.PermissionRead, (*Server).getOrderBook),
+	"GetTrades":       unary(gateway.PermissionRead, (*Server).getTrades),
+	"GetBalances":     unary(gateway.PermissionRead, (*Server).getBalances),
+	"GetOpenOrders":   unary(gateway.PermissionRead, (*Server).getOpenOrders),
+	"GetOrderHistory": unary(gateway.PermissionRead, (*Server).getOrderHistory),
+	"GetOrder":        unary(gateway.PermissionRead, (*Server).getOrder),
+	"GetTradeHistory": unary(gateway.PermissionRead, (*Server).getTradeHistory),
+	"PlaceOrder":      unary(gateway.PermissionTrade, (*Server).placeOrder),
+	"CancelOrder":     unary(gateway.PermissionTrade, (*Server).cancelOrder),
+
+	"StreamTrades":    stream(gateway.PermissionRead, (*Server).streamTrades),
+	"StreamOrderBook": stream(gateway.PermissionRead, (*Server).streamOrderBook),
+}
+
+// Method returning one response
+func unary[Req any, Resp any](permission gateway.Permission, f func(*Server, context.Context, *Req) (Resp, error)) method {
+	return method{permission: permission, call: func(s *Server, ctx context.Context, request []byte, send func(interface{}) error) error {
+		req := new(Req)
+		if err := unmarshal(request, req); err != nil {
+			return invalid("invalid request: %v", err)
+		}
+		resp, err := f(s, ctx, req)
+		if err != nil {
+			return err
+		}
+		return send(resp)
+	}}
+}
+
+// Method sending responses until the context is done
+func stream[Req any, Resp any](permission gateway.Permission, f func(*Server, context.Context, *Req, func(Resp) error) error) method {
+	return method{permission: permission, call: func(s *Server, ctx context.Context, request []byte, send func(interface{}) error) error {
+		req := new(Req)
+		if err := unmarshal(request, req); err != nil {
+			return invalid("invalid request: %v", err)
+		}
+		return f(s, ctx, req, func(resp Resp) error { return send(resp) })
+	}}
+}
+
+// Market data
+
+func (s *Server) getTicker(ctx context.Context, req *TickerRequest) (Ticker, error) {
+	pair, err := pairOf(req.CurrencyPair)
+	if err != nil {
+		return Ticker{}, err
+	}
+	resp, err := (&public.Ticker{Client: s.Client}).GetTicker(pair)
+	if err != nil {
+		return Ticker{}, err
+	}
+	if resp.Error {
+		return Ticker{}, refused(resp.ErrorMessage)
+	}
+	d := resp.Data
+	return Ticker{
+		CurrencyPair: pair, Last: d.Last, High: d.High, Low: d.Low, Amount: d.Amount,
+		Bid: d.Bid, Ask: d.Ask, Change: d.Change, Open: d.Open, Timestamp: int64(d.Timestamp),
+	}, nil
+}
+
+func (s *Server) getOrderBook(ctx context.Context, req *OrderBookRequest) (OrderBook, error) {
+	pair, err := pairOf(req.CurrencyPair)
+	if err != nil {
+		return OrderBook{}, err
+	}
+	data, err := s.orderBook(pair, req.GroupByPrice)
+	if err != nil {
+		return OrderBook{}, err
+	}
+	return OrderBook{CurrencyPair: pair, Asks: levels(data.Asks, req.Depth), Bids: levels(data.Bids, req.Depth)}, nil
+}
+
+func (s *Server) getTrades(ctx context.Context, req *TradesRequest) (Trades, error) {
+	pair, err := pairOf(req.CurrencyPair)
+	if err != nil {
+		return Trades{}, err
+	}
+	minutes := req.MinutesIntoHistory
+	if minutes == 0 {
+		minutes = 10
+	}
+	data, err := s.transactions(pair, minutes)
+	if err != nil {
+		return Trades{}, err
+	}
+	result := Trades{Trades: make([]Trade, len(data))}
+	for i, t := range data {
+		result.Trades[i] = tradeFrom(t)
+	}
+	return result, nil
+}
+
+// Account data
+
+func (s *Server) getBalances(ctx context.Context, req *BalancesRequest) (Balances, error) {
+	resp, err := (&secure.Balances{Client: s.Client}).GetBalances()
+	if err != nil {
+		return Balances{}, err
+	}
+	if resp.Error {
+		return Balances{}, refused(resp.ErrorMessage)
+	}
+	return Balances{Balances: sortedBalances(resp.Data)}, nil
+}
+
+func (s *Server) getOpenOrders(ctx context.Context, req *OpenOrdersRequest) (Orders, error) {
+	resp, err := s.orders().GetOpenOrders(strings.ToUpper(req.CurrencyPair))
+	if err != nil {
+		return Orders{}, err
+	}
+	if resp.Error {
+		return Orders{}, refused(resp.ErrorMessage)
+	}
+	result := Orders{Orders: make([]Order, len(resp.Data))}
+	for i, o := range resp.Data {
+		result.Orders[i] = Order{
+			Id: o.Id, Timestamp: o.Timestamp, Type: o.Type, CurrencyPair: o.CurrencyPair, Price: o.Price,
+			OriginalAmount: o.Amount, RemainingAmount: o.Amount, Status: "OPEN", StopPrice: o.StopPrice,
+			OrderTradeType: o.OrderTradeType, Hidden: o.Hidden, ClientOrderId: o.ClientOrderId,
+		}
+	}
+	return result, nil
+}
+
+func (s *Server) getOrderHistory(ctx context.Context, req *OrderHistoryRequest) (Orders, error) {
+	pair, err := pairOf(req.CurrencyPair)
+	if err != nil {
+		return Orders{}, err
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	resp, err := s.orders().GetHistory(pair, limit)
+	if err != nil {
+		return Orders{}, err
+	}
+	if resp.Error {
+		return Orders{}, refused(resp.ErrorMessage)
+	}
+	result := Orders{Orders: make([]Order, len(resp.Data))}
+	for i, o := range resp.Data {
+		result.Orders[i] = orderFrom(o)
+	}
+	return result, nil
+}
+
+func (s *Server) getOrder(ctx context.Context, req *GetOrderRequest) (Order, error) {
+	if req.OrderId == 0 {
+		return Order{}, invalid("order_id must not be 0")
+	}
+	resp, err := s.orders().GetOrderById(req.OrderId)
+	if err != nil {
+		return Order{}, err
+	}
+	if resp.Error {
+		return Order{}, refused(resp.ErrorMessage)
+	}
+	if resp.Data == nil {
+		return Order{}, &Status{Code: CodeNotFound, Message: fmt.Sprintf("order %d not found", req.OrderId)}
+	}
+	return orderFrom(*resp.Data), nil
+}
+
+func (s *Server) getTradeHistory(ctx context.Context, req *TradeHistoryRequest) (OwnTrades, error) {
+	sort := strings.ToUpper(req.Sort)
+	if sort != "" && sort != "ASC" && sort != "DESC" {
+		return OwnTrades{}, invalid("invalid sort %q, expected ASC or DESC", req.Sort)
+	}
+	params := secure.TradeHistoryParams{
+		Limit: req.Limit, LastId: req.LastId, Sort: sort, TimestampFrom: req.TimestampFrom,
+		TimestampTo: req.TimestampTo, CurrencyPair: strings.ToUpper(req.CurrencyPair), OrderId: req.OrderId,
+	}
+	resp, err := (&secure.TradeHistory{Client: s.Client}).GetTradeHistory(params)
+	if err != nil {
+		return OwnTrades{}, err
+	}
+	if resp.Error {
+		return OwnTrades{}, refused(resp.ErrorMessage)
+	}
+	result := OwnTrades{Trades: make([]OwnTrade, len(resp.Data))}
+	for i, t := range resp.Data {
+		result.Trades[i] = OwnTrade{
+			TransactionId: t.TransactionId, CreatedTimestamp: t.CreatedTimestamp, CurrencyPair: t.CurrencyPair, Type: t.Type,
+			OrderType: t.OrderType, OrderId: t.OrderId, Amount: t.Amount, Price: t.Price, Fee: t.Fee, FeeType: t.FeeType,
+		}
+	}
+	return result, nil
+}
+
+// Trading
+
+func (s *Server) placeOrder(ctx context.Context, req *PlaceOrderRequest) (PlaceOrderResponse, error) {
+	pair, err := pairOf(req.CurrencyPair)
+	if err != nil {
+		return PlaceOrderResponse{}, err
+	}
+	if req.Side != SideBuy && req.Side != SideSell {
+		return PlaceOrderResponse{}, invalid("invalid side %d, expected SIDE_BUY or SIDE_SELL", req.Side)
+	}
+
+	orders := s.orders()
+	switch req.Type {
+	case OrderTypeUnspecified, OrderTypeLimit:
+		if req.Amount <= 0 || req.Price <= 0 {
+			return PlaceOrderResponse{}, invalid("limit orders need a positive amount and price")
+		}
+		var resp secure.SellLimit
+		if req.Side == SideBuy {
+			resp, err = orders.BuyLimit(req.Amount, req.Price, req.StopPrice, pair, req.Hidden, req.ImmediateOrCancel, req.ClientOrderId)
+		} else {
+			resp, err = orders.SellLimit(req.Amount, req.Price, req.StopPrice, pair, req.Hidden, req.ImmediateOrCancel, req.ClientOrderId)
+		}
+		if err != nil {
+			return PlaceOrderResponse{}, err
+		}
+		if resp.Error {
+			return PlaceOrderResponse{}, refused(resp.ErrorMessage)
+		}
+		return PlaceOrderResponse{OrderId: resp.OrderId}, nil
+
+	case OrderTypeInstant:
+		var resp secure.BuyAndSellResponse
+		if req.Side == SideBuy {
+			if req.Total <= 0 {
+				return PlaceOrderResponse{}, invalid("instant buys need a positive total")
+			}
+			resp, err = orders.BuyInstant(req.Total, pair, req.ClientOrderId)
+		} else {
+			if req.Amount <= 0 {
+				return PlaceOrderResponse{}, invalid("instant sells need a positive amount")
+			}
+			resp, err = orders.SellInstant(req.Amount, pair, req.ClientOrderId)
+		}
+		if err != nil {
+			return PlaceOrderResponse{}, err
+		}
+		if resp.Error {
+			return PlaceOrderResponse{}, refused(resp.ErrorMessage)
+		}
+		return PlaceOrderResponse{OrderId: resp.OrderId}, nil
+	}
+	return PlaceOrderResponse{}, invalid("invalid type %d, expected ORDER_TYPE_LIMIT or ORDER_TYPE_INSTANT", req.Type)
+}
+
+func (s *Server) cancelOrder(ctx context.Context, req *CancelOrderRequest) (CancelOrderResponse, error) {
+	if req.OrderId == 0 {
+		return CancelOrderResponse{}, invalid("order_id must not be 0")
+	}
+	resp, err := s.orders().CancelOrderWithInfo(req.OrderId)
+	if err != nil {
+		return CancelOrderResponse{}, err
+	}
+	if resp.Error {
+		return CancelOrderResponse{}, refused(resp.ErrorMessage)
+	}
+	return CancelOrderResponse{Success: resp.Data.Success, RemainingAmount: resp.Data.RemainingAmount}, nil
+}
+
+// Helper functions
+
+func (s *Server) orderBook(pair string, group bool) (public.OrderBookData, error) {
+	resp, err := (&public.OrderBook{Client: s.Client}).GetOrderBook(pair, group)
+	if err != nil {
+		return public.OrderBookData{}, err
+	}
+	if resp.Error {
+		return public.OrderBookData{}, refused(resp.ErrorMessage)
+	}
+	return resp.Data, nil
+}
+
+func (s *Server) transactions(pair string, minutes uint64) ([]public.TransactionsData, error) {
+	resp, err := (&public.Transactions{Client: s.Client}).GetTransactions(pair, minutes)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error {
+		return nil, refused(resp.ErrorMessage)
+	}
+	return resp.Data, nil
+}
+
+func tradeFrom(t public.TransactionsData) Trade {
+	return Trade{
+		TransactionId: t.TransactionId, Timestamp: t.Timestamp, CurrencyPair: t.CurrencyPair,
+		TradeType: t.TradeType, Price: t.Price, Amount: t.Amount,
+	}
+}
@@ -0,0 +1,350 @@
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"tourGo/coinmate"
+	"tourGo/coinmate/gateway"
+	"tourGo/coinmate/public"
+	"tourGo/coinmate/secure"
+)
+
+const (
+	// Full method names are /coinmate.v1.Coinmate/<Method>
+	serviceName = "coinmate.v1.Coinmate"
+
+	defaultInterval = time.Second
+	maxMessageSize  = 4 << 20
+)
+
+// gRPC status codes
+type Code int
+
+const (
+	CodeOK                 Code = 0
+	CodeCanceled           Code = 1
+	CodeInvalidArgument    Code = 3
+	CodeDeadlineExceeded   Code = 4
+	CodeNotFound           Code = 5
+	CodePermissionDenied   Code = 7
+	CodeResourceExhausted  Code = 8
+	CodeFailedPrecondition Code = 9
+	CodeAborted            Code = 10
+	CodeUnimplemented      Code = 12
+	CodeInternal           Code = 13
+	CodeUnavailable        Code = 14
+	CodeUnauthenticated    Code = 16
+)
+
+// Error returned to the caller with its gRPC status
+type Status struct {
+	Code    Code
+	Message string
+}
+
+func (s *Status) Error() string {
+	return fmt.Sprintf("rpc error: code = %d desc = %s", s.Code, s.Message)
+}
+
+// RPC of the service
+type method struct {
+	permission gateway.Permission
+	// Decodes the request and calls the implementation, send is called once
+	// for unary methods
+	call func(s *Server, ctx context.Context, request []byte, send func(interface{}) error) error
+}
+
+// Server implements the Coinmate service of proto/coinmate/v1/coinmate.proto
+// over gRPC on top of the client, without the gRPC libraries: requests are
+// served over HTTP/2 in the gRPC wire format, so clients generated from the
+// proto file can call it. Calls without TLS need a server accepting
+// unencrypted HTTP/2, see ListenAndServe.
+//
+// Callers send "authorization: Bearer <token>" metadata unless Tokens is
+// empty. PlaceOrder and CancelOrder need a trade token, the other methods a
+// read token. Coinmate refusing a request is FAILED_PRECONDITION, Coinmate
+// not reachable UNAVAILABLE and an order gate refusing an order ABORTED.
+//
+// Streams of the same pair share one poller, so the number of requests to
+// Coinmate does not grow with the number of subscribers. Hand in a
+// ratelimit.Client to share one rate limit between all callers.
+type Server struct {
+	Client coinmate.ClientInterface
+	// Bearer tokens and their permissions, every call is accepted when empty,
+	// e.g. behind a mesh authenticating callers with mTLS
+	Tokens map[string]gateway.Permission
+	// Checked before orders are placed, the default gate when nil
+	Gate secure.Gate
+	// Polling interval of streams
+	Interval time.Duration
+	// Called when polling for a stream fails, the stream ends with UNAVAILABLE
+	OnPollError func(key string, err error)
+
+	mu    sync.Mutex
+	feeds map[string]*feed
+	now   func() time.Time
+}
+
+// Return server using the client for all requests
+func NewServer(client coinmate.ClientInterface) *Server {
+	return &Server{
+		Client:   client,
+		Interval: defaultInterval,
+		feeds:    make(map[string]*feed),
+		now:      time.Now,
+	}
+}
+
+// Serve gRPC requests over HTTP/2
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+	if r.ProtoMajor != 2 {
+		http.Error(w, "gRPC requires HTTP/2", http.StatusHTTPVersionNotSupported)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc+proto")
+	w.Header().Add("Trailer", "Grpc-Status")
+	w.Header().Add("Trailer", "Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	err := s.serve(w, r, flusher)
+	status := &Status{Code: CodeOK}
+	if err != nil {
+		status = toStatus(err)
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(int(status.Code)))
+	w.Header().Set("Grpc-Message", encodeMessage(status.Message))
+}
+
+// Serve the gRPC service on addr without TLS until the context is done
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve the gRPC service on the listener without TLS until the context is
+// done
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	server := &http.Server{Handler: s, Protocols: &protocols, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Helper functions
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, flusher http.Flusher) error {
+	service, name, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	m, ok := methods[name]
+	if service != serviceName || !ok {
+		return &Status{Code: CodeUnimplemented, Message: "unknown method " + r.URL.Path}
+	}
+	if err := s.authorize(r, m.permission); err != nil {
+		return err
+	}
+
+	ctx := r.Context()
+	if timeout := r.Header.Get("Grpc-Timeout"); timeout != "" {
+		d, err := parseTimeout(timeout)
+		if err != nil {
+			return &Status{Code: CodeInvalidArgument, Message: err.Error()}
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	request, err := readFrame(r.Body)
+	if err != nil {
+		return err
+	}
+	send := func(message interface{}) error {
+		if err := writeFrame(w, marshal(message)); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+	if err := m.call(s, ctx, request, send); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (s *Server) authorize(r *http.Request, required gateway.Permission) error {
+	if len(s.Tokens) == 0 {
+		return nil
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return &Status{Code: CodeUnauthenticated, Message: "missing bearer token"}
+	}
+	granted := gateway.PermissionNone
+	for t, p := range s.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			granted = p
+		}
+	}
+	switch {
+	case granted == gateway.PermissionNone:
+		return &Status{Code: CodeUnauthenticated, Message: "invalid bearer token"}
+	case granted < required:
+		return &Status{Code: CodePermissionDenied, Message: fmt.Sprintf("token lacks %s permission", required)}
+	}
+	return nil
+}
+
+func (s *Server) orders() *secure.Order {
+	return &secure.Order{Client: s.Client, Gate: s.Gate}
+}
+
+// Read one length-prefixed message
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, &Status{Code: CodeInvalidArgument, Message: "missing request message"}
+	}
+	if header[0] != 0 {
+		return nil, &Status{Code: CodeUnimplemented, Message: "compressed messages are not supported"}
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxMessageSize {
+		return nil, &Status{Code: CodeResourceExhausted, Message: fmt.Sprintf("message of %d bytes exceeds %d", size, maxMessageSize)}
+	}
+	message := make([]byte, size)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return nil, &Status{Code: CodeInvalidArgument, Message: "truncated request message"}
+	}
+	return message, nil
+}
+
+func writeFrame(w io.Writer, message []byte) error {
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	_, err := w.Write(append(frame, message...))
+	return err
+}
+
+func toStatus(err error) *Status {
+	var status *Status
+	switch {
+	case errors.As(err, &status):
+		return status
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Status{Code: CodeDeadlineExceeded, Message: err.Error()}
+	case errors.Is(err, context.Canceled):
+		return &Status{Code: CodeCanceled, Message: err.Error()}
+	case errors.Is(err, secure.ErrNotSent):
+		return &Status{Code: CodeAborted, Message: err.Error()}
+	}
+	return &Status{Code: CodeUnavailable, Message: err.Error()}
+}
+
+// Percent-encode a status message as required for grpc-message
+func encodeMessage(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// Parse grpc-timeout, e.g. "100m" for 100 milliseconds
+func parseTimeout(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second, 'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond}
+	if len(s) < 2 {
+		return 0, fmt.Errorf("invalid grpc-timeout %q", s)
+	}
+	unit, ok := units[s[len(s)-1]]
+	value, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if !ok || err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid grpc-timeout %q", s)
+	}
+	return time.Duration(value) * unit, nil
+}
+
+func invalid(format string, args ...interface{}) error {
+	return &Status{Code: CodeInvalidArgument, Message: fmt.Sprintf(format, args...)}
+}
+
+func refused(message string) error {
+	return &Status{Code: CodeFailedPrecondition, Message: "coinmate: " + message}
+}
+
+func pairOf(pair string) (string, error) {
+	pair = strings.ToUpper(strings.TrimSpace(pair))
+	if pair == "" {
+		return "", invalid("currency_pair must not be empty")
+	}
+	return pair, nil
+}
+
+func levels(book []public.OrderBookAsksBids, depth uint32) []PriceLevel {
+	if depth > 0 && int(depth) < len(book) {
+		book = book[:depth]
+	}
+	result := make([]PriceLevel, len(book))
+	for i, l := range book {
+		result[i] = PriceLevel{Price: l.Price, Amount: l.Amount}
+	}
+	return result
+}
+
+func orderFrom(o secure.OrderHistoryData) Order {
+	return Order{
+		Id: o.Id, Timestamp: o.Timestamp, Type: o.Type, CurrencyPair: o.CurrencyPair, Price: o.Price,
+		OriginalAmount: o.OriginalAmount, RemainingAmount: o.RemainingAmount, Status: o.Status, StopPrice: o.StopPrice,
+		OrderTradeType: o.OrderTradeType, Hidden: o.Hidden, ClientOrderId: o.ClientOrderId,
+	}
+}
+
+func sortedBalances(data map[string]secure.BalanceCurrency) []Balance {
+	result := make([]Balance, 0, len(data))
+	for currency, b := range data {
+		result = append(result, Balance{Currency: currency, Balance: float64(b.Balance), Reserved: float64(b.Reserved), Available: float64(b.Available)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Currency < result[j].Currency })
+	return result
+}
@@ -0,0 +1,257 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/gateway"
+)
+
+const (
+	readToken  = "read-token"
+	tradeToken = "trade-token"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *coinmatetest.Server) {
+	t.Helper()
+	exchange := coinmatetest.NewServer()
+	t.Cleanup(exchange.Close)
+
+	exchange.AddAccount("1", "public-key", "private-key")
+	exchange.Deposit("1", "EUR", 10000)
+	exchange.AddLiquidity("BTC_EUR", "SELL", 50000, 1)
+	exchange.AddLiquidity("BTC_EUR", "BUY", 49000, 1)
+
+	s := NewServer(exchange.NewClient("1"))
+	s.Tokens = map[string]gateway.Permission{readToken: gateway.PermissionRead, tradeToken: gateway.PermissionTrade}
+	s.Interval = 10 * time.Millisecond
+
+	ts := httptest.NewUnstartedServer(s)
+	ts.Config.Protocols = new(http.Protocols)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return ts, exchange
+}
+
+// Call started with the request message
+type call struct {
+	resp *http.Response
+}
+
+func startCall(t *testing.T, ctx context.Context, ts *httptest.Server, method, token string, request interface{}) *call {
+	t.Helper()
+	var body bytes.Buffer
+	writeFrame(&body, marshal(request))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+"/"+serviceName+"/"+method, &body)
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Te", "trailers")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	t.Cleanup(transport.CloseIdleConnections)
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	return &call{resp: resp}
+}
+
+// Receive the next message, false at the end of the stream
+func (c *call) receive(t *testing.T, m interface{}) bool {
+	t.Helper()
+	var header [5]byte
+	if _, err := io.ReadFull(c.resp.Body, header[:]); err != nil {
+		return false
+	}
+	data := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	if _, err := io.ReadFull(c.resp.Body, data); err != nil {
+		t.Fatalf("Expected message, got %v", err)
+	}
+	if err := unmarshal(data, m); err != nil {
+		t.Fatalf("Expected valid message, got %v", err)
+	}
+	return true
+}
+
+// Status from the trailers, the body must have been read
+func (c *call) status() Code {
+	code, _ := strconv.Atoi(c.resp.Trailer.Get("Grpc-Status"))
+	return Code(code)
+}
+
+// Unary call returning the status
+func invoke(t *testing.T, ts *httptest.Server, method, token string, request, response interface{}) Code {
+	t.Helper()
+	c := startCall(t, context.Background(), ts, method, token, request)
+	c.receive(t, response)
+	io.Copy(io.Discard, c.resp.Body)
+	if c.resp.Trailer.Get("Grpc-Status") == "" {
+		t.Fatalf("Expected grpc-status trailer, got %v", c.resp.Trailer)
+	}
+	return c.status()
+}
+
+func TestWireRoundTrip(t *testing.T) {
+	in := OrderBookUpdate{
+		CurrencyPair: "BTC_EUR",
+		Snapshot:     true,
+		Asks:         []PriceLevel{{Price: 50000, Amount: 1.5}, {Price: 50100}},
+		Bids:         []PriceLevel{{Price: 49000, Amount: 0.25}},
+		Timestamp:    -1,
+	}
+	var out OrderBookUpdate
+	if err := unmarshal(marshal(in), &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if out.CurrencyPair != in.CurrencyPair || !out.Snapshot || len(out.Asks) != 2 || out.Asks[1].Price != 50100 || out.Bids[0].Amount != 0.25 || out.Timestamp != -1 {
+		t.Errorf("Expected %+v, got %+v", in, out)
+	}
+
+	// Encoded as by protoc: field 1 "BTC_EUR", field 2 varint 1
+	expected := []byte{0x0a, 7, 'B', 'T', 'C', '_', 'E', 'U', 'R', 0x10, 1}
+	if got := marshal(StreamTradesRequest{CurrencyPair: "BTC_EUR", IncludeRecent: true}); !bytes.Equal(got, expected) {
+		t.Errorf("Expected %x, got %x", expected, got)
+	}
+	if err := unmarshal([]byte{0x0a, 7, 'B'}, &out); err == nil {
+		t.Errorf("Expected error for a truncated message")
+	}
+}
+
+func TestUnary(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	var ticker Ticker
+	if code := invoke(t, ts, "GetTicker", readToken, TickerRequest{CurrencyPair: "btc_eur"}, &ticker); code != CodeOK {
+		t.Fatalf("Expected OK, got %d", code)
+	}
+	if ticker.CurrencyPair != "BTC_EUR" || ticker.Ask != 50000 || ticker.Bid != 49000 {
+		t.Errorf("Expected BTC_EUR ticker with ask 50000 and bid 49000, got %+v", ticker)
+	}
+
+	var balances Balances
+	if code := invoke(t, ts, "GetBalances", readToken, BalancesRequest{}, &balances); code != CodeOK {
+		t.Fatalf("Expected OK, got %d", code)
+	}
+	found := false
+	for _, b := range balances.Balances {
+		found = found || (b.Currency == "EUR" && b.Available == 10000)
+	}
+	if !found {
+		t.Errorf("Expected 10000 EUR available, got %+v", balances)
+	}
+
+	if code := invoke(t, ts, "GetTicker", readToken, TickerRequest{}, &ticker); code != CodeInvalidArgument {
+		t.Errorf("Expected INVALID_ARGUMENT without pair, got %d", code)
+	}
+	if code := invoke(t, ts, "GetOrder", readToken, GetOrderRequest{OrderId: 999}, &Order{}); code != CodeNotFound {
+		t.Errorf("Expected NOT_FOUND, got %d", code)
+	}
+	if code := invoke(t, ts, "Unknown", readToken, TickerRequest{}, &ticker); code != CodeUnimplemented {
+		t.Errorf("Expected UNIMPLEMENTED, got %d", code)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	if code := invoke(t, ts, "GetBalances", "", BalancesRequest{}, &Balances{}); code != CodeUnauthenticated {
+		t.Errorf("Expected UNAUTHENTICATED without token, got %d", code)
+	}
+	if code := invoke(t, ts, "GetBalances", "wrong", BalancesRequest{}, &Balances{}); code != CodeUnauthenticated {
+		t.Errorf("Expected UNAUTHENTICATED for a wrong token, got %d", code)
+	}
+
+	order := PlaceOrderRequest{Side: SideBuy, CurrencyPair: "BTC_EUR", Amount: 0.01, Price: 48000}
+	if code := invoke(t, ts, "PlaceOrder", readToken, order, &PlaceOrderResponse{}); code != CodePermissionDenied {
+		t.Errorf("Expected PERMISSION_DENIED for a read token, got %d", code)
+	}
+
+	var placed PlaceOrderResponse
+	if code := invoke(t, ts, "PlaceOrder", tradeToken, order, &placed); code != CodeOK || placed.OrderId == 0 {
+		t.Fatalf("Expected order to be placed, got %d %+v", code, placed)
+	}
+	var orders Orders
+	if code := invoke(t, ts, "GetOpenOrders", readToken, OpenOrdersRequest{CurrencyPair: "BTC_EUR"}, &orders); code != CodeOK || len(orders.Orders) != 1 || orders.Orders[0].Id != placed.OrderId {
+		t.Errorf("Expected open order %d, got %d %+v", placed.OrderId, code, orders)
+	}
+	var cancelled CancelOrderResponse
+	if code := invoke(t, ts, "CancelOrder", tradeToken, CancelOrderRequest{OrderId: placed.OrderId}, &cancelled); code != CodeOK || !cancelled.Success {
+		t.Errorf("Expected order to be cancelled, got %d %+v", code, cancelled)
+	}
+}
+
+func TestStreamTrades(t *testing.T) {
+	ts, _ := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream := startCall(t, ctx, ts, "StreamTrades", readToken, StreamTradesRequest{CurrencyPair: "BTC_EUR"})
+	// Trade after the stream polled once
+	time.Sleep(50 * time.Millisecond)
+	order := PlaceOrderRequest{Side: SideBuy, Type: OrderTypeInstant, CurrencyPair: "BTC_EUR", Total: 500}
+	if code := invoke(t, ts, "PlaceOrder", tradeToken, order, &PlaceOrderResponse{}); code != CodeOK {
+		t.Fatalf("Expected instant buy, got %d", code)
+	}
+
+	var trade Trade
+	if !stream.receive(t, &trade) {
+		t.Fatalf("Expected trade, got end of stream with %d", stream.status())
+	}
+	if trade.CurrencyPair != "BTC_EUR" || trade.Price != 50000 || trade.Amount != 0.01 {
+		t.Errorf("Expected 0.01 BTC at 50000, got %+v", trade)
+	}
+}
+
+func TestStreamOrderBook(t *testing.T) {
+	ts, exchange := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream := startCall(t, ctx, ts, "StreamOrderBook", readToken, StreamOrderBookRequest{CurrencyPair: "BTC_EUR", Depth: 5})
+	var update OrderBookUpdate
+	if !stream.receive(t, &update) {
+		t.Fatalf("Expected snapshot, got end of stream with %d", stream.status())
+	}
+	if !update.Snapshot || len(update.Asks) != 1 || len(update.Bids) != 1 || update.Asks[0].Price != 50000 {
+		t.Errorf("Expected snapshot with one level per side, got %+v", update)
+	}
+
+	exchange.AddLiquidity("BTC_EUR", "SELL", 51000, 2)
+	update = OrderBookUpdate{}
+	if !stream.receive(t, &update) {
+		t.Fatalf("Expected update, got end of stream with %d", stream.status())
+	}
+	if update.Snapshot || len(update.Asks) != 1 || update.Asks[0].Price != 51000 || update.Asks[0].Amount != 2 || len(update.Bids) != 0 {
+		t.Errorf("Expected added ask at 51000, got %+v", update)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	prev := []PriceLevel{{Price: 100, Amount: 1}, {Price: 101, Amount: 2}}
+	next := []PriceLevel{{Price: 100, Amount: 1.5}, {Price: 102, Amount: 1}}
+	got := changes(prev, next, false)
+	expected := []PriceLevel{{Price: 100, Amount: 1.5}, {Price: 101}, {Price: 102, Amount: 1}}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package rpc
+
+import (
+	"context"
+	"sort"
+	"time"
+	"tourGo/coinmate/public"
+)
+
+// Minutes of trades fetched per poll of a trade stream
+const tradeMinutes = 10
+
+// Result of one poll, shared by all subscribers of a feed
+type poll struct {
+	Trades []public.TransactionsData
+	Book   public.OrderBookData
+	At     time.Time
+	Err    error
+}
+
+// Poller shared by the streams of one pair, runs while it has subscribers
+type feed struct {
+	subscribers map[chan poll]struct{}
+	cancel      context.CancelFunc
+}
+
+// New public trades of a pair, oldest first
+func (s *Server) streamTrades(ctx context.Context, req *StreamTradesRequest, send func(Trade) error) error {
+	pair, err := pairOf(req.CurrencyPair)
+	if err != nil {
+		return err
+	}
+	updates, unsubscribe := s.subscribe("trades/"+pair, func() poll {
+		trades, err := s.transactions(pair, tradeMinutes)
+		return poll{Trades: trades, Err: err}
+	})
+	defer unsubscribe()
+
+	var seen map[string]bool
+	for {
+		var p poll
+		select {
+		case <-ctx.Done():
+			return nil
+		case p = <-updates:
+		}
+		if p.Err != nil {
+			return p.Err
+		}
+
+		trades := append([]public.TransactionsData(nil), p.Trades...)
+		sort.SliceStable(trades, func(i, j int) bool { return trades[i].Timestamp < trades[j].Timestamp })
+		for _, t := range trades {
+			if seen[t.TransactionId] || (seen == nil && !req.IncludeRecent) {
+				continue
+			}
+			if err := send(tradeFrom(t)); err != nil {
+				return err
+			}
+		}
+		// Trades older than the polled minutes do not come back
+		seen = make(map[string]bool, len(trades))
+		for _, t := range trades {
+			seen[t.TransactionId] = true
+		}
+	}
+}
+
+// Order book snapshot followed by the changed price levels, removed levels
+// have a zero amount
+func (s *Server) streamOrderBook(ctx context.Context, req *StreamOrderBookRequest, send func(OrderBookUpdate) error) error {
+	pair, err := pairOf(req.CurrencyPair)
+	if err != nil {
+		return err
+	}
+	updates, unsubscribe := s.subscribe("book/"+pair, func() poll {
+		book, err := s.orderBook(pair, false)
+		return poll{Book: book, Err: err}
+	})
+	defer unsubscribe()
+
+	var asks, bids []PriceLevel
+	snapshot := true
+	for {
+		var p poll
+		select {
+		case <-ctx.Done():
+			return nil
+		case p = <-updates:
+		}
+		if p.Err != nil {
+			return p.Err
+		}
+
+		newAsks, newBids := levels(p.Book.Asks, req.Depth), levels(p.Book.Bids, req.Depth)
+		update := OrderBookUpdate{CurrencyPair: pair, Snapshot: snapshot, Asks: newAsks, Bids: newBids, Timestamp: p.At.UnixMilli()}
+		if !snapshot {
+			update.Asks = changes(asks, newAsks, false)
+			update.Bids = changes(bids, newBids, true)
+			if len(update.Asks) == 0 && len(update.Bids) == 0 {
+				continue
+			}
+		}
+		if err := send(update); err != nil {
+			return err
+		}
+		asks, bids, snapshot = newAsks, newBids, false
+	}
+}
+
+// Helper functions
+
+// Subscribe to the feed of key, starting it with the first subscriber. The
+// channel holds the latest poll only, slow subscribers skip polls.
+func (s *Server) subscribe(key string, fetch func() poll) (<-chan poll, func()) {
+	ch := make(chan poll, 1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.feeds == nil {
+		s.feeds = make(map[string]*feed)
+	}
+	f := s.feeds[key]
+	if f == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		f = &feed{subscribers: map[chan poll]struct{}{}, cancel: cancel}
+		s.feeds[key] = f
+		go s.run(ctx, key, f, fetch)
+	}
+	f.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(f.subscribers, ch)
+		if len(f.subscribers) == 0 && s.feeds[key] == f {
+			f.cancel()
+			delete(s.feeds, key)
+		}
+	}
+}
+
+func (s *Server) run(ctx context.Context, key string, f *feed, fetch func() poll) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	now := s.now
+	if now == nil {
+		now = time.Now
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p := fetch()
+		p.At = now()
+		if p.Err != nil && s.OnPollError != nil {
+			s.OnPollError(key, p.Err)
+		}
+
+		s.mu.Lock()
+		if ctx.Err() != nil {
+			s.mu.Unlock()
+			return
+		}
+		for ch := range f.subscribers {
+			// Replace a poll the subscriber has not taken yet
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- p
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Levels of next differing from prev, levels missing in next with a zero
+// amount. Asks are sorted by ascending, bids by descending price.
+func changes(prev, next []PriceLevel, descending bool) []PriceLevel {
+	amounts := make(map[float64]float64, len(next))
+	for _, l := range next {
+		amounts[l.Price] = l.Amount
+	}
+	old := make(map[float64]float64, len(prev))
+	var result []PriceLevel
+	for _, l := range prev {
+		old[l.Price] = l.Amount
+		if _, ok := amounts[l.Price]; !ok {
+			result = append(result, PriceLevel{Price: l.Price})
+		}
+	}
+	for _, l := range next {
+		if amount, ok := old[l.Price]; !ok || amount != l.Amount {
+			result = append(result, l)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if descending {
+			return result[i].Price > result[j].Price
+		}
+		return result[i].Price < result[j].Price
+	})
+	return result
+}
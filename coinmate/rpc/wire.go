@@ -0,0 +1,230 @@
+package rpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated message")
+
+// Encode a message in the protobuf wire format. Fields are taken from the
+// `protobuf:"N"` tags: string, bool, double, int32 and int64 (also enums),
+// uint32 and uint64, nested messages and repeated messages. Zero values are
+// omitted as in proto3.
+func marshal(m interface{}) []byte {
+	return appendMessage(nil, reflect.ValueOf(m))
+}
+
+// Decode a message in the protobuf wire format into the struct m points to,
+// unknown fields are skipped
+func unmarshal(data []byte, m interface{}) error {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected pointer to struct, got %T", m)
+	}
+	return decodeMessage(data, v.Elem())
+}
+
+// Helper functions
+
+func appendMessage(b []byte, v reflect.Value) []byte {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return b
+		}
+		v = v.Elem()
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		number, ok := fieldNumber(t.Field(i))
+		if !ok {
+			continue
+		}
+		f := v.Field(i)
+		if f.Kind() == reflect.Slice {
+			for j := 0; j < f.Len(); j++ {
+				b = appendField(b, number, f.Index(j), true)
+			}
+			continue
+		}
+		b = appendField(b, number, f, false)
+	}
+	return b
+}
+
+// Append field unless it has the zero value, elements of repeated fields are
+// always appended
+func appendField(b []byte, number uint64, f reflect.Value, repeated bool) []byte {
+	switch f.Kind() {
+	case reflect.String:
+		if f.Len() > 0 || repeated {
+			b = binary.AppendUvarint(b, number<<3|wireBytes)
+			b = binary.AppendUvarint(b, uint64(f.Len()))
+			b = append(b, f.String()...)
+		}
+	case reflect.Bool:
+		if f.Bool() {
+			b = binary.AppendUvarint(b, number<<3|wireVarint)
+			b = append(b, 1)
+		}
+	case reflect.Float64:
+		if f.Float() != 0 || repeated {
+			b = binary.AppendUvarint(b, number<<3|wireFixed64)
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(f.Float()))
+		}
+	case reflect.Int32, reflect.Int64:
+		if f.Int() != 0 || repeated {
+			b = binary.AppendUvarint(b, number<<3|wireVarint)
+			// Negative values are sign extended to 64 bits
+			b = binary.AppendUvarint(b, uint64(f.Int()))
+		}
+	case reflect.Uint32, reflect.Uint64:
+		if f.Uint() != 0 || repeated {
+			b = binary.AppendUvarint(b, number<<3|wireVarint)
+			b = binary.AppendUvarint(b, f.Uint())
+		}
+	case reflect.Struct, reflect.Pointer:
+		if f.Kind() == reflect.Pointer && f.IsNil() {
+			return b
+		}
+		nested := appendMessage(nil, f)
+		if len(nested) > 0 || repeated || f.Kind() == reflect.Pointer {
+			b = binary.AppendUvarint(b, number<<3|wireBytes)
+			b = binary.AppendUvarint(b, uint64(len(nested)))
+			b = append(b, nested...)
+		}
+	default:
+		panic(fmt.Sprintf("rpc: unsupported field kind %s", f.Kind()))
+	}
+	return b
+}
+
+func decodeMessage(data []byte, v reflect.Value) error {
+	fields := map[uint64]int{}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if number, ok := fieldNumber(t.Field(i)); ok {
+			fields[number] = i
+		}
+	}
+
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errTruncated
+		}
+		data = data[n:]
+		number, wire := key>>3, key&7
+
+		var raw uint64
+		var bytes []byte
+		switch wire {
+		case wireVarint:
+			raw, n = binary.Uvarint(data)
+			if n <= 0 {
+				return errTruncated
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errTruncated
+			}
+			raw, data = binary.LittleEndian.Uint64(data), data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errTruncated
+			}
+			raw, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return errTruncated
+			}
+			bytes, data = data[n:n+int(size)], data[n+int(size):]
+		default:
+			return fmt.Errorf("unsupported wire type %d of field %d", wire, number)
+		}
+
+		i, ok := fields[number]
+		if !ok {
+			continue
+		}
+		if err := setField(v.Field(i), wire, raw, bytes); err != nil {
+			return fmt.Errorf("field %s: %w", t.Field(i).Name, err)
+		}
+	}
+	return nil
+}
+
+func setField(f reflect.Value, wire, raw uint64, bytes []byte) error {
+	if f.Kind() == reflect.Slice {
+		elem := reflect.New(f.Type().Elem()).Elem()
+		if err := setField(elem, wire, raw, bytes); err != nil {
+			return err
+		}
+		f.Set(reflect.Append(f, elem))
+		return nil
+	}
+
+	expected := uint64(wireVarint)
+	switch f.Kind() {
+	case reflect.String, reflect.Struct, reflect.Pointer:
+		expected = wireBytes
+	case reflect.Float64:
+		expected = wireFixed64
+	}
+	if wire != expected {
+		return fmt.Errorf("unexpected wire type %d", wire)
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(string(bytes))
+	case reflect.Bool:
+		f.SetBool(raw != 0)
+	case reflect.Float64:
+		f.SetFloat(math.Float64frombits(raw))
+	case reflect.Int32:
+		f.SetInt(int64(int32(raw)))
+	case reflect.Int64:
+		f.SetInt(int64(raw))
+	case reflect.Uint32:
+		f.SetUint(uint64(uint32(raw)))
+	case reflect.Uint64:
+		f.SetUint(raw)
+	case reflect.Struct:
+		return decodeMessage(bytes, f)
+	case reflect.Pointer:
+		if f.IsNil() {
+			f.Set(reflect.New(f.Type().Elem()))
+		}
+		return decodeMessage(bytes, f.Elem())
+	default:
+		return fmt.Errorf("unsupported kind %s", f.Kind())
+	}
+	return nil
+}
+
+func fieldNumber(f reflect.StructField) (uint64, bool) {
+	tag := f.Tag.Get("protobuf")
+	if tag == "" {
+		return 0, false
+	}
+	number, err := strconv.ParseUint(tag, 10, 32)
+	if err != nil || number == 0 {
+		panic(fmt.Sprintf("rpc: invalid protobuf tag %q of %s", tag, f.Name))
+	}
+	return number, true
+}
@@ -13,6 +13,11 @@ type Balances struct {
 	Client coinmate.ClientInterface
 }
 
+// Balance operations, implemented by Balances and by simulated traders
+type BalancesInterface interface {
+	GetBalances() (BalancesResponse, error)
+}
+
 // Order book response
 type BalancesResponse struct {
 	Error        bool                       `json:"error"`
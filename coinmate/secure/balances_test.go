@@ -228,3 +228,10 @@ func TestBalancesDataStructure(t *testing.T) {
 		t.Errorf("Expected available to be %f, got %f", data.Available, unmarshaledData.Available)
 	}
 }
+
+func TestBalancesImplementsBalancesInterface(t *testing.T) {
+	var b BalancesInterface = &Balances{Client: &MockSecureClient{}}
+	if b == nil {
+		t.Error("Expected Balances to implement BalancesInterface")
+	}
+}
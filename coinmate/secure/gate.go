@@ -0,0 +1,44 @@
+package secure
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// Returned for orders refused before sending, the exchange never saw them
+var ErrNotSent = errors.New("order not sent")
+
+// Check run before an order is sent, e.g. a trading halt
+type Gate interface {
+	Allow() error
+}
+
+type gateHolder struct {
+	gate Gate
+}
+
+var defaultGate atomic.Value
+
+// Set gate checked by every Order without its own Gate, nil removes it
+func SetDefaultGate(g Gate) {
+	defaultGate.Store(gateHolder{gate: g})
+}
+
+// Helper functions
+
+func allow(o *Order) error {
+	gate := o.Gate
+	if gate == nil {
+		if h, ok := defaultGate.Load().(gateHolder); ok {
+			gate = h.gate
+		}
+	}
+	if gate == nil {
+		return nil
+	}
+	if err := gate.Allow(); err != nil {
+		return fmt.Errorf("%w: %w", ErrNotSent, err)
+	}
+	return nil
+}
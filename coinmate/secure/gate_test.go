@@ -0,0 +1,44 @@
+package secure
+
+import (
+	"errors"
+	"testing"
+)
+
+type gateFunc func() error
+
+func (f gateFunc) Allow() error {
+	return f()
+}
+
+func TestGateRefusesOrders(t *testing.T) {
+	halted := errors.New("halted")
+	// No response configured, a request would panic
+	order := &Order{Client: &MockSecureClient{}, Gate: gateFunc(func() error { return halted })}
+
+	if _, err := order.BuyLimit(0.1, 50000, 0, "BTC_EUR", false, false, 0); !errors.Is(err, ErrNotSent) || !errors.Is(err, halted) {
+		t.Errorf("Expected order not sent, got %v", err)
+	}
+	if _, err := order.SellInstant(0.1, "BTC_EUR", 0); !errors.Is(err, ErrNotSent) {
+		t.Errorf("Expected order not sent, got %v", err)
+	}
+	if _, err := order.ReplaceByBuyLimit(1, 0.1, 50000, 0, "BTC_EUR", false, false, 0); !errors.Is(err, ErrNotSent) {
+		t.Errorf("Expected replacement not sent, got %v", err)
+	}
+}
+
+func TestDefaultGate(t *testing.T) {
+	SetDefaultGate(gateFunc(func() error { return errors.New("halted") }))
+	t.Cleanup(func() { SetDefaultGate(nil) })
+
+	order := &Order{Client: &MockSecureClient{}}
+	if _, err := order.SellLimit(0.1, 50000, 0, "BTC_EUR", false, false, 0); !errors.Is(err, ErrNotSent) {
+		t.Errorf("Expected default gate to refuse order, got %v", err)
+	}
+
+	open := &Order{Client: &MockSecureClient{}, Gate: gateFunc(func() error { return nil })}
+	open.Client.(*MockSecureClient).err = errors.New("network down")
+	if _, err := open.BuyInstant(100, "BTC_EUR", 0); err == nil || errors.Is(err, ErrNotSent) {
+		t.Errorf("Expected own gate to take precedence, got %v", err)
+	}
+}
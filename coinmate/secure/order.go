@@ -11,28 +11,59 @@ import (
 )
 
 const (
-	orderHistoryEndpoint        = "/orderHistory"
-	openOrdersEndpoint          = "/openOrders"
-	cancelOrderEndpoint         = "/cancelOrder"
-	cancelOrderWithInfoEndpoint = "/cancelOrderWithInfo"
-	buyLimitOrderEndpoint       = "/buyLimit"
-	sellLimitOrderEndpoint      = "/sellLimit"
-	buyInstantOrderEndpoint     = "/buyInstant"
-	sellInstantOrderEndpoint    = "/sellInstant"
-	currencyPairParamName       = "currencyPair"
-	limitReturnedOrders         = "limit"
-	orderIdParamName            = "orderId"
-	amountParamName             = "amount"
-	priceParamName              = "price"
-	stopPriceParamName          = "stopPrice"
-	hiddenParamName             = "hidden"
-	immediateOrCancelParamName  = "immediateOrCancel"
-	clientOrderIdParamName      = "clientOrderId"
-	totalParamName              = "total"
+	orderHistoryEndpoint         = "/orderHistory"
+	openOrdersEndpoint           = "/openOrders"
+	cancelOrderEndpoint          = "/cancelOrder"
+	cancelOrderWithInfoEndpoint  = "/cancelOrderWithInfo"
+	buyLimitOrderEndpoint        = "/buyLimit"
+	sellLimitOrderEndpoint       = "/sellLimit"
+	buyInstantOrderEndpoint      = "/buyInstant"
+	sellInstantOrderEndpoint     = "/sellInstant"
+	orderByIdEndpoint            = "/orderById"
+	orderByClientOrderIdEndpoint = "/order"
+	replaceByBuyLimitEndpoint    = "/replaceByBuyLimit"
+	replaceBySellLimitEndpoint   = "/replaceBySellLimit"
+	currencyPairParamName        = "currencyPair"
+	limitReturnedOrders          = "limit"
+	orderIdParamName             = "orderId"
+	amountParamName              = "amount"
+	priceParamName               = "price"
+	stopPriceParamName           = "stopPrice"
+	hiddenParamName              = "hidden"
+	immediateOrCancelParamName   = "immediateOrCancel"
+	clientOrderIdParamName       = "clientOrderId"
+	totalParamName               = "total"
+	orderIdToBeReplacedParamName = "orderIdToBeReplaced"
 )
 
 type Order struct {
 	Client coinmate.ClientInterface
+	// Checked before placing orders, the default gate when nil
+	Gate Gate
+}
+
+// Order operations, implemented by Order and by simulated traders
+type OrderInterface interface {
+	GetHistory(currencyPair string, limit int64) (OrderHistoryResponse, error)
+	GetOpenOrders(currencyPair string) (OpenOrdersResponse, error)
+	BuyLimit(amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (SellLimit, error)
+	SellLimit(amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (SellLimit, error)
+	BuyInstant(total float64, cp string, clientOrderId uint64) (BuyAndSellResponse, error)
+	SellInstant(total float64, cp string, clientOrderId uint64) (BuyAndSellResponse, error)
+	CancelOrder(orderId uint64) (CancelOrderResponse, error)
+	CancelOrderWithInfo(orderId uint64) (CancelOrderWithInfoResponse, error)
+}
+
+// Order lookups, implemented by Order
+type OrderLookupInterface interface {
+	GetOrderById(orderId uint64) (OrderByIdResponse, error)
+	GetOrderByClientOrderId(clientOrderId uint64) (OrderByClientOrderIdResponse, error)
+}
+
+// Replacing limit orders in one request, implemented by Order
+type OrderReplaceInterface interface {
+	ReplaceByBuyLimit(orderIdToBeReplaced uint64, amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (ReplaceOrderResponse, error)
+	ReplaceBySellLimit(orderIdToBeReplaced uint64, amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (ReplaceOrderResponse, error)
 }
 
 // Order history response
@@ -47,6 +78,7 @@ type OrderHistoryData struct {
 	Id              uint64  `json:"id"`
 	Timestamp       int64   `json:"timestamp"`
 	Type            string  `json:"type"`
+	CurrencyPair    string  `json:"currencyPair"`
 	Price           float64 `json:"price"`
 	RemainingAmount float64 `json:"remainingAmount"`
 	OriginalAmount  float64 `json:"originalAmount"`
@@ -54,6 +86,7 @@ type OrderHistoryData struct {
 	StopPrice       float64 `json:"stopPrice"`
 	OrderTradeType  string  `json:"orderTradeType"`
 	Hidden          bool    `json:"hidden"`
+	ClientOrderId   uint64  `json:"clientOrderId"`
 }
 
 // Open orders history response
@@ -74,6 +107,21 @@ type OpenOrdersData struct {
 	OrderTradeType string  `json:"orderTradeType"`
 	StopPrice      float64 `json:"stopPrice"`
 	Hidden         bool    `json:"hidden"`
+	ClientOrderId  uint64  `json:"clientOrderId"`
+}
+
+// Order by ID response, data is nil when the order does not exist
+type OrderByIdResponse struct {
+	Error        bool              `json:"error"`
+	ErrorMessage string            `json:"errorMessage"`
+	Data         *OrderHistoryData `json:"data"`
+}
+
+// Order by client order ID response, empty when no order was placed with the ID
+type OrderByClientOrderIdResponse struct {
+	Error        bool               `json:"error"`
+	ErrorMessage string             `json:"errorMessage"`
+	Data         []OrderHistoryData `json:"data"`
 }
 
 // Cancel order
@@ -96,6 +144,21 @@ type CancelOrderWithInfoData struct {
 	RemainingAmount float64 `json:"remainingAmount"`
 }
 
+// Replace order response
+type ReplaceOrderResponse struct {
+	Error        bool             `json:"error"`
+	ErrorMessage string           `json:"errorMessage"`
+	Data         ReplaceOrderData `json:"data"`
+}
+
+// Replace order data, no order is created when the replaced order could
+// not be cancelled
+type ReplaceOrderData struct {
+	Success         bool    `json:"success"`
+	RemainingAmount float64 `json:"remainingAmount"`
+	CreatedOrderId  uint64  `json:"createdOrderId"`
+}
+
 // Buy limit response
 type BuyLimitResponse struct {
 	Error        bool   `json:"error"`
@@ -225,6 +288,10 @@ func (o *Order) BuyLimit(amount, price, stopPrice float64, currencyPair string,
 	buyLimitResponse := BuyLimitResponse{}
 	sellLimit := SellLimit{}
 
+	if err := allow(o); err != nil {
+		return sellLimit, err
+	}
+
 	response, err := limitOrders(o, amount, price, currencyPair, buyLimitOrderEndpoint, stopPrice, hidden, immediateOrCancel, clientOrderId)
 	if err != nil {
 		return sellLimit, fmt.Errorf("buy limit request failed: %w", err)
@@ -249,6 +316,10 @@ func (o *Order) SellLimit(amount, price, stopPrice float64, currencyPair string,
 	sellLimitResponse := SellLimitResponse{}
 	sellLimit := SellLimit{}
 
+	if err := allow(o); err != nil {
+		return sellLimit, err
+	}
+
 	response, err := limitOrders(o, amount, price, currencyPair, sellLimitOrderEndpoint, stopPrice, hidden, immediateOrCancel, clientOrderId)
 	if err != nil {
 		return sellLimit, fmt.Errorf("sell limit request failed: %w", err)
@@ -318,18 +389,96 @@ func (o *Order) CancelOrderWithInfo(orderId uint64) (CancelOrderWithInfoResponse
 	return cancelOrderWithInfoResponse, err
 }
 
+// Order by ID
+func (o *Order) GetOrderById(orderId uint64) (OrderByIdResponse, error) {
+	orderByIdResponse := OrderByIdResponse{}
+
+	response, err := orderLookupRequest(o, orderByIdEndpoint, orderIdParamName, orderId)
+	if err != nil {
+		return orderByIdResponse, fmt.Errorf("order by id request failed: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return orderByIdResponse, fmt.Errorf("order by id request failed: status=%d body=%s", response.StatusCode, string(response.Body))
+	}
+
+	err = json.Unmarshal(response.Body, &orderByIdResponse)
+	if err != nil {
+		return orderByIdResponse, fmt.Errorf("failed to decode order by id response: %w", err)
+	}
+
+	return orderByIdResponse, err
+}
+
+// Orders placed with client order ID
+func (o *Order) GetOrderByClientOrderId(clientOrderId uint64) (OrderByClientOrderIdResponse, error) {
+	orderByClientOrderIdResponse := OrderByClientOrderIdResponse{}
+
+	response, err := orderLookupRequest(o, orderByClientOrderIdEndpoint, clientOrderIdParamName, clientOrderId)
+	if err != nil {
+		return orderByClientOrderIdResponse, fmt.Errorf("order by client order id request failed: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return orderByClientOrderIdResponse, fmt.Errorf("order by client order id request failed: status=%d body=%s", response.StatusCode, string(response.Body))
+	}
+
+	err = json.Unmarshal(response.Body, &orderByClientOrderIdResponse)
+	if err != nil {
+		return orderByClientOrderIdResponse, fmt.Errorf("failed to decode order by client order id response: %w", err)
+	}
+
+	return orderByClientOrderIdResponse, err
+}
+
+// Cancel order and place buy limit order instead
+func (o *Order) ReplaceByBuyLimit(orderIdToBeReplaced uint64, amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (ReplaceOrderResponse, error) {
+	return replaceRequest(o, replaceByBuyLimitEndpoint, orderIdToBeReplaced, amount, price, stopPrice, currencyPair, hidden, immediateOrCancel, clientOrderId)
+}
+
+// Cancel order and place sell limit order instead
+func (o *Order) ReplaceBySellLimit(orderIdToBeReplaced uint64, amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (ReplaceOrderResponse, error) {
+	return replaceRequest(o, replaceBySellLimitEndpoint, orderIdToBeReplaced, amount, price, stopPrice, currencyPair, hidden, immediateOrCancel, clientOrderId)
+}
+
 // Helper functions
 
 // Calling limit orders endpoints
 func limitOrders(o *Order, amount float64, price float64, currencyPair, endpoint string, stopPrice float64, hidden bool, immediateOrCancel bool, clientOrderId uint64) (coinmate.Response, error) {
-	// URL compose
-	u, _ := url.Parse(o.Client.GetBaseUrl() + endpoint)
+	return limitOrderRequest(o, endpoint, limitOrderParams(amount, price, currencyPair, stopPrice, hidden, immediateOrCancel, clientOrderId))
+}
+
+// Calling replace endpoints
+func replaceRequest(o *Order, endpoint string, orderIdToBeReplaced uint64, amount, price, stopPrice float64, currencyPair string, hidden, immediateOrCancel bool, clientOrderId uint64) (ReplaceOrderResponse, error) {
+	replaceOrderResponse := ReplaceOrderResponse{}
+
+	if err := allow(o); err != nil {
+		return replaceOrderResponse, err
+	}
+
+	ap := limitOrderParams(amount, price, currencyPair, stopPrice, hidden, immediateOrCancel, clientOrderId)
+	ap[orderIdToBeReplacedParamName] = strconv.FormatUint(orderIdToBeReplaced, 10)
+	response, err := limitOrderRequest(o, endpoint, ap)
+	if err != nil {
+		return replaceOrderResponse, fmt.Errorf("replace order request failed: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return replaceOrderResponse, fmt.Errorf("replace order request failed: status=%d body=%s", response.StatusCode, string(response.Body))
+	}
+	err = json.Unmarshal(response.Body, &replaceOrderResponse)
+	if err != nil {
+		return replaceOrderResponse, fmt.Errorf("failed to decode replace order response: %w", err)
+	}
+
+	return replaceOrderResponse, err
+}
+
+func limitOrderParams(amount float64, price float64, currencyPair string, stopPrice float64, hidden bool, immediateOrCancel bool, clientOrderId uint64) map[string]string {
 	ap := make(map[string]string)
-	ap[amountParamName] = strconv.FormatFloat(amount, 'f', 2, 64)
-	ap[priceParamName] = strconv.FormatFloat(price, 'f', 2, 64)
+	ap[amountParamName] = strconv.FormatFloat(amount, 'f', 8, 64)
+	// Shortest representation, pairs differ in price decimals
+	ap[priceParamName] = strconv.FormatFloat(price, 'f', -1, 64)
 	ap[currencyPairParamName] = strings.ToLower(currencyPair)
 	if stopPrice > 0 {
-		ap[stopPriceParamName] = strconv.FormatFloat(stopPrice, 'f', 2, 64)
+		ap[stopPriceParamName] = strconv.FormatFloat(stopPrice, 'f', -1, 64)
 	}
 	if hidden == true {
 		ap[hiddenParamName] = "1"
@@ -340,6 +489,12 @@ func limitOrders(o *Order, amount float64, price float64, currencyPair, endpoint
 	if clientOrderId > 0 {
 		ap[clientOrderIdParamName] = strconv.FormatUint(clientOrderId, 10)
 	}
+	return ap
+}
+
+func limitOrderRequest(o *Order, endpoint string, ap map[string]string) (coinmate.Response, error) {
+	// URL compose
+	u, _ := url.Parse(o.Client.GetBaseUrl() + endpoint)
 	r := coinmate.Request{
 		HTTPMethod: http.MethodPost,
 		URL:        u.String(),
@@ -353,6 +508,9 @@ func limitOrders(o *Order, amount float64, price float64, currencyPair, endpoint
 func buySellInstantRequest(o *Order, endpoint string, total float64, currencyPair string, clientOrderId uint64) (BuyAndSellResponse, error) {
 	bir := BuySell{}
 	basr := BuyAndSellResponse{}
+	if err := allow(o); err != nil {
+		return basr, err
+	}
 	u, _ := url.Parse(o.Client.GetBaseUrl() + endpoint)
 	ap := make(map[string]string)
 	if endpoint == sellInstantOrderEndpoint {
@@ -401,3 +559,18 @@ func cancelOrderRequest(o *Order, endpoint string, orderId uint64) (coinmate.Res
 	response, err := o.Client.MakeSecureRequest(r)
 	return response, err
 }
+
+// Calling order lookup endpoints
+func orderLookupRequest(o *Order, endpoint, paramName string, id uint64) (coinmate.Response, error) {
+	// URL compose
+	u, _ := url.Parse(o.Client.GetBaseUrl() + endpoint)
+	ap := make(map[string]string)
+	ap[paramName] = strconv.FormatUint(id, 10)
+	r := coinmate.Request{
+		HTTPMethod: http.MethodPost,
+		URL:        u.String(),
+		Body:       o.Client.GetRequestBody(ap),
+	}
+	response, err := o.Client.MakeSecureRequest(r)
+	return response, err
+}
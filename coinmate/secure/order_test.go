@@ -306,6 +306,114 @@ func TestCancelOrderHTTPError(t *testing.T) {
 	}
 }
 
+func TestGetOrderByIdSuccess(t *testing.T) {
+	// Create mock response
+	mockResponse := &coinmate.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Body: []byte(`{
+			"error": false,
+			"errorMessage": "",
+			"data": {
+				"id": 12345,
+				"timestamp": 1640995200000,
+				"type": "BUY",
+				"currencyPair": "BTC_EUR",
+				"price": 50000.0,
+				"remainingAmount": 0.0,
+				"originalAmount": 0.1,
+				"status": "FILLED",
+				"orderTradeType": "LIMIT",
+				"clientOrderId": 77
+			}
+		}`),
+	}
+
+	mockClient := &MockSecureClient{response: mockResponse}
+	order := &Order{Client: mockClient}
+
+	response, err := order.GetOrderById(12345)
+
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if response.Data == nil || response.Data.Id != 12345 || response.Data.CurrencyPair != "BTC_EUR" {
+		t.Errorf("Expected order 12345 on BTC_EUR, got %+v", response.Data)
+	}
+}
+
+func TestGetOrderByIdNotFound(t *testing.T) {
+	// Create mock response
+	mockResponse := &coinmate.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Body:       []byte(`{"error": false, "errorMessage": null, "data": null}`),
+	}
+
+	mockClient := &MockSecureClient{response: mockResponse}
+	order := &Order{Client: mockClient}
+
+	response, err := order.GetOrderById(12345)
+
+	if err != nil || response.Data != nil {
+		t.Errorf("Expected no order and no error, got %+v (%v)", response.Data, err)
+	}
+}
+
+func TestGetOrderByClientOrderIdSuccess(t *testing.T) {
+	// Create mock response
+	mockResponse := &coinmate.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Body: []byte(`{
+			"error": false,
+			"errorMessage": "",
+			"data": [{
+				"id": 12345,
+				"type": "SELL",
+				"currencyPair": "BTC_EUR",
+				"price": 51000.0,
+				"originalAmount": 0.2,
+				"remainingAmount": 0.2,
+				"status": "OPEN",
+				"clientOrderId": 77
+			}]
+		}`),
+	}
+
+	mockClient := &MockSecureClient{response: mockResponse}
+	order := &Order{Client: mockClient}
+
+	response, err := order.GetOrderByClientOrderId(77)
+
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if len(response.Data) != 1 || response.Data[0].ClientOrderId != 77 {
+		t.Errorf("Expected one order with client order ID 77, got %+v", response.Data)
+	}
+}
+
+func TestGetOrderByClientOrderIdHTTPError(t *testing.T) {
+	// Create mock HTTP error
+	mockResponse := &coinmate.Response{
+		StatusCode: http.StatusBadRequest,
+		Status:     "400 Bad Request",
+		Body:       []byte("Bad Request"),
+	}
+
+	mockClient := &MockSecureClient{response: mockResponse}
+	order := &Order{Client: mockClient}
+
+	_, err := order.GetOrderByClientOrderId(77)
+
+	if err == nil {
+		t.Errorf("Expected error for non-200 response")
+	}
+}
+
 func TestCancelOrderWithInfoSuccess(t *testing.T) {
 	// Create mock response
 	mockResponse := &coinmate.Response{
@@ -457,6 +565,54 @@ func TestSellInstantHTTPError(t *testing.T) {
 	}
 }
 
+func TestReplaceByBuyLimitSuccess(t *testing.T) {
+	// Create mock response
+	mockResponse := &coinmate.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Body: []byte(`{
+			"error": false,
+			"errorMessage": "",
+			"data": {
+				"success": true,
+				"remainingAmount": 0.4,
+				"createdOrderId": 12346
+			}
+		}`),
+	}
+
+	mockClient := &MockSecureClient{response: mockResponse}
+	order := &Order{Client: mockClient}
+
+	response, err := order.ReplaceByBuyLimit(12345, 0.5, 49000.0, 0.0, "BTC_EUR", false, false, 0)
+
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if !response.Data.Success || response.Data.CreatedOrderId != 12346 || response.Data.RemainingAmount != 0.4 {
+		t.Errorf("Expected replaced order 12346, got %+v", response.Data)
+	}
+}
+
+func TestReplaceBySellLimitHTTPError(t *testing.T) {
+	// Create mock HTTP error
+	mockResponse := &coinmate.Response{
+		StatusCode: http.StatusBadRequest,
+		Status:     "400 Bad Request",
+		Body:       []byte("Bad Request"),
+	}
+
+	mockClient := &MockSecureClient{response: mockResponse}
+	order := &Order{Client: mockClient}
+
+	_, err := order.ReplaceBySellLimit(12345, 0.5, 51000.0, 0.0, "BTC_EUR", false, false, 0)
+
+	if err == nil {
+		t.Errorf("Expected error for non-200 response")
+	}
+}
+
 func TestOrderDataStructures(t *testing.T) {
 	// Test OrderHistoryData marshaling/unmarshaling
 	historyData := OrderHistoryData{
@@ -515,3 +671,24 @@ func TestOrderDataStructures(t *testing.T) {
 		t.Errorf("Expected ID to be %d, got %d", openOrderData.Id, unmarshaledOpenData.Id)
 	}
 }
+
+func TestOrderImplementsOrderInterface(t *testing.T) {
+	var o OrderInterface = &Order{Client: &MockSecureClient{}}
+	if o == nil {
+		t.Error("Expected Order to implement OrderInterface")
+	}
+}
+
+func TestOrderImplementsOrderLookupInterface(t *testing.T) {
+	var o OrderLookupInterface = &Order{Client: &MockSecureClient{}}
+	if o == nil {
+		t.Error("Expected Order to implement OrderLookupInterface")
+	}
+}
+
+func TestOrderImplementsOrderReplaceInterface(t *testing.T) {
+	var o OrderReplaceInterface = &Order{Client: &MockSecureClient{}}
+	if o == nil {
+		t.Error("Expected Order to implement OrderReplaceInterface")
+	}
+}
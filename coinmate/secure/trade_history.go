@@ -0,0 +1,116 @@
+package secure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"tourGo/coinmate"
+)
+
+const (
+	tradeHistoryEndpoint   = "/tradeHistory"
+	offsetParamName        = "offset"
+	lastIdParamName        = "lastId"
+	sortParamName          = "sort"
+	timestampFromParamName = "timestampFrom"
+	timestampToParamName   = "timestampTo"
+)
+
+type TradeHistory struct {
+	Client coinmate.ClientInterface
+}
+
+// Trade history operations, implemented by TradeHistory
+type TradeHistoryInterface interface {
+	GetTradeHistory(params TradeHistoryParams) (TradeHistoryResponse, error)
+}
+
+// Optional trade history filters, zero values are not sent
+type TradeHistoryParams struct {
+	Limit         int64
+	Offset        int64
+	LastId        uint64
+	Sort          string // ASC or DESC
+	TimestampFrom int64
+	TimestampTo   int64
+	CurrencyPair  string
+	OrderId       uint64
+}
+
+// Trade history response
+type TradeHistoryResponse struct {
+	Error        bool               `json:"error"`
+	ErrorMessage string             `json:"errorMessage"`
+	Data         []TradeHistoryData `json:"data"`
+}
+
+// Trade history data
+type TradeHistoryData struct {
+	TransactionId    uint64  `json:"transactionId"`
+	CreatedTimestamp int64   `json:"createdTimestamp"`
+	CurrencyPair     string  `json:"currencyPair"`
+	Type             string  `json:"type"`
+	OrderType        string  `json:"orderType"`
+	OrderId          uint64  `json:"orderId"`
+	Amount           float64 `json:"amount"`
+	Price            float64 `json:"price"`
+	Fee              float64 `json:"fee"`
+	FeeType          string  `json:"feeType"`
+}
+
+// Trade history endpoint
+func (t *TradeHistory) GetTradeHistory(params TradeHistoryParams) (TradeHistoryResponse, error) {
+	tradeHistoryResponse := TradeHistoryResponse{}
+
+	ap := map[string]string{}
+	if params.Limit > 0 {
+		ap[limitReturnedOrders] = strconv.FormatInt(params.Limit, 10)
+	}
+	if params.Offset > 0 {
+		ap[offsetParamName] = strconv.FormatInt(params.Offset, 10)
+	}
+	if params.LastId > 0 {
+		ap[lastIdParamName] = strconv.FormatUint(params.LastId, 10)
+	}
+	if params.Sort != "" {
+		sort := strings.ToUpper(params.Sort)
+		if sort != "ASC" && sort != "DESC" {
+			return tradeHistoryResponse, fmt.Errorf("invalid sort %q, expected ASC or DESC", params.Sort)
+		}
+		ap[sortParamName] = sort
+	}
+	if params.TimestampFrom > 0 {
+		ap[timestampFromParamName] = strconv.FormatInt(params.TimestampFrom, 10)
+	}
+	if params.TimestampTo > 0 {
+		ap[timestampToParamName] = strconv.FormatInt(params.TimestampTo, 10)
+	}
+	if params.CurrencyPair != "" {
+		ap[currencyPairParamName] = strings.ToUpper(params.CurrencyPair)
+	}
+	if params.OrderId > 0 {
+		ap[orderIdParamName] = strconv.FormatUint(params.OrderId, 10)
+	}
+
+	r := coinmate.Request{
+		HTTPMethod: http.MethodPost,
+		URL:        t.Client.GetBaseUrl() + tradeHistoryEndpoint,
+		Body:       t.Client.GetRequestBody(ap),
+	}
+	response, err := t.Client.MakeSecureRequest(r)
+	if err != nil {
+		return tradeHistoryResponse, fmt.Errorf("trade history request failed: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return tradeHistoryResponse, fmt.Errorf("trade history request failed: status=%d body=%s", response.StatusCode, string(response.Body))
+	}
+
+	err = json.Unmarshal(response.Body, &tradeHistoryResponse)
+	if err != nil {
+		return tradeHistoryResponse, fmt.Errorf("failed to decode trade history response: %w", err)
+	}
+
+	return tradeHistoryResponse, err
+}
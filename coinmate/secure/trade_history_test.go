@@ -0,0 +1,93 @@
+package secure
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"tourGo/coinmate"
+)
+
+func TestGetTradeHistorySuccess(t *testing.T) {
+	mockResponse := &coinmate.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Body: []byte(`{
+			"error": false,
+			"errorMessage": null,
+			"data": [
+				{
+					"transactionId": 2671819,
+					"createdTimestamp": 1529649127605,
+					"currencyPair": "BTC_EUR",
+					"type": "BUY",
+					"orderType": "LIMIT",
+					"orderId": 101879,
+					"amount": 0.00005,
+					"price": 5555.55,
+					"fee": 0.00069444375,
+					"feeType": "MAKER"
+				}
+			]
+		}`),
+	}
+
+	mockClient := &MockSecureClient{response: mockResponse}
+	tradeHistory := &TradeHistory{Client: mockClient}
+
+	response, err := tradeHistory.GetTradeHistory(TradeHistoryParams{CurrencyPair: "BTC_EUR", Limit: 10, Sort: "desc"})
+
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if len(response.Data) != 1 {
+		t.Fatalf("Expected 1 trade, got %d", len(response.Data))
+	}
+
+	trade := response.Data[0]
+	if trade.TransactionId != 2671819 || trade.OrderId != 101879 || trade.FeeType != "MAKER" {
+		t.Errorf("Unexpected trade %+v", trade)
+	}
+}
+
+func TestGetTradeHistoryInvalidSort(t *testing.T) {
+	tradeHistory := &TradeHistory{Client: &MockSecureClient{}}
+
+	_, err := tradeHistory.GetTradeHistory(TradeHistoryParams{Sort: "newest"})
+
+	if err == nil {
+		t.Error("Expected error for invalid sort")
+	}
+}
+
+func TestGetTradeHistoryHTTPError(t *testing.T) {
+	mockResponse := &coinmate.Response{
+		StatusCode: http.StatusInternalServerError,
+		Status:     "500 Internal Server Error",
+		Body:       []byte(`Internal Server Error`),
+	}
+
+	mockClient := &MockSecureClient{response: mockResponse}
+	tradeHistory := &TradeHistory{Client: mockClient}
+
+	_, err := tradeHistory.GetTradeHistory(TradeHistoryParams{})
+
+	if err == nil {
+		t.Error("Expected error for HTTP 500")
+	}
+}
+
+func TestGetTradeHistoryNetworkError(t *testing.T) {
+	mockClient := &MockSecureClient{err: errors.New("network error")}
+	tradeHistory := &TradeHistory{Client: mockClient}
+
+	_, err := tradeHistory.GetTradeHistory(TradeHistoryParams{})
+
+	if err == nil {
+		t.Error("Expected network error")
+	}
+}
+
+func TestTradeHistoryImplementsTradeHistoryInterface(t *testing.T) {
+	var _ TradeHistoryInterface = &TradeHistory{}
+}
@@ -0,0 +1,108 @@
+package secure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"tourGo/coinmate"
+)
+
+const (
+	transferHistoryEndpoint = "/transferHistory"
+	currencyParamName       = "currency"
+
+	TransferDeposit    = "DEPOSIT"
+	TransferWithdrawal = "WITHDRAWAL"
+	TransferCompleted  = "COMPLETED"
+)
+
+type TransferHistory struct {
+	Client coinmate.ClientInterface
+}
+
+// Transfer history operations, implemented by TransferHistory
+type TransferHistoryInterface interface {
+	GetTransferHistory(params TransferHistoryParams) (TransferHistoryResponse, error)
+}
+
+// Optional transfer history filters, zero values are not sent
+type TransferHistoryParams struct {
+	Limit         int64
+	LastId        uint64
+	Sort          string // ASC or DESC
+	TimestampFrom int64
+	TimestampTo   int64
+	Currency      string
+}
+
+// Transfer history response
+type TransferHistoryResponse struct {
+	Error        bool                  `json:"error"`
+	ErrorMessage string                `json:"errorMessage"`
+	Data         []TransferHistoryData `json:"data"`
+}
+
+// Deposit or withdrawal
+type TransferHistoryData struct {
+	TransactionId  uint64  `json:"transactionId"`
+	Timestamp      int64   `json:"timestamp"`
+	AmountCurrency string  `json:"amountCurrency"`
+	Amount         float64 `json:"amount"`
+	Fee            float64 `json:"fee"`
+	WalletType     string  `json:"walletType"`
+	TransferType   string  `json:"transferType"`
+	TransferStatus string  `json:"transferStatus"`
+	Txid           string  `json:"txid"`
+	Destination    string  `json:"destination"`
+}
+
+// Transfer history endpoint
+func (t *TransferHistory) GetTransferHistory(params TransferHistoryParams) (TransferHistoryResponse, error) {
+	transferHistoryResponse := TransferHistoryResponse{}
+
+	ap := map[string]string{}
+	if params.Limit > 0 {
+		ap[limitReturnedOrders] = strconv.FormatInt(params.Limit, 10)
+	}
+	if params.LastId > 0 {
+		ap[lastIdParamName] = strconv.FormatUint(params.LastId, 10)
+	}
+	if params.Sort != "" {
+		sort := strings.ToUpper(params.Sort)
+		if sort != "ASC" && sort != "DESC" {
+			return transferHistoryResponse, fmt.Errorf("invalid sort %q, expected ASC or DESC", params.Sort)
+		}
+		ap[sortParamName] = sort
+	}
+	if params.TimestampFrom > 0 {
+		ap[timestampFromParamName] = strconv.FormatInt(params.TimestampFrom, 10)
+	}
+	if params.TimestampTo > 0 {
+		ap[timestampToParamName] = strconv.FormatInt(params.TimestampTo, 10)
+	}
+	if params.Currency != "" {
+		ap[currencyParamName] = strings.ToUpper(params.Currency)
+	}
+
+	r := coinmate.Request{
+		HTTPMethod: http.MethodPost,
+		URL:        t.Client.GetBaseUrl() + transferHistoryEndpoint,
+		Body:       t.Client.GetRequestBody(ap),
+	}
+	response, err := t.Client.MakeSecureRequest(r)
+	if err != nil {
+		return transferHistoryResponse, fmt.Errorf("transfer history request failed: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return transferHistoryResponse, fmt.Errorf("transfer history request failed: status=%d body=%s", response.StatusCode, string(response.Body))
+	}
+
+	err = json.Unmarshal(response.Body, &transferHistoryResponse)
+	if err != nil {
+		return transferHistoryResponse, fmt.Errorf("failed to decode transfer history response: %w", err)
+	}
+
+	return transferHistoryResponse, err
+}
@@ -0,0 +1,93 @@
+package secure
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"tourGo/coinmate"
+)
+
+func TestGetTransferHistorySuccess(t *testing.T) {
+	mockResponse := &coinmate.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Body: []byte(`{
+			"error": false,
+			"errorMessage": null,
+			"data": [
+				{
+					"transactionId": 1347712,
+					"timestamp": 1529649127605,
+					"amountCurrency": "BTC",
+					"amount": 0.5,
+					"fee": 0.0004,
+					"walletType": "BTC",
+					"transferType": "WITHDRAWAL",
+					"transferStatus": "COMPLETED",
+					"txid": "9f2c",
+					"destination": "bc1q"
+				}
+			]
+		}`),
+	}
+
+	mockClient := &MockSecureClient{response: mockResponse}
+	transferHistory := &TransferHistory{Client: mockClient}
+
+	response, err := transferHistory.GetTransferHistory(TransferHistoryParams{Currency: "btc", Limit: 10, Sort: "asc"})
+
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if len(response.Data) != 1 {
+		t.Fatalf("Expected 1 transfer, got %d", len(response.Data))
+	}
+
+	transfer := response.Data[0]
+	if transfer.TransactionId != 1347712 || transfer.TransferType != TransferWithdrawal || transfer.Fee != 0.0004 {
+		t.Errorf("Unexpected transfer %+v", transfer)
+	}
+}
+
+func TestGetTransferHistoryInvalidSort(t *testing.T) {
+	transferHistory := &TransferHistory{Client: &MockSecureClient{}}
+
+	_, err := transferHistory.GetTransferHistory(TransferHistoryParams{Sort: "newest"})
+
+	if err == nil {
+		t.Error("Expected error for invalid sort")
+	}
+}
+
+func TestGetTransferHistoryHTTPError(t *testing.T) {
+	mockResponse := &coinmate.Response{
+		StatusCode: http.StatusInternalServerError,
+		Status:     "500 Internal Server Error",
+		Body:       []byte(`Internal Server Error`),
+	}
+
+	mockClient := &MockSecureClient{response: mockResponse}
+	transferHistory := &TransferHistory{Client: mockClient}
+
+	_, err := transferHistory.GetTransferHistory(TransferHistoryParams{})
+
+	if err == nil {
+		t.Error("Expected error for HTTP 500")
+	}
+}
+
+func TestGetTransferHistoryNetworkError(t *testing.T) {
+	mockClient := &MockSecureClient{err: errors.New("network error")}
+	transferHistory := &TransferHistory{Client: mockClient}
+
+	_, err := transferHistory.GetTransferHistory(TransferHistoryParams{})
+
+	if err == nil {
+		t.Error("Expected network error")
+	}
+}
+
+func TestTransferHistoryImplementsTransferHistoryInterface(t *testing.T) {
+	var _ TransferHistoryInterface = &TransferHistory{}
+}
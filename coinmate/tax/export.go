@@ -0,0 +1,85 @@
+package tax
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+var csvHeader = []string{"date", "kind", "source", "currency", "amount", "acquired", "proceeds", "cost", "gain", "exempt"}
+
+// Write report lines as CSV with a header row, amounts with a dot as the
+// decimal separator
+func (r Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	for _, l := range r.Lines {
+		record := []string{
+			r.date(l.Timestamp),
+			string(l.Kind),
+			strconv.FormatUint(l.Source, 10),
+			l.Currency,
+			strconv.FormatFloat(l.Amount, 'f', 8, 64),
+			r.date(l.Acquired),
+			strconv.FormatFloat(l.Proceeds, 'f', 2, 64),
+			strconv.FormatFloat(l.Cost, 'f', 2, 64),
+			strconv.FormatFloat(l.Gain, 'f', 2, 64),
+			l.Exempt,
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV line: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// Write report as a printable text table followed by the totals
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	c := r.Currency
+
+	fmt.Fprintf(tw, "Tax report %d\t%s\t%s\t\n\n", r.Year, r.Rules, r.Method)
+	fmt.Fprintf(tw, "Date\tKind\tCurrency\tAmount\tAcquired\tProceeds %s\tCost %s\tGain %s\tExempt\t\n", c, c, c)
+	for _, l := range r.Lines {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.8f\t%s\t%.2f\t%.2f\t%.2f\t%s\t\n",
+			r.date(l.Timestamp), l.Kind, l.Currency, l.Amount, r.date(l.Acquired), l.Proceeds, l.Cost, l.Gain, l.Exempt)
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "Proceeds\t%.2f %s\t\n", r.Proceeds, c)
+	fmt.Fprintf(tw, "Cost\t%.2f %s\t\n", r.Cost, c)
+	fmt.Fprintf(tw, "Gain\t%.2f %s\t\n", r.Gain, c)
+	fmt.Fprintf(tw, "Exempt by holding period\t%.2f %s\t\n", r.ExemptGain, c)
+	if r.YearExempt != "" {
+		fmt.Fprintf(tw, "Year exempt\t%s\t\n", r.YearExempt)
+	}
+	fmt.Fprintf(tw, "Taxable gain\t%.2f %s\t\n", r.TaxableGain, c)
+	fmt.Fprintf(tw, "Fees\t%.2f %s\t\n", r.Fees, c)
+	fmt.Fprintf(tw, "Deposited\t%.2f %s\t\n", r.Deposited, c)
+	fmt.Fprintf(tw, "Withdrawn\t%.2f %s\t\n", r.Withdrawn, c)
+	return tw.Flush()
+}
+
+// Helper functions
+
+func (r Report) date(timestamp int64) string {
+	if timestamp == 0 {
+		return ""
+	}
+	loc := r.location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return time.UnixMilli(timestamp).In(loc).Format(dateLayout)
+}
@@ -0,0 +1,175 @@
+package tax
+
+import (
+	"math"
+	"strings"
+	"time"
+	"tourGo/coinmate/pnl"
+	"tourGo/coinmate/secure"
+)
+
+const epsilon = 1e-9
+
+// Why a line is not taxed
+const (
+	ExemptHolding  = "holding period"
+	ExemptProceeds = "proceeds below limit"
+	ExemptGain     = "gain below limit"
+)
+
+// Taxable or exempt part of one disposal
+type Line struct {
+	Timestamp int64    `json:"timestamp"`
+	Kind      pnl.Kind `json:"kind"`
+	Source    uint64   `json:"source,omitempty"`
+	Currency  string   `json:"currency"`
+	Amount    float64  `json:"amount"`
+	Proceeds  float64  `json:"proceeds"`
+	Cost      float64  `json:"cost"`
+	Gain      float64  `json:"gain"`
+	// Earliest acquisition of the units, zero when unknown
+	Acquired int64 `json:"acquired,omitempty"`
+	// Empty when taxable
+	Exempt string `json:"exempt,omitempty"`
+}
+
+// Gains of one year in the rules currency
+type Report struct {
+	Year     int    `json:"year"`
+	Rules    string `json:"rules"`
+	Currency string `json:"currency"`
+	Method   string `json:"method"`
+	Lines    []Line `json:"lines"`
+
+	// Totals of lines not exempt by the holding period
+	Proceeds float64 `json:"proceeds"`
+	Cost     float64 `json:"cost"`
+	Gain     float64 `json:"gain"`
+	// Totals of lines exempt by the holding period
+	ExemptProceeds float64 `json:"exemptProceeds"`
+	ExemptGain     float64 `json:"exemptGain"`
+	// Gain to declare, zero for a net loss or when the year is exempt
+	TaxableGain float64 `json:"taxableGain"`
+	// Set when the yearly limits exempt the whole year
+	YearExempt string `json:"yearExempt,omitempty"`
+
+	Fees      float64 `json:"fees"`
+	Deposited float64 `json:"deposited"`
+	Withdrawn float64 `json:"withdrawn"`
+
+	// Location dates are printed in
+	location *time.Location
+}
+
+// Exporter turns the account history into yearly tax reports
+type Exporter struct {
+	Trades    secure.TradeHistoryInterface
+	Transfers secure.TransferHistoryInterface
+	Rules     Rules
+	// Converts trades, fees and transfers not in the rules currency
+	Convert pnl.Converter
+}
+
+// Return exporter, transfers may be nil when there are none to take into
+// account
+func NewExporter(trades secure.TradeHistoryInterface, transfers secure.TransferHistoryInterface, rules Rules) *Exporter {
+	return &Exporter{Trades: trades, Transfers: transfers, Rules: rules}
+}
+
+// Report of a year, lots are built from the full history by pnl.Engine
+func (e *Exporter) Report(year int) (Report, error) {
+	engine := pnl.NewEngine(e.Trades, nil, e.Rules.Currency, e.Rules.Method)
+	engine.Convert, engine.TransferHistory = e.Convert, e.Transfers
+	ledger, err := engine.Ledger()
+	if err != nil {
+		return Report{}, err
+	}
+	return Build(ledger, e.Rules, year), nil
+}
+
+// Report of a year from a ledger valued in the rules currency
+func Build(ledger *pnl.Ledger, rules Rules, year int) Report {
+	loc := rules.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, loc)
+	to := from.AddDate(1, 0, 0)
+
+	r := Report{Year: year, Rules: rules.Name, Currency: strings.ToUpper(rules.Currency), Method: string(rules.Method), location: loc}
+	for _, realization := range ledger.Realizations(from, to) {
+		for _, line := range split(realization, rules) {
+			r.Lines = append(r.Lines, line)
+			if line.Exempt != "" {
+				r.ExemptProceeds += line.Proceeds
+				r.ExemptGain += line.Gain
+				continue
+			}
+			r.Proceeds += line.Proceeds
+			r.Cost += line.Cost
+			r.Gain += line.Gain
+		}
+	}
+	for _, f := range ledger.Flows(from, to) {
+		switch f.Kind {
+		case pnl.KindDeposit:
+			r.Deposited += f.Value
+		case pnl.KindWithdrawal:
+			r.Withdrawn += f.Value
+		case pnl.KindFee:
+			r.Fees += f.Value
+		}
+	}
+
+	r.TaxableGain = math.Max(0, r.Gain)
+	switch {
+	case rules.ProceedsExemption > 0 && r.Proceeds <= rules.ProceedsExemption:
+		r.YearExempt = ExemptProceeds
+	case rules.GainExemption > 0 && r.Gain < rules.GainExemption:
+		r.YearExempt = ExemptGain
+	}
+	if r.YearExempt != "" {
+		r.TaxableGain = 0
+	}
+	return r
+}
+
+// Helper functions
+
+// Split a disposal into the part held longer than the holding period and the
+// rest, proceeds are shared by amount
+func split(r pnl.Realization, rules Rules) []Line {
+	disposed := time.UnixMilli(r.Timestamp)
+	taxable := Line{Timestamp: r.Timestamp, Kind: r.Kind, Source: r.Source, Currency: r.Currency, Amount: r.Unmatched}
+	exempt := taxable
+	exempt.Amount, exempt.Exempt = 0, ExemptHolding
+
+	for _, m := range r.Matches {
+		line := &taxable
+		if rules.HoldingYears > 0 && time.UnixMilli(m.Acquired).AddDate(rules.HoldingYears, 0, 0).Before(disposed) {
+			line = &exempt
+		}
+		line.Amount += m.Amount
+		line.Cost += m.Cost
+		if line.Acquired == 0 || m.Acquired < line.Acquired {
+			line.Acquired = m.Acquired
+		}
+	}
+	if r.Unmatched > 0 {
+		// Units of unknown age are never exempt
+		taxable.Acquired = 0
+	}
+
+	var lines []Line
+	for _, line := range []Line{taxable, exempt} {
+		if line.Amount <= epsilon {
+			continue
+		}
+		if r.Amount > 0 {
+			line.Proceeds = r.Proceeds * line.Amount / r.Amount
+		}
+		line.Gain = line.Proceeds - line.Cost
+		lines = append(lines, line)
+	}
+	return lines
+}
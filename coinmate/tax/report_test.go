@@ -0,0 +1,132 @@
+package tax
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+	"tourGo/coinmate/coinmatetest"
+	"tourGo/coinmate/pnl"
+	"tourGo/coinmate/secure"
+)
+
+func millis(year int, month time.Month, day int) int64 {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC).UnixMilli()
+}
+
+func trade(id uint64, timestamp int64, side string, amount, price float64) secure.TradeHistoryData {
+	return secure.TradeHistoryData{TransactionId: id, CreatedTimestamp: timestamp, CurrencyPair: "BTC_CZK", Type: side, Amount: amount, Price: price}
+}
+
+func ledger(t *testing.T, trades ...secure.TradeHistoryData) *pnl.Ledger {
+	t.Helper()
+	l := pnl.NewLedger("CZK", pnl.FIFO)
+	if err := l.Apply(trades, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return l
+}
+
+func TestHoldingPeriod(t *testing.T) {
+	l := ledger(t,
+		trade(1, millis(2020, 1, 10), "BUY", 1, 200000),
+		trade(2, millis(2023, 6, 1), "BUY", 1, 600000),
+		trade(3, millis(2024, 3, 1), "SELL", 1.5, 1500000),
+	)
+
+	r := Build(l, CzechRules(), 2024)
+	if len(r.Lines) != 2 {
+		t.Fatalf("Expected taxable and exempt lines, got %+v", r.Lines)
+	}
+	taxable, exempt := r.Lines[0], r.Lines[1]
+	if taxable.Exempt != "" || taxable.Amount != 0.5 || taxable.Proceeds != 750000 || taxable.Gain != 450000 || taxable.Acquired != millis(2023, 6, 1) {
+		t.Errorf("Expected 0.5 BTC taxable with gain 450000, got %+v", taxable)
+	}
+	if exempt.Exempt != ExemptHolding || exempt.Amount != 1 || exempt.Gain != 1300000 {
+		t.Errorf("Expected 1 BTC exempt by holding period, got %+v", exempt)
+	}
+	if r.Gain != 450000 || r.ExemptGain != 1300000 || r.TaxableGain != 450000 || r.YearExempt != "" {
+		t.Errorf("Expected taxable gain 450000, got %+v", r)
+	}
+
+	if r := Build(l, SlovakRules(), 2024); len(r.Lines) != 1 || r.Gain != 1750000 {
+		t.Errorf("Expected everything taxable without a holding test, got %+v", r)
+	}
+	if r := Build(l, CzechRules(), 2023); len(r.Lines) != 0 || r.TaxableGain != 0 {
+		t.Errorf("Expected nothing in 2023, got %+v", r)
+	}
+}
+
+func TestYearlyExemptions(t *testing.T) {
+	l := ledger(t,
+		trade(1, millis(2024, 1, 10), "BUY", 0.1, 1000000),
+		trade(2, millis(2024, 6, 1), "SELL", 0.05, 1400000),
+		trade(3, millis(2024, 7, 1), "SELL", 0.05, 600000),
+	)
+
+	r := Build(l, CzechRules(), 2024)
+	if r.Proceeds != 100000 || r.Gain != 0 || r.YearExempt != ExemptProceeds || r.TaxableGain != 0 {
+		t.Errorf("Expected year exempt with proceeds of 100000, got %+v", r)
+	}
+
+	rules := GermanRules()
+	rules.Currency = "CZK"
+	l = ledger(t,
+		trade(1, millis(2024, 1, 10), "BUY", 0.1, 1000000),
+		trade(2, millis(2024, 6, 1), "SELL", 0.1, 1009000),
+	)
+	if r := Build(l, rules, 2024); r.Gain != 900 || r.YearExempt != ExemptGain {
+		t.Errorf("Expected gain below the limit exempt, got %+v", r)
+	}
+}
+
+func TestExporter(t *testing.T) {
+	server := coinmatetest.NewServer()
+	t.Cleanup(server.Close)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	server.SetClock(func() time.Time { return now })
+	server.AddAccount("1", "public-key", "private-key")
+	client := server.NewClient("1")
+	orders := &secure.Order{Client: client}
+
+	server.AddTransfer("1", "CZK", secure.TransferDeposit, 1000000, 0)
+	server.AddTransfer("1", "EUR", secure.TransferDeposit, 10000, 0)
+	server.AddLiquidity("BTC_CZK", "SELL", 1250000, 1)
+	server.AddLiquidity("BTC_EUR", "BUY", 60000, 1)
+	orders.BuyInstant(500000, "BTC_CZK", 0)
+	now = now.AddDate(0, 2, 0)
+	orders.SellInstant(0.1, "BTC_EUR", 0)
+	now = now.Add(time.Hour)
+	server.AddTransfer("1", "BTC", secure.TransferWithdrawal, 0.2, 0.001)
+
+	exporter := NewExporter(&secure.TradeHistory{Client: client}, &secure.TransferHistory{Client: client}, CzechRules())
+	exporter.Convert = UniformRates{2024: {"EUR": 25}}.Convert
+	r, err := exporter.Report(2024)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 0.1 BTC bought for 125000 CZK sold for 6000 EUR, withdrawal fee at cost
+	if len(r.Lines) != 2 || math.Abs(r.Proceeds-150000) > 1e-6 || math.Abs(r.Gain-23750) > 1e-6 || math.Abs(r.TaxableGain-23750) > 1e-6 {
+		t.Errorf("Expected gain 23750 from the sell and the fee, got %+v", r)
+	}
+	if math.Abs(r.Deposited-1250000) > 1e-6 || math.Abs(r.Withdrawn-250000) > 1e-6 || math.Abs(r.Fees-1250) > 1e-6 {
+		t.Errorf("Expected deposits 1250000 and withdrawals 250000, got %+v", r)
+	}
+
+	var csv bytes.Buffer
+	if err := r.WriteCSV(&csv); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	rows := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(rows) != 3 || rows[0] != strings.Join(csvHeader, ",") || !strings.HasPrefix(rows[1], "2024-05-01,SELL,") || !strings.Contains(rows[1], ",2024-03-01,150000.00,125000.00,25000.00,") {
+		t.Errorf("Unexpected CSV:\n%s", csv.String())
+	}
+
+	var text bytes.Buffer
+	r.WriteText(&text)
+	if !strings.Contains(text.String(), "Taxable gain              23750.00 CZK") {
+		t.Errorf("Unexpected report:\n%s", text.String())
+	}
+}
@@ -0,0 +1,106 @@
+package tax
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// Year boundaries do not depend on the zone database of the host
+	_ "time/tzdata"
+	"tourGo/coinmate/pnl"
+)
+
+// Jurisdiction rules of a yearly report
+type Rules struct {
+	Name string
+	// Currency of the report, all amounts are converted to it
+	Currency string
+	Method   pnl.Method
+	// Disposals of units held longer are exempt, zero for no holding test
+	HoldingYears int
+	// Taxable gains of a year are exempt when the taxable proceeds of the
+	// year do not exceed this, zero for no limit
+	ProceedsExemption float64
+	// Taxable gains of a year are exempt when they are below this, zero for
+	// no limit
+	GainExemption float64
+	// Location of year boundaries, UTC when nil
+	Location *time.Location
+}
+
+// Czech rules since 2025: units held over three years and years with gross
+// proceeds up to 100 000 CZK are exempt
+func CzechRules() Rules {
+	return Rules{
+		Name:              "CZ",
+		Currency:          "CZK",
+		Method:            pnl.FIFO,
+		HoldingYears:      3,
+		ProceedsExemption: 100000,
+		Location:          location("Europe/Prague"),
+	}
+}
+
+// Slovak rules: all gains are taxable
+func SlovakRules() Rules {
+	return Rules{
+		Name:     "SK",
+		Currency: "EUR",
+		Method:   pnl.FIFO,
+		Location: location("Europe/Bratislava"),
+	}
+}
+
+// German rules: units held over one year are exempt, as are yearly gains
+// below 1 000 EUR
+func GermanRules() Rules {
+	return Rules{
+		Name:          "DE",
+		Currency:      "EUR",
+		Method:        pnl.FIFO,
+		HoldingYears:  1,
+		GainExemption: 1000,
+		Location:      location("Europe/Berlin"),
+	}
+}
+
+// Rules by name, e.g. "CZ"
+func RulesFor(name string) (Rules, error) {
+	switch strings.ToUpper(name) {
+	case "CZ":
+		return CzechRules(), nil
+	case "SK":
+		return SlovakRules(), nil
+	case "DE":
+		return GermanRules(), nil
+	}
+	return Rules{}, fmt.Errorf("unknown jurisdiction %q", name)
+}
+
+// Yearly exchange rates of one unit of a currency in the report currency,
+// e.g. the uniform rates published by the Czech tax administration
+type UniformRates map[int]map[string]float64
+
+// Convert at the rate of the year of at, usable as pnl.Converter
+func (u UniformRates) Convert(amount float64, from, to string, at time.Time) (float64, error) {
+	if from == to {
+		return amount, nil
+	}
+	if rate, ok := u[at.Year()][strings.ToUpper(from)]; ok {
+		return amount * rate, nil
+	}
+	if rate, ok := u[at.Year()][strings.ToUpper(to)]; ok && rate > 0 {
+		return amount / rate, nil
+	}
+	return 0, fmt.Errorf("no %d rate between %s and %s", at.Year(), from, to)
+}
+
+// Helper functions
+
+// Location of the rules, always in the embedded zone database
+func location(name string) *time.Location {
+	l, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}